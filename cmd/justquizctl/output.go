@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// table is the output of a command as columns of text.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(cells ...any) {
+	row := make([]string, len(cells))
+	for i, cell := range cells {
+		row[i] = cellText(cell)
+	}
+	t.rows = append(t.rows, row)
+}
+
+// cellText formats a value of a table cell. Missing values are shown as a dash.
+func cellText(v any) string {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return "-"
+		}
+		return v
+	case []string:
+		if len(v) == 0 {
+			return "-"
+		}
+		return strings.Join(v, ",")
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return "-"
+		}
+		return v.Format(time.RFC3339)
+	case *int64:
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprint(*v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// print prints the result of a command: v as indented JSON with -format json, t otherwise.
+func (c *ctl) print(v any, t *table) error {
+	if c.format == "json" {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "\t")
+		return enc.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+// printList prints a page of records. With -format json they are in the envelope of the API,
+// under name and with the metadata, otherwise the table is followed by the page it shows.
+func (c *ctl) printList(name string, records any, metadata model.Metadata, t *table) error {
+	if c.format == "json" {
+		return c.print(map[string]any{name: records, "metadata": metadata}, t)
+	}
+
+	if err := c.print(records, t); err != nil {
+		return err
+	}
+	if metadata.TotalRecords > 0 {
+		fmt.Fprintf(c.out, "\npage %d of %d, %d %s\n", metadata.CurrentPage, metadata.LastPage, metadata.TotalRecords, name)
+	}
+
+	return nil
+}
+
+// listFlags adds the -page, -page-size and -sort flags of a list to fs and returns the filters
+// they set. columns are the columns the list can be sorted by, in either direction.
+func listFlags(fs *flag.FlagSet, sort string, columns ...string) *model.Filters {
+	filters := &model.Filters{}
+	fs.IntVar(&filters.Page, "page", 1, "Page of the list")
+	fs.IntVar(&filters.PageSize, "page-size", 20, "Number of records per page")
+	fs.StringVar(&filters.Sort, "sort", sort, fmt.Sprintf("Column to sort by, prefixed with - to sort descending (%s)", strings.Join(columns, "|")))
+
+	for _, column := range columns {
+		filters.SortSafeList = append(filters.SortSafeList, column, "-"+column)
+	}
+
+	return filters
+}
+
+// validateFilters checks the flags of listFlags like the API checks the query string of a list.
+func validateFilters(filters model.Filters) error {
+	v := validator.New()
+	if model.ValidateFilters(v, filters); !v.Valid() {
+		return fmt.Errorf("invalid flags: %v", v.Errors)
+	}
+
+	return nil
+}
+
+// printf prints the confirmation of a change, only in table format, where it isn't mixed with
+// the JSON of the changed record.
+func (c *ctl) printf(format string, args ...any) {
+	if c.format != "json" {
+		fmt.Fprintf(c.out, format+"\n", args...)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// id parses the id of a record.
+func id(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid id %q", s)
+	}
+
+	return n, nil
+}
+
+func (c *ctl) playersCommand(ctx context.Context, args []string) error {
+	name, args, err := subcommand("players", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "list":
+		return c.listPlayers(ctx, args)
+	case "show":
+		return c.showPlayer(ctx, args)
+	case "create":
+		return c.createPlayer(ctx, args)
+	case "delete":
+		return c.deletePlayer(ctx, args)
+	default:
+		return fmt.Errorf("players: unknown subcommand %q", name)
+	}
+}
+
+func playersTable(players ...*model.Player) *table {
+	t := &table{header: []string{"ID", "NAME", "SCORE", "JOINED", "LAST UPDATE", "OWNER"}}
+	for _, p := range players {
+		t.add(p.Id, p.Name, p.Score, p.Joined, p.LastUpdate, p.OwnerID)
+	}
+
+	return t
+}
+
+// player returns the player with the id s.
+func (c *ctl) player(ctx context.Context, s string) (*model.Player, error) {
+	n, err := id(s)
+	if err != nil {
+		return nil, err
+	}
+
+	player, err := c.models.Players.Get(ctx, n)
+	if errors.Is(err, model.ErrRecordNotFound) {
+		return nil, fmt.Errorf("player %d not found", n)
+	}
+
+	return player, err
+}
+
+func (c *ctl) listPlayers(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("players list", flag.ContinueOnError)
+
+	var (
+		name      = fs.String("name", "", "Only list the players with the name")
+		scoreFrom = fs.Int("score-from", 0, "Only list the players with at least the score")
+		scoreTo   = fs.Int("score-to", 0, "Only list the players with at most the score")
+	)
+	filters := listFlags(fs, "id", "id", "name", "score", "joined")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := validateFilters(*filters); err != nil {
+		return err
+	}
+
+	players, metadata, err := c.models.Players.GetAll(ctx, *name, *scoreFrom, *scoreTo, model.TimeRange{}, *filters)
+	if err != nil {
+		return err
+	}
+
+	return c.printList("players", players, metadata, playersTable(players...))
+}
+
+func (c *ctl) showPlayer(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("players show", flag.ContinueOnError)
+	if err := parse(fs, args, "id"); err != nil {
+		return err
+	}
+
+	player, err := c.player(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return c.print(player, playersTable(player))
+}
+
+func (c *ctl) createPlayer(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("players create", flag.ContinueOnError)
+
+	var (
+		name  = fs.String("name", "", "Name of the player")
+		score = fs.Int("score", 0, "Score of the player")
+	)
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	player := &model.Player{Name: *name, Score: *score}
+	if err := c.insertPlayer(ctx, player); err != nil {
+		return err
+	}
+
+	return c.print(player, playersTable(player))
+}
+
+// insertPlayer validates and inserts a player. New players start without a score, so a score is
+// set by updating the player afterwards.
+func (c *ctl) insertPlayer(ctx context.Context, player *model.Player) error {
+	v := validator.New()
+	if model.ValidatePlayer(v, player); !v.Valid() {
+		return fmt.Errorf("invalid player: %v", v.Errors)
+	}
+
+	score := player.Score
+
+	err := c.models.Players.Insert(ctx, player)
+	if err != nil || score == 0 {
+		return err
+	}
+
+	player.Score = score
+	return c.models.Players.Update(ctx, player)
+}
+
+// deletePlayer deletes a player with their games.
+func (c *ctl) deletePlayer(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("players delete", flag.ContinueOnError)
+	if err := parse(fs, args, "id"); err != nil {
+		return err
+	}
+
+	player, err := c.player(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	n, _ := strconv.Atoi(player.Id)
+
+	err = c.models.Players.Delete(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	c.printf("deleted player %d", n)
+	return nil
+}
+
+func (c *ctl) quizesCommand(ctx context.Context, args []string) error {
+	name, args, err := subcommand("quizes", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "list":
+		return c.listQuizes(ctx, args)
+	case "show":
+		return c.showQuiz(ctx, args)
+	case "delete":
+		return c.deleteQuiz(ctx, args)
+	default:
+		return fmt.Errorf("quizes: unknown subcommand %q", name)
+	}
+}
+
+func quizesTable(quizes ...*model.Quiz) *table {
+	t := &table{header: []string{"ID", "CATEGORY", "REWARD", "QUESTIONS", "OWNER"}}
+	for _, q := range quizes {
+		t.add(q.Id, q.Category, q.Reward, len(q.Questions), q.OwnerID)
+	}
+
+	return t
+}
+
+// quiz returns the quiz with the id s.
+func (c *ctl) quiz(ctx context.Context, s string) (*model.Quiz, error) {
+	n, err := id(s)
+	if err != nil {
+		return nil, err
+	}
+
+	quiz, err := c.models.Quizes.Get(ctx, n)
+	if errors.Is(err, model.ErrRecordNotFound) {
+		return nil, fmt.Errorf("quiz %d not found", n)
+	}
+
+	return quiz, err
+}
+
+func (c *ctl) listQuizes(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("quizes list", flag.ContinueOnError)
+
+	var (
+		category   = fs.String("category", "", "Only list the quizes of the category")
+		rewardFrom = fs.Int("reward-from", 0, "Only list the quizes with at least the reward")
+		rewardTo   = fs.Int("reward-to", 0, "Only list the quizes with at most the reward")
+	)
+	filters := listFlags(fs, "id", "id", "category", "reward")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := validateFilters(*filters); err != nil {
+		return err
+	}
+
+	quizes, metadata, err := c.models.Quizes.GetAll(ctx, *category, *rewardFrom, *rewardTo, *filters)
+	if err != nil {
+		return err
+	}
+
+	return c.printList("quizes", quizes, metadata, quizesTable(quizes...))
+}
+
+// showQuiz shows a quiz with its questions and answers.
+func (c *ctl) showQuiz(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("quizes show", flag.ContinueOnError)
+	if err := parse(fs, args, "id"); err != nil {
+		return err
+	}
+
+	quiz, err := c.quiz(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	t := quizesTable(quiz)
+	if c.format != "json" {
+		if err := c.print(quiz, t); err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out)
+
+		t = &table{header: []string{"#", "QUESTION", "ANSWER"}}
+		for i, question := range quiz.Questions {
+			answer := ""
+			if i < len(quiz.Answers) {
+				answer = quiz.Answers[i]
+			}
+			t.add(i+1, question, answer)
+		}
+	}
+
+	return c.print(quiz, t)
+}
+
+// deleteQuiz deletes a quiz with its games.
+func (c *ctl) deleteQuiz(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("quizes delete", flag.ContinueOnError)
+	if err := parse(fs, args, "id"); err != nil {
+		return err
+	}
+
+	quiz, err := c.quiz(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	n, _ := strconv.Atoi(quiz.Id)
+
+	err = c.models.Quizes.Delete(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	c.printf("deleted quiz %d", n)
+	return nil
+}
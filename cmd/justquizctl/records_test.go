@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+func TestPlayers(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			models := backend.models(t)
+			try := func(args ...string) (string, error) { return runCtl(t, models, args...) }
+
+			player := decode(t, mustRunCtl(t, models, "players", "create", "-name", "alice", "-score", "30"))
+			if player["name"] != "alice" || player["score"] != float64(30) {
+				t.Errorf("got player %v, want alice with a score of 30", player)
+			}
+			playerID := jsonID(t, player["id"])
+			mustRunCtl(t, models, "players", "create", "-name", "bob")
+
+			wantError(t, try, "invalid player", "players", "create", "-name", "")
+
+			list := decode(t, mustRunCtl(t, models, "players", "list", "-score-from", "10"))
+			if players, _ := list["players"].([]any); len(players) != 1 {
+				t.Errorf("got players %v, want alice only", list["players"])
+			}
+
+			if shown := decode(t, mustRunCtl(t, models, "players", "show", playerID)); shown["name"] != "alice" {
+				t.Errorf("got player %v, want alice", shown)
+			}
+			wantError(t, try, `invalid id "alice"`, "players", "show", "alice")
+			wantError(t, try, "player 999 not found", "players", "show", "999")
+
+			mustRunCtl(t, models, "players", "delete", playerID)
+			wantError(t, try, "player "+playerID+" not found", "players", "delete", playerID)
+		})
+	}
+}
+
+func TestQuizes(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			models := backend.models(t)
+			try := func(args ...string) (string, error) { return runCtl(t, models, args...) }
+
+			for _, quiz := range []*model.Quiz{
+				{Category: "geography", Reward: 10, Questions: []string{"Capital of Kazakhstan?"}, Answers: []string{"Astana"}},
+				{Category: "history", Reward: 5, Questions: []string{"Year of the moon landing?"}, Answers: []string{"1969"}},
+			} {
+				if err := models.Quizes.Insert(context.Background(), quiz); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			list := decode(t, mustRunCtl(t, models, "quizes", "list", "-category", "history"))
+			quizes, _ := list["quizes"].([]any)
+			if len(quizes) != 1 {
+				t.Fatalf("got quizes %v, want the history quiz", list["quizes"])
+			}
+			quizID := jsonID(t, quizes[0].(map[string]any)["id"])
+
+			if shown := decode(t, mustRunCtl(t, models, "quizes", "show", quizID)); shown["category"] != "history" {
+				t.Errorf("got quiz %v, want the history quiz", shown)
+			}
+			wantError(t, try, "quiz 999 not found", "quizes", "show", "999")
+			wantError(t, try, `invalid id "0"`, "quizes", "show", "0")
+
+			// The table of a quiz shows its answers too.
+			var out strings.Builder
+			c := &ctl{models: models, format: "table", out: &out}
+			if err := c.run(context.Background(), []string{"quizes", "show", quizID}); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out.String(), "Year of the moon landing?  1969") {
+				t.Errorf("got output\n%s\nwant the question with its answer", out.String())
+			}
+
+			mustRunCtl(t, models, "quizes", "delete", quizID)
+			wantError(t, try, "quiz "+quizID+" not found", "quizes", "show", quizID)
+		})
+	}
+}
+
+// jsonID returns the id of a record in the JSON output of a command.
+func jsonID(t *testing.T, v any) string {
+	t.Helper()
+
+	s, ok := v.(string)
+	if !ok {
+		t.Fatalf("%v is not an id", v)
+	}
+
+	return s
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// dump is the file of an export or an import, the records as the API encodes them.
+type dump struct {
+	Players []*model.Player `json:"players"`
+	Quizes  []*model.Quiz   `json:"quizes"`
+	Games   []*model.Game   `json:"games"`
+}
+
+// transferred counts the records of an export or an import. Skipped records were in the file
+// but not imported.
+type transferred struct {
+	Kind    string `json:"kind"`
+	Records int    `json:"records"`
+	Skipped int    `json:"skipped,omitempty"`
+}
+
+func (c *ctl) printTransferred(counts ...transferred) error {
+	t := &table{header: []string{"KIND", "RECORDS", "SKIPPED"}}
+	for _, n := range counts {
+		t.add(n.Kind, n.Records, n.Skipped)
+	}
+
+	return c.print(counts, t)
+}
+
+// all returns the records of every page of list, which lists the records of filters.
+func all[T any](list func(filters model.Filters) ([]T, model.Metadata, error)) ([]T, error) {
+	filters := model.Filters{Page: 1, PageSize: 100, Sort: "id", SortSafeList: []string{"id"}}
+
+	var records []T
+	for {
+		page, metadata, err := list(filters)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, page...)
+
+		if filters.Page >= metadata.LastPage {
+			return records, nil
+		}
+		filters.Page++
+	}
+}
+
+// exportCommand writes the players, quizes and games to a file, or to the standard output, as
+// JSON regardless of -format. Users aren't exported, their passwords must not leave the
+// database.
+func (c *ctl) exportCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := fs.String("o", "-", "File to write the export to, - for the standard output")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	var (
+		d   dump
+		err error
+	)
+
+	d.Players, err = all(func(filters model.Filters) ([]*model.Player, model.Metadata, error) {
+		return c.models.Players.GetAll(ctx, "", 0, 0, model.TimeRange{}, filters)
+	})
+	if err != nil {
+		return err
+	}
+
+	d.Quizes, err = all(func(filters model.Filters) ([]*model.Quiz, model.Metadata, error) {
+		return c.models.Quizes.GetAll(ctx, "", 0, 0, filters)
+	})
+	if err != nil {
+		return err
+	}
+
+	d.Games, err = all(func(filters model.Filters) ([]*model.Game, model.Metadata, error) {
+		return c.models.Games.GetAll(ctx, 0, 0, false, model.TimeRange{}, filters)
+	})
+	if err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "\t")
+		return enc.Encode(d)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(d); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return c.printTransferred(
+		transferred{Kind: "players", Records: len(d.Players)},
+		transferred{Kind: "quizes", Records: len(d.Quizes)},
+		transferred{Kind: "games", Records: len(d.Games)},
+	)
+}
+
+// importCommand creates the players, quizes and games of a file written by export, e.g. of
+// another database, in one transaction: an import that fails leaves nothing behind. The records
+// get new ids, games are linked to the new ids of their player and quiz and skipped if either
+// isn't in the file. Times are kept, and players and quizes whose creator isn't a user of the
+// database have no owner. A file whose records were imported before is refused, as importing it
+// again would duplicate them.
+func (c *ctl) importCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	input := fs.String("i", "-", "File to read the import from, - for the standard input")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var d dump
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return fmt.Errorf("invalid import: %w", err)
+	}
+
+	for i, player := range d.Players {
+		v := validator.New()
+		if model.ValidatePlayer(v, player); !v.Valid() {
+			return fmt.Errorf("player %d of the import: invalid player: %v", i+1, v.Errors)
+		}
+	}
+	for i, quiz := range d.Quizes {
+		v := validator.New()
+		if model.ValidateQuiz(v, quiz); !v.Valid() {
+			return fmt.Errorf("quiz %d of the import: invalid quiz: %v", i+1, v.Errors)
+		}
+	}
+
+	// The digest is the one of the records rather than of the file, so that a file formatted
+	// differently is still recognized.
+	js, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(js)
+
+	skipped, err := c.models.Imports.Import(ctx, &model.Import{
+		Digest:  hex.EncodeToString(digest[:]),
+		Players: d.Players,
+		Quizes:  d.Quizes,
+		Games:   d.Games,
+	})
+	if errors.Is(err, model.ErrDuplicateImport) {
+		return errors.New("the records of the file were already imported")
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.printTransferred(
+		transferred{Kind: "players", Records: len(d.Players)},
+		transferred{Kind: "quizes", Records: len(d.Quizes)},
+		transferred{Kind: "games", Records: len(d.Games) - skipped, Skipped: skipped},
+	)
+}
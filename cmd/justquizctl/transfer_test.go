@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/migrations"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+// newSQLiteModels returns the models of a new SQLite database file, migrated with the embedded
+// migrations, and the database.
+func newSQLiteModels(t *testing.T) (model.Models, *sql.DB) {
+	t.Helper()
+
+	db, err := model.Open(model.SQLiteScheme + filepath.Join(t.TempDir(), "quiz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ups, err := fs.Glob(migrations.SQLite, "sqlite/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range ups {
+		migration, err := fs.ReadFile(migrations.SQLite, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(migration)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	return model.NewSQLiteModels(db, 3*time.Second), db
+}
+
+// backends are the models the commands are tested with, each call returns new, empty ones.
+var backends = []struct {
+	name   string
+	models func(t *testing.T) model.Models
+}{
+	{"memory", func(t *testing.T) model.Models { return model.NewMemoryModels() }},
+	{"sqlite", func(t *testing.T) model.Models {
+		models, _ := newSQLiteModels(t)
+		return models
+	}},
+}
+
+// runCtl runs a command with JSON output and returns the output.
+func runCtl(t *testing.T, models model.Models, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+	c := &ctl{models: models, format: "json", out: &out}
+	err := c.run(context.Background(), args)
+
+	return out.String(), err
+}
+
+// mustRunCtl runs a command like runCtl and fails the test if it fails.
+func mustRunCtl(t *testing.T, models model.Models, args ...string) string {
+	t.Helper()
+
+	out, err := runCtl(t, models, args...)
+	if err != nil {
+		t.Fatalf("justquizctl %s: %v", strings.Join(args, " "), err)
+	}
+
+	return out
+}
+
+// writeDump writes d to a file of the test and returns its path.
+func writeDump(t *testing.T, d dump) string {
+	t.Helper()
+
+	js, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "import.json")
+	if err := os.WriteFile(path, js, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func readDump(t *testing.T, path string) dump {
+	t.Helper()
+
+	js, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var d dump
+	if err := json.Unmarshal(js, &d); err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+// testDump is a file of another database: its ids have gaps, its owner isn't a user of the
+// databases of the tests, and the game of player 42 has no player.
+func testDump() dump {
+	ownerID := int64(5)
+	joined := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	return dump{
+		Players: []*model.Player{
+			{Id: "7", Name: "alice", Joined: joined, LastUpdate: joined.Add(time.Hour), Score: 30, OwnerID: &ownerID},
+			{Id: "9", Name: "bob", Joined: joined.Add(24 * time.Hour), LastUpdate: joined.Add(24 * time.Hour)},
+		},
+		Quizes: []*model.Quiz{
+			{Id: "3", Category: "science", Reward: 10, Questions: []string{"H2O?"}, Answers: []string{"water"}, OwnerID: &ownerID},
+		},
+		Games: []*model.Game{
+			{Id: "11", Finished: joined.Add(2 * time.Hour), Player: 7, Quiz: 3},
+			{Id: "12", Finished: joined.Add(48 * time.Hour), Player: 9, Quiz: 3, InProgress: true},
+			{Id: "13", Finished: joined.Add(3 * time.Hour), Player: 42, Quiz: 3},
+		},
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			src := backend.models(t)
+			out := mustRunCtl(t, src, "import", "-i", writeDump(t, testDump()))
+			if !strings.Contains(out, `"kind": "games",`+"\n\t\t"+`"records": 2,`+"\n\t\t"+`"skipped": 1`) {
+				t.Errorf("got output\n%s\nwant 2 games imported and 1 skipped", out)
+			}
+
+			exported := filepath.Join(dir, "export.json")
+			mustRunCtl(t, src, "export", "-o", exported)
+			d := readDump(t, exported)
+
+			if len(d.Players) != 2 || len(d.Quizes) != 1 || len(d.Games) != 2 {
+				t.Fatalf("got %d players, %d quizes and %d games, want 2, 1 and 2", len(d.Players), len(d.Quizes), len(d.Games))
+			}
+			want := testDump()
+			alice := d.Players[0]
+			if alice.Name != "alice" || alice.Score != 30 || !alice.Joined.Equal(want.Players[0].Joined) || !alice.LastUpdate.Equal(want.Players[0].LastUpdate) {
+				t.Errorf("got player %+v, want alice as in the file", alice)
+			}
+			if alice.OwnerID != nil || d.Quizes[0].OwnerID != nil {
+				t.Errorf("got owners %v and %v, want none as the owner isn't a user", alice.OwnerID, d.Quizes[0].OwnerID)
+			}
+			for i, game := range d.Games {
+				if !game.Finished.Equal(want.Games[i].Finished) || game.InProgress != want.Games[i].InProgress {
+					t.Errorf("got game %+v, want finished at %v and in progress %t", game, want.Games[i].Finished, want.Games[i].InProgress)
+				}
+			}
+			if d.Games[0].Player != mustID(t, alice.Id) || d.Games[0].Quiz != mustID(t, d.Quizes[0].Id) {
+				t.Errorf("got game %+v, want it linked to alice and the quiz", d.Games[0])
+			}
+
+			// Another database ends up with the same records.
+			dst := backend.models(t)
+			mustRunCtl(t, dst, "import", "-i", exported)
+
+			reexported := filepath.Join(dir, "reexport.json")
+			mustRunCtl(t, dst, "export", "-o", reexported)
+			if got := readDump(t, reexported); !equalDumps(got, d) {
+				t.Errorf("got export\n%+v\nafter the import, want\n%+v", got, d)
+			}
+
+			// Importing the file again would duplicate the records.
+			if _, err := runCtl(t, dst, "import", "-i", exported); err == nil || !strings.Contains(err.Error(), "already imported") {
+				t.Errorf("got error %v importing the file again, want it refused", err)
+			}
+			mustRunCtl(t, dst, "export", "-o", reexported)
+			if got := readDump(t, reexported); !equalDumps(got, d) {
+				t.Errorf("got export\n%+v\nafter the refused import, want it unchanged", got)
+			}
+		})
+	}
+}
+
+func TestImportRollback(t *testing.T) {
+	models, db := newSQLiteModels(t)
+
+	// The games fail after the players and quizes were inserted.
+	_, err := db.Exec(`CREATE TRIGGER fail_games BEFORE INSERT ON games BEGIN SELECT RAISE(ABORT, 'no games'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeDump(t, testDump())
+	if _, err := runCtl(t, models, "import", "-i", path); err == nil || !strings.Contains(err.Error(), "game 1 of the import") {
+		t.Fatalf("got error %v, want the failure of the first game", err)
+	}
+
+	players, _, err := models.Players.GetAll(context.Background(), "", 0, 0, model.TimeRange{}, model.Filters{Page: 1, PageSize: 20, Sort: "id", SortSafeList: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 0 {
+		t.Errorf("got players %v after the failed import, want none", players)
+	}
+
+	// The failed import isn't recorded, the file can be imported once the failure is fixed.
+	if _, err := db.Exec(`DROP TRIGGER fail_games`); err != nil {
+		t.Fatal(err)
+	}
+	mustRunCtl(t, models, "import", "-i", path)
+}
+
+func TestImportInvalid(t *testing.T) {
+	models := model.NewMemoryModels()
+
+	d := testDump()
+	d.Quizes[0].Category = ""
+	if _, err := runCtl(t, models, "import", "-i", writeDump(t, d)); err == nil || !strings.Contains(err.Error(), "quiz 1 of the import") {
+		t.Fatalf("got error %v, want the invalid quiz", err)
+	}
+
+	players, _, err := models.Players.GetAll(context.Background(), "", 0, 0, model.TimeRange{}, model.Filters{Page: 1, PageSize: 20, Sort: "id", SortSafeList: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 0 {
+		t.Errorf("got players %v after the invalid import, want none", players)
+	}
+}
+
+// equalDumps reports whether two exports hold the same records.
+func equalDumps(a, b dump) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// mustID parses the id of a record and fails the test if it's invalid.
+func mustID(t *testing.T, s string) int {
+	t.Helper()
+
+	n, err := id(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// access is what a user may do, as GET /v1/users/{id}/access returns it.
+type access struct {
+	UserID            int64             `json:"user_id"`
+	Roles             []string          `json:"roles"`
+	DirectPermissions model.Permissions `json:"direct_permissions"`
+	Permissions       model.Permissions `json:"permissions"`
+}
+
+func (c *ctl) usersCommand(ctx context.Context, args []string) error {
+	name, args, err := subcommand("users", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "list":
+		return c.listUsers(ctx, args)
+	case "show":
+		return c.showUser(ctx, args)
+	case "create":
+		return c.createUser(ctx, args)
+	case "activate":
+		return c.activateUser(ctx, args)
+	case "unlock":
+		return c.unlockUser(ctx, args)
+	default:
+		return fmt.Errorf("users: unknown subcommand %q", name)
+	}
+}
+
+// user returns the user with the id or email address s.
+func (c *ctl) user(ctx context.Context, s string) (*model.User, error) {
+	var (
+		user *model.User
+		err  error
+	)
+	if id, convErr := strconv.ParseInt(s, 10, 64); convErr == nil {
+		user, err = c.models.Users.Get(ctx, id)
+	} else {
+		user, err = c.models.Users.GetByEmail(ctx, s)
+	}
+
+	if errors.Is(err, model.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user %s not found", s)
+	}
+
+	return user, err
+}
+
+func usersTable(users ...*model.User) *table {
+	t := &table{header: []string{"ID", "NAME", "EMAIL", "ACTIVATED", "CREATED", "LOCKED UNTIL"}}
+	for _, u := range users {
+		t.add(u.ID, u.Name, u.Email, u.Activated, u.CreatedAt, u.LockedUntil)
+	}
+
+	return t
+}
+
+func (c *ctl) listUsers(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	email := fs.String("email", "", "Only list the user with the email address")
+	filters := listFlags(fs, "id", "id", "name", "email", "created_at")
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := validateFilters(*filters); err != nil {
+		return err
+	}
+
+	users, metadata, err := c.models.Users.GetAll(ctx, *email, *filters)
+	if err != nil {
+		return err
+	}
+
+	return c.printList("users", users, metadata, usersTable(users...))
+}
+
+func (c *ctl) showUser(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("users show", flag.ContinueOnError)
+	if err := parse(fs, args, "user"); err != nil {
+		return err
+	}
+
+	user, err := c.user(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	a, err := c.access(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"FIELD", "VALUE"}}
+	t.add("id", user.ID)
+	t.add("name", user.Name)
+	t.add("email", user.Email)
+	t.add("activated", user.Activated)
+	t.add("created", user.CreatedAt)
+	t.add("locked until", user.LockedUntil)
+	t.add("failed logins", user.FailedLogins)
+	t.add("roles", a.Roles)
+	t.add("direct permissions", []string(a.DirectPermissions))
+	t.add("permissions", []string(a.Permissions))
+
+	return c.print(struct {
+		*model.User
+		Access access `json:"access"`
+	}{user, a}, t)
+}
+
+func (c *ctl) createUser(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("users create", flag.ContinueOnError)
+
+	var (
+		name      = fs.String("name", "", "Name of the user")
+		email     = fs.String("email", "", "Email address of the user")
+		password  = fs.String("password", "", "Password of the user")
+		activated = fs.Bool("activated", true, "Create the user activated, otherwise they can't log in")
+		roles     = fs.String("roles", model.RolePlayer, "Comma-separated roles of the user")
+	)
+
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	codes := strings.Split(*roles, ",")
+	for _, code := range codes {
+		exists, err := c.models.Roles.Exists(ctx, code)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("unknown role %q", code)
+		}
+	}
+
+	user := &model.User{
+		Name:      *name,
+		Email:     *email,
+		Activated: *activated,
+	}
+
+	err := user.Password.Set(*password)
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+	if model.ValidateUser(v, user); !v.Valid() {
+		return fmt.Errorf("invalid user: %v", v.Errors)
+	}
+
+	err = c.models.Users.Insert(ctx, user)
+	if err != nil {
+		if errors.Is(err, model.ErrDuplicateEmail) {
+			return fmt.Errorf("a user with the email address %s already exists", user.Email)
+		}
+		return err
+	}
+
+	err = c.models.Roles.AddForUser(ctx, user.ID, codes...)
+	if err != nil {
+		return err
+	}
+
+	return c.print(user, usersTable(user))
+}
+
+func (c *ctl) activateUser(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("users activate", flag.ContinueOnError)
+	if err := parse(fs, args, "user"); err != nil {
+		return err
+	}
+
+	user, err := c.user(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if !user.Activated {
+		user.Activated = true
+
+		err = c.models.Users.Update(ctx, user)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.print(user, usersTable(user))
+}
+
+// unlockUser ends the lockout of a user after too many failed logins and resets the count.
+func (c *ctl) unlockUser(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("users unlock", flag.ContinueOnError)
+	if err := parse(fs, args, "user"); err != nil {
+		return err
+	}
+
+	user, err := c.user(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	err = c.models.Users.Unlock(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	user.LockedUntil = nil
+
+	return c.print(user, usersTable(user))
+}
+
+func (c *ctl) access(ctx context.Context, user *model.User) (access, error) {
+	a := access{UserID: user.ID}
+
+	var err error
+	if a.Roles, err = c.models.Roles.GetAllForUser(ctx, user.ID); err != nil {
+		return access{}, err
+	}
+	if a.DirectPermissions, err = c.models.Permissions.GetDirectForUser(ctx, user.ID); err != nil {
+		return access{}, err
+	}
+	if a.Permissions, err = c.models.Permissions.GetAllForUser(ctx, user.ID); err != nil {
+		return access{}, err
+	}
+
+	return a, nil
+}
+
+func (c *ctl) printAccess(ctx context.Context, user *model.User) error {
+	a, err := c.access(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"USER", "ROLES", "DIRECT PERMISSIONS", "PERMISSIONS"}}
+	t.add(user.Email, a.Roles, []string(a.DirectPermissions), []string(a.Permissions))
+
+	return c.print(a, t)
+}
+
+func (c *ctl) rolesCommand(ctx context.Context, args []string) error {
+	name, args, err := subcommand("roles", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "list":
+		return c.listRoles(ctx, args)
+	case "grant":
+		return c.grantRole(ctx, args)
+	case "revoke":
+		return c.revokeRole(ctx, args)
+	default:
+		return fmt.Errorf("roles: unknown subcommand %q", name)
+	}
+}
+
+func (c *ctl) listRoles(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("roles list", flag.ContinueOnError)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	roles, err := c.models.Roles.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"CODE", "DESCRIPTION", "PERMISSIONS"}}
+	for _, role := range roles {
+		t.add(role.Code, role.Description, []string(role.Permissions))
+	}
+
+	return c.print(roles, t)
+}
+
+// grantRole grants a role to a user. Granting a role the user already has is not an error.
+func (c *ctl) grantRole(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("roles grant", flag.ContinueOnError)
+	if err := parse(fs, args, "user", "role"); err != nil {
+		return err
+	}
+
+	user, err := c.user(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	role := fs.Arg(1)
+
+	exists, err := c.models.Roles.Exists(ctx, role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("unknown role %q", role)
+	}
+
+	err = c.models.Roles.AddForUser(ctx, user.ID, role)
+	if err != nil {
+		return err
+	}
+
+	return c.printAccess(ctx, user)
+}
+
+// revokeRole revokes a role from a user. Like through the API, the admin role can't be revoked
+// from the last admin.
+func (c *ctl) revokeRole(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("roles revoke", flag.ContinueOnError)
+	if err := parse(fs, args, "user", "role"); err != nil {
+		return err
+	}
+
+	user, err := c.user(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	role := fs.Arg(1)
+
+	if role == model.RoleAdmin {
+		err = c.models.Roles.RemoveForUserUnlessLast(ctx, user.ID, role)
+	} else {
+		err = c.models.Roles.RemoveForUser(ctx, user.ID, role)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			return fmt.Errorf("user %s doesn't have the role %q", fs.Arg(0), role)
+		case errors.Is(err, model.ErrLastRoleHolder):
+			return errors.New("the admin role can't be revoked from the last admin")
+		}
+		return err
+	}
+
+	return c.printAccess(ctx, user)
+}
+
+func (c *ctl) permissionsCommand(ctx context.Context, args []string) error {
+	name, args, err := subcommand("permissions", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "list":
+		return c.listPermissions(ctx, args)
+	case "grant":
+		return c.grantPermission(ctx, args)
+	case "revoke":
+		return c.revokePermission(ctx, args)
+	default:
+		return fmt.Errorf("permissions: unknown subcommand %q", name)
+	}
+}
+
+func (c *ctl) listPermissions(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("permissions list", flag.ContinueOnError)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	permissions, err := c.models.Permissions.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"CODE"}}
+	for _, code := range permissions {
+		t.add(code)
+	}
+
+	return c.print(permissions, t)
+}
+
+// grantPermission grants a permission to a user directly, on top of the permissions of their
+// roles.
+func (c *ctl) grantPermission(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("permissions grant", flag.ContinueOnError)
+	if err := parse(fs, args, "user", "code"); err != nil {
+		return err
+	}
+
+	user, err := c.user(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	code := fs.Arg(1)
+
+	permissions, err := c.models.Permissions.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(permissions, code) {
+		return fmt.Errorf("unknown permission %q", code)
+	}
+
+	err = c.models.Permissions.AddForUser(ctx, user.ID, code)
+	if err != nil {
+		return err
+	}
+
+	return c.printAccess(ctx, user)
+}
+
+// revokePermission revokes a permission granted to a user directly. Permissions that come from a
+// role can only be taken away by revoking the role.
+func (c *ctl) revokePermission(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("permissions revoke", flag.ContinueOnError)
+	if err := parse(fs, args, "user", "code"); err != nil {
+		return err
+	}
+
+	user, err := c.user(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	code := fs.Arg(1)
+
+	err = c.models.Permissions.RemoveForUser(ctx, user.ID, code)
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return fmt.Errorf("the permission %q isn't granted to user %s directly", code, fs.Arg(0))
+		}
+		return err
+	}
+
+	return c.printAccess(ctx, user)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+)
+
+// decode decodes the JSON output of a command.
+func decode(t *testing.T, out string) map[string]any {
+	t.Helper()
+
+	var v map[string]any
+	if err := json.Unmarshal([]byte(out), &v); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+
+	return v
+}
+
+// wantError runs a command which must fail with an error containing want.
+func wantError(t *testing.T, try func(args ...string) (string, error), want string, args ...string) {
+	t.Helper()
+
+	if _, err := try(args...); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("justquizctl %s: got error %v, want %q", strings.Join(args, " "), err, want)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			models := backend.models(t)
+			try := func(args ...string) (string, error) { return runCtl(t, models, args...) }
+
+			user := decode(t, mustRunCtl(t, models, "users", "create", "-name", "alice", "-email", "alice@example.com", "-password", "pa55word123", "-roles", "author"))
+			if user["name"] != "alice" || user["activated"] != true {
+				t.Errorf("got user %v, want alice activated", user)
+			}
+
+			wantError(t, try, "already exists", "users", "create", "-name", "alice", "-email", "alice@example.com", "-password", "pa55word123")
+			wantError(t, try, `unknown role "wizard"`, "users", "create", "-name", "bob", "-email", "bob@example.com", "-password", "pa55word123", "-roles", "wizard")
+			wantError(t, try, "invalid user", "users", "create", "-name", "bob", "-email", "bob", "-password", "pa55word123")
+
+			list := decode(t, mustRunCtl(t, models, "users", "list", "-email", "alice@example.com"))
+			if users, _ := list["users"].([]any); len(users) != 1 {
+				t.Errorf("got users %v, want alice", list["users"])
+			}
+
+			shown := decode(t, mustRunCtl(t, models, "users", "show", "alice@example.com"))
+			if roles, _ := shown["access"].(map[string]any)["roles"].([]any); len(roles) != 1 || roles[0] != "author" {
+				t.Errorf("got access %v, want the author role", shown["access"])
+			}
+			wantError(t, try, "user 999 not found", "users", "show", "999")
+			wantError(t, try, "user nobody@example.com not found", "users", "show", "nobody@example.com")
+
+			// Roles and permissions granted and revoked.
+			access := decode(t, mustRunCtl(t, models, "roles", "grant", "alice@example.com", "moderator"))
+			if roles, _ := access["roles"].([]any); len(roles) != 2 {
+				t.Errorf("got roles %v, want author and moderator", access["roles"])
+			}
+			wantError(t, try, `unknown role "wizard"`, "roles", "grant", "alice@example.com", "wizard")
+			mustRunCtl(t, models, "roles", "revoke", "alice@example.com", "moderator")
+			wantError(t, try, `doesn't have the role "moderator"`, "roles", "revoke", "alice@example.com", "moderator")
+
+			access = decode(t, mustRunCtl(t, models, "permissions", "grant", "alice@example.com", "user:read"))
+			if direct, _ := access["direct_permissions"].([]any); len(direct) != 1 || direct[0] != "user:read" {
+				t.Errorf("got direct permissions %v, want user:read", access["direct_permissions"])
+			}
+			wantError(t, try, `unknown permission "user:fly"`, "permissions", "grant", "alice@example.com", "user:fly")
+			mustRunCtl(t, models, "permissions", "revoke", "alice@example.com", "user:read")
+			wantError(t, try, "isn't granted", "permissions", "revoke", "alice@example.com", "user:read")
+
+			// The last admin keeps the admin role.
+			mustRunCtl(t, models, "users", "create", "-name", "root", "-email", "root@example.com", "-password", "pa55word123", "-roles", "admin")
+			wantError(t, try, "last admin", "roles", "revoke", "root@example.com", "admin")
+
+			inactive := decode(t, mustRunCtl(t, models, "users", "create", "-name", "carol", "-email", "carol@example.com", "-password", "pa55word123", "-activated=false"))
+			if inactive["activated"] != false {
+				t.Errorf("got user %v, want carol not activated", inactive)
+			}
+			if activated := decode(t, mustRunCtl(t, models, "users", "activate", "carol@example.com")); activated["activated"] != true {
+				t.Errorf("got user %v, want carol activated", activated)
+			}
+
+			if unlocked := decode(t, mustRunCtl(t, models, "users", "unlock", "alice@example.com")); unlocked["locked_until"] != nil {
+				t.Errorf("got user %v, want alice unlocked", unlocked)
+			}
+
+			var roles []map[string]any
+			if err := json.Unmarshal([]byte(mustRunCtl(t, models, "roles", "list")), &roles); err != nil {
+				t.Fatal(err)
+			}
+			if len(roles) != 4 {
+				t.Errorf("got roles %v, want player, author, moderator and admin", roles)
+			}
+		})
+	}
+}
+
+func TestCommandParsing(t *testing.T) {
+	models := backends[0].models(t)
+	try := func(args ...string) (string, error) { return runCtl(t, models, args...) }
+
+	wantError(t, try, `unknown command "games"`, "games", "list")
+	wantError(t, try, "users: missing subcommand", "users")
+	wantError(t, try, `roles: unknown subcommand "show"`, "roles", "show")
+	wantError(t, try, `permissions: unknown subcommand "show"`, "permissions", "show")
+	wantError(t, try, `players: unknown subcommand "update"`, "players", "update")
+	wantError(t, try, `quizes: unknown subcommand "create"`, "quizes", "create")
+	wantError(t, try, "users show: expected 1 arguments, got 0", "users", "show")
+	wantError(t, try, "roles grant: expected 2 arguments, got 1", "roles", "grant", "alice@example.com")
+	wantError(t, try, "flag provided but not defined: -nope", "users", "list", "-nope")
+	wantError(t, try, "page", "players", "list", "-page", "0")
+
+	if err := run([]string{"-format", "xml", "users", "list"}); err == nil || err.Error() != `unknown format "xml"` {
+		t.Errorf("got error %v, want the unknown format", err)
+	}
+	if err := run(nil); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("got error %v without a command, want the usage", err)
+	}
+}
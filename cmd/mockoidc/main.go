@@ -0,0 +1,57 @@
+// Command mockoidc is a minimal OpenID Connect provider for local development and testing of the
+// JustQuiz external login flow. It approves every authorization request without asking, and
+// issues RS256 signed ID tokens for a single configurable identity. The provider itself is
+// pkg/quiz/oidc/oidctest, which the tests serve with httptest.
+//
+// Run it next to the API and point the API at it:
+//
+//	$ go run ./cmd/mockoidc -port 9000
+//	$ go run ./cmd/quiz -oidc-providers '[{"name":"mock","issuer":"http://localhost:9000","client_id":"justquiz","client_secret":"secret"}]'
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/oidc/oidctest"
+	"github.com/peterbourgon/ff/v3"
+)
+
+func main() {
+	fs := flag.NewFlagSet("mockoidc", flag.ContinueOnError)
+
+	var (
+		port          = fs.Int("port", 9000, "Listen port")
+		issuer        = fs.String("issuer", "", "Issuer URL (defaults to http://localhost:<port>)")
+		clientID      = fs.String("client-id", "justquiz", "Accepted client ID")
+		clientSecret  = fs.String("client-secret", "secret", "Accepted client secret")
+		subject       = fs.String("subject", "mock-user-1", "Subject of the issued ID tokens")
+		email         = fs.String("email", "mock.user@example.com", "Email of the issued ID tokens")
+		name          = fs.String("name", "Mock User", "Name of the issued ID tokens")
+		emailVerified = fs.Bool("email-verified", true, "Whether the email is reported as verified")
+	)
+
+	if err := ff.Parse(fs, os.Args[1:], ff.WithEnvVarPrefix("MOCKOIDC")); err != nil {
+		log.Fatal(err)
+	}
+
+	if *issuer == "" {
+		*issuer = fmt.Sprintf("http://localhost:%d", *port)
+	}
+
+	provider, err := oidctest.NewProvider(*issuer, *clientID, *clientSecret, oidctest.Identity{
+		Subject:       *subject,
+		Email:         *email,
+		Name:          *name,
+		EmailVerified: *emailVerified,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("mock OpenID Connect provider %s listening on :%d", *issuer, *port)
+	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *port), provider))
+}
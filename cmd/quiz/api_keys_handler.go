@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// createAPIKeyHandler creates an API key for the authenticated user. The scopes must be a subset
+// of the user's permissions. The plaintext key is only part of this response.
+func (app *application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Name   string            `json:"name"`
+		Scopes model.Permissions `json:"scopes"`
+		Expiry *time.Time        `json:"expiry"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	key := &model.APIKey{
+		Name:   input.Name,
+		Scopes: input.Scopes,
+		Expiry: input.Expiry,
+	}
+
+	v := validator.New()
+
+	if model.ValidateAPIKey(v, key, permissions); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	key, err = app.models.APIKeys.New(r.Context(), user.ID, key.Name, key.Scopes, key.Expiry)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"api_key": key}, nil)
+}
+
+// getAPIKeysList returns the API keys of the authenticated user, without the keys themselves.
+func (app *application) getAPIKeysList(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	keys, err := app.models.APIKeys.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"api_keys": keys}, nil)
+}
+
+// revokeAPIKeyHandler revokes an API key of the authenticated user. It stops working right away.
+func (app *application) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.APIKeys.Revoke(r.Context(), user.ID, int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "API key revoked"}, nil)
+}
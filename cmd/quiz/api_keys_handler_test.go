@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+func TestAPIKeys(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+
+	_, token := newTestUser(t, app, "alice@example.com", model.RolePlayer)
+	_, other := newTestUser(t, app, "bob@example.com", model.RolePlayer)
+
+	// The scopes can't exceed the permissions of the user.
+	ts.request(t, http.MethodPost, "/v1/api-keys", token, map[string]any{
+		"name":   "ci",
+		"scopes": []string{"quiz:create"},
+	}).wantStatus(t, http.StatusUnprocessableEntity)
+
+	res := ts.request(t, http.MethodPost, "/v1/api-keys", token, map[string]any{
+		"name":   "ci",
+		"scopes": []string{"player:read", "player:create"},
+	}).wantStatus(t, http.StatusCreated)
+	key := res.object(t, "api_key")
+	plaintext, _ := key["key"].(string)
+	keyID := id(t, key["id"])
+
+	// An API key works within its scopes, but can't manage credentials.
+	ts.request(t, http.MethodPost, "/v1/players", plaintext, map[string]any{"name": "bot"}).wantStatus(t, http.StatusCreated)
+	ts.request(t, http.MethodPost, "/v1/games", plaintext, map[string]any{"player": 1, "quiz": 1}).wantStatus(t, http.StatusForbidden)
+	ts.request(t, http.MethodGet, "/v1/api-keys", plaintext, nil).wantStatus(t, http.StatusForbidden)
+
+	res = ts.request(t, http.MethodGet, "/v1/api-keys", token, nil).wantStatus(t, http.StatusOK)
+	keys := res.list(t, "api_keys")
+	if len(keys) != 1 {
+		t.Fatalf("got %d API keys, want 1", len(keys))
+	}
+	if _, ok := keys[0].(map[string]any)["key"]; ok {
+		t.Error("got the plaintext key in the list")
+	}
+	if _, ok := keys[0].(map[string]any)["last_used_at"]; !ok {
+		t.Error("got no last_used_at for a used key")
+	}
+
+	ts.request(t, http.MethodDelete, "/v1/api-keys/"+keyID, other, nil).wantStatus(t, http.StatusNotFound)
+	ts.request(t, http.MethodDelete, "/v1/api-keys/"+keyID, token, nil).wantStatus(t, http.StatusOK)
+	ts.request(t, http.MethodDelete, "/v1/api-keys/"+keyID, token, nil).wantStatus(t, http.StatusNotFound)
+
+	ts.request(t, http.MethodPost, "/v1/players", plaintext, map[string]any{"name": "bot"}).wantStatus(t, http.StatusUnauthorized)
+}
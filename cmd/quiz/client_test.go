@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/client"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/totp"
+)
+
+// newTestClient returns a client of the test server logged in as a new user with the roles.
+func newTestClient(t *testing.T, app *application, ts *testServer, email string, roles ...string) *client.Client {
+	t.Helper()
+
+	newTestUser(t, app, email, roles...)
+
+	c := client.New(ts.URL)
+	if err := c.Login(context.Background(), email, testPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestClientUsers(t *testing.T) {
+	forEachBackend(t, testClientUsers)
+}
+
+func testClientUsers(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+	ctx := context.Background()
+
+	c := client.New(ts.URL)
+
+	user, activation, err := c.Register(ctx, "alice", "alice@example.com", testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Activated || activation == "" {
+		t.Fatalf("got activated %t and token %q, want an inactive user and a token", user.Activated, activation)
+	}
+
+	_, _, err = c.Register(ctx, "alice", "alice@example.com", testPassword)
+	var apiErr *client.Error
+	if !client.IsValidation(err) || !errors.As(err, &apiErr) || apiErr.Fields["email"] == "" {
+		t.Fatalf("got error %v, want a validation error of the email", err)
+	}
+
+	user, err = c.Activate(ctx, activation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.Activated {
+		t.Error("got an inactive user after the activation")
+	}
+
+	if err := c.Login(ctx, "alice@example.com", "wrong password"); !client.IsUnauthorized(err) {
+		t.Fatalf("got error %v, want unauthorized", err)
+	}
+	if err := c.Login(ctx, "alice@example.com", testPassword); err != nil {
+		t.Fatal(err)
+	}
+	if c.Token().Plaintext == "" || c.Token().Expiry.IsZero() {
+		t.Errorf("got token %+v, want one with an expiry", c.Token())
+	}
+
+	key, err := c.CreateAPIKey(ctx, "ci", []string{"player:read", "player:create"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := c.APIKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].ID != key.ID || keys[0].Plaintext != "" {
+		t.Fatalf("got API keys %+v, want the new key without its plaintext", keys)
+	}
+
+	bot := client.New(ts.URL)
+	bot.SetAPIKey(key.Plaintext)
+
+	if _, err := bot.CreatePlayer(ctx, "bot"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bot.APIKeys(ctx); !client.IsForbidden(err) {
+		t.Fatalf("got error %v, want forbidden for an API key managing API keys", err)
+	}
+
+	if err := c.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RevokeAPIKey(ctx, key.ID); !client.IsNotFound(err) {
+		t.Fatalf("got error %v, want not found for a revoked API key", err)
+	}
+	if _, err := bot.CreatePlayer(ctx, "bot"); !client.IsUnauthorized(err) {
+		t.Fatalf("got error %v, want unauthorized for a revoked API key", err)
+	}
+}
+
+func TestClientTwoFactorLogin(t *testing.T) {
+	forEachBackend(t, testClientTwoFactorLogin)
+}
+
+func testClientTwoFactorLogin(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+	ctx := context.Background()
+
+	user, _ := newTestUser(t, app, "alice@example.com", model.RolePlayer)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.TwoFactor.Enroll(ctx, user.ID, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.TwoFactor.Confirm(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	c := client.New(ts.URL)
+	if err := c.LoginTwoFactor(ctx, "123456"); err == nil {
+		t.Fatal("got no error completing a login which wasn't started")
+	}
+
+	if err := c.Login(ctx, "alice@example.com", testPassword); !errors.Is(err, client.ErrTwoFactorRequired) {
+		t.Fatalf("got error %v, want ErrTwoFactorRequired", err)
+	}
+	if err := c.LoginTwoFactor(ctx, totpCode(t, secret, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.APIKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientGames(t *testing.T) {
+	forEachBackend(t, testClientGames)
+}
+
+func testClientGames(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+	ctx := context.Background()
+
+	author := newTestClient(t, app, ts, "author@example.com", model.RoleAuthor)
+	player := newTestClient(t, app, ts, "player@example.com", model.RolePlayer)
+	moderator := newTestClient(t, app, ts, "mod@example.com", model.RoleModerator)
+
+	quiz, err := author.CreateQuiz(ctx, client.QuizInput{
+		Category:  "geography",
+		Reward:    10,
+		Questions: []string{"Capital of Kazakhstan?"},
+		Answers:   []string{"Astana"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	quizID, _ := strconv.Atoi(quiz.ID)
+
+	if _, err := player.CreateQuiz(ctx, client.QuizInput{Category: "history"}); !client.IsForbidden(err) {
+		t.Fatalf("got error %v, want forbidden for a player creating a quiz", err)
+	}
+
+	reward := 15
+	quiz, err = author.UpdateQuiz(ctx, quizID, client.QuizUpdate{Reward: &reward})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quiz.Reward != reward {
+		t.Errorf("got reward %d, want %d", quiz.Reward, reward)
+	}
+
+	alice, err := player.CreatePlayer(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliceID, _ := strconv.Atoi(alice.ID)
+
+	game, err := player.CreateGame(ctx, aliceID, quizID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gameID, _ := strconv.Atoi(game.ID)
+
+	result, err := player.AnswerGame(ctx, quizID, alice.ID, []string{"Astana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "Answers are correct" {
+		t.Errorf("got result %q, want Answers are correct", result)
+	}
+
+	game, err = player.GetGame(ctx, gameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.InProgress {
+		t.Error("got the game in progress after the answers")
+	}
+
+	alice, err = player.GetPlayer(ctx, aliceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Score != reward {
+		t.Errorf("got score %d, want %d", alice.Score, reward)
+	}
+
+	quizes, _, err := player.ListPlayerQuizes(ctx, aliceID, client.GameFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quizes) != 1 || quizes[0].ID != quiz.ID {
+		t.Errorf("got quizes %+v, want the quiz %s", quizes, quiz.ID)
+	}
+
+	players, _, err := player.ListQuizPlayers(ctx, quizID, client.GameFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 1 || players[0].ID != alice.ID {
+		t.Errorf("got players %+v, want the player %s", players, alice.ID)
+	}
+
+	if _, err := app.models.Leaderboard.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err := player.Leaderboard(ctx, client.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Rank != 1 || entries[0].Games != 1 {
+		t.Errorf("got leaderboard %+v, want alice first with one game", entries)
+	}
+
+	if err := player.DeleteGame(ctx, gameID); !client.IsForbidden(err) {
+		t.Fatalf("got error %v, want forbidden for a player deleting a game", err)
+	}
+	if err := moderator.DeleteGame(ctx, gameID); err != nil {
+		t.Fatal(err)
+	}
+	if games, _, err := player.ListGames(ctx, client.GameFilter{Player: aliceID}); err != nil || len(games) != 0 {
+		t.Fatalf("got games %+v and error %v, want none after the delete", games, err)
+	}
+	if _, err := player.GetGame(ctx, gameID); !client.IsNotFound(err) {
+		t.Fatalf("got error %v, want not found for a deleted game", err)
+	}
+	if err := moderator.DeleteGame(ctx, gameID); !client.IsNotFound(err) {
+		t.Fatalf("got error %v, want not found deleting the game again", err)
+	}
+
+	if err := author.DeleteQuiz(ctx, quizID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientIterators(t *testing.T) {
+	forEachBackend(t, testClientIterators)
+}
+
+func testClientIterators(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+	ctx := context.Background()
+
+	c := newTestClient(t, app, ts, "alice@example.com", model.RoleModerator)
+
+	var names []string
+	for i := 0; i < 5; i++ {
+		player, err := c.CreatePlayer(ctx, "player "+strconv.Itoa(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, player.Name)
+	}
+
+	// Pages of numbers, and pages of cursors.
+	for _, opts := range []client.ListOptions{
+		{PageSize: 2},
+		{PageSize: 2, SkipCount: true},
+	} {
+		players, err := c.Players(client.PlayerFilter{ListOptions: opts}).All(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, player := range players {
+			got = append(got, player.Name)
+		}
+		if len(got) != len(names) {
+			t.Fatalf("got players %v with %+v, want %v", got, opts, names)
+		}
+		for i := range got {
+			if got[i] != names[i] {
+				t.Fatalf("got players %v with %+v, want %v", got, opts, names)
+			}
+		}
+	}
+
+	players, _, err := c.ListPlayers(ctx, client.PlayerFilter{ListOptions: client.ListOptions{Sort: "-id", Fields: []string{"id", "name"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 5 || players[0].Name != names[4] || !players[0].Joined.IsZero() {
+		t.Errorf("got players %+v, want the last one first with the id and name only", players)
+	}
+
+	score := 50
+	player, err := c.UpdatePlayer(ctx, 1, client.PlayerUpdate{Score: &score})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.Score != score {
+		t.Errorf("got score %d, want %d", player.Score, score)
+	}
+
+	if err := c.DeletePlayer(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.ListPlayers(ctx, client.PlayerFilter{ListOptions: client.ListOptions{Sort: "password"}}); !client.IsValidation(err) {
+		t.Fatalf("got error %v, want a validation error of the sort", err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/seed"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+	"github.com/peterbourgon/ff/v3"
+)
+
+// runCommand runs the subcommand named by the first of args instead of the API server. The
+// remaining args are the flags of the subcommand.
+func (app *application) runCommand(args []string) error {
+	switch args[0] {
+	case "create-admin":
+		return app.createAdminCommand(args[1:])
+	case "seed":
+		return app.seedCommand(args[1:])
+	case "migrate":
+		return app.migrateCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// createAdminCommand bootstraps the first admin. If a user with the email address exists they are
+// activated and promoted, otherwise a new activated user is created. As admins are supposed to
+// be managed through the API afterwards, the command refuses to run once an admin exists unless
+// -force is given.
+func (app *application) createAdminCommand(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+
+	var (
+		name     = fs.String("name", "Admin", "Name of the admin")
+		email    = fs.String("email", "", "Email address of the admin")
+		password = fs.String("password", "", "Password of the admin, not needed to promote an existing user")
+		force    = fs.Bool("force", false, "Create the admin even if there already is one")
+	)
+
+	if err := ff.Parse(fs, args, ff.WithEnvVarPrefix("ADMIN")); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	admins, err := app.models.Roles.CountUsers(ctx, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins > 0 && !*force {
+		return errors.New("an admin already exists, grant roles through the API or use -force")
+	}
+
+	v := validator.New()
+
+	user, err := app.models.Users.GetByEmail(ctx, *email)
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound):
+		user = &model.User{
+			Name:      *name,
+			Email:     *email,
+			Activated: true,
+		}
+
+		err = user.Password.Set(*password)
+		if err != nil {
+			return err
+		}
+
+		if model.ValidateUser(v, user); !v.Valid() {
+			return fmt.Errorf("invalid admin: %v", v.Errors)
+		}
+
+		err = app.models.Users.Insert(ctx, user)
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !user.Activated:
+		user.Activated = true
+
+		err = app.models.Users.Update(ctx, user)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = app.models.Roles.AddForUser(ctx, user.ID, model.RolePlayer, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	app.logger.PrintInfo("admin created", map[string]string{
+		"id":    fmt.Sprint(user.ID),
+		"email": user.Email,
+	})
+
+	return nil
+}
+
+// seedCommand fills the database with dummy data like -fill does on start, see package seed. The
+// sizes default to the -fill-* flags.
+func (app *application) seedCommand(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+
+	cfg := app.config.seed
+	fs.Int64Var(&cfg.Seed, "seed", cfg.Seed, "Seed of the data, the same seed always gives the same data")
+	fs.IntVar(&cfg.Users, "users", cfg.Users, "Number of users, every fourth is an author")
+	fs.IntVar(&cfg.Players, "players", cfg.Players, "Number of players")
+	fs.IntVar(&cfg.Quizes, "quizes", cfg.Quizes, "Number of quizes")
+	fs.IntVar(&cfg.Games, "games", cfg.Games, "Number of games")
+
+	if err := ff.Parse(fs, args, ff.WithEnvVarPrefix("SEED")); err != nil {
+		return err
+	}
+
+	return app.seed(context.Background(), cfg)
+}
+
+// seed runs the seeding and logs how many records were created.
+func (app *application) seed(ctx context.Context, cfg seed.Config) error {
+	result, err := seed.Run(ctx, app.models, cfg)
+	if err != nil {
+		return err
+	}
+
+	app.logger.PrintInfo("database seeded", map[string]string{
+		"seed":             fmt.Sprint(cfg.Seed),
+		"users_created":    fmt.Sprint(result.Created.Users),
+		"players_created":  fmt.Sprint(result.Created.Players),
+		"quizes_created":   fmt.Sprint(result.Created.Quizes),
+		"games_created":    fmt.Sprint(result.Created.Games),
+		"users_existing":   fmt.Sprint(result.Existing.Users),
+		"players_existing": fmt.Sprint(result.Existing.Players),
+		"quizes_existing":  fmt.Sprint(result.Existing.Quizes),
+		"games_existing":   fmt.Sprint(result.Existing.Games),
+	})
+
+	return nil
+}
@@ -14,6 +14,9 @@ type contextKey string
 // in the request context.
 const userContextKey = contextKey("user")
 
+// apiKeyContextKey is the key of the API key a request was authenticated with, if any.
+const apiKeyContextKey = contextKey("api_key")
+
 // The contextSetUser() method returns a new copy of the request with the provided
 // User struct added to the context. Note that we use our userContextKey constant as the
 // key.
@@ -34,4 +37,18 @@ func (app *application) contextGetUser(r *http.Request) *model.User {
 	}
 
 	return user
-}
\ No newline at end of file
+}
+
+// contextSetAPIKey returns a new copy of the request with the API key that authenticated it added
+// to the context.
+func (app *application) contextSetAPIKey(r *http.Request, key *model.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
+// contextGetAPIKey retrieves the API key that authenticated the request. It returns nil if the
+// request wasn't authenticated with an API key.
+func (app *application) contextGetAPIKey(r *http.Request) *model.APIKey {
+	key, _ := r.Context().Value(apiKeyContextKey).(*model.APIKey)
+	return key
+}
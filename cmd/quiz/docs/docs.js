@@ -0,0 +1,104 @@
+// Renders the OpenAPI document of the API, grouping the operations by their tag.
+"use strict";
+
+function element(tag, attributes, ...children) {
+	const el = document.createElement(tag);
+	Object.assign(el, attributes);
+	el.append(...children);
+	return el;
+}
+
+// resolve replaces the references to component schemas with the schemas, at most depth levels
+// deep.
+function resolve(spec, schema, depth = 4) {
+	if (Array.isArray(schema)) {
+		return schema.map((s) => resolve(spec, s, depth));
+	}
+	if (schema === null || typeof schema !== "object") {
+		return schema;
+	}
+	if (schema.$ref) {
+		const name = schema.$ref.split("/").pop();
+		return depth > 0 ? resolve(spec, spec.components.schemas[name], depth - 1) : name;
+	}
+
+	const resolved = {};
+	for (const [key, value] of Object.entries(schema)) {
+		resolved[key] = resolve(spec, value, depth);
+	}
+	return resolved;
+}
+
+function schemaBlock(spec, schema) {
+	return element("pre", {}, JSON.stringify(resolve(spec, schema), null, 2));
+}
+
+function operation(spec, path, method, op) {
+	const body = element("div");
+
+	if (op.description) {
+		body.append(element("p", { className: "access" }, op.description));
+	}
+
+	if (op.parameters) {
+		const rows = op.parameters.map((p) =>
+			element("tr", {},
+				element("td", {}, element("code", {}, p.name)),
+				element("td", {}, p.in),
+				element("td", {}, p.schema.type),
+				element("td", {}, p.description || "")));
+		body.append(element("h4", {}, "Parameters"), element("table", {}, ...rows));
+	}
+
+	if (op.requestBody) {
+		body.append(element("h4", {}, "Request"), schemaBlock(spec, op.requestBody.content["application/json"].schema));
+	}
+
+	body.append(element("h4", {}, "Responses"));
+	for (const [status, response] of Object.entries(op.responses)) {
+		body.append(element("p", {}, element("strong", {}, status), " " + response.description));
+		if (response.content && response.content["application/json"] && status < 400) {
+			body.append(schemaBlock(spec, response.content["application/json"].schema));
+		}
+	}
+
+	return element("details", {},
+		element("summary", {},
+			element("span", { className: "method " + method }, method.toUpperCase()),
+			element("code", {}, path), " " + op.summary),
+		body);
+}
+
+async function render() {
+	const info = document.getElementById("info");
+
+	let spec;
+	try {
+		const response = await fetch("../openapi.json");
+		spec = await response.json();
+	} catch (err) {
+		info.textContent = "Couldn't load openapi.json: " + err;
+		return;
+	}
+
+	info.textContent = spec.info.description + " Version " + spec.info.version + ". ";
+	info.append(element("a", { href: "../openapi.json" }, "openapi.json"));
+
+	const tags = new Map();
+	for (const [path, methods] of Object.entries(spec.paths)) {
+		for (const [method, op] of Object.entries(methods)) {
+			const tag = op.tags[0];
+			if (!tags.has(tag)) {
+				tags.set(tag, []);
+			}
+			tags.get(tag).push(operation(spec, path, method, op));
+		}
+	}
+
+	const operations = document.getElementById("operations");
+	for (const [tag, ops] of [...tags].sort((a, b) => a[0].localeCompare(b[0]))) {
+		operations.append(element("h2", {}, tag), ...ops);
+	}
+}
+
+render();
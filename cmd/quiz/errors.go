@@ -1,14 +1,22 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/jsonlog"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
 )
 
 // logError method is a generic helper for logging an error message in *application, as well
-// as the requested method and request URL.
+// as the requested method and request URL. The request ID and trace ID are added from the request
+// context.
 func (app *application) logError(r *http.Request, err error) {
-	app.logger.PrintError(err, map[string]string{
+	app.logger.PrintErrorContext(r.Context(), err, map[string]string{
 		"request_method": r.Method,
 		"request_url":    r.URL.String(),
 	})
@@ -17,9 +25,13 @@ func (app *application) logError(r *http.Request, err error) {
 // errorResponse method is a generic helper for sending JSON-formatted error messages to the
 // client with a given status code. Note that we're using an interface{} type for the message
 // parameter, rather than just a string type, as this gives us more flexibility over the values
-// that we can include in the response.
+// that we can include in the response. The request ID lets clients point us to the log entries of
+// the request.
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message interface{}) {
 	env := envelope{"error": message}
+	if id := jsonlog.RequestID(r.Context()); id != "" {
+		env["request_id"] = id
+	}
 
 	// Write the response using the writeJSON() helper. If this happens to return an error
 	// then log it, and fall back to sending the client an empty response with a 500 Internal
@@ -34,14 +46,48 @@ func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, st
 // serverErrorResponse method is used when our application encounters an unexpected problem
 // at runtime. it logs the detailed error message, then uses the errorResponse() helper to send a
 // 500 Internal Server Error status code and JSON response (containing the generic error message)
-// to the client
+// to the client. Queries that were cancelled or timed out get their own responses.
 func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	err = model.QueryError(r.Context(), err)
+	switch {
+	case errors.Is(err, model.ErrQueryCanceled):
+		app.queryCanceledResponse(w, r, err)
+		return
+	case errors.Is(err, model.ErrQueryTimeout):
+		app.queryTimeoutResponse(w, r, err)
+		return
+	}
+
 	app.logError(r, err)
 
 	message := "the server encountered a problem and could not process your request"
 	app.errorResponse(w, r, 500, message)
 }
 
+// statusClientClosedRequest is the non-standard status code nginx logs for requests the client
+// closed before the response was sent. Nobody receives it, it only shows up in metrics and traces.
+const statusClientClosedRequest = 499
+
+// queryCanceledResponse is used when a query was cancelled because the client went away. That is
+// not a problem of the server, so it is only logged at the INFO level.
+func (app *application) queryCanceledResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.PrintInfoContext(r.Context(), err.Error(), map[string]string{
+		"request_method": r.Method,
+		"request_url":    r.URL.String(),
+	})
+
+	w.WriteHeader(statusClientClosedRequest)
+}
+
+// queryTimeoutResponse is used when a query ran longer than -db-query-timeout. It sends a 503
+// Service Unavailable status code, the request may well succeed when retried.
+func (app *application) queryTimeoutResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, err)
+
+	message := "the database took too long to respond, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
 // notFoundResponse method is used to send a 404 Not Found status code and JSON response to the
 // client.
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
@@ -92,6 +138,13 @@ func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
 }
 
+// invalidAPIKeyResponse sends a JSON-formatted error with a 401 Unauthorized status code to the
+// client when an API key is unknown, revoked or expired.
+func (app *application) invalidAPIKeyResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid, revoked or expired API key"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
 // authenticationRequiredResponse sends a JSON-formatted error with a 401 Unauthorized status code
 // to the client.
 func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
@@ -109,4 +162,43 @@ func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.R
 func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
-}
\ No newline at end of file
+}
+
+// twoFactorEnabledResponse sends a JSON-formatted error with a 409 Conflict status code when the
+// user tries to enroll a second factor while two-factor authentication is already enabled.
+func (app *application) twoFactorEnabledResponse(w http.ResponseWriter, r *http.Request) {
+	message := "two-factor authentication is already enabled for your user account"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// twoFactorRequiredResponse sends a JSON-formatted error with a 403 Forbidden status code to
+// privileged users who must enable two-factor authentication first.
+func (app *application) twoFactorRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account must have two-factor authentication enabled to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// tooManyLoginAttemptsResponse sends a JSON-formatted error with a 429 Too Many Requests status
+// code and a Retry-After header to clients that are locked out after failed logins.
+func (app *application) tooManyLoginAttemptsResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	message := "too many failed login attempts, please try again later"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+// lastAdminResponse sends a JSON-formatted error with a 409 Conflict status code when the admin
+// role would be revoked from the only user who has it.
+func (app *application) lastAdminResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the admin role can't be revoked from the last admin"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// rateLimitExceededResponse sends a JSON-formatted error with a 429 Too Many Requests status code
+// and a Retry-After header to clients that exceed a rate limit.
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	message := "rate limit exceeded"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// readList reads a comma separated query string value, checking that every item is one of
+// allowed.
+func (app *application) readList(qs url.Values, key string, allowed []string, v *validator.Validator) []string {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if !validator.In(item, allowed...) {
+			v.AddError(key, fmt.Sprintf("must only contain %s", strings.Join(allowed, ", ")))
+			return nil
+		}
+		if !slices.Contains(list, item) {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// readFields reads the fields parameter, the fields of record to respond with. All of them are
+// returned if it's empty.
+func (app *application) readFields(qs url.Values, record any, v *validator.Validator) []string {
+	return app.readList(qs, "fields", jsonFields(record), v)
+}
+
+// jsonFields returns the names of the JSON fields of a struct.
+func jsonFields(record any) []string {
+	t := reflect.TypeOf(record)
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
+
+// project converts records to JSON objects with only the given fields, all of them if fields is
+// empty. The values returned by embed replace the fields of the same name, whether they were
+// selected or not; embed may be nil. Records are returned as they are when nothing changes, so
+// that their fields keep their order.
+func project[T any](records []T, fields []string, embed func(T) map[string]any) (any, error) {
+	if len(fields) == 0 && embed == nil {
+		return records, nil
+	}
+	if records == nil {
+		return nil, nil
+	}
+
+	objects := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		js, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(js, &all); err != nil {
+			return nil, err
+		}
+
+		object := make(map[string]any, len(all))
+		for name, value := range all {
+			if len(fields) == 0 || slices.Contains(fields, name) {
+				object[name] = value
+			}
+		}
+
+		if embed != nil {
+			for name, value := range embed(record) {
+				object[name] = value
+			}
+		}
+
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// projectOne is project for a single record.
+func projectOne[T any](record T, fields []string, embed func(T) map[string]any) (any, error) {
+	projected, err := project([]T{record}, fields, embed)
+	if err != nil {
+		return nil, err
+	}
+
+	if objects, ok := projected.([]map[string]any); ok {
+		return objects[0], nil
+	}
+
+	return record, nil
+}
+
+// gameIncludes are the related records which can be embedded into games.
+var gameIncludes = []string{"quiz", "player"}
+
+// gameRelations holds the quizes and players of games by id.
+type gameRelations struct {
+	quizes  map[int]*model.Quiz
+	players map[int]*model.Player
+}
+
+// loadGameRelations retrieves the related records of games named in include, each kind with a
+// single query.
+func (app *application) loadGameRelations(ctx context.Context, games []*model.Game, include []string) (gameRelations, error) {
+	var relations gameRelations
+	if len(games) == 0 {
+		return relations, nil
+	}
+
+	if slices.Contains(include, "quiz") {
+		quizes, err := app.quizesByID(ctx, uniqueIDs(games, func(g *model.Game) int { return g.Quiz }))
+		if err != nil {
+			return relations, err
+		}
+		relations.quizes = quizes
+	}
+
+	if slices.Contains(include, "player") {
+		players, err := app.playersByID(ctx, uniqueIDs(games, func(g *model.Game) int { return g.Player }))
+		if err != nil {
+			return relations, err
+		}
+		relations.players = players
+	}
+
+	return relations, nil
+}
+
+// embed returns the function for project which replaces the ids of the related records loaded
+// into relations with the records, or nil if none were loaded. A record which is gone by now
+// becomes null.
+func (relations gameRelations) embed() func(*model.Game) map[string]any {
+	if relations.quizes == nil && relations.players == nil {
+		return nil
+	}
+
+	return func(g *model.Game) map[string]any {
+		embedded := make(map[string]any)
+		if relations.quizes != nil {
+			embedded["quiz"] = relations.quizes[g.Quiz]
+		}
+		if relations.players != nil {
+			embedded["player"] = relations.players[g.Player]
+		}
+		return embedded
+	}
+}
+
+// quizesByID retrieves the quizes with the ids with a single query.
+func (app *application) quizesByID(ctx context.Context, ids []int) (map[int]*model.Quiz, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	quizes, err := app.models.Quizes.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*model.Quiz, len(quizes))
+	for _, quiz := range quizes {
+		i, _ := strconv.Atoi(quiz.Id)
+		byID[i] = quiz
+	}
+
+	return byID, nil
+}
+
+// playersByID retrieves the players with the ids with a single query.
+func (app *application) playersByID(ctx context.Context, ids []int) (map[int]*model.Player, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	players, err := app.models.Players.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*model.Player, len(players))
+	for _, player := range players {
+		i, _ := strconv.Atoi(player.Id)
+		byID[i] = player
+	}
+
+	return byID, nil
+}
+
+// uniqueIDs returns the ids returned by id for the records, without repetitions.
+func uniqueIDs[T any, ID comparable](records []T, id func(T) ID) []ID {
+	var ids []ID
+	for _, record := range records {
+		if i := id(record); !slices.Contains(ids, i) {
+			ids = append(ids, i)
+		}
+	}
+
+	return ids
+}
+
+// owner is the user who created a player or a quiz, as embedded with include=owner. Only the
+// name is public, not the email address.
+type owner struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// ownersByID retrieves the owners with the ids with a single query. The map is empty rather than
+// nil without ids, so that it tells that the owners were loaded.
+func (app *application) ownersByID(ctx context.Context, ids []int64) (map[int64]*owner, error) {
+	byID := make(map[int64]*owner, len(ids))
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	users, err := app.models.Users.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		byID[user.ID] = &owner{ID: user.ID, Name: user.Name}
+	}
+
+	return byID, nil
+}
+
+// ownerIDs returns the ids of the owners of records, without repetitions and records without an
+// owner.
+func ownerIDs[T any](records []T, ownerID func(T) *int64) []int64 {
+	var ids []int64
+	for _, record := range records {
+		if id := ownerID(record); id != nil && !slices.Contains(ids, *id) {
+			ids = append(ids, *id)
+		}
+	}
+
+	return ids
+}
+
+// embedOwner returns the owner to embed for an owner id, null for records without an owner or
+// whose owner is gone.
+func embedOwner(owners map[int64]*owner, ownerID *int64) *owner {
+	if ownerID == nil {
+		return nil
+	}
+
+	return owners[*ownerID]
+}
+
+// playerIncludes are the related records which can be embedded into players.
+var playerIncludes = []string{"owner", "games"}
+
+// playerRelations holds the owners of players by id and their finished games by player.
+type playerRelations struct {
+	owners map[int64]*owner
+	games  map[int][]*model.Game
+}
+
+// loadPlayerRelations retrieves the related records of players named in include, each kind with
+// a single query.
+func (app *application) loadPlayerRelations(ctx context.Context, players []*model.Player, include []string) (playerRelations, error) {
+	var relations playerRelations
+	if len(players) == 0 {
+		return relations, nil
+	}
+
+	if slices.Contains(include, "owner") {
+		owners, err := app.ownersByID(ctx, ownerIDs(players, func(p *model.Player) *int64 { return p.OwnerID }))
+		if err != nil {
+			return relations, err
+		}
+		relations.owners = owners
+	}
+
+	if slices.Contains(include, "games") {
+		ids := uniqueIDs(players, func(p *model.Player) int {
+			id, _ := strconv.Atoi(p.Id)
+			return id
+		})
+
+		games, err := app.models.Games.GetForPlayers(ctx, ids)
+		if err != nil {
+			return relations, err
+		}
+
+		relations.games = make(map[int][]*model.Game, len(ids))
+		for _, game := range games {
+			relations.games[game.Player] = append(relations.games[game.Player], game)
+		}
+	}
+
+	return relations, nil
+}
+
+// embed returns the function for project which adds the related records loaded into relations,
+// or nil if none were loaded. A player without games gets an empty list.
+func (relations playerRelations) embed() func(*model.Player) map[string]any {
+	if relations.owners == nil && relations.games == nil {
+		return nil
+	}
+
+	return func(p *model.Player) map[string]any {
+		embedded := make(map[string]any)
+		if relations.owners != nil {
+			embedded["owner"] = embedOwner(relations.owners, p.OwnerID)
+		}
+		if relations.games != nil {
+			id, _ := strconv.Atoi(p.Id)
+			games := relations.games[id]
+			if games == nil {
+				games = []*model.Game{}
+			}
+			embedded["games"] = games
+		}
+		return embedded
+	}
+}
+
+// quizIncludes are the related records which can be embedded into quizes.
+var quizIncludes = []string{"owner"}
+
+// quizRelations holds the owners of quizes by id.
+type quizRelations struct {
+	owners map[int64]*owner
+}
+
+// loadQuizRelations retrieves the related records of quizes named in include, each kind with a
+// single query.
+func (app *application) loadQuizRelations(ctx context.Context, quizes []*model.Quiz, include []string) (quizRelations, error) {
+	var relations quizRelations
+	if len(quizes) == 0 {
+		return relations, nil
+	}
+
+	if slices.Contains(include, "owner") {
+		owners, err := app.ownersByID(ctx, ownerIDs(quizes, func(q *model.Quiz) *int64 { return q.OwnerID }))
+		if err != nil {
+			return relations, err
+		}
+		relations.owners = owners
+	}
+
+	return relations, nil
+}
+
+// embed returns the function for project which adds the related records loaded into relations,
+// or nil if none were loaded.
+func (relations quizRelations) embed() func(*model.Quiz) map[string]any {
+	if relations.owners == nil {
+		return nil
+	}
+
+	return func(q *model.Quiz) map[string]any {
+		return map[string]any{"owner": embedOwner(relations.owners, q.OwnerID)}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+// countingUsers counts the queries for the owners of players and quizes.
+type countingUsers struct {
+	model.UserStore
+	getByIDs int
+}
+
+func (u *countingUsers) GetByIDs(ctx context.Context, ids []int64) ([]*model.User, error) {
+	u.getByIDs++
+	return u.UserStore.GetByIDs(ctx, ids)
+}
+
+// countingGames counts the queries for the games of players.
+type countingGames struct {
+	model.GameStore
+	getForPlayers int
+}
+
+func (g *countingGames) GetForPlayers(ctx context.Context, players []int) ([]*model.Game, error) {
+	g.getForPlayers++
+	return g.GameStore.GetForPlayers(ctx, players)
+}
+
+func TestInclude(t *testing.T) {
+	forEachBackend(t, testInclude)
+}
+
+func testInclude(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+	ctx := context.Background()
+
+	alice, aliceToken := newTestUser(t, app, "alice@example.com", model.RoleAuthor)
+	_, bobToken := newTestUser(t, app, "bob@example.com", model.RolePlayer)
+
+	res := ts.request(t, http.MethodPost, "/v1/quizes", aliceToken, map[string]any{
+		"category":  "history",
+		"reward":    5,
+		"questions": []string{"Year of the moon landing?"},
+		"answers":   []string{"1969"},
+	}).wantStatus(t, http.StatusCreated)
+	quiz := id(t, res.object(t, "quiz")["id"])
+
+	var players []string
+	for _, p := range []struct{ name, token string }{{"first", aliceToken}, {"second", aliceToken}, {"third", bobToken}} {
+		res := ts.request(t, http.MethodPost, "/v1/players", p.token, map[string]any{"name": p.name}).wantStatus(t, http.StatusCreated)
+		players = append(players, id(t, res.object(t, "player")["id"]))
+	}
+
+	// first finishes the quiz twice, second has a game in progress, third hasn't played.
+	quizID, _ := strconv.Atoi(quiz)
+	first, _ := strconv.Atoi(players[0])
+	second, _ := strconv.Atoi(players[1])
+	for i := 0; i < 2; i++ {
+		if err := app.models.Games.Finish(ctx, &model.Game{Player: first, Quiz: quizID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := app.models.Games.Insert(ctx, &model.Game{Player: second, Quiz: quizID, InProgress: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	users := &countingUsers{UserStore: app.models.Users}
+	games := &countingGames{GameStore: app.models.Games}
+	app.models.Users, app.models.Games = users, games
+
+	res = ts.request(t, http.MethodGet, "/v1/players?include=owner,games&fields=id,name&sort=id", "", nil).wantStatus(t, http.StatusOK)
+	list := res.list(t, "players")
+	if len(list) != 3 {
+		t.Fatalf("got players %v, want 3", list)
+	}
+	if users.getByIDs != 1 || games.getForPlayers != 1 {
+		t.Errorf("got %d queries for owners and %d for games, want one of each for the page", users.getByIDs, games.getForPlayers)
+	}
+
+	wantGames := []int{2, 0, 0}
+	wantOwners := []string{"alice", "alice", "bob"}
+	for i, item := range list {
+		player := item.(map[string]any)
+		if _, ok := player["score"]; ok {
+			t.Errorf("got player %v, want only the fields asked for and the includes", player)
+		}
+
+		owner, _ := player["owner"].(map[string]any)
+		if owner["name"] != wantOwners[i] {
+			t.Errorf("got owner %v of player %v, want %s", player["owner"], player["name"], wantOwners[i])
+		}
+		if _, ok := owner["email"]; ok {
+			t.Errorf("got owner %v, want no email address", owner)
+		}
+
+		played, ok := player["games"].([]any)
+		if !ok || len(played) != wantGames[i] {
+			t.Errorf("got games %v of player %v, want %d finished games", player["games"], player["name"], wantGames[i])
+		}
+	}
+
+	// A single player, and the players of a quiz.
+	res = ts.request(t, http.MethodGet, "/v1/players/"+players[0]+"?include=games", "", nil).wantStatus(t, http.StatusOK)
+	if played, _ := res.object(t, "player")["games"].([]any); len(played) != 2 {
+		t.Errorf("got games %v, want the 2 finished games", played)
+	}
+	if _, ok := res.object(t, "player")["owner"]; ok {
+		t.Errorf("got player %v, want no owner unless included", res.object(t, "player"))
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/quizes/"+quiz+"/players?include=owner", "", nil).wantStatus(t, http.StatusOK)
+	for _, item := range res.list(t, "players") {
+		if owner, _ := item.(map[string]any)["owner"].(map[string]any); owner["id"] != float64(alice.ID) {
+			t.Errorf("got owner %v, want alice", owner)
+		}
+	}
+
+	// Quizes, by themselves and as played by a player.
+	for _, path := range []string{"/v1/quizes?include=owner", "/v1/players/" + players[0] + "/quizes?include=owner"} {
+		res = ts.request(t, http.MethodGet, path, "", nil).wantStatus(t, http.StatusOK)
+		quizes := res.list(t, "quizes")
+		if len(quizes) == 0 {
+			t.Fatalf("got no quizes from %s", path)
+		}
+		for _, item := range quizes {
+			if owner, _ := item.(map[string]any)["owner"].(map[string]any); owner["name"] != "alice" {
+				t.Errorf("got owner %v from %s, want alice", owner, path)
+			}
+		}
+	}
+
+	// A record without an owner embeds null.
+	orphan := &model.Quiz{Category: "misc", Questions: []string{"?"}, Answers: []string{"!"}}
+	if err := app.models.Quizes.Insert(ctx, orphan); err != nil {
+		t.Fatal(err)
+	}
+	res = ts.request(t, http.MethodGet, "/v1/quizes/"+orphan.Id+"?include=owner", "", nil).wantStatus(t, http.StatusOK)
+	if owner, ok := res.object(t, "quiz")["owner"]; !ok || owner != nil {
+		t.Errorf("got owner %v, want null", owner)
+	}
+
+	for _, path := range []string{
+		"/v1/players?include=quiz",
+		"/v1/players/" + players[0] + "?include=email",
+		"/v1/quizes?include=games",
+		"/v1/quizes/" + quiz + "?include=player",
+	} {
+		ts.request(t, http.MethodGet, path, "", nil).wantStatus(t, http.StatusUnprocessableEntity)
+	}
+}
@@ -24,17 +24,41 @@ func (app *application) createGameHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// A game is started by an existing player on an existing quiz.
+	v := validator.New()
+
+	if _, err := app.models.Players.Get(r.Context(), input.Player); errors.Is(err, model.ErrRecordNotFound) {
+		v.AddError("player", "must be an existing player")
+	} else if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if _, err := app.models.Quizes.Get(r.Context(), input.Quiz); errors.Is(err, model.ErrRecordNotFound) {
+		v.AddError("quiz", "must be an existing quiz")
+	} else if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	game := &model.Game{
-		Player: input.Player,
-		Quiz:   input.Quiz,
+		Player:     input.Player,
+		Quiz:       input.Quiz,
+		InProgress: true,
 	}
 
-	err = app.models.Games.Insert(game)
+	err = app.models.Games.Insert(r.Context(), game)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.metrics.gamesStarted.Inc()
+
 	app.writeJSON(w, http.StatusCreated, envelope{"game": game}, nil)
 }
 
@@ -42,8 +66,7 @@ func (app *application) getGamesList(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Player       int
 		Quiz         int
-		FinishedFrom string
-		FinishedTo   string
+		Finished     model.TimeRange
 		model.Filters
 	}
 	v := validator.New()
@@ -54,8 +77,7 @@ func (app *application) getGamesList(w http.ResponseWriter, r *http.Request) {
 	// by the client.
 	input.Player = app.readInt(qs, "player", 0, v)
 	input.Quiz = app.readInt(qs, "quiz", 0, v)
-	input.FinishedFrom = app.readStrings(qs, "finisedFrom", "1980-01-01 00:00:00+06")
-	input.FinishedTo = app.readStrings(qs, "finishedTo", "1980-01-01 00:00:00+06")
+	input.Finished = app.readFinishedRange(qs, v)
 
 	// Ge the page and page_size query string value as integers. Notice that we set the default
 	// page value to 1 and default page_size to 20, and that we pass the validator instance
@@ -63,6 +85,10 @@ func (app *application) getGamesList(w http.ResponseWriter, r *http.Request) {
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 
+	// Pages may also start after or before a cursor of a previous page, and skip counting the
+	// records with count=false.
+	app.readCursors(qs, &input.Filters, v)
+
 	// Extract the sort query string value, falling back to "id" if it is not provided
 	// by the client (which will imply an ascending sort on game ID).
 	input.Filters.Sort = app.readStrings(qs, "sort", "id")
@@ -76,18 +102,35 @@ func (app *application) getGamesList(w http.ResponseWriter, r *http.Request) {
 		"-id", "-finished", "-player", "-quiz",
 	}
 
+	// Respond with only the fields asked for, and embed the quizes and players of the games
+	// asked for in place of their ids.
+	fields := app.readFields(qs, model.Game{}, v)
+	include := app.readList(qs, "include", gameIncludes, v)
+
 	if model.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	games, metadata, err := app.models.Games.GetAll(input.Player, input.Quiz, input.FinishedFrom, input.FinishedTo, input.Filters)
+	games, metadata, err := app.models.Games.GetAll(r.Context(), input.Player, input.Quiz, false, input.Finished, input.Filters)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
+	relations, err := app.loadGameRelations(r.Context(), games, include)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"games": games, "metadata": metadata}, nil)
+	records, err := project(games, fields, relations.embed())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"games": records, "metadata": metadata}, nil)
 }
 
 func (app *application) getGameHandler(w http.ResponseWriter, r *http.Request) {
@@ -97,7 +140,18 @@ func (app *application) getGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	game, err := app.models.Games.Get(id)
+	v := validator.New()
+	qs := r.URL.Query()
+
+	fields := app.readFields(qs, model.Game{}, v)
+	include := app.readList(qs, "include", gameIncludes, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	game, err := app.models.Games.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -108,7 +162,19 @@ func (app *application) getGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"game": game}, nil)
+	relations, err := app.loadGameRelations(r.Context(), []*model.Game{game}, include)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	record, err := projectOne(game, fields, relations.embed())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"game": record}, nil)
 }
 
 func (app *application) answerGameHandler(w http.ResponseWriter, r *http.Request) {
@@ -118,7 +184,7 @@ func (app *application) answerGameHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	quiz, err := app.models.Quizes.Get(id)
+	quiz, err := app.models.Quizes.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -140,15 +206,28 @@ func (app *application) answerGameHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	v := validator.New()
+
+	v.Check(input.Player != nil, "playerId", "must be provided")
+	v.Check(input.Answers != nil, "answers", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	playerAnswers := *input.Answers
 	if !reflect.DeepEqual(quiz.Answers, playerAnswers) {
+		app.metrics.answersGraded.Inc("incorrect")
 		app.writeJSON(w, http.StatusOK, envelope{"result": "Answers are incorrect"}, nil)
 		return
 	}
 
+	app.metrics.answersGraded.Inc("correct")
+
 	// Give player a score
 	playerId, _ := strconv.Atoi(*input.Player)
-	player, err := app.models.Players.Get(playerId)
+	player, err := app.models.Players.Get(r.Context(), playerId)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -159,7 +238,7 @@ func (app *application) answerGameHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 	player.Score += quiz.Reward
-	err = app.models.Players.Update(player)
+	err = app.models.Players.Update(r.Context(), player)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -170,18 +249,25 @@ func (app *application) answerGameHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Create new games record
+	// Finish the game the player started on the quiz, or record a finished one
 	quizId, _ := strconv.Atoi(quiz.Id)
 	game := &model.Game{
 		Player: playerId,
 		Quiz:   quizId,
 	}
-	err = app.models.Games.Insert(game)
+	err = app.models.Games.Finish(r.Context(), game)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.metrics.gamesCompleted.Inc()
+	app.publishEvent(r.Context(), model.EventGameCompleted, envelope{
+		"game":   envelope{"id": game.Id, "player": game.Player, "quiz": game.Quiz},
+		"player": player,
+		"reward": quiz.Reward,
+	})
+
 	app.writeJSON(w, http.StatusOK, envelope{"result": "Answers are correct"}, nil)
 }
 
@@ -254,7 +340,7 @@ func (app *application) deleteGameHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Games.Delete(id)
+	err = app.models.Games.Delete(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
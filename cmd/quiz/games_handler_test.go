@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+func TestGames(t *testing.T) {
+	forEachBackend(t, testGames)
+}
+
+func testGames(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+
+	_, author := newTestUser(t, app, "author@example.com", model.RoleAuthor)
+	_, token := newTestUser(t, app, "player@example.com", model.RolePlayer)
+	_, moderator := newTestUser(t, app, "mod@example.com", model.RoleModerator)
+
+	res := ts.request(t, http.MethodPost, "/v1/quizes", author, map[string]any{
+		"category":  "geography",
+		"reward":    10,
+		"questions": []string{"Capital of Kazakhstan?", "Longest river?"},
+		"answers":   []string{"Astana", "Nile"},
+	}).wantStatus(t, http.StatusCreated)
+	quiz := id(t, res.object(t, "quiz")["id"])
+
+	res = ts.request(t, http.MethodPost, "/v1/players", token, map[string]any{"name": "alice"}).wantStatus(t, http.StatusCreated)
+	player := id(t, res.object(t, "player")["id"])
+
+	start := map[string]any{"player": json.Number(player), "quiz": json.Number(quiz)}
+
+	ts.request(t, http.MethodPost, "/v1/games", "", start).wantStatus(t, http.StatusUnauthorized)
+
+	res = ts.request(t, http.MethodPost, "/v1/games", token, start).wantStatus(t, http.StatusCreated)
+	game := id(t, res.object(t, "game")["id"])
+
+	res = ts.request(t, http.MethodGet, "/v1/games/"+game+"?include=quiz,player", "", nil).wantStatus(t, http.StatusOK)
+	if got := res.object(t, "game")["in_progress"]; got != true {
+		t.Errorf("got in_progress %v, want true", got)
+	}
+	if got := res.object(t, "game", "quiz")["category"]; got != "geography" {
+		t.Errorf("got embedded quiz category %v, want geography", got)
+	}
+
+	// A game in progress hasn't been played yet, and hasn't finished at any time.
+	for _, path := range []string{
+		"/v1/players/" + player + "/quizes",
+		"/v1/quizes/" + quiz + "/players",
+		"/v1/games?player=" + player + "&last=1d",
+	} {
+		res = ts.request(t, http.MethodGet, path, "", nil).wantStatus(t, http.StatusOK)
+		for key, value := range res.body {
+			if list, ok := value.([]any); ok && len(list) != 0 {
+				t.Errorf("got %s %v from %s, want none while the game is in progress", key, list, path)
+			}
+		}
+	}
+	res = ts.request(t, http.MethodGet, "/v1/games?player="+player, "", nil).wantStatus(t, http.StatusOK)
+	if games := res.list(t, "games"); len(games) != 1 {
+		t.Errorf("got games %v, want the game in progress", games)
+	}
+
+	res = ts.request(t, http.MethodPost, "/v1/games/"+quiz, token, map[string]any{
+		"playerId": player,
+		"answers":  []string{"Astana", "Amazon"},
+	}).wantStatus(t, http.StatusOK)
+	if res.body["result"] != "Answers are incorrect" {
+		t.Errorf("got result %v, want Answers are incorrect", res.body["result"])
+	}
+
+	res = ts.request(t, http.MethodPost, "/v1/games/"+quiz, token, map[string]any{
+		"playerId": player,
+		"answers":  []string{"Astana", "Nile"},
+	}).wantStatus(t, http.StatusOK)
+	if res.body["result"] != "Answers are correct" {
+		t.Errorf("got result %v, want Answers are correct", res.body["result"])
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/players/"+player, "", nil).wantStatus(t, http.StatusOK)
+	if got := res.object(t, "player")["score"]; got != float64(10) {
+		t.Errorf("got score %v, want the reward of 10", got)
+	}
+
+	// The answer finished the game the player started.
+	res = ts.request(t, http.MethodGet, "/v1/games?player="+player+"&last=1d", "", nil).wantStatus(t, http.StatusOK)
+	games := res.list(t, "games")
+	if len(games) != 1 || games[0].(map[string]any)["in_progress"] != false {
+		t.Errorf("got games %v, want the one finished game", games)
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/players/"+player+"/quizes", "", nil).wantStatus(t, http.StatusOK)
+	if got := res.list(t, "quizes"); len(got) != 1 || id(t, got[0].(map[string]any)["id"]) != quiz {
+		t.Errorf("got quizes %v, want the quiz %s", got, quiz)
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/quizes/"+quiz+"/players", "", nil).wantStatus(t, http.StatusOK)
+	if got := res.list(t, "players"); len(got) != 1 || id(t, got[0].(map[string]any)["id"]) != player {
+		t.Errorf("got players %v, want the player %s", got, player)
+	}
+
+	ts.request(t, http.MethodDelete, "/v1/games/"+game, token, nil).wantStatus(t, http.StatusForbidden)
+	ts.request(t, http.MethodDelete, "/v1/games/"+game, moderator, nil).wantStatus(t, http.StatusOK)
+
+	ts.request(t, http.MethodGet, "/v1/games/"+game, "", nil).wantStatus(t, http.StatusNotFound)
+	ts.request(t, http.MethodDelete, "/v1/games/"+game, moderator, nil).wantStatus(t, http.StatusNotFound)
+}
+
+func TestGamesErrors(t *testing.T) {
+	forEachBackend(t, testGamesErrors)
+}
+
+func testGamesErrors(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+
+	_, author := newTestUser(t, app, "author@example.com", model.RoleAuthor)
+	_, token := newTestUser(t, app, "player@example.com", model.RolePlayer)
+	_, moderator := newTestUser(t, app, "mod@example.com", model.RoleModerator)
+	_, nobody := newTestUser(t, app, "nobody@example.com")
+
+	res := ts.request(t, http.MethodPost, "/v1/quizes", author, map[string]any{
+		"category":  "geography",
+		"reward":    10,
+		"questions": []string{"Capital of Kazakhstan?"},
+		"answers":   []string{"Astana"},
+	}).wantStatus(t, http.StatusCreated)
+	quiz := id(t, res.object(t, "quiz")["id"])
+
+	res = ts.request(t, http.MethodPost, "/v1/players", token, map[string]any{"name": "alice"}).wantStatus(t, http.StatusCreated)
+	player := id(t, res.object(t, "player")["id"])
+
+	res = ts.request(t, http.MethodPost, "/v1/games", token, map[string]any{"player": json.Number(player), "quiz": json.Number(quiz)}).wantStatus(t, http.StatusCreated)
+	game := "/v1/games/" + id(t, res.object(t, "game")["id"])
+
+	answers := map[string]any{"playerId": player, "answers": []string{"Astana"}}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   any
+		want   int
+	}{
+		{"list with an unknown sort", http.MethodGet, "/v1/games?sort=answers", "", nil, http.StatusUnprocessableEntity},
+		{"list with an invalid range", http.MethodGet, "/v1/games?last=forever", "", nil, http.StatusUnprocessableEntity},
+		{"list with a range too long", http.MethodGet, "/v1/games?last=9999999999999999d", "", nil, http.StatusUnprocessableEntity},
+		{"start without the permission", http.MethodPost, "/v1/games", nobody, map[string]any{"player": json.Number(player), "quiz": json.Number(quiz)}, http.StatusForbidden},
+		{"start a missing quiz", http.MethodPost, "/v1/games", token, map[string]any{"player": json.Number(player), "quiz": json.Number("999999")}, http.StatusUnprocessableEntity},
+		{"start with a missing player", http.MethodPost, "/v1/games", token, map[string]any{"player": json.Number("999999"), "quiz": json.Number(quiz)}, http.StatusUnprocessableEntity},
+		{"get a missing game", http.MethodGet, "/v1/games/999999", "", nil, http.StatusNotFound},
+		{"answer without the permission", http.MethodPost, "/v1/games/" + quiz, nobody, answers, http.StatusForbidden},
+		{"answer a missing quiz", http.MethodPost, "/v1/games/999999", token, answers, http.StatusNotFound},
+		{"answer without answers", http.MethodPost, "/v1/games/" + quiz, token, map[string]any{"playerId": player}, http.StatusUnprocessableEntity},
+		{"answer without a player", http.MethodPost, "/v1/games/" + quiz, token, map[string]any{"answers": []string{"Astana"}}, http.StatusUnprocessableEntity},
+		{"answer with a missing player", http.MethodPost, "/v1/games/" + quiz, token, map[string]any{"playerId": "999999", "answers": []string{"Astana"}}, http.StatusNotFound},
+		{"delete a missing game", http.MethodDelete, "/v1/games/999999", moderator, nil, http.StatusNotFound},
+		{"delete without the permission", http.MethodDelete, game, token, nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.request(t, tt.method, tt.path, tt.token, tt.body).wantStatus(t, tt.want)
+		})
+	}
+}
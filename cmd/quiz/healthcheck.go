@@ -1,14 +1,27 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/vcs"
 )
 
 func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
+	build := vcs.Info()
+
 	env := envelope{
 		"status": "available", "system_info": map[string]string{
 			"environment": app.config.env,
-			"version":     version},
+			"version":     version,
+			"commit":      build.Revision,
+			"build_time":  build.Time,
+			"modified":    fmt.Sprintf("%t", build.Modified),
+			"uptime":      time.Since(app.startedAt).Round(time.Second).String()},
 	}
 	// Add a 10 second delay to demonstrate the server returning a response after shutting down
 	// time.Sleep(10 * time.Second)
@@ -16,4 +29,128 @@ func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
-}
\ No newline at end of file
+}
+
+// livezHandler tells that the process is running. It never checks dependencies, restarting the
+// process wouldn't fix them.
+func (app *application) livezHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, http.StatusOK, envelope{"status": "alive"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readyzHandler tells whether the instance should get traffic: the database answers, its schema
+// is migrated, the background workers keep working and the server isn't shutting down. It
+// answers 503 Service Unavailable with the failed checks otherwise.
+func (app *application) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{
+		"database":   "ok",
+		"migrations": "ok",
+		"workers":    "ok",
+		"shutdown":   "ok",
+	}
+	ready := true
+
+	fail := func(check, reason string) {
+		checks[check] = reason
+		ready = false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := app.db.PingContext(ctx); err != nil {
+		app.logger.PrintError(err, map[string]string{"check": "database"})
+		fail("database", "unavailable")
+	} else if reason := app.checkMigrations(ctx); reason != "" {
+		fail("migrations", reason)
+	}
+
+	if stalled := app.workers.stalled(time.Now()); len(stalled) > 0 {
+		fail("workers", fmt.Sprintf("stalled: %v", stalled))
+	}
+
+	if app.shuttingDown.Load() {
+		fail("shutdown", "shutting down")
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+
+	err := app.writeJSON(w, code, envelope{"status": status, "checks": checks}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkMigrations compares the version of the schema with the one the application migrated to on
+// start. A later version is fine, a newer instance may have migrated already. It returns why the
+// schema isn't usable, or an empty string.
+func (app *application) checkMigrations(ctx context.Context) string {
+	// Nothing to compare with if the migrations aren't applied on start.
+	if app.migrationVersion == 0 {
+		return ""
+	}
+
+	var (
+		version uint
+		dirty   bool
+	)
+
+	// Both migrate drivers keep the version in this table.
+	err := app.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	switch {
+	case err != nil:
+		app.logger.PrintError(err, map[string]string{"check": "migrations"})
+		return "unknown version"
+	case dirty:
+		return fmt.Sprintf("version %d is dirty", version)
+	case version < app.migrationVersion:
+		return fmt.Sprintf("version %d is older than %d", version, app.migrationVersion)
+	}
+
+	return ""
+}
+
+// workerHealth tracks the background workers. A worker beats whenever it finished its work, and is
+// stalled when it missed three beats in a row.
+type workerHealth struct {
+	mu      sync.Mutex
+	workers map[string]workerBeat
+}
+
+type workerBeat struct {
+	last     time.Time
+	interval time.Duration
+}
+
+func newWorkerHealth() *workerHealth {
+	return &workerHealth{workers: make(map[string]workerBeat)}
+}
+
+// beat records that the named worker, which works every interval, just finished its work.
+func (h *workerHealth) beat(name string, interval time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.workers[name] = workerBeat{last: time.Now(), interval: interval}
+}
+
+// stalled returns the names of the stalled workers, sorted.
+func (h *workerHealth) stalled(now time.Time) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var names []string
+	for name, b := range h.workers {
+		if now.Sub(b.last) > 3*b.interval {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHealthcheck(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+
+	res := ts.request(t, http.MethodGet, "/v1/healthcheck", "", nil).wantStatus(t, http.StatusOK)
+	if got := res.object(t, "system_info")["environment"]; got != "testing" {
+		t.Errorf("got environment %v, want testing", got)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+
+	res := ts.request(t, http.MethodGet, "/livez", "", nil).wantStatus(t, http.StatusOK)
+	if res.body["status"] != "alive" {
+		t.Errorf("got status %v, want alive", res.body["status"])
+	}
+
+	ts.request(t, http.MethodGet, "/readyz", "", nil).wantStatus(t, http.StatusOK)
+
+	app.shuttingDown.Store(true)
+
+	res = ts.request(t, http.MethodGet, "/readyz", "", nil).wantStatus(t, http.StatusServiceUnavailable)
+	if got := res.object(t, "checks")["shutdown"]; got != "shutting down" {
+		t.Errorf("got shutdown check %v, want shutting down", got)
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
 	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
 	"github.com/gorilla/mux"
 )
@@ -200,4 +202,38 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 
 	// Otherwise, return the converted integer value.
 	return i
-}
\ No newline at end of file
+}
+
+// readBool is like readInt for boolean values, which are parsed by strconv.ParseBool.
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+
+	return b
+}
+
+// readCursors reads the after and before cursors of a list and whether to count its records
+// into filters.
+func (app *application) readCursors(qs url.Values, filters *model.Filters, v *validator.Validator) {
+	filters.After = app.readStrings(qs, "after", "")
+	filters.Before = app.readStrings(qs, "before", "")
+	filters.SkipCount = !app.readBool(qs, "count", true, v)
+}
+
+// clientIP returns the IP address of the client that sent the request, without the port.
+func (app *application) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
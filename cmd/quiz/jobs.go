@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/scheduler"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// jobs returns the periodic jobs of the application. A job with an interval of 0 is disabled.
+func (app *application) jobs() []scheduler.Job {
+	jobs := []scheduler.Job{
+		{
+			Name:     "purge_expired_tokens",
+			Interval: app.config.jobs.purgeTokens,
+			Timeout:  time.Minute,
+			Run: func(ctx context.Context) (int64, error) {
+				return app.models.Tokens.DeleteExpired(ctx, time.Now())
+			},
+		},
+		{
+			Name:     "expire_abandoned_games",
+			Interval: app.config.jobs.expireGames,
+			Timeout:  time.Minute,
+			Run: func(ctx context.Context) (int64, error) {
+				return app.models.Games.DeleteAbandoned(ctx, time.Now().Add(-app.config.jobs.gamesAbandonedAfter))
+			},
+		},
+		{
+			Name:     "refresh_leaderboard",
+			Interval: app.config.jobs.refreshLeaderboard,
+			Timeout:  time.Minute,
+			Run:      app.models.Leaderboard.Refresh,
+		},
+	}
+
+	var enabled []scheduler.Job
+	for _, job := range jobs {
+		if job.Interval > 0 {
+			enabled = append(enabled, job)
+		}
+	}
+
+	return enabled
+}
+
+// startJobs starts the periodic jobs. They are added to app.wg, and stop once app.stopJobs is
+// called.
+func (app *application) startJobs() {
+	var locker scheduler.Locker = scheduler.PostgresLocker{DB: app.db}
+	if app.config.db.sqlite {
+		locker = scheduler.NewLocalLocker()
+	}
+
+	s := &scheduler.Scheduler{
+		Locker:  locker,
+		History: app.models.JobRuns,
+		Jitter:  app.config.jobs.jitter,
+		Report:  app.recordJobRun,
+	}
+
+	jobs := app.jobs()
+	for _, job := range jobs {
+		app.workers.beat("job_"+job.Name, job.Interval)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.stopJobs = cancel
+
+	s.Start(ctx, &app.wg, jobs...)
+}
+
+// recordJobRun logs a run of a job and adds it to the history, where the other instances find
+// it. Runs which another instance did count as beats of the job too.
+func (app *application) recordJobRun(run scheduler.Run) {
+	if run.Skipped {
+		app.workers.beat("job_"+run.Job.Name, run.Job.Interval)
+		return
+	}
+
+	record := &model.JobRun{
+		Job:        run.Job.Name,
+		StartedAt:  run.StartedAt,
+		DurationMS: run.Duration.Milliseconds(),
+		Succeeded:  run.Err == nil,
+		Affected:   run.Affected,
+	}
+
+	properties := map[string]string{
+		"job":      run.Job.Name,
+		"duration": run.Duration.String(),
+		"affected": fmt.Sprint(run.Affected),
+	}
+
+	if run.Err != nil {
+		record.Error = run.Err.Error()
+		app.logger.PrintError(run.Err, properties)
+	} else {
+		app.workers.beat("job_"+run.Job.Name, run.Job.Interval)
+		app.logger.PrintInfo("job completed", properties)
+	}
+
+	if err := app.models.JobRuns.Insert(context.Background(), record); err != nil {
+		app.logger.PrintError(err, map[string]string{"job": run.Job.Name})
+	}
+}
+
+func (app *application) getJobRunsList(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Job string
+		model.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Job = app.readStrings(qs, "job", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	// Show the most recent runs first unless asked otherwise.
+	input.Filters.Sort = app.readStrings(qs, "sort", "-id")
+
+	input.Filters.SortSafeList = []string{
+		"id", "job", "started_at", "duration_ms",
+		"-id", "-job", "-started_at", "-duration_ms",
+	}
+
+	if model.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	runs, metadata, err := app.models.JobRuns.GetAll(r.Context(), input.Job, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"job_runs": runs, "metadata": metadata}, nil)
+}
+
+func (app *application) getLeaderboard(w http.ResponseWriter, r *http.Request) {
+	var filters model.Filters
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	filters.Sort = app.readStrings(qs, "sort", "rank")
+
+	filters.SortSafeList = []string{
+		"rank", "name", "score", "games",
+		"-rank", "-name", "-score", "-games",
+	}
+
+	if model.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	entries, metadata, err := app.models.Leaderboard.GetAll(r.Context(), filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"leaderboard": entries, "metadata": metadata}, nil)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/scheduler"
+)
+
+func TestJobRuns(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+
+	_, admin := newTestUser(t, app, "admin@example.com", model.RoleAdmin)
+
+	if last, err := app.models.JobRuns.LastSucceeded(context.Background(), "refresh_leaderboard"); err != nil || !last.IsZero() {
+		t.Fatalf("got last success %v and error %v, want none before the first run", last, err)
+	}
+
+	// Run every job once, as the scheduler would.
+	start := time.Now()
+	for _, job := range app.jobs() {
+		start := time.Now()
+		affected, err := job.Run(context.Background())
+		app.recordJobRun(scheduler.Run{Job: job, StartedAt: start, Duration: time.Since(start), Affected: affected, Err: err})
+	}
+
+	jobs := app.jobs()
+	app.recordJobRun(scheduler.Run{Job: jobs[0], StartedAt: time.Now(), Err: errors.New("connection refused")})
+
+	// The failed run isn't the last success.
+	last, err := app.models.JobRuns.LastSucceeded(context.Background(), jobs[0].Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := last.Sub(start); d < -time.Second || d > time.Second {
+		t.Errorf("got last success %v, want the run at %v", last, start)
+	}
+
+	res := ts.request(t, http.MethodGet, "/v1/jobs/runs", admin, nil).wantStatus(t, http.StatusOK)
+	runs := res.list(t, "job_runs")
+	if len(runs) != len(jobs)+1 {
+		t.Fatalf("got %d job runs, want %d", len(runs), len(jobs)+1)
+	}
+	succeeded := 0
+	for _, run := range runs {
+		if run.(map[string]any)["succeeded"] == true {
+			succeeded++
+		}
+	}
+	if succeeded != len(jobs) {
+		t.Errorf("got %d succeeded runs, want %d", succeeded, len(jobs))
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/jobs/runs?job="+jobs[0].Name+"&sort=-id", admin, nil).wantStatus(t, http.StatusOK)
+	runs = res.list(t, "job_runs")
+	if len(runs) != 2 || runs[0].(map[string]any)["error"] != "connection refused" {
+		t.Errorf("got runs %v, want the failed run first", runs)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+// loginBackoff returns how long logins are blocked after the given number of consecutive failures.
+// Nothing is blocked below the threshold. From there on the delay starts at the configured
+// lockout duration and doubles with every further failure, up to the configured maximum.
+func (app *application) loginBackoff(failures, threshold int) time.Duration {
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+
+	// Cap the exponent, the maximum is reached long before the shift could overflow.
+	exponent := failures - threshold
+	if exponent > 30 {
+		exponent = 30
+	}
+
+	backoff := app.config.lockout.base << exponent
+	if backoff <= 0 || backoff > app.config.lockout.max {
+		backoff = app.config.lockout.max
+	}
+
+	return backoff
+}
+
+// ipLockout returns how long the client IP address of the request has to wait before it may try
+// to log in again. It returns zero or a negative duration if it isn't blocked.
+func (app *application) ipLockout(r *http.Request) (time.Duration, error) {
+	since := time.Now().Add(-app.config.lockout.ipWindow)
+
+	failures, last, err := app.models.LoginAttempts.FailuresForIP(r.Context(), app.clientIP(r), since)
+	if err != nil {
+		return 0, err
+	}
+
+	// The timestamps are rounded to the second, so the last failure may lie a moment ahead.
+	backoff := app.loginBackoff(failures, app.config.lockout.ipMaxFailures)
+	if backoff == 0 {
+		return 0, nil
+	}
+
+	return time.Until(last.Add(backoff)), nil
+}
+
+// registerFailedLogin counts a failed login against the account of the user, and locks the
+// account once there were too many of them.
+func (app *application) registerFailedLogin(ctx context.Context, user *model.User) error {
+	failures, err := app.models.Users.RecordFailedLogin(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if backoff := app.loginBackoff(failures, app.config.lockout.maxFailures); backoff > 0 {
+		return app.models.Users.Lock(ctx, user.ID, time.Now().Add(backoff))
+	}
+
+	return nil
+}
+
+// resetFailedLogins clears the failed login counter of a user after a complete login.
+func (app *application) resetFailedLogins(ctx context.Context, user *model.User) error {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return nil
+	}
+
+	return app.models.Users.Unlock(ctx, user.ID)
+}
+
+// recordLoginAttempt writes an audit record of a login attempt. user may be nil if the email
+// address doesn't belong to any user. A failure to write the record is only logged, so that it
+// doesn't prevent users from logging in.
+func (app *application) recordLoginAttempt(r *http.Request, email string, user *model.User, succeeded bool, reason string) {
+	attempt := &model.LoginAttempt{
+		Email:     email,
+		IP:        app.clientIP(r),
+		Succeeded: succeeded,
+		Reason:    reason,
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+
+	if !succeeded {
+		app.metrics.loginsFailed.Inc(reason)
+	}
+
+	if err := app.models.LoginAttempts.Insert(r.Context(), attempt); err != nil {
+		app.logError(r, err)
+	}
+}
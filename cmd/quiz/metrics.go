@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/metrics"
+)
+
+// appMetrics holds the metrics of the application, exposed on /metrics.
+type appMetrics struct {
+	registry *metrics.Registry
+
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	inFlight *metrics.Gauge
+
+	gamesStarted   *metrics.CounterVec
+	gamesCompleted *metrics.CounterVec
+	answersGraded  *metrics.CounterVec
+	registrations  *metrics.CounterVec
+	loginsFailed   *metrics.CounterVec
+
+	webhookDeliveries *metrics.CounterVec
+}
+
+// newAppMetrics registers the metrics of the application, including the connection pool
+// statistics of db.
+func newAppMetrics(db *sql.DB) *appMetrics {
+	registry := metrics.NewRegistry()
+
+	m := &appMetrics{
+		registry: registry,
+
+		requests: registry.NewCounterVec("justquiz_http_requests_total", "HTTP requests by method, route and status code.", "method", "route", "status"),
+		duration: registry.NewHistogramVec("justquiz_http_request_duration_seconds", "HTTP request latency by method, route and status code.", metrics.DefaultBuckets, "method", "route", "status"),
+		inFlight: registry.NewGauge("justquiz_http_requests_in_flight", "HTTP requests currently being served."),
+
+		gamesStarted:   registry.NewCounterVec("justquiz_games_started_total", "Games started."),
+		gamesCompleted: registry.NewCounterVec("justquiz_games_completed_total", "Games completed with correct answers."),
+		answersGraded:  registry.NewCounterVec("justquiz_answers_graded_total", "Answer submissions graded, by result.", "result"),
+		registrations:  registry.NewCounterVec("justquiz_registrations_total", "Users registered, by method.", "method"),
+		loginsFailed:   registry.NewCounterVec("justquiz_logins_failed_total", "Failed logins, by reason.", "reason"),
+
+		webhookDeliveries: registry.NewCounterVec("justquiz_webhook_delivery_attempts_total", "Webhook delivery attempts, by result.", "result"),
+	}
+
+	stat := func(fn func(sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(db.Stats()) }
+	}
+
+	registry.NewGaugeFunc("justquiz_db_max_open_connections", "Maximum number of open connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	registry.NewGaugeFunc("justquiz_db_open_connections", "Established connections to the database, in use and idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	registry.NewGaugeFunc("justquiz_db_in_use_connections", "Connections to the database currently in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	registry.NewGaugeFunc("justquiz_db_idle_connections", "Idle connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	registry.NewCounterFunc("justquiz_db_wait_count_total", "Times a query had to wait for a free connection.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	registry.NewCounterFunc("justquiz_db_wait_duration_seconds_total", "Time spent waiting for a free connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	registry.NewCounterFunc("justquiz_db_max_idle_closed_total", "Connections closed because of the idle connection limit.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	registry.NewCounterFunc("justquiz_db_max_lifetime_closed_total", "Connections closed because of their maximum lifetime.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+
+	return m
+}
+
+// statusResponseWriter records the status code of a response, for the metrics and traces.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (mw *statusResponseWriter) WriteHeader(status int) {
+	if !mw.wroteHeader {
+		mw.status = status
+		mw.wroteHeader = true
+	}
+
+	mw.ResponseWriter.WriteHeader(status)
+}
+
+func (mw *statusResponseWriter) Write(b []byte) (int, error) {
+	if !mw.wroteHeader {
+		mw.status = http.StatusOK
+		mw.wroteHeader = true
+	}
+
+	return mw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter.
+func (mw *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return mw.ResponseWriter
+}
+
+// routeContextKey holds a *string where recordRoute stores the route template of the request.
+const routeContextKey = contextKey("route")
+
+// recordMetrics counts requests and records their latency. It labels requests with the route
+// template rather than the path, so that ids don't make the number of series grow without limit.
+func (app *application) recordMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		app.metrics.inFlight.Add(1)
+		defer app.metrics.inFlight.Add(-1)
+
+		// Requests that don't match any route keep this label.
+		route := "unmatched"
+		r = r.WithContext(context.WithValue(r.Context(), routeContextKey, &route))
+
+		mw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(mw, r)
+
+		method := metricsMethod(r.Method)
+		status := strconv.Itoa(mw.status)
+		app.metrics.requests.Inc(method, route, status)
+		app.metrics.duration.Observe(time.Since(start).Seconds(), method, route, status)
+	})
+}
+
+// metricsMethods are the methods that label requests as they are. Clients can send any token as
+// the method, so the others are labeled "other" to keep the number of series bounded.
+var metricsMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// metricsMethod returns the method label of a request.
+func metricsMethod(method string) string {
+	if slices.Contains(metricsMethods, method) {
+		return method
+	}
+
+	return "other"
+}
+
+// recordRoute is a router middleware, it runs once a route matched and tells recordMetrics its
+// template.
+func (app *application) recordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeContextKey).(*string); ok {
+			if template, err := mux.CurrentRoute(r).GetPathTemplate(); err == nil {
+				*route = template
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// metricsHandler serves the metrics. When it is served on the API address it requires the
+// configured basic auth credentials.
+func (app *application) metricsHandler() http.Handler {
+	handler := app.metrics.registry.Handler()
+
+	if app.config.metrics.addr != "" {
+		return handler
+	}
+
+	// Compare hashes so that the comparison takes the same time whatever the lengths are.
+	expectedUsername := sha256.Sum256([]byte(app.config.metrics.username))
+	expectedPassword := sha256.Sum256([]byte(app.config.metrics.password))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		usernameHash := sha256.Sum256([]byte(username))
+		passwordHash := sha256.Sum256([]byte(password))
+
+		if !ok ||
+			subtle.ConstantTimeCompare(usernameHash[:], expectedUsername[:]) != 1 ||
+			subtle.ConstantTimeCompare(passwordHash[:], expectedPassword[:]) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics", charset="UTF-8"`)
+			app.invalidCredentialsResponse(w, r)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scrapeMetrics returns the metrics of the application in the Prometheus text format.
+func scrapeMetrics(t *testing.T, app *application) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	app.metrics.registry.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	return rec.Body.String()
+}
+
+// wantMetric fails the test if the metrics of the application don't have the sample line.
+func wantMetric(t *testing.T, app *application, sample string) {
+	t.Helper()
+
+	if got := scrapeMetrics(t, app); !strings.Contains(got, "\n"+sample+"\n") {
+		t.Errorf("got metrics\n%s\nwant %s", got, sample)
+	}
+}
+
+func TestMetricsMethods(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+
+	// Any token is a valid method, each one mustn't make a series of its own.
+	for _, method := range []string{"BREW", "PROPFIND", "get"} {
+		ts.request(t, method, "/v1/nowhere", "", nil).wantStatus(t, http.StatusNotFound)
+	}
+	ts.request(t, http.MethodGet, "/v1/players", "", nil).wantStatus(t, http.StatusOK)
+
+	wantMetric(t, app, `justquiz_http_requests_total{method="other",route="unmatched",status="404"} 3`)
+	wantMetric(t, app, `justquiz_http_requests_total{method="GET",route="/v1/players",status="200"} 1`)
+	if got := scrapeMetrics(t, app); strings.Contains(got, `method="BREW"`) {
+		t.Errorf("got metrics\n%s\nwant no series of the method BREW", got)
+	}
+}
@@ -2,23 +2,130 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
 	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
 )
 
+// middleware wraps a handler with behaviour shared by many routes.
+type middleware func(http.Handler) http.Handler
+
+// chain wraps h with the middlewares. The first middleware is the outermost one, so it sees the
+// request first and the response last.
+func chain(h http.Handler, middlewares ...middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+
+	return h
+}
+
+// recoverPanic turns a panic in a handler into a JSON 500 Internal Server Error response instead
+// of the empty response net/http sends, and closes the connection after it.
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Create a deferred function (which will always be run in the event of a panic as Go
+		// unwinds the stack).
+		defer func() {
+			if err := recover(); err != nil {
+				// http.ErrAbortHandler is how handlers abort a response on purpose, net/http
+				// handles it quietly.
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				// Setting this header makes net/http close the connection after the response
+				// has been sent.
+				w.Header().Set("Connection", "close")
+				app.serverErrorResponse(w, r, fmt.Errorf("%v", err))
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// enableCORS lets browsers on the trusted origins call the API, and answers their preflight
+// requests. Requests from other origins get no CORS headers, so browsers block them.
+func (app *application) enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response depends on these request headers, caches must not mix them up.
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+
+		origin := r.Header.Get("Origin")
+
+		if origin != "" && slices.Contains(app.config.cors.trustedOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
+
+			// A preflight request is an OPTIONS request with the Access-Control-Request-Method
+			// header. Answer it with the allowed methods and headers and don't route it.
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET, POST, PUT, PATCH, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-API-Key")
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(app.config.cors.maxAge.Seconds())))
+
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// secureHeaders sets the standard security headers. The API only serves JSON, so nothing may be
+// loaded or framed by its responses.
+func (app *application) secureHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.config.security.headers {
+			w.Header().Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+			w.Header().Set("Referrer-Policy", "no-referrer")
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.Header().Set("X-Frame-Options", "DENY")
+			w.Header().Set("Cross-Origin-Resource-Policy", "same-site")
+		}
+
+		// HSTS only makes sense behind TLS, so it's off unless a max age is configured.
+		if app.config.security.hstsMaxAge > 0 {
+			w.Header().Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", int(app.config.security.hstsMaxAge.Seconds())))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Add the "Vary: Authorization" header to the response. This indicates to any caches
 		// that the response may vary based on the value of the Authorization header in the request.
-		w.Header().Set("Vary", "Authorization")
+		w.Header().Add("Vary", "Authorization")
+		w.Header().Add("Vary", "X-API-Key")
 
 		// Retrieve the value of the Authorization header from teh request. This will return the
 		// empty string "" if there is no such header found.
 		authorizationHeader := r.Header.Get("Authorization")
 
+		// Integrations authenticate with an API key, either in the X-API-Key header or as a
+		// bearer token starting with the API key prefix.
+		apiKey := r.Header.Get("X-API-Key")
+		if strings.HasPrefix(authorizationHeader, "Bearer "+model.APIKeyPrefix) {
+			apiKey = strings.TrimPrefix(authorizationHeader, "Bearer ")
+		}
+		if apiKey != "" {
+			r, ok := app.authenticateAPIKey(w, r, apiKey)
+			if ok {
+				next.ServeHTTP(w, r)
+			}
+			return
+		}
+
 		// If there is no Authorization header found, use the contextSetUser() helper to add
 		// an AnonymousUser to the request context. Then we call the next handler in the chain
 		// and return without executing any of the code below.
@@ -53,7 +160,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 
 		// Retrieve the details of the user associated with the authentication token.
 		// call invalidAuthenticationTokenResponse if no matching record was found.
-		user, err := app.models.Users.GetForToken(model.ScopeAuthentication, token)
+		user, err := app.models.Users.GetForToken(r.Context(), model.ScopeAuthentication, token)
 		if err != nil {
 			switch {
 			case errors.Is(err, model.ErrRecordNotFound):
@@ -72,6 +179,37 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// authenticateAPIKey adds the owner of the API key and the key itself to the request context. If
+// the key isn't valid the error response is already sent and false is returned.
+func (app *application) authenticateAPIKey(w http.ResponseWriter, r *http.Request, plaintext string) (*http.Request, bool) {
+	key, err := app.models.APIKeys.GetForKey(r.Context(), plaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.invalidAPIKeyResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	user, err := app.models.Users.Get(r.Context(), key.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	// Tracking the last use is only informational, so a failure doesn't fail the request.
+	if err := app.models.APIKeys.Touch(r.Context(), key); err != nil {
+		app.logError(r, err)
+	}
+
+	r = app.contextSetUser(r, user)
+	r = app.contextSetAPIKey(r, key)
+
+	return r, true
+}
+
 // requireAuthenticatedUser checks that the user is not anonymous (i.e., they are authenticated).
 func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -89,6 +227,22 @@ func (app *application) requireAuthenticatedUser(next http.HandlerFunc) http.Han
 	}
 }
 
+// requireUserSession checks that the user is activated and authenticated with a token from a login
+// rather than an API key. Credentials are managed this way only, so that a leaked API key can't
+// be used to create more keys or to change the second factor.
+func (app *application) requireUserSession(next http.HandlerFunc) http.HandlerFunc {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.contextGetAPIKey(r) != nil {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	return app.requireActivatedUser(fn)
+}
+
 // requiredActivatedUser checks that the user is both authenticated and activated.
 func (app *application) requireActivatedUser(next http.HandlerFunc) http.HandlerFunc {
 	// Rather than returning this http.HandlerFunc we assign it to the variable fn.
@@ -108,21 +262,44 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 	return app.requireAuthenticatedUser(fn)
 }
 
+// requirePermissions checks that the user is activated and has the permission code. Wildcard
+// grants such as "quiz:*" count.
 func (app *application) requirePermissions(code string, next http.HandlerFunc) http.HandlerFunc {
+	return app.requireAllPermissions([]string{code}, next)
+}
+
+// requireAllPermissions checks that the user is activated and has every one of the permission
+// codes.
+func (app *application) requireAllPermissions(codes []string, next http.HandlerFunc) http.HandlerFunc {
+	return app.requirePermissionsFunc(func(permissions model.Permissions) bool {
+		return permissions.IncludeAll(codes...)
+	}, next)
+}
+
+// requireAnyPermission checks that the user is activated and has at least one of the permission
+// codes.
+func (app *application) requireAnyPermission(codes []string, next http.HandlerFunc) http.HandlerFunc {
+	return app.requirePermissionsFunc(func(permissions model.Permissions) bool {
+		return permissions.IncludeAny(codes...)
+	}, next)
+}
+
+// requirePermissionsFunc checks that the user is activated and that their permissions satisfy
+// allowed.
+func (app *application) requirePermissionsFunc(allowed func(model.Permissions) bool, next http.HandlerFunc) http.HandlerFunc {
 	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Retrieve the user from the request context.
 		user := app.contextGetUser(r)
 
 		// Get the slice of permission for the user
-		permissions, err := app.models.Permissions.GetAllForUser(user.ID)
-		if err != nil {
-			app.serverErrorResponse(w, r, err)
+		permissions, ok := app.userPermissions(w, r, user)
+		if !ok {
 			return
 		}
 
-		// Check if the slice includes the required permission. If it doesn't, then return a 403
+		// Check if the slice includes the required permissions. If it doesn't, then return a 403
 		// Forbidden response.
-		if !permissions.Include(code) {
+		if !allowed(permissions) {
 			app.notPermittedResponse(w, r)
 			return
 		}
@@ -133,4 +310,79 @@ func (app *application) requirePermissions(code string, next http.HandlerFunc) h
 
 	// Wrap this with the requireActivatedUser middleware before returning
 	return app.requireActivatedUser(fn)
+}
+
+// requireOwnerOrPermission checks that the user is activated and either owns the resource of the
+// request or has the permission code, e.g. the author of a quiz may edit it without quiz:write.
+// ownerOf returns the id of the user owning the resource, or model.ErrRecordNotFound if there is
+// no such resource. Requests made with an API key always need the permission, as the scopes of
+// the key can't express ownership.
+func (app *application) requireOwnerOrPermission(ownerOf func(r *http.Request) (int64, error), code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		permissions, ok := app.userPermissions(w, r, user)
+		if !ok {
+			return
+		}
+
+		if !permissions.Include(code) {
+			if app.contextGetAPIKey(r) != nil {
+				app.notPermittedResponse(w, r)
+				return
+			}
+
+			ownerID, err := ownerOf(r)
+			if err != nil {
+				switch {
+				case errors.Is(err, model.ErrRecordNotFound):
+					app.notFoundResponse(w, r)
+				default:
+					app.serverErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			if ownerID != user.ID {
+				app.notPermittedResponse(w, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	return app.requireActivatedUser(fn)
+}
+
+// userPermissions returns the permissions of the user. Moderators and admins may be required to
+// use two-factor authentication: they can still log in and enroll, but can't use any endpoint
+// that requires a permission until they have. If the permissions can't be used the error
+// response is already sent and false is returned.
+func (app *application) userPermissions(w http.ResponseWriter, r *http.Request, user *model.User) (model.Permissions, bool) {
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	// Requests made with an API key are limited to the scopes of the key.
+	if key := app.contextGetAPIKey(r); key != nil {
+		permissions = key.Permissions(permissions)
+	}
+
+	if app.config.twoFactor.requireAdmins && permissions.IncludeAny("player:write", "user:write") {
+		enabled, err := app.models.TwoFactor.Enabled(r.Context(), user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return nil, false
+		}
+
+		if !enabled {
+			app.twoFactorRequiredResponse(w, r)
+			return nil, false
+		}
+	}
+
+	return permissions, true
 }
\ No newline at end of file
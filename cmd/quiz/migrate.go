@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/jsonlog"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/migrations"
+)
+
+// embeddedMigrations is the value of -migrations that selects the migrations built into the
+// binary, the PostgreSQL or the SQLite ones depending on -dsn.
+const embeddedMigrations = "embedded"
+
+// newMigrate prepares the migrations of cfg on db. Closing the returned Migrate doesn't close db.
+//
+// On PostgreSQL the migrations hold an advisory lock while they run, so replicas that start at
+// the same time wait for each other instead of applying the same migration twice. They wait for
+// at most -migrations-lock-timeout.
+func newMigrate(db *sql.DB, cfg config) (*migrate.Migrate, error) {
+	driverName := "postgres"
+
+	var driver database.Driver
+	if cfg.db.sqlite {
+		driverName = "sqlite"
+
+		sqliteDriver, err := sqlite.WithInstance(db, &sqlite.Config{})
+		if err != nil {
+			return nil, err
+		}
+		driver = sqliteMigrateDriver{sqliteDriver}
+	} else {
+		// The advisory lock belongs to a session, so the driver needs a connection of its own.
+		// Unlike with postgres.WithInstance, closing the driver only closes the connection.
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		driver, err = postgres.WithConnection(context.Background(), conn, &postgres.Config{})
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	var (
+		m   *migrate.Migrate
+		err error
+	)
+	if cfg.migrations == embeddedMigrations {
+		var src source.Driver
+		if cfg.db.sqlite {
+			src, err = iofs.New(migrations.SQLite, "sqlite")
+		} else {
+			src, err = iofs.New(migrations.Postgres, ".")
+		}
+		if err == nil {
+			m, err = migrate.NewWithInstance("iofs", src, driverName, driver)
+		}
+	} else {
+		m, err = migrate.NewWithDatabaseInstance(cfg.migrations, driverName, driver)
+	}
+	if err != nil {
+		driver.Close()
+		return nil, err
+	}
+
+	m.LockTimeout = cfg.migrationsLockTimeout
+
+	return m, nil
+}
+
+// sqliteMigrateDriver keeps the sqlite driver from closing the connection pool of the application
+// when the migrations are closed.
+type sqliteMigrateDriver struct {
+	database.Driver
+}
+
+func (sqliteMigrateDriver) Close() error {
+	return nil
+}
+
+// migrateUp applies the migrations which haven't been applied yet and returns the version of the
+// database, 0 if there are no migrations.
+func migrateUp(db *sql.DB, cfg config) (uint, error) {
+	m, err := newMigrate(db, cfg)
+	if err != nil {
+		return 0, fmt.Errorf("migrations: %w", err)
+	}
+	defer m.Close()
+
+	err = m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return 0, fmt.Errorf("migrations: %w", err)
+	}
+
+	version, _, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return 0, fmt.Errorf("migrations: %w", err)
+	}
+
+	return version, nil
+}
+
+// migrateCommand runs one of the migrate subcommands:
+//
+//	migrate up [N]        apply all or the next N migrations
+//	migrate down N|-all   roll back the last N or all migrations
+//	migrate goto V        migrate up or down to version V
+//	migrate version       print the current version
+//	migrate force V       set the version to V without running migrations, -1 for none
+//
+// migrate create is handled by createMigration, as it doesn't need a database.
+func (app *application) migrateCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate needs one of up, down, goto, version, force or create")
+	}
+
+	name, rest := "migrate "+args[0], args[1:]
+
+	m, err := newMigrate(app.db, app.config)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		if len(rest) == 0 {
+			err = m.Up()
+			break
+		}
+
+		n, convErr := parseMigrateArg(name, rest, 1)
+		if convErr != nil {
+			return convErr
+		}
+		err = m.Steps(n)
+	case "down":
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		all := fs.Bool("all", false, "Roll back all migrations")
+
+		if err := fs.Parse(rest); err != nil {
+			return err
+		}
+
+		if *all {
+			err = m.Down()
+			break
+		}
+		if fs.NArg() == 0 {
+			return errors.New("migrate down needs the number of migrations to roll back, or -all")
+		}
+
+		n, convErr := parseMigrateArg(name, fs.Args(), 1)
+		if convErr != nil {
+			return convErr
+		}
+		err = m.Steps(-n)
+	case "goto":
+		v, convErr := parseMigrateArg(name, rest, 0)
+		if convErr != nil {
+			return convErr
+		}
+		err = m.Migrate(uint(v))
+	case "force":
+		v, convErr := parseMigrateArg(name, rest, -1)
+		if convErr != nil {
+			return convErr
+		}
+		err = m.Force(v)
+	case "version":
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		app.logger.PrintInfo("no migrations applied", nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	app.logger.PrintInfo("database migrated", map[string]string{
+		"version": fmt.Sprint(version),
+		"dirty":   fmt.Sprint(dirty),
+	})
+
+	return nil
+}
+
+// parseMigrateArg parses the only argument of the migrate subcommand name as a number not less
+// than least.
+func parseMigrateArg(name string, args []string, least int) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("%s needs exactly one number", name)
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < least {
+		return 0, fmt.Errorf("%s: invalid number %q", name, args[0])
+	}
+
+	return n, nil
+}
+
+// migrationFile matches the names of migration files and captures their version.
+var migrationFile = regexp.MustCompile(`^(\d+)_.*\.(up|down)\.sql$`)
+
+// createMigration creates empty up and down migrations named after the only argument in -dir and
+// in its sqlite directory, with the version after the highest one in -dir. Both sets of
+// migrations need to be written.
+func createMigration(args []string, logger *jsonlog.Logger) error {
+	fs := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := fs.String("dir", "pkg/quiz/migrations", "Directory of the PostgreSQL migrations")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || !regexp.MustCompile(`^[a-z0-9_]+$`).MatchString(fs.Arg(0)) {
+		return errors.New("migrate create needs a name of lowercase letters, digits and underscores")
+	}
+
+	entries, err := os.ReadDir(*dir)
+	if err != nil {
+		return err
+	}
+
+	version := 0
+	for _, entry := range entries {
+		if match := migrationFile.FindStringSubmatch(entry.Name()); match != nil {
+			v, _ := strconv.Atoi(match[1])
+			version = max(version, v)
+		}
+	}
+	version++
+
+	for _, d := range []string{*dir, filepath.Join(*dir, "sqlite")} {
+		for _, direction := range []string{"up", "down"} {
+			name := filepath.Join(d, fmt.Sprintf("%06d_%s.%s.sql", version, fs.Arg(0), direction))
+
+			// O_EXCL, so that existing migrations are never overwritten.
+			f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+
+			logger.PrintInfo("migration created", map[string]string{"file": name})
+		}
+	}
+
+	return nil
+}
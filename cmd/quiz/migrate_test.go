@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+// newTestMigrate opens a new SQLite database file and prepares the embedded migrations on it.
+func newTestMigrate(t *testing.T) (*sql.DB, *migrate.Migrate) {
+	t.Helper()
+
+	db, err := model.Open(model.SQLiteScheme + filepath.Join(t.TempDir(), "quiz.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	var cfg config
+	cfg.db.sqlite = true
+	cfg.migrations = embeddedMigrations
+	cfg.migrationsLockTimeout = 15 * time.Second
+
+	m, err := newMigrate(db, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+
+	return db, m
+}
+
+func TestMigratePlayerWriteGrants(t *testing.T) {
+	db, m := newTestMigrate(t)
+	ctx := context.Background()
+
+	if err := m.Migrate(8); err != nil {
+		t.Fatal(err)
+	}
+
+	// Before 000009 every write route checked player:write.
+	var userID int64
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO users (name, email, password_hash, activated)
+		VALUES ('writer', 'writer@example.com', x'00', true)
+		RETURNING id`).Scan(&userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO users_permissions (user_id, permission_id)
+		SELECT $1, id FROM permissions WHERE code = 'player:write'`, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	models := model.NewSQLiteModels(db, 3*time.Second)
+
+	permissions, err := models.Permissions.GetAllForUser(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range []string{"player:write", "player:create", "player:delete", "quiz:create", "quiz:delete", "game:delete"} {
+		if !permissions.Include(code) {
+			t.Errorf("got permissions %v after the upgrade, want %s", permissions, code)
+		}
+	}
+	if permissions.Include("user:write") {
+		t.Errorf("got permissions %v after the upgrade, want no user:write", permissions)
+	}
+
+	// Rolling back removes the codes of 000009 with their grants, quiz:create is older and stays.
+	if err := m.Migrate(8); err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	rows, err := db.QueryContext(ctx, `
+		SELECT permissions.code
+		FROM users_permissions INNER JOIN permissions ON permissions.id = users_permissions.permission_id
+		WHERE users_permissions.user_id = $1
+		ORDER BY permissions.code`, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, code)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 2 || codes[0] != "player:write" || codes[1] != "quiz:create" {
+		t.Errorf("got permissions %v after the rollback, want player:write and quiz:create", codes)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/oidc"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// oidcLoginHandler starts an authorization code login with PKCE against the provider named in
+// the URL. It stores the state, nonce and code verifier, and returns the URL of the provider's
+// authorization endpoint that the client should open.
+func (app *application) oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	provider, ok := app.oidc[mux.Vars(r)["provider"]]
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	state := &model.LoginState{
+		Provider: provider.Name(),
+		Expiry:   time.Now().Add(10 * time.Minute),
+	}
+
+	var err error
+	if state.Plaintext, err = oidc.RandomString(); err == nil {
+		if state.Nonce, err = oidc.RandomString(); err == nil {
+			state.CodeVerifier, err = oidc.NewCodeVerifier()
+		}
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state.Plaintext, state.Nonce, state.CodeVerifier)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Identities.InsertLoginState(r.Context(), state)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"authorization_url": authURL, "expiry": state.Expiry}, nil)
+}
+
+// oidcCallbackHandler completes a login started by oidcLoginHandler. The provider redirects the
+// user here with an authorization code, which we exchange for a verified ID token. The external
+// identity is then resolved to a user: an already linked identity is used as is, otherwise it is
+// linked to the user with the same verified email address, or a new activated user is created.
+func (app *application) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	provider, ok := app.oidc[mux.Vars(r)["provider"]]
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	qs := r.URL.Query()
+
+	// The provider reports a denied or failed authorization with an error parameter instead
+	// of a code.
+	if providerErr := qs.Get("error"); providerErr != "" {
+		app.errorResponse(w, r, http.StatusUnauthorized, "identity provider returned "+providerErr+": "+qs.Get("error_description"))
+		return
+	}
+
+	code := app.readStrings(qs, "code", "")
+	plaintextState := app.readStrings(qs, "state", "")
+
+	v := validator.New()
+	v.Check(code != "", "code", "must be provided")
+	v.Check(plaintextState != "", "state", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	state, err := app.models.Identities.ConsumeLoginState(r.Context(), provider.Name(), plaintextState)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			v.AddError("state", "invalid or expired login state")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrInvalidIDToken), errors.Is(err, oidc.ErrExchangeFailed):
+			app.logError(r, err)
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.userForIdentity(r.Context(), provider.Name(), claims, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	app.writeAuthenticationResponse(w, r, user, envelope{"user": user})
+}
+
+// userForIdentity resolves the verified ID token claims of an external identity to a user,
+// linking or creating the user when needed. Problems with the claims themselves are reported
+// through the validator, so callers must check v.Valid() when err is nil.
+func (app *application) userForIdentity(ctx context.Context, provider string, claims *oidc.Claims, v *validator.Validator) (*model.User, error) {
+	identity, err := app.models.Identities.GetByProviderSubject(ctx, provider, claims.Subject)
+	switch {
+	case err == nil:
+		return app.models.Users.Get(ctx, identity.UserID)
+	case !errors.Is(err, model.ErrRecordNotFound):
+		return nil, err
+	}
+
+	// Linking by email is only safe when the provider vouches for the address, otherwise anybody
+	// could take over an account by registering its email address at the provider.
+	v.Check(claims.Email != "", "email", "identity provider did not return an email address")
+	v.Check(claims.EmailVerified, "email", "identity provider did not verify the email address")
+	if !v.Valid() {
+		return nil, nil
+	}
+
+	// Only a new user is a registration, linking an existing one isn't.
+	created := false
+
+	user, err := app.models.Users.GetByEmail(ctx, claims.Email)
+	if err != nil {
+		if !errors.Is(err, model.ErrRecordNotFound) {
+			return nil, err
+		}
+
+		if user, err = app.createOIDCUser(ctx, claims); err != nil {
+			return nil, err
+		}
+		created = true
+	}
+
+	identity = &model.Identity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	err = app.models.Identities.Insert(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		app.metrics.registrations.Inc("oidc")
+		app.publishEvent(ctx, model.EventUserRegistered, envelope{"user": user, "method": "oidc"})
+	}
+
+	return user, nil
+}
+
+// createOIDCUser registers a new user for an external identity. The email address has been
+// verified by the provider, so the user is activated straight away. The user never learns the
+// random password, they can only log in through the provider.
+func (app *application) createOIDCUser(ctx context.Context, claims *oidc.Claims) (*model.User, error) {
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+
+	user := &model.User{
+		Name:      name,
+		Email:     claims.Email,
+		Activated: true,
+	}
+
+	randomPassword, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	err = user.Password.Set(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.models.Users.Insert(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Grant the same role as registerUserHandler does.
+	err = app.models.Roles.AddForUser(ctx, user.ID, model.RolePlayer)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
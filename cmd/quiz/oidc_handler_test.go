@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/oidc"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/oidc/oidctest"
+)
+
+// newTestOIDCProvider serves an OpenID Connect provider and configures it as the "mock" provider
+// of the application.
+func newTestOIDCProvider(t *testing.T, app *application, ts *testServer) *oidctest.Provider {
+	t.Helper()
+
+	provider, err := oidctest.NewProvider("", "justquiz", "secret", oidctest.Identity{
+		Subject:       "mock-user-1",
+		Email:         "mock.user@example.com",
+		Name:          "Mock User",
+		EmailVerified: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(provider)
+	t.Cleanup(srv.Close)
+
+	app.oidc["mock"] = oidc.NewProvider(oidc.ProviderConfig{
+		Name:         "mock",
+		Issuer:       srv.URL,
+		ClientID:     "justquiz",
+		ClientSecret: "secret",
+	}, ts.URL+"/v1/users/oidc/mock/callback")
+
+	return provider
+}
+
+// oidcLogin starts a login with the mock provider, lets the provider authorize it and follows
+// its redirect to the callback. tamper may change the parameters of the authorization request
+// on the way to the provider, like an attacker could.
+func oidcLogin(t *testing.T, ts *testServer, tamper func(params url.Values)) testResponse {
+	t.Helper()
+
+	res := ts.request(t, http.MethodGet, "/v1/users/oidc/mock/login", "", nil).wantStatus(t, http.StatusOK)
+
+	authURL, err := url.Parse(res.body["authorization_url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tamper != nil {
+		params := authURL.Query()
+		tamper(params)
+		authURL.RawQuery = params.Encode()
+	}
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	authorized, err := client.Get(authURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorized.Body.Close()
+	if authorized.StatusCode != http.StatusFound {
+		t.Fatalf("got status %d from the provider, want %d", authorized.StatusCode, http.StatusFound)
+	}
+
+	callback := authorized.Header.Get("Location")
+	if !strings.HasPrefix(callback, ts.URL+"/") {
+		t.Fatalf("got redirect to %q, want the callback of the API", callback)
+	}
+
+	return ts.request(t, http.MethodGet, strings.TrimPrefix(callback, ts.URL), "", nil)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+	newTestOIDCProvider(t, app, ts)
+
+	// The first login registers an activated player.
+	res := oidcLogin(t, ts, nil).wantStatus(t, http.StatusCreated)
+	user := res.object(t, "user")
+	if user["email"] != "mock.user@example.com" || user["name"] != "Mock User" || user["activated"] != true {
+		t.Errorf("got user %v, want the activated mock user", user)
+	}
+	token, _ := res.object(t, "authentication_token")["token"].(string)
+	ts.request(t, http.MethodPost, "/v1/players", token, map[string]any{"name": "mock"}).wantStatus(t, http.StatusCreated)
+
+	// The next one finds the linked identity.
+	res = oidcLogin(t, ts, nil).wantStatus(t, http.StatusCreated)
+	if got := res.object(t, "user")["id"]; got != user["id"] {
+		t.Errorf("got user %v on the second login, want %v", got, user["id"])
+	}
+	wantMetric(t, app, `justquiz_registrations_total{method="oidc"} 1`)
+}
+
+func TestOIDCLinkByEmail(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+	provider := newTestOIDCProvider(t, app, ts)
+
+	alice, _ := newTestUser(t, app, "alice@example.com", model.RoleAuthor)
+
+	_, admin := newTestUser(t, app, "admin@example.com", model.RoleAdmin)
+	res := ts.request(t, http.MethodPost, "/v1/webhooks", admin, map[string]any{
+		"url":    "https://hooks.example.com/users",
+		"events": []string{model.EventUserRegistered},
+	}).wantStatus(t, http.StatusCreated)
+	webhook := id(t, res.object(t, "webhook")["id"])
+
+	// An unverified address could belong to anybody, it isn't linked.
+	provider.SetIdentity(oidctest.Identity{Subject: "mallory", Email: "alice@example.com"})
+	oidcLogin(t, ts, nil).wantStatus(t, http.StatusUnprocessableEntity)
+
+	provider.SetIdentity(oidctest.Identity{Subject: "alice", Email: "alice@example.com", EmailVerified: true})
+	res = oidcLogin(t, ts, nil).wantStatus(t, http.StatusCreated)
+	if got := id(t, res.object(t, "user")["id"]); got != id(t, float64(alice.ID)) {
+		t.Errorf("got user %s, want alice %d", got, alice.ID)
+	}
+
+	identity, err := app.models.Identities.GetByProviderSubject(context.Background(), "mock", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserID != alice.ID {
+		t.Errorf("got the identity linked to user %d, want alice %d", identity.UserID, alice.ID)
+	}
+
+	// Linking isn't registering.
+	if got := scrapeMetrics(t, app); strings.Contains(got, `justquiz_registrations_total{method="oidc"}`) {
+		t.Errorf("got metrics\n%s\nwant no OpenID Connect registration", got)
+	}
+	res = ts.request(t, http.MethodGet, "/v1/webhooks/"+webhook+"/deliveries", admin, nil).wantStatus(t, http.StatusOK)
+	if deliveries := res.list(t, "deliveries"); len(deliveries) != 0 {
+		t.Errorf("got deliveries %v, want no user.registered for a linked user", deliveries)
+	}
+
+	// A new user is.
+	provider.SetIdentity(oidctest.Identity{Subject: "bob", Email: "bob@example.com", EmailVerified: true})
+	oidcLogin(t, ts, nil).wantStatus(t, http.StatusCreated)
+
+	res = ts.request(t, http.MethodGet, "/v1/webhooks/"+webhook+"/deliveries", admin, nil).wantStatus(t, http.StatusOK)
+	if deliveries := res.list(t, "deliveries"); len(deliveries) != 1 {
+		t.Errorf("got deliveries %v, want user.registered for the new user", deliveries)
+	}
+}
+
+func TestOIDCRejectsTampering(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+	newTestOIDCProvider(t, app, ts)
+
+	otherVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(params url.Values)
+		status int
+	}{
+		{
+			name:   "nonce",
+			tamper: func(params url.Values) { params.Set("nonce", "replayed") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "code challenge",
+			tamper: func(params url.Values) { params.Set("code_challenge", oidc.CodeChallenge(otherVerifier)) },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "state",
+			tamper: func(params url.Values) { params.Set("state", "forged") },
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oidcLogin(t, ts, tt.tamper).wantStatus(t, tt.status)
+		})
+	}
+
+	users, _, err := app.models.Users.GetAll(context.Background(), "", model.Filters{Page: 1, PageSize: 20, Sort: "id", SortSafeList: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 0 {
+		t.Errorf("got %d users after rejected logins, want none", len(users))
+	}
+}
+
+func TestOIDCCallbackReplay(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+	newTestOIDCProvider(t, app, ts)
+
+	// Keep the state of the login to call back with it once more.
+	var callback string
+	oidcLogin(t, ts, func(params url.Values) {
+		callback = "/v1/users/oidc/mock/callback?state=" + url.QueryEscape(params.Get("state"))
+	}).wantStatus(t, http.StatusCreated)
+
+	// The login state is used up by the first callback.
+	ts.request(t, http.MethodGet, callback+"&code=replayed", "", nil).wantStatus(t, http.StatusUnprocessableEntity)
+}
+
+func TestOIDCUnknownProvider(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+
+	ts.request(t, http.MethodGet, "/v1/users/oidc/unknown/login", "", nil).wantStatus(t, http.StatusNotFound)
+	ts.request(t, http.MethodGet, "/v1/users/oidc/unknown/callback?state=x&code=y", "", nil).wantStatus(t, http.StatusNotFound)
+}
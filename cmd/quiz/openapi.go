@@ -0,0 +1,946 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+// apiOperation documents a route of the API. Request and Response are example values whose
+// types are turned into JSON schemas, envelopes become objects with a property for each key.
+type apiOperation struct {
+	Summary string
+	// Access names who may call the operation besides anyone, like a permission code. Session
+	// operations need a token from a login, API keys aren't accepted.
+	Access  string
+	Session bool
+	Query   []apiParam
+	Request any
+	// Status is the status of a successful response, 200 OK if it's zero. Also holds the other
+	// successful responses by status.
+	Status   int
+	Response any
+	Also     map[int]any
+	// ContentType of the response, JSON if it's empty.
+	ContentType string
+	// Errors are the statuses of the errors the operation responds with, besides the ones every
+	// operation of its kind does.
+	Errors []int
+}
+
+// apiParam is a query string parameter of an operation.
+type apiParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// apiMessage is the response of operations which only tell that they succeeded.
+var apiMessage = envelope{"message": ""}
+
+// apiAccess is the response of the operations that change the access of a user.
+var apiAccess = envelope{"access": envelope{
+	"user_id":            int64(0),
+	"roles":              []string{},
+	"direct_permissions": model.Permissions{},
+	"permissions":        model.Permissions{},
+}}
+
+// apiPage are the parameters of lists split into pages.
+var apiPage = []apiParam{
+	{"page", "integer", "Page number, from 1."},
+	{"page_size", "integer", "Records per page, at most 100. Defaults to 20."},
+}
+
+// apiCursors are the parameters of lists which can also be paged through with cursors.
+var apiCursors = []apiParam{
+	{"after", "string", "Start after the record of this cursor, the next_cursor of the metadata."},
+	{"before", "string", "End before the record of this cursor, the prev_cursor of the metadata."},
+	{"count", "boolean", "Count the records for the metadata. Defaults to true."},
+}
+
+// apiSort returns the sort parameter of a list sorted by one of columns, by def by default.
+func apiSort(def string, columns ...string) apiParam {
+	return apiParam{"sort", "string", fmt.Sprintf("Sort by %s, prefixed with - for descending order. Defaults to %s.", strings.Join(columns, ", "), def)}
+}
+
+// apiFields returns the fields parameter of responses with records.
+func apiFields(record any) apiParam {
+	return apiParam{"fields", "string", "Comma separated fields of the records to respond with: " + strings.Join(jsonFields(record), ", ") + "."}
+}
+
+// apiTimeRange returns the parameters of readTimeRange for field.
+func apiTimeRange(field string) []apiParam {
+	return []apiParam{
+		{field + "From", "string", "Only from this time on, an RFC 3339 timestamp or a date."},
+		{field + "To", "string", "Only before this time, an RFC 3339 timestamp or a date."},
+		{"last", "string", "Only the last duration, like 90m, 12h, 7d or 2w, instead of " + field + "From."},
+		{"tz", "string", "Time zone of dates and timestamps without an offset, like Asia/Almaty. Defaults to UTC."},
+	}
+}
+
+// params joins lists of parameters.
+func params(lists ...[]apiParam) []apiParam {
+	var joined []apiParam
+	for _, list := range lists {
+		joined = append(joined, list...)
+	}
+	return joined
+}
+
+var (
+	apiPlayersQuery = params([]apiParam{
+		{"name", "string", "Only players with this name."},
+		{"scoreFrom", "integer", "Only players with at least this score."},
+		{"scoreTo", "integer", "Only players with at most this score."},
+	}, apiTimeRange("joined"), apiPage, apiCursors, []apiParam{
+		apiSort("id", "id", "name", "score", "joined"),
+		apiFields(model.Player{}),
+		apiPlayersInclude,
+	})
+
+	apiQuizesQuery = params([]apiParam{
+		{"category", "string", "Only quizes of this category."},
+		{"rewardFrom", "integer", "Only quizes with at least this reward."},
+		{"rewardTo", "integer", "Only quizes with at most this reward."},
+	}, apiPage, apiCursors, []apiParam{
+		apiSort("id", "id", "category", "reward"),
+		apiFields(model.Quiz{}),
+		apiQuizesInclude,
+	})
+
+	apiGamesQuery = params(apiTimeRange("finished"), apiPage, apiCursors, []apiParam{
+		apiSort("id", "id", "finished", "player", "quiz"),
+	})
+
+	apiGamesInclude   = apiParam{"include", "string", "Comma separated related records to embed in place of their ids: " + strings.Join(gameIncludes, ", ") + "."}
+	apiPlayersInclude = apiParam{"include", "string", "Comma separated related records to embed: " + strings.Join(playerIncludes, ", ") + ". The owner is embedded with their id and name, games are the finished ones, the most recent first."}
+	apiQuizesInclude  = apiParam{"include", "string", "Comma separated related records to embed: " + strings.Join(quizIncludes, ", ") + ". The owner is embedded with their id and name."}
+)
+
+// apiDocs documents every route of the API by its method and path template, like
+// "GET /v1/players/{id}". "justquiz openapi check" fails if a route is missing.
+var apiDocs = map[string]apiOperation{
+	"GET /v1/healthcheck": {
+		Summary: "Show the status and the build of the instance",
+		Response: envelope{"status": "", "system_info": envelope{
+			"environment": "", "version": "", "commit": "", "build_time": "", "modified": "", "uptime": "",
+		}},
+	},
+	"GET /livez": {
+		Summary:  "Tell that the process is running",
+		Response: envelope{"status": ""},
+	},
+	"GET /readyz": {
+		Summary:  "Tell whether the instance should get traffic",
+		Response: envelope{"status": "", "checks": map[string]string{}},
+		Also:     map[int]any{http.StatusServiceUnavailable: envelope{"status": "", "checks": map[string]string{}}},
+	},
+	"GET /v1/openapi.json": {
+		Summary:  "Show this OpenAPI document",
+		Response: envelope{},
+	},
+	"GET /v1/docs": {
+		Summary: "Redirect to the API documentation",
+		Status:  http.StatusMovedPermanently,
+	},
+	"GET /v1/docs/": {
+		Summary:     "Show the API documentation",
+		ContentType: "text/html",
+		Response:    "",
+	},
+
+	"GET /v1/players": {
+		Summary:  "List players",
+		Query:    apiPlayersQuery,
+		Response: envelope{"players": []model.Player{}, "metadata": model.Metadata{}},
+	},
+	"POST /v1/players": {
+		Summary: "Create a player",
+		Access:  "player:create",
+		Request: struct {
+			Name string `json:"name"`
+		}{},
+		Status:   http.StatusCreated,
+		Response: envelope{"player": model.Player{}},
+	},
+	"GET /v1/players/{id}": {
+		Summary:  "Show a player",
+		Query:    []apiParam{apiFields(model.Player{}), apiPlayersInclude},
+		Response: envelope{"player": model.Player{}},
+	},
+	"PUT /v1/players/{id}": {
+		Summary: "Update a player",
+		Access:  "the creator of the player or player:write, only player:write may change the score",
+		Request: struct {
+			Name  *string `json:"name"`
+			Score *int    `json:"score"`
+		}{},
+		Response: envelope{"player": model.Player{}},
+	},
+	"DELETE /v1/players/{id}": {
+		Summary:  "Delete a player",
+		Access:   "player:delete",
+		Response: apiMessage,
+	},
+	"GET /v1/players/{id}/quizes": {
+		Summary:  "List the quizes a player finished",
+		Query:    params([]apiParam{{"quiz", "integer", "Only this quiz."}}, apiGamesQuery, []apiParam{apiFields(model.Quiz{}), apiQuizesInclude}),
+		Response: envelope{"quizes": []model.Quiz{}, "metadata": model.Metadata{}},
+	},
+	"GET /v1/leaderboard": {
+		Summary:  "List players ranked by their score",
+		Query:    params(apiPage, []apiParam{apiSort("rank", "rank", "name", "score", "games")}),
+		Response: envelope{"leaderboard": []model.LeaderboardEntry{}, "metadata": model.Metadata{}},
+	},
+
+	"GET /v1/quizes": {
+		Summary:  "List quizes",
+		Query:    apiQuizesQuery,
+		Response: envelope{"quizes": []model.Quiz{}, "metadata": model.Metadata{}},
+	},
+	"POST /v1/quizes": {
+		Summary: "Create a quiz",
+		Access:  "quiz:create",
+		Request: struct {
+			Category  string   `json:"category"`
+			Reward    int      `json:"reward"`
+			Questions []string `json:"questions"`
+			Answers   []string `json:"answers"`
+		}{},
+		Status:   http.StatusCreated,
+		Response: envelope{"quiz": model.Quiz{}},
+	},
+	"GET /v1/quizes/{id}": {
+		Summary:  "Show a quiz",
+		Query:    []apiParam{apiFields(model.Quiz{}), apiQuizesInclude},
+		Response: envelope{"quiz": model.Quiz{}},
+	},
+	"PUT /v1/quizes/{id}": {
+		Summary: "Update a quiz",
+		Access:  "the author of the quiz or quiz:write",
+		Request: struct {
+			Category  *string   `json:"category"`
+			Reward    *int      `json:"reward"`
+			Questions *[]string `json:"questions"`
+			Answers   *[]string `json:"answers"`
+		}{},
+		Response: envelope{"quiz": model.Quiz{}},
+	},
+	"DELETE /v1/quizes/{id}": {
+		Summary:  "Delete a quiz",
+		Access:   "the author of the quiz or quiz:delete",
+		Response: apiMessage,
+	},
+	"GET /v1/quizes/{id}/players": {
+		Summary:  "List the players who finished a quiz",
+		Query:    params([]apiParam{{"player", "integer", "Only this player."}}, apiGamesQuery, []apiParam{apiFields(model.Player{}), apiPlayersInclude}),
+		Response: envelope{"players": []model.Player{}, "metadata": model.Metadata{}},
+	},
+
+	"GET /v1/games": {
+		Summary: "List games",
+		Query: params([]apiParam{
+			{"player", "integer", "Only games of this player."},
+			{"quiz", "integer", "Only games of this quiz."},
+		}, apiGamesQuery, []apiParam{apiFields(model.Game{}), apiGamesInclude}),
+		Response: envelope{"games": []model.Game{}, "metadata": model.Metadata{}},
+	},
+	"POST /v1/games": {
+		Summary: "Start a game",
+		Access:  "game:play",
+		Request: struct {
+			Player int `json:"player"`
+			Quiz   int `json:"quiz"`
+		}{},
+		Status:   http.StatusCreated,
+		Response: envelope{"game": model.Game{}},
+	},
+	"GET /v1/games/{id}": {
+		Summary:  "Show a game",
+		Query:    []apiParam{apiFields(model.Game{}), apiGamesInclude},
+		Response: envelope{"game": model.Game{}},
+	},
+	"POST /v1/games/{id}": {
+		Summary: "Answer the questions of a quiz, finishing the game in progress of the player on it",
+		Access:  "game:play",
+		Request: struct {
+			Player  *string   `json:"playerId"`
+			Answers *[]string `json:"answers"`
+		}{},
+		Response: envelope{"result": ""},
+	},
+	"DELETE /v1/games/{id}": {
+		Summary:  "Delete a game",
+		Access:   "game:delete",
+		Response: apiMessage,
+	},
+
+	"POST /v1/users": {
+		Summary: "Register a user",
+		Request: struct {
+			Name     string `json:"name"`
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}{},
+		Status: http.StatusCreated,
+		Response: envelope{"user": struct {
+			Token string     `json:"token"`
+			User  model.User `json:"user"`
+		}{}},
+	},
+	"PUT /v1/users/activated": {
+		Summary: "Activate a user with the token from registration",
+		Request: struct {
+			Token string `json:"token"`
+		}{},
+		Response: envelope{"user": model.User{}},
+		Errors:   []int{http.StatusConflict},
+	},
+	"POST /v1/users/login": {
+		Summary: "Log in with an email address and a password",
+		Request: struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}{},
+		Status:   http.StatusCreated,
+		Response: envelope{"authentication_token": model.Token{}},
+		Also:     map[int]any{http.StatusAccepted: envelope{"two_factor_token": model.Token{}}},
+		Errors:   []int{http.StatusUnauthorized},
+	},
+	"POST /v1/users/login/2fa": {
+		Summary: "Complete a login with the second factor",
+		Request: struct {
+			Token        string `json:"token"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}{},
+		Status:   http.StatusCreated,
+		Response: envelope{"authentication_token": model.Token{}},
+		Errors:   []int{http.StatusUnauthorized},
+	},
+	"POST /v1/users/2fa/totp": {
+		Summary:  "Start enrolling an authenticator app",
+		Session:  true,
+		Status:   http.StatusCreated,
+		Response: envelope{"totp": envelope{"secret": "", "provisioning_uri": ""}},
+		Errors:   []int{http.StatusConflict},
+	},
+	"DELETE /v1/users/2fa/totp": {
+		Summary: "Turn off two-factor authentication",
+		Session: true,
+		Request: struct {
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}{},
+		Response: apiMessage,
+	},
+	"POST /v1/users/2fa/totp/confirm": {
+		Summary: "Confirm the authenticator app, turning on two-factor authentication",
+		Session: true,
+		Request: struct {
+			Code string `json:"code"`
+		}{},
+		Response: envelope{"recovery_codes": []string{}},
+		Errors:   []int{http.StatusConflict},
+	},
+	"POST /v1/users/2fa/recovery-codes": {
+		Summary: "Replace the recovery codes",
+		Session: true,
+		Request: struct {
+			Code string `json:"code"`
+		}{},
+		Response: envelope{"recovery_codes": []string{}},
+	},
+	"POST /v1/api-keys": {
+		Summary: "Create an API key",
+		Session: true,
+		Request: struct {
+			Name   string            `json:"name"`
+			Scopes model.Permissions `json:"scopes"`
+			Expiry *time.Time        `json:"expiry"`
+		}{},
+		Status:   http.StatusCreated,
+		Response: envelope{"api_key": model.APIKey{}},
+	},
+	"GET /v1/api-keys": {
+		Summary:  "List the API keys of the user",
+		Session:  true,
+		Response: envelope{"api_keys": []model.APIKey{}},
+	},
+	"DELETE /v1/api-keys/{id}": {
+		Summary:  "Revoke an API key",
+		Session:  true,
+		Response: apiMessage,
+	},
+	"POST /v1/webhooks": {
+		Summary: "Create a webhook",
+		Session: true,
+		Request: struct {
+			URL    string   `json:"url"`
+			Events []string `json:"events"`
+			Secret string   `json:"secret,omitempty"`
+			Active *bool    `json:"active"`
+		}{},
+		Status:   http.StatusCreated,
+		Response: envelope{"webhook": model.Webhook{}, "secret": ""},
+	},
+	"GET /v1/webhooks": {
+		Summary:  "List the webhooks of the user",
+		Session:  true,
+		Response: envelope{"webhooks": []model.Webhook{}},
+	},
+	"GET /v1/webhooks/{id}": {
+		Summary:  "Get a webhook",
+		Session:  true,
+		Response: envelope{"webhook": model.Webhook{}},
+	},
+	"PUT /v1/webhooks/{id}": {
+		Summary: "Update a webhook",
+		Session: true,
+		Request: struct {
+			URL    *string   `json:"url"`
+			Events *[]string `json:"events"`
+			Active *bool     `json:"active"`
+		}{},
+		Response: envelope{"webhook": model.Webhook{}},
+		Errors:   []int{http.StatusConflict},
+	},
+	"DELETE /v1/webhooks/{id}": {
+		Summary:  "Delete a webhook and its deliveries",
+		Session:  true,
+		Response: apiMessage,
+	},
+	"GET /v1/webhooks/{id}/deliveries": {
+		Summary: "List the deliveries of a webhook",
+		Session: true,
+		Query: params([]apiParam{
+			{"status", "string", "Only deliveries with this status: pending, succeeded or failed."},
+		}, apiPage, []apiParam{apiSort("-id", "id", "created_at", "attempts")}),
+		Response: envelope{"deliveries": []model.WebhookDelivery{}, "metadata": model.Metadata{}},
+	},
+	"POST /v1/webhooks/{id}/deliveries/{delivery}/redeliver": {
+		Summary:  "Deliver the event of a delivery again",
+		Session:  true,
+		Status:   http.StatusAccepted,
+		Response: envelope{"delivery": model.WebhookDelivery{}},
+	},
+	"POST /v1/webhooks/{id}/ping": {
+		Summary:  "Send a webhook.ping event to a webhook",
+		Session:  true,
+		Status:   http.StatusAccepted,
+		Response: envelope{"delivery": model.WebhookDelivery{}},
+	},
+	"PUT /v1/users/{id}/unlock": {
+		Summary:  "Unlock a user locked out by failed logins",
+		Access:   "user:read and user:write",
+		Response: envelope{"user": model.User{}},
+	},
+	"GET /v1/login-attempts": {
+		Summary: "List login attempts",
+		Access:  "user:read or user:write",
+		Query: params([]apiParam{
+			{"email", "string", "Only attempts with this email address."},
+			{"ip", "string", "Only attempts from this IP address."},
+		}, apiPage, []apiParam{apiSort("-id", "id", "email", "ip", "created_at")}),
+		Response: envelope{"login_attempts": []model.LoginAttempt{}, "metadata": model.Metadata{}},
+	},
+	"GET /v1/roles": {
+		Summary:  "List roles and the permissions they grant",
+		Access:   "user:read",
+		Response: envelope{"roles": []model.Role{}},
+	},
+	"GET /v1/permissions": {
+		Summary:  "List permissions",
+		Access:   "user:read",
+		Response: envelope{"permissions": model.Permissions{}},
+	},
+	"GET /v1/users/{id}/access": {
+		Summary:  "Show the roles and permissions of a user",
+		Access:   "user:read",
+		Response: apiAccess,
+	},
+	"PUT /v1/users/{id}/roles/{role}": {
+		Summary:  "Grant a role to a user",
+		Access:   "user:write",
+		Response: apiAccess,
+	},
+	"DELETE /v1/users/{id}/roles/{role}": {
+		Summary:  "Revoke a role from a user",
+		Access:   "user:write",
+		Response: apiAccess,
+		Errors:   []int{http.StatusConflict},
+	},
+	"PUT /v1/users/{id}/permissions/{code}": {
+		Summary:  "Grant a permission to a user",
+		Access:   "user:write",
+		Response: apiAccess,
+	},
+	"DELETE /v1/users/{id}/permissions/{code}": {
+		Summary:  "Revoke a permission from a user",
+		Access:   "user:write",
+		Response: apiAccess,
+	},
+	"GET /v1/jobs/runs": {
+		Summary: "List runs of the periodic jobs",
+		Access:  "user:read",
+		Query: params([]apiParam{
+			{"job", "string", "Only runs of this job."},
+		}, apiPage, []apiParam{apiSort("-id", "id", "job", "started_at", "duration_ms")}),
+		Response: envelope{"job_runs": []model.JobRun{}, "metadata": model.Metadata{}},
+	},
+	"GET /v1/users/oidc/{provider}/login": {
+		Summary:  "Start a login with an OpenID Connect provider",
+		Response: envelope{"authorization_url": "", "expiry": time.Time{}},
+	},
+	"GET /v1/users/oidc/{provider}/callback": {
+		Summary: "Complete a login with an OpenID Connect provider",
+		Query: []apiParam{
+			{"code", "string", "Authorization code from the provider."},
+			{"state", "string", "State of the login, from the authorization URL."},
+			{"error", "string", "Error from the provider."},
+			{"error_description", "string", "Description of the error from the provider."},
+		},
+		Status:   http.StatusCreated,
+		Response: envelope{"user": model.User{}, "authentication_token": model.Token{}},
+		Also:     map[int]any{http.StatusAccepted: envelope{"user": model.User{}, "two_factor_token": model.Token{}}},
+		Errors:   []int{http.StatusUnauthorized},
+	},
+}
+
+// apiErrors describe the errors of errors.go by status.
+var apiErrors = map[int]string{
+	http.StatusBadRequest:          "The body is not valid JSON of the request.",
+	http.StatusUnauthorized:        "The credentials or the authentication token are invalid or missing.",
+	http.StatusForbidden:           "The user isn't activated, isn't permitted or has to turn on two-factor authentication.",
+	http.StatusNotFound:            "The record doesn't exist.",
+	http.StatusConflict:            "The record was changed by another request, or the change conflicts with the state of the user.",
+	http.StatusUnprocessableEntity: "The values are invalid, the error is an object of messages by field.",
+	http.StatusTooManyRequests:     "A rate limit was exceeded, retry after the Retry-After header.",
+	http.StatusInternalServerError: "The server encountered a problem.",
+	http.StatusServiceUnavailable:  "The database didn't answer in time.",
+}
+
+// openAPIRoute matches the variables of mux path templates, like {id:[0-9]+}.
+var openAPIRoute = regexp.MustCompile(`\{(\w+)(:[^}]*)?\}`)
+
+// openAPIRoutes returns the routes of r as "METHOD /path" like the keys of apiDocs. The probes,
+// which bypass the router, are included. /metrics isn't, it's no part of the API.
+func openAPIRoutes(r *mux.Router) ([]string, error) {
+	routes := []string{"GET /livez", "GET /readyz"}
+
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		// Subrouters only have a path prefix.
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		template = openAPIRoute.ReplaceAllString(template, "{$1}")
+
+		for _, method := range methods {
+			routes = append(routes, method+" "+template)
+		}
+		return nil
+	})
+
+	return routes, err
+}
+
+// openAPIProblems returns the routes of r without an entry in apiDocs, and the entries without a
+// route.
+func openAPIProblems(r *mux.Router) ([]string, error) {
+	routes, err := openAPIRoutes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, route := range routes {
+		if _, ok := apiDocs[route]; !ok {
+			problems = append(problems, fmt.Sprintf("route %q is not documented in apiDocs", route))
+		}
+	}
+	for route := range apiDocs {
+		if !slices.Contains(routes, route) {
+			problems = append(problems, fmt.Sprintf("apiDocs documents %q, which is not a route", route))
+		}
+	}
+	slices.Sort(problems)
+
+	return problems, nil
+}
+
+// openAPICommand prints the OpenAPI document of the API. It's run as
+//
+//	openapi         print the document
+//	openapi check   fail if a route isn't documented in apiDocs, or an entry isn't a route
+//
+// The check runs in the Docker build, so that a route can't be added without documenting it.
+func (app *application) openAPICommand(args []string) error {
+	r := app.router()
+
+	if len(args) == 0 {
+		js, err := json.MarshalIndent(app.openAPIDocument(r), "", "\t")
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.Write(append(js, '\n'))
+		return err
+	}
+
+	if args[0] != "check" {
+		return fmt.Errorf("unknown openapi command %q", args[0])
+	}
+
+	problems, err := openAPIProblems(r)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("the OpenAPI document is out of date: %s", strings.Join(problems, "; "))
+	}
+
+	app.logger.PrintInfo("every route is documented", nil)
+	return nil
+}
+
+// openAPIDocument generates the OpenAPI document of the routes of r from apiDocs. Routes which
+// aren't documented are left out.
+func (app *application) openAPIDocument(r *mux.Router) envelope {
+	routes, err := openAPIRoutes(r)
+	if err != nil {
+		app.logger.PrintError(err, nil)
+	}
+
+	// Every error of errors.go has the same shape, failed validations have a message for each
+	// field instead of one message.
+	schemas := map[string]any{
+		"Error": envelope{
+			"type":     "object",
+			"required": []string{"error"},
+			"properties": envelope{
+				"error": envelope{"oneOf": []envelope{
+					{"type": "string"},
+					{"type": "object", "additionalProperties": envelope{"type": "string"}},
+				}},
+				"request_id": envelope{"type": "string"},
+			},
+		},
+	}
+	paths := make(map[string]envelope)
+	for _, route := range routes {
+		op, ok := apiDocs[route]
+		if !ok {
+			continue
+		}
+
+		method, path, _ := strings.Cut(route, " ")
+		if paths[path] == nil {
+			paths[path] = envelope{}
+		}
+		paths[path][strings.ToLower(method)] = op.document(path, schemas)
+	}
+
+	return envelope{
+		"openapi": "3.0.3",
+		"info": envelope{
+			"title":       "JustQuiz API",
+			"version":     version,
+			"description": "Quizes, the players who play them and the users who manage them.",
+		},
+		"paths": paths,
+		"components": envelope{
+			"schemas": schemas,
+			"securitySchemes": envelope{
+				"bearer": envelope{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "An authentication token from a login, or an API key.",
+				},
+				"apiKey": envelope{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-API-Key",
+				},
+			},
+		},
+	}
+}
+
+// document returns the OpenAPI operation object of op at path, adding the schemas of its named
+// types to schemas.
+func (op apiOperation) document(path string, schemas map[string]any) envelope {
+	doc := envelope{
+		"summary": op.Summary,
+		"tags":    []string{openAPITag(path)},
+	}
+
+	var parameters []envelope
+	for _, m := range openAPIRoute.FindAllStringSubmatch(path, -1) {
+		typ := "string"
+		if m[1] == "id" {
+			typ = "integer"
+		}
+		parameters = append(parameters, envelope{"name": m[1], "in": "path", "required": true, "schema": envelope{"type": typ}})
+	}
+	for _, p := range op.Query {
+		parameters = append(parameters, envelope{"name": p.Name, "in": "query", "description": p.Description, "schema": envelope{"type": p.Type}})
+	}
+	if parameters != nil {
+		doc["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		doc["requestBody"] = envelope{
+			"required": true,
+			"content":  envelope{"application/json": envelope{"schema": valueSchema(op.Request, schemas)}},
+		}
+	}
+
+	switch {
+	case op.Session:
+		doc["description"] = "Requires a token from a login, API keys aren't accepted."
+		doc["security"] = []envelope{{"bearer": []string{}}}
+	case op.Access != "":
+		doc["description"] = "Requires " + op.Access + "."
+		doc["x-access"] = op.Access
+		doc["security"] = []envelope{{"bearer": []string{}}, {"apiKey": []string{}}}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	responses := envelope{fmt.Sprint(status): op.response(status, op.Response, schemas)}
+	for status, response := range op.Also {
+		responses[fmt.Sprint(status)] = op.response(status, response, schemas)
+	}
+	for _, status := range op.errors(path) {
+		responses[fmt.Sprint(status)] = envelope{
+			"description": apiErrors[status],
+			"content":     envelope{"application/json": envelope{"schema": envelope{"$ref": "#/components/schemas/Error"}}},
+		}
+	}
+	doc["responses"] = responses
+
+	return doc
+}
+
+// response returns the OpenAPI response object of a successful response.
+func (op apiOperation) response(status int, body any, schemas map[string]any) envelope {
+	response := envelope{"description": http.StatusText(status)}
+	if body == nil {
+		return response
+	}
+
+	contentType := op.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	response["content"] = envelope{contentType: envelope{"schema": valueSchema(body, schemas)}}
+
+	return response
+}
+
+// errors returns the statuses of the errors of op at path: the ones of its request, its path
+// parameters and its access, and the ones of every operation.
+func (op apiOperation) errors(path string) []int {
+	statuses := slices.Clone(op.Errors)
+	if op.Request != nil {
+		statuses = append(statuses, http.StatusBadRequest)
+	}
+	if op.Request != nil || op.Query != nil {
+		statuses = append(statuses, http.StatusUnprocessableEntity)
+	}
+	if openAPIRoute.MatchString(path) {
+		statuses = append(statuses, http.StatusNotFound)
+	}
+	if op.Session || op.Access != "" {
+		statuses = append(statuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	// The probes bypass the middleware, and with it the rate limits, and don't query the
+	// database with a timeout.
+	if strings.HasPrefix(path, "/v1/") {
+		statuses = append(statuses, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	}
+	statuses = append(statuses, http.StatusInternalServerError)
+
+	slices.Sort(statuses)
+	return slices.Compact(statuses)
+}
+
+// openAPITag returns the tag grouping the operations at path, the first segment after the
+// version.
+func openAPITag(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/v1"), "/")
+	if segment == "" {
+		segment, _, _ = strings.Cut(strings.TrimPrefix(path, "/v1/"), "/")
+	}
+
+	switch segment {
+	case "livez", "readyz":
+		return "healthcheck"
+	case "openapi.json":
+		return "docs"
+	case "login-attempts", "roles", "permissions":
+		return "users"
+	}
+
+	return segment
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// rawMessageType is the type of JSON encoded as is, e.g. the payloads of webhook deliveries.
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// valueSchema returns the JSON schema of v. Envelopes are objects with the schemas of their
+// values as properties, all other values have the schema of their type.
+func valueSchema(v any, schemas map[string]any) envelope {
+	if env, ok := v.(envelope); ok {
+		properties := envelope{}
+		required := []string{}
+		for key, value := range env {
+			properties[key] = valueSchema(value, schemas)
+			required = append(required, key)
+		}
+		slices.Sort(required)
+
+		schema := envelope{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	return typeSchema(reflect.TypeOf(v), schemas)
+}
+
+// typeSchema returns the JSON schema of values of t as encoding/json marshals them. Named structs
+// are added to schemas and referenced.
+func typeSchema(t reflect.Type, schemas map[string]any) envelope {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return envelope{"type": "string", "format": "date-time"}
+	}
+	if t == rawMessageType {
+		return envelope{"type": "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return envelope{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return envelope{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return envelope{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return envelope{"type": "number"}
+	case reflect.String:
+		return envelope{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return envelope{"type": "string", "format": "byte"}
+		}
+		return envelope{"type": "array", "items": typeSchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return envelope{"type": "object", "additionalProperties": typeSchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+		if _, ok := schemas[t.Name()]; !ok {
+			// Mark the schema as taken first, in case the struct refers to itself.
+			schemas[t.Name()] = envelope{}
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return envelope{"$ref": "#/components/schemas/" + t.Name()}
+	}
+
+	return envelope{}
+}
+
+// structSchema returns the JSON schema of the struct type t. Fields which are neither pointers
+// nor omitted when empty are required. The fields of embedded structs are promoted like
+// encoding/json does.
+func structSchema(t reflect.Type, schemas map[string]any) envelope {
+	properties := envelope{}
+	required := []string{}
+
+	var add func(t reflect.Type)
+	add = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				add(field.Type)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = typeSchema(field.Type, schemas)
+			if field.Type.Kind() != reflect.Pointer && !strings.Contains(options, "omitempty") {
+				required = append(required, name)
+			}
+		}
+	}
+	add(t)
+
+	schema := envelope{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+//go:embed docs
+var docsFiles embed.FS
+
+// docsHandler serves the page which shows the OpenAPI document. It loads its own script and
+// style, which the Content-Security-Policy of the API doesn't allow, so it's relaxed for them.
+func (app *application) docsHandler() http.Handler {
+	files, err := fs.Sub(docsFiles, "docs")
+	if err != nil {
+		panic(err)
+	}
+	server := http.StripPrefix("/v1/docs/", http.FileServer(http.FS(files)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.config.security.headers {
+			w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'self'; style-src 'self'; connect-src 'self'; frame-ancestors 'none'")
+		}
+
+		server.ServeHTTP(w, r)
+	})
+}
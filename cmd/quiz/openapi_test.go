@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/jsonlog"
+)
+
+// TestAPIDocsCoverRoutes walks the router and checks that every route has an entry in apiDocs,
+// and that every entry is a route, like "justquiz openapi check".
+func TestAPIDocsCoverRoutes(t *testing.T) {
+	app := &application{logger: jsonlog.NewLogger(io.Discard, jsonlog.LevelOff)}
+	r := app.router()
+
+	routes := map[string]bool{"GET /livez": true, "GET /readyz": true}
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		template = openAPIRoute.ReplaceAllString(template, "{$1}")
+
+		for _, method := range methods {
+			routes[method+" "+template] = true
+			if _, ok := apiDocs[method+" "+template]; !ok {
+				t.Errorf("route %s %s has no entry in apiDocs", method, template)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for route := range apiDocs {
+		if !routes[route] {
+			t.Errorf("apiDocs documents %q, which is not a route", route)
+		}
+	}
+
+	problems, err := openAPIProblems(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range problems {
+		t.Error(problem)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+
+	res := ts.request(t, http.MethodGet, "/v1/openapi.json", "", nil).wantStatus(t, http.StatusOK)
+	if got, _ := res.body["openapi"].(string); !strings.HasPrefix(got, "3.") {
+		t.Errorf("got openapi version %q, want 3.x", got)
+	}
+
+	// Every documented operation is in the document.
+	paths := res.object(t, "paths")
+	for route := range apiDocs {
+		method, path, _ := strings.Cut(route, " ")
+
+		operations, ok := paths[path].(map[string]any)
+		if !ok {
+			t.Errorf("got no %s in the paths", path)
+			continue
+		}
+		if _, ok := operations[strings.ToLower(method)]; !ok {
+			t.Errorf("got no %s operation for %s", method, path)
+		}
+	}
+}
+
+func TestDocs(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+
+	res := ts.request(t, http.MethodGet, "/v1/docs", "", nil).wantStatus(t, http.StatusMovedPermanently)
+	if got := res.header.Get("Location"); got != "/v1/docs/" {
+		t.Errorf("got Location %q, want /v1/docs/", got)
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/docs/", "", nil).wantStatus(t, http.StatusOK)
+	if got := res.header.Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Errorf("got Content-Type %q, want text/html", got)
+	}
+	if got := res.header.Get("Content-Security-Policy"); !strings.Contains(got, "script-src 'self'") {
+		t.Errorf("got Content-Security-Policy %q, want scripts from the API only", got)
+	}
+}
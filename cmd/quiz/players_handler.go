@@ -27,11 +27,21 @@ func (app *application) createPlayerHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	user := app.contextGetUser(r)
+
 	player := &model.Player{
-		Name: input.Name,
+		Name:    input.Name,
+		OwnerID: &user.ID,
+	}
+
+	v := validator.New()
+
+	if model.ValidatePlayer(v, player); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
 	}
 
-	err = app.models.Players.Insert(player)
+	err = app.models.Players.Insert(r.Context(), player)
 	if err != nil {
 		// app.respondWithError(w, http.StatusInternalServerError, "500 Internal Server Error")
 		// return
@@ -48,6 +58,7 @@ func (app *application) getPlayersList(w http.ResponseWriter, r *http.Request) {
 		Name		string
 		ScoreFrom	int
 		ScoreTo		int
+		Joined		model.TimeRange
 		model.Filters
 	}
 	v := validator.New()
@@ -59,6 +70,7 @@ func (app *application) getPlayersList(w http.ResponseWriter, r *http.Request) {
 	input.Name = app.readStrings(qs, "name", "")
 	input.ScoreFrom = app.readInt(qs, "scoreFrom", 0, v)
 	input.ScoreTo = app.readInt(qs, "scoreTo", 0, v)
+	input.Joined = app.readTimeRange(qs, "joined", v)
 
 	// Ge the page and page_size query string value as integers. Notice that we set the default
 	// page value to 1 and default page_size to 20, and that we pass the validator instance
@@ -66,6 +78,10 @@ func (app *application) getPlayersList(w http.ResponseWriter, r *http.Request) {
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 
+	// Pages may also start after or before a cursor of a previous page, and skip counting the
+	// records with count=false.
+	app.readCursors(qs, &input.Filters, v)
+
 	// Extract the sort query string value, falling back to "id" if it is not provided
 	// by the client (which will imply an ascending sort on player ID).
 	input.Filters.Sort = app.readStrings(qs, "sort", "id")
@@ -79,11 +95,16 @@ func (app *application) getPlayersList(w http.ResponseWriter, r *http.Request) {
 		"-id", "-name", "-score", "-joined",
 	}
 
+	// Respond with only the fields asked for, and embed the owners and games of the players asked
+	// for.
+	fields := app.readFields(qs, model.Player{}, v)
+	include := app.readList(qs, "include", playerIncludes, v)
+
 	if model.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	players, metadata, err := app.models.Players.GetAll(input.Name, input.ScoreFrom, input.ScoreTo, input.Filters)
+	players, metadata, err := app.models.Players.GetAll(r.Context(), input.Name, input.ScoreFrom, input.ScoreTo, input.Joined, input.Filters)
 
 
 	if err != nil {
@@ -91,7 +112,19 @@ func (app *application) getPlayersList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"players": players, "metadata": metadata}, nil)
+	relations, err := app.loadPlayerRelations(r.Context(), players, include)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	records, err := project(players, fields, relations.embed())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"players": records, "metadata": metadata}, nil)
 }
 
 func (app *application) getPlayerHandler(w http.ResponseWriter, r *http.Request) {
@@ -101,7 +134,18 @@ func (app *application) getPlayerHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	player, err := app.models.Players.Get(id)
+	v := validator.New()
+	qs := r.URL.Query()
+
+	fields := app.readFields(qs, model.Player{}, v)
+	include := app.readList(qs, "include", playerIncludes, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	player, err := app.models.Players.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -112,7 +156,19 @@ func (app *application) getPlayerHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"player": player}, nil)
+	relations, err := app.loadPlayerRelations(r.Context(), []*model.Player{player}, include)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	record, err := projectOne(player, fields, relations.embed())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"player": record}, nil)
 }
 
 func (app *application) getPlayerQuizes(w http.ResponseWriter, r *http.Request) {
@@ -125,8 +181,7 @@ func (app *application) getPlayerQuizes(w http.ResponseWriter, r *http.Request)
 	var input struct {
 		Player       int
 		Quiz         int
-		FinishedFrom string
-		FinishedTo   string
+		Finished     model.TimeRange
 		model.Filters
 	}
 	v := validator.New()
@@ -134,11 +189,11 @@ func (app *application) getPlayerQuizes(w http.ResponseWriter, r *http.Request)
 
 	input.Player = id
 	input.Quiz = app.readInt(qs, "quiz", 0, v)
-	input.FinishedFrom = app.readStrings(qs, "finisedFrom", "1980-01-01 00:00:00+06")
-	input.FinishedTo = app.readStrings(qs, "finishedTo", "1980-01-01 00:00:00+06")
+	input.Finished = app.readFinishedRange(qs, v)
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	app.readCursors(qs, &input.Filters, v)
 
 	input.Filters.Sort = app.readStrings(qs, "sort", "id")
 
@@ -147,35 +202,59 @@ func (app *application) getPlayerQuizes(w http.ResponseWriter, r *http.Request)
 		"-id", "-finished", "-player", "-quiz",
 	}
 
+	fields := app.readFields(qs, model.Quiz{}, v)
+	include := app.readList(qs, "include", quizIncludes, v)
+
 	if model.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	games, metadata, err := app.models.Games.GetAll(input.Player, input.Quiz, input.FinishedFrom, input.FinishedTo, input.Filters)
+
+	// A missing player has no list of quizes, rather than an empty one.
+	if _, err := app.models.Players.Get(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	games, metadata, err := app.models.Games.GetAll(r.Context(), input.Player, input.Quiz, true, input.Finished, input.Filters)
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	// Get all relevant quizes
+	// Get all relevant quizes with a single query, the quiz of every game in its order.
+	byID, err := app.quizesByID(r.Context(), uniqueIDs(games, func(g *model.Game) int { return g.Quiz }))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	var quizes []*model.Quiz
 	for _, game := range games {
-		quizId := game.Quiz
-		quiz, err := app.models.Quizes.Get(quizId)
-		if err != nil {
-			switch {
-			case errors.Is(err, model.ErrRecordNotFound):
-				app.notFoundResponse(w, r)
-			default:
-				app.serverErrorResponse(w, r, err)
-			}
-			return
+		if quiz, ok := byID[game.Quiz]; ok {
+			quizes = append(quizes, quiz)
 		}
-		quizes = append(quizes, quiz)
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"quizes": quizes, "metadata": metadata}, nil)
+	relations, err := app.loadQuizRelations(r.Context(), quizes, include)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	records, err := project(quizes, fields, relations.embed())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"quizes": records, "metadata": metadata}, nil)
 }
 
 func (app *application) updatePlayerHandler(w http.ResponseWriter, r *http.Request) {
@@ -185,7 +264,7 @@ func (app *application) updatePlayerHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	player, err := app.models.Players.Get(id)
+	player, err := app.models.Players.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -211,7 +290,17 @@ func (app *application) updatePlayerHandler(w http.ResponseWriter, r *http.Reque
 	if input.Name != nil {
 		player.Name = *input.Name
 	}
-	if input.Score != nil {
+	// Scores are earned in games, the owner of a player may only rename it.
+	if input.Score != nil && *input.Score != player.Score {
+		permissions, ok := app.userPermissions(w, r, app.contextGetUser(r))
+		if !ok {
+			return
+		}
+		if !permissions.Include("player:write") {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
 		player.Score = *input.Score
 	}
 
@@ -222,7 +311,7 @@ func (app *application) updatePlayerHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Players.Update(player)
+	err = app.models.Players.Update(r.Context(), player)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -236,6 +325,17 @@ func (app *application) updatePlayerHandler(w http.ResponseWriter, r *http.Reque
 	app.writeJSON(w, http.StatusOK, envelope{"player": player}, nil)
 }
 
+// playerOwner returns the id of the user who created the player in the URL, for
+// requireOwnerOrPermission.
+func (app *application) playerOwner(r *http.Request) (int64, error) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		return 0, model.ErrRecordNotFound
+	}
+
+	return app.models.Players.GetOwner(r.Context(), id)
+}
+
 func (app *application) deletePlayerHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -243,7 +343,7 @@ func (app *application) deletePlayerHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Players.Delete(id)
+	err = app.models.Players.Delete(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+func TestPlayers(t *testing.T) {
+	forEachBackend(t, testPlayers)
+}
+
+func testPlayers(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+
+	_, token := newTestUser(t, app, "alice@example.com", model.RolePlayer)
+	_, other := newTestUser(t, app, "bob@example.com", model.RolePlayer)
+	_, moderator := newTestUser(t, app, "mod@example.com", model.RoleModerator)
+
+	ts.request(t, http.MethodPost, "/v1/players", "", map[string]any{"name": "alice"}).wantStatus(t, http.StatusUnauthorized)
+
+	res := ts.request(t, http.MethodPost, "/v1/players", token, map[string]any{"name": "alice"}).wantStatus(t, http.StatusCreated)
+	player := id(t, res.object(t, "player")["id"])
+
+	res = ts.request(t, http.MethodGet, "/v1/players/"+player, "", nil).wantStatus(t, http.StatusOK)
+	if got := res.object(t, "player")["name"]; got != "alice" {
+		t.Errorf("got name %v, want alice", got)
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/players?name=alice", "", nil).wantStatus(t, http.StatusOK)
+	if got := len(res.list(t, "players")); got != 1 {
+		t.Errorf("got %d players, want 1", got)
+	}
+
+	// The creator may rename the player, but not change its score.
+	ts.request(t, http.MethodPut, "/v1/players/"+player, token, map[string]any{"name": "alicia"}).wantStatus(t, http.StatusOK)
+	ts.request(t, http.MethodPut, "/v1/players/"+player, token, map[string]any{"score": 100}).wantStatus(t, http.StatusForbidden)
+	ts.request(t, http.MethodPut, "/v1/players/"+player, other, map[string]any{"name": "bob"}).wantStatus(t, http.StatusForbidden)
+
+	res = ts.request(t, http.MethodPut, "/v1/players/"+player, moderator, map[string]any{"score": 100}).wantStatus(t, http.StatusOK)
+	if got := res.object(t, "player")["score"]; got != float64(100) {
+		t.Errorf("got score %v, want 100", got)
+	}
+
+	ts.request(t, http.MethodDelete, "/v1/players/"+player, token, nil).wantStatus(t, http.StatusForbidden)
+	ts.request(t, http.MethodDelete, "/v1/players/"+player, moderator, nil).wantStatus(t, http.StatusOK)
+
+	res = ts.request(t, http.MethodGet, "/v1/players", "", nil).wantStatus(t, http.StatusOK)
+	if got := len(res.list(t, "players")); got != 0 {
+		t.Errorf("got %d players after the delete, want 0", got)
+	}
+
+	ts.request(t, http.MethodGet, "/v1/players/"+player, "", nil).wantStatus(t, http.StatusNotFound)
+	ts.request(t, http.MethodDelete, "/v1/players/"+player, moderator, nil).wantStatus(t, http.StatusNotFound)
+}
+
+func TestLeaderboard(t *testing.T) {
+	forEachBackend(t, testLeaderboard)
+}
+
+func testLeaderboard(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+
+	ctx := context.Background()
+	scores := map[string]int{"first": 30, "second": 20, "tied": 20}
+	for _, name := range []string{"first", "second", "tied"} {
+		player := &model.Player{Name: name}
+		if err := app.models.Players.Insert(ctx, player); err != nil {
+			t.Fatal(err)
+		}
+		player.Score = scores[name]
+		if err := app.models.Players.Update(ctx, player); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := app.models.Leaderboard.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	res := ts.request(t, http.MethodGet, "/v1/leaderboard", "", nil).wantStatus(t, http.StatusOK)
+
+	var ranks []float64
+	for _, entry := range res.list(t, "leaderboard") {
+		ranks = append(ranks, entry.(map[string]any)["rank"].(float64))
+	}
+	if len(ranks) != 3 || ranks[0] != 1 || ranks[1] != 2 || ranks[2] != 2 {
+		t.Errorf("got ranks %v, want [1 2 2]", ranks)
+	}
+}
+
+func TestPlayersErrors(t *testing.T) {
+	forEachBackend(t, testPlayersErrors)
+}
+
+func testPlayersErrors(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+
+	_, token := newTestUser(t, app, "alice@example.com", model.RolePlayer)
+	_, other := newTestUser(t, app, "bob@example.com", model.RolePlayer)
+	_, moderator := newTestUser(t, app, "mod@example.com", model.RoleModerator)
+	_, nobody := newTestUser(t, app, "nobody@example.com")
+
+	res := ts.request(t, http.MethodPost, "/v1/players", token, map[string]any{"name": "alice"}).wantStatus(t, http.StatusCreated)
+	player := "/v1/players/" + id(t, res.object(t, "player")["id"])
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   any
+		want   int
+	}{
+		{"list with an unknown sort", http.MethodGet, "/v1/players?sort=password", "", nil, http.StatusUnprocessableEntity},
+		{"list with page 0", http.MethodGet, "/v1/players?page=0", "", nil, http.StatusUnprocessableEntity},
+		{"create without the permission", http.MethodPost, "/v1/players", nobody, map[string]any{"name": "nobody"}, http.StatusForbidden},
+		{"create without a name", http.MethodPost, "/v1/players", token, map[string]any{"name": ""}, http.StatusUnprocessableEntity},
+		{"get a missing player", http.MethodGet, "/v1/players/999999", "", nil, http.StatusNotFound},
+		{"update a missing player", http.MethodPut, "/v1/players/999999", moderator, map[string]any{"name": "alicia"}, http.StatusNotFound},
+		{"update another's player", http.MethodPut, player, other, map[string]any{"name": "bob"}, http.StatusForbidden},
+		{"update without a name", http.MethodPut, player, token, map[string]any{"name": ""}, http.StatusUnprocessableEntity},
+		{"delete a missing player", http.MethodDelete, "/v1/players/999999", moderator, nil, http.StatusNotFound},
+		{"delete without the permission", http.MethodDelete, player, token, nil, http.StatusForbidden},
+		{"quizes of a missing player", http.MethodGet, "/v1/players/999999/quizes", "", nil, http.StatusNotFound},
+		{"leaderboard with page 0", http.MethodGet, "/v1/leaderboard?page=0", "", nil, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.request(t, tt.method, tt.path, tt.token, tt.body).wantStatus(t, tt.want)
+		})
+	}
+}
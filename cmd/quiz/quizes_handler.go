@@ -24,19 +24,38 @@ func (app *application) createQuizHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	user := app.contextGetUser(r)
+
 	quiz := &model.Quiz{
 		Category:  input.Category,
 		Reward:    input.Reward,
 		Questions: input.Questions,
 		Answers:   input.Answers,
+		OwnerID:   &user.ID,
 	}
 
-	err = app.models.Quizes.Insert(quiz)
+	v := validator.New()
+
+	if model.ValidateQuiz(v, quiz); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Quizes.Insert(r.Context(), quiz)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	// Subscribers get the quiz without its answers.
+	app.publishEvent(r.Context(), model.EventQuizPublished, envelope{"quiz": envelope{
+		"id":        quiz.Id,
+		"category":  quiz.Category,
+		"reward":    quiz.Reward,
+		"questions": quiz.Questions,
+		"owner_id":  quiz.OwnerID,
+	}})
+
 	app.writeJSON(w, http.StatusCreated, envelope{"quiz": quiz}, nil)
 }
 
@@ -63,6 +82,10 @@ func (app *application) getQuizesList(w http.ResponseWriter, r *http.Request) {
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 
+	// Pages may also start after or before a cursor of a previous page, and skip counting the
+	// records with count=false.
+	app.readCursors(qs, &input.Filters, v)
+
 	// Extract the sort query string value, falling back to "id" if it is not provided
 	// by the client (which will imply an ascending sort on quiz ID).
 	input.Filters.Sort = app.readStrings(qs, "sort", "id")
@@ -76,18 +99,34 @@ func (app *application) getQuizesList(w http.ResponseWriter, r *http.Request) {
 		"-id", "-category", "-reward",
 	}
 
+	// Respond with only the fields asked for, and embed the owners of the quizes asked for.
+	fields := app.readFields(qs, model.Quiz{}, v)
+	include := app.readList(qs, "include", quizIncludes, v)
+
 	if model.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	quizes, metadata, err := app.models.Quizes.GetAll(input.Category, input.RewardFrom, input.RewrdTo, input.Filters)
+	quizes, metadata, err := app.models.Quizes.GetAll(r.Context(), input.Category, input.RewardFrom, input.RewrdTo, input.Filters)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	relations, err := app.loadQuizRelations(r.Context(), quizes, include)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
+	records, err := project(quizes, fields, relations.embed())
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"quizes": quizes, "metadata": metadata}, nil)
+	app.writeJSON(w, http.StatusOK, envelope{"quizes": records, "metadata": metadata}, nil)
 }
 
 func (app *application) getQuizHandler(w http.ResponseWriter, r *http.Request) {
@@ -97,7 +136,18 @@ func (app *application) getQuizHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	quiz, err := app.models.Quizes.Get(id)
+	v := validator.New()
+	qs := r.URL.Query()
+
+	fields := app.readFields(qs, model.Quiz{}, v)
+	include := app.readList(qs, "include", quizIncludes, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	quiz, err := app.models.Quizes.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -108,7 +158,19 @@ func (app *application) getQuizHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"quiz": quiz}, nil)
+	relations, err := app.loadQuizRelations(r.Context(), []*model.Quiz{quiz}, include)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	record, err := projectOne(quiz, fields, relations.embed())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"quiz": record}, nil)
 }
 
 func (app *application) getQuizePlayers(w http.ResponseWriter, r *http.Request) {
@@ -121,8 +183,7 @@ func (app *application) getQuizePlayers(w http.ResponseWriter, r *http.Request)
 	var input struct {
 		Player       int
 		Quiz         int
-		FinishedFrom string
-		FinishedTo   string
+		Finished     model.TimeRange
 		model.Filters
 	}
 	v := validator.New()
@@ -130,11 +191,11 @@ func (app *application) getQuizePlayers(w http.ResponseWriter, r *http.Request)
 
 	input.Player = app.readInt(qs, "player", 0, v)
 	input.Quiz = id
-	input.FinishedFrom = app.readStrings(qs, "finisedFrom", "1980-01-01 00:00:00+06")
-	input.FinishedTo = app.readStrings(qs, "finishedTo", "1980-01-01 00:00:00+06")
+	input.Finished = app.readFinishedRange(qs, v)
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	app.readCursors(qs, &input.Filters, v)
 
 	input.Filters.Sort = app.readStrings(qs, "sort", "id")
 
@@ -143,35 +204,59 @@ func (app *application) getQuizePlayers(w http.ResponseWriter, r *http.Request)
 		"-id", "-finished", "-player", "-quiz",
 	}
 
+	fields := app.readFields(qs, model.Player{}, v)
+	include := app.readList(qs, "include", playerIncludes, v)
+
 	if model.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	games, metadata, err := app.models.Games.GetAll(input.Player, input.Quiz, input.FinishedFrom, input.FinishedTo, input.Filters)
 
+	// A missing quiz has no list of players, rather than an empty one.
+	if _, err := app.models.Quizes.Get(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	games, metadata, err := app.models.Games.GetAll(r.Context(), input.Player, input.Quiz, true, input.Finished, input.Filters)
+
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Get all relevant players with a single query, the player of every game in its order.
+	byID, err := app.playersByID(r.Context(), uniqueIDs(games, func(g *model.Game) int { return g.Player }))
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	// Get all relevant quizes
 	var players []*model.Player
 	for _, game := range games {
-		playerId := game.Player
-		player, err := app.models.Players.Get(playerId)
-		if err != nil {
-			switch {
-			case errors.Is(err, model.ErrRecordNotFound):
-				app.notFoundResponse(w, r)
-			default:
-				app.serverErrorResponse(w, r, err)
-			}
-			return
+		if player, ok := byID[game.Player]; ok {
+			players = append(players, player)
 		}
-		players = append(players, player)
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"players": players, "metadata": metadata}, nil)
+	relations, err := app.loadPlayerRelations(r.Context(), players, include)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	records, err := project(players, fields, relations.embed())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"players": records, "metadata": metadata}, nil)
 }
 
 func (app *application) updateQuizHandler(w http.ResponseWriter, r *http.Request) {
@@ -181,7 +266,7 @@ func (app *application) updateQuizHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	quiz, err := app.models.Quizes.Get(id)
+	quiz, err := app.models.Quizes.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -220,7 +305,7 @@ func (app *application) updateQuizHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Quizes.Update(quiz)
+	err = app.models.Quizes.Update(r.Context(), quiz)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -234,6 +319,17 @@ func (app *application) updateQuizHandler(w http.ResponseWriter, r *http.Request
 	app.writeJSON(w, http.StatusOK, envelope{"quiz": quiz}, nil)
 }
 
+// quizOwner returns the id of the user who created the quiz in the URL, for
+// requireOwnerOrPermission.
+func (app *application) quizOwner(r *http.Request) (int64, error) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		return 0, model.ErrRecordNotFound
+	}
+
+	return app.models.Quizes.GetOwner(r.Context(), id)
+}
+
 func (app *application) deleteQuizHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -241,7 +337,7 @@ func (app *application) deleteQuizHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.models.Quizes.Delete(id)
+	err = app.models.Quizes.Delete(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+func TestQuizes(t *testing.T) {
+	forEachBackend(t, testQuizes)
+}
+
+func testQuizes(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+
+	_, author := newTestUser(t, app, "author@example.com", model.RoleAuthor)
+	_, other := newTestUser(t, app, "other@example.com", model.RoleAuthor)
+	_, player := newTestUser(t, app, "player@example.com", model.RolePlayer)
+
+	quiz := map[string]any{
+		"category":  "geography",
+		"reward":    10,
+		"questions": []string{"Capital of Kazakhstan?"},
+		"answers":   []string{"Astana"},
+	}
+
+	ts.request(t, http.MethodPost, "/v1/quizes", player, quiz).wantStatus(t, http.StatusForbidden)
+
+	res := ts.request(t, http.MethodPost, "/v1/quizes", author, quiz).wantStatus(t, http.StatusCreated)
+	quizID := id(t, res.object(t, "quiz")["id"])
+
+	res = ts.request(t, http.MethodGet, "/v1/quizes/"+quizID, "", nil).wantStatus(t, http.StatusOK)
+	if got := res.object(t, "quiz")["category"]; got != "geography" {
+		t.Errorf("got category %v, want geography", got)
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/quizes?category=geography", "", nil).wantStatus(t, http.StatusOK)
+	if got := len(res.list(t, "quizes")); got != 1 {
+		t.Errorf("got %d quizes, want 1", got)
+	}
+
+	// Only the author of the quiz, or a moderator, may change it.
+	ts.request(t, http.MethodPut, "/v1/quizes/"+quizID, other, map[string]any{"reward": 20}).wantStatus(t, http.StatusForbidden)
+
+	res = ts.request(t, http.MethodPut, "/v1/quizes/"+quizID, author, map[string]any{"reward": 20}).wantStatus(t, http.StatusOK)
+	if got := res.object(t, "quiz")["reward"]; got != float64(20) {
+		t.Errorf("got reward %v, want 20", got)
+	}
+
+	ts.request(t, http.MethodDelete, "/v1/quizes/"+quizID, other, nil).wantStatus(t, http.StatusForbidden)
+	ts.request(t, http.MethodDelete, "/v1/quizes/"+quizID, author, nil).wantStatus(t, http.StatusOK)
+
+	res = ts.request(t, http.MethodGet, "/v1/quizes", "", nil).wantStatus(t, http.StatusOK)
+	if got := len(res.list(t, "quizes")); got != 0 {
+		t.Errorf("got %d quizes after the delete, want 0", got)
+	}
+
+	ts.request(t, http.MethodGet, "/v1/quizes/"+quizID, "", nil).wantStatus(t, http.StatusNotFound)
+	ts.request(t, http.MethodDelete, "/v1/quizes/"+quizID, author, nil).wantStatus(t, http.StatusNotFound)
+}
+
+func TestQuizesErrors(t *testing.T) {
+	forEachBackend(t, testQuizesErrors)
+}
+
+func testQuizesErrors(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+
+	_, author := newTestUser(t, app, "author@example.com", model.RoleAuthor)
+	_, other := newTestUser(t, app, "other@example.com", model.RoleAuthor)
+	_, player := newTestUser(t, app, "player@example.com", model.RolePlayer)
+
+	valid := map[string]any{
+		"category":  "geography",
+		"reward":    10,
+		"questions": []string{"Capital of Kazakhstan?"},
+		"answers":   []string{"Astana"},
+	}
+
+	res := ts.request(t, http.MethodPost, "/v1/quizes", author, valid).wantStatus(t, http.StatusCreated)
+	quiz := "/v1/quizes/" + id(t, res.object(t, "quiz")["id"])
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   any
+		want   int
+	}{
+		{"list with an unknown sort", http.MethodGet, "/v1/quizes?sort=answers", "", nil, http.StatusUnprocessableEntity},
+		{"list with page 0", http.MethodGet, "/v1/quizes?page=0", "", nil, http.StatusUnprocessableEntity},
+		{"create without the permission", http.MethodPost, "/v1/quizes", player, valid, http.StatusForbidden},
+		{"create without a category", http.MethodPost, "/v1/quizes", author, map[string]any{"reward": 10, "questions": []string{"?"}, "answers": []string{"!"}}, http.StatusUnprocessableEntity},
+		{"get a missing quiz", http.MethodGet, "/v1/quizes/999999", "", nil, http.StatusNotFound},
+		{"update a missing quiz", http.MethodPut, "/v1/quizes/999999", author, map[string]any{"reward": 20}, http.StatusNotFound},
+		{"update another's quiz", http.MethodPut, quiz, other, map[string]any{"reward": 20}, http.StatusForbidden},
+		{"update without a category", http.MethodPut, quiz, author, map[string]any{"category": ""}, http.StatusUnprocessableEntity},
+		{"delete a missing quiz", http.MethodDelete, "/v1/quizes/999999", author, nil, http.StatusNotFound},
+		{"delete another's quiz", http.MethodDelete, quiz, other, nil, http.StatusForbidden},
+		{"players of a missing quiz", http.MethodGet, "/v1/quizes/999999/players", "", nil, http.StatusNotFound},
+		{"players with page 0", http.MethodGet, quiz + "/players?page=0", "", nil, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.request(t, tt.method, tt.path, tt.token, tt.body).wantStatus(t, tt.want)
+		})
+	}
+}
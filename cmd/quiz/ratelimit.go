@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/ratelimit"
+)
+
+// rateLimit applies the global limit and the per-IP limit to every request. It runs before
+// authentication, so that floods are refused before any token is looked up.
+func (app *application) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.allow(w, r, "global", app.config.limiter.global) {
+			return
+		}
+
+		if !app.allow(w, r, "ip:"+app.clientIP(r), app.config.limiter.ip) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitUser applies the per-user limit to authenticated requests. It has to run after the
+// authenticate middleware.
+func (app *application) rateLimitUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+
+		if !user.IsAnonymous() && !app.allow(w, r, fmt.Sprintf("user:%d", user.ID), app.config.limiter.user) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitRoute applies a stricter limit to a single route, on top of the others. The limit
+// applies per user for authenticated requests and per IP address otherwise. Routes sharing a
+// name share their buckets.
+func (app *application) rateLimitRoute(name string, limit ratelimit.Limit, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := name + ":ip:" + app.clientIP(r)
+		if user := app.contextGetUser(r); !user.IsAnonymous() {
+			key = fmt.Sprintf("%s:user:%d", name, user.ID)
+		}
+
+		if !app.allow(w, r, key, limit) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// allow takes a token from the bucket of key and sets the RateLimit-* headers. If the request
+// isn't allowed the error response is already sent and false is returned. The limiter fails
+// open: if the store can't be reached the error is logged and the request allowed.
+func (app *application) allow(w http.ResponseWriter, r *http.Request, key string, limit ratelimit.Limit) bool {
+	if !app.config.limiter.enabled || !limit.Enabled() {
+		return true
+	}
+
+	res, err := app.limiter.Take(r.Context(), key, limit, time.Now())
+	if err != nil {
+		app.logError(r, err)
+		return true
+	}
+
+	// Several limits apply to most requests, the headers describe the one closest to running out.
+	remaining, err := strconv.Atoi(w.Header().Get("RateLimit-Remaining"))
+	if err != nil || res.Remaining <= remaining {
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(res.Reset.Seconds()))))
+	}
+
+	if !res.Allowed {
+		app.rateLimitExceededResponse(w, r, res.RetryAfter)
+		return false
+	}
+
+	return true
+}
+
+// startRateLimitEviction starts removing the buckets of clients that have been gone long enough
+// for their buckets to be full again, which is the same as having no bucket at all, every minute.
+// It is added to app.wg, and stops once app.stopRateLimitEviction is called.
+func (app *application) startRateLimitEviction() {
+	idle := time.Minute
+	for _, limit := range []ratelimit.Limit{
+		app.config.limiter.global,
+		app.config.limiter.ip,
+		app.config.limiter.user,
+		app.config.limiter.login,
+		app.config.limiter.answer,
+	} {
+		if !limit.Enabled() {
+			continue
+		}
+
+		if refill := time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second)); refill > idle {
+			idle = refill
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.stopRateLimitEviction = cancel
+
+	app.workers.beat("rate_limit_eviction", time.Minute)
+
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			evictCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			err := app.limiter.Evict(evictCtx, time.Now().Add(-idle))
+			cancel()
+
+			if err != nil {
+				if ctx.Err() == nil {
+					app.logger.PrintError(err, nil)
+				}
+				continue
+			}
+
+			app.workers.beat("rate_limit_eviction", time.Minute)
+		}
+	}()
+}
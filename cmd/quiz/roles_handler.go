@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// getRolesList returns all roles with the permissions they grant.
+func (app *application) getRolesList(w http.ResponseWriter, r *http.Request) {
+	roles, err := app.models.Roles.GetAll(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"roles": roles}, nil)
+}
+
+// getPermissionsList returns the codes of all known permissions.
+func (app *application) getPermissionsList(w http.ResponseWriter, r *http.Request) {
+	permissions, err := app.models.Permissions.GetAll(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"permissions": permissions}, nil)
+}
+
+// getUserAccessHandler returns the roles of a user, the permissions granted to them directly and
+// the resulting effective permissions.
+func (app *application) getUserAccessHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	app.writeUserAccess(w, r, user)
+}
+
+// grantUserRoleHandler grants the role named in the URL to a user. Granting a role the user
+// already has is not an error.
+func (app *application) grantUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	role := mux.Vars(r)["role"]
+
+	exists, err := app.models.Roles.Exists(r.Context(), role)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !exists {
+		v := validator.New()
+		v.AddError("role", "unknown role")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Roles.AddForUser(r.Context(), user.ID, role)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeUserAccess(w, r, user)
+}
+
+// revokeUserRoleHandler revokes the role named in the URL from a user. The admin role can't be
+// revoked from the last admin, so that there is always someone left to manage users.
+func (app *application) revokeUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	role := mux.Vars(r)["role"]
+
+	var err error
+	if role == model.RoleAdmin {
+		err = app.models.Roles.RemoveForUserUnlessLast(r.Context(), user.ID, role)
+	} else {
+		err = app.models.Roles.RemoveForUser(r.Context(), user.ID, role)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, model.ErrLastRoleHolder):
+			app.lastAdminResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeUserAccess(w, r, user)
+}
+
+// grantUserPermissionHandler grants the permission named in the URL to a user directly, on top of
+// the permissions of their roles.
+func (app *application) grantUserPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	code := mux.Vars(r)["code"]
+
+	permissions, err := app.models.Permissions.GetAll(r.Context())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !slices.Contains(permissions, code) {
+		v := validator.New()
+		v.AddError("code", "unknown permission")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Permissions.AddForUser(r.Context(), user.ID, code)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeUserAccess(w, r, user)
+}
+
+// revokeUserPermissionHandler revokes a permission granted to a user directly. Permissions that
+// come from a role can only be taken away by revoking the role.
+func (app *application) revokeUserPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readUserParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.models.Permissions.RemoveForUser(r.Context(), user.ID, mux.Vars(r)["code"])
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeUserAccess(w, r, user)
+}
+
+// readUserParam retrieves the user whose id is in the URL. If that fails the error response is
+// already sent and false is returned.
+func (app *application) readUserParam(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	user, err := app.models.Users.Get(r.Context(), int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return user, true
+}
+
+// writeUserAccess sends the roles, direct permissions and effective permissions of a user.
+func (app *application) writeUserAccess(w http.ResponseWriter, r *http.Request, user *model.User) {
+	roles, err := app.models.Roles.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	direct, err := app.models.Permissions.GetDirectForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"access": envelope{
+		"user_id":            user.ID,
+		"roles":              roles,
+		"direct_permissions": direct,
+		"permissions":        permissions,
+	}}, nil)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+// accessList returns the strings of a list in the access of a user.
+func accessList(t *testing.T, res testResponse, key string) []string {
+	t.Helper()
+
+	list, ok := res.object(t, "access")[key].([]any)
+	if !ok && res.object(t, "access")[key] != nil {
+		t.Fatalf("%q is not an array in %v", key, res.body)
+	}
+
+	var values []string
+	for _, value := range list {
+		values = append(values, value.(string))
+	}
+
+	return values
+}
+
+func TestRolesAndPermissions(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+
+	user, token := newTestUser(t, app, "alice@example.com", model.RolePlayer)
+	admin, adminToken := newTestUser(t, app, "admin@example.com", model.RoleAdmin)
+	userID, adminID := id(t, float64(user.ID)), id(t, float64(admin.ID))
+
+	ts.request(t, http.MethodGet, "/v1/roles", token, nil).wantStatus(t, http.StatusForbidden)
+
+	res := ts.request(t, http.MethodGet, "/v1/roles", adminToken, nil).wantStatus(t, http.StatusOK)
+	var roles []string
+	for _, role := range res.list(t, "roles") {
+		roles = append(roles, role.(map[string]any)["code"].(string))
+	}
+	if want := []string{model.RolePlayer, model.RoleAuthor, model.RoleModerator, model.RoleAdmin}; !slices.Equal(roles, want) {
+		t.Errorf("got roles %v, want %v", roles, want)
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/permissions", adminToken, nil).wantStatus(t, http.StatusOK)
+	if len(res.list(t, "permissions")) == 0 {
+		t.Error("got no permissions")
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/users/"+userID+"/access", adminToken, nil).wantStatus(t, http.StatusOK)
+	if got := accessList(t, res, "permissions"); slices.Contains(got, "quiz:create") {
+		t.Errorf("got permissions %v for a player, want no quiz:create", got)
+	}
+
+	ts.request(t, http.MethodPost, "/v1/quizes", token, map[string]any{"category": "geography"}).wantStatus(t, http.StatusForbidden)
+
+	// A direct permission adds to the roles.
+	res = ts.request(t, http.MethodPut, "/v1/users/"+userID+"/permissions/quiz:create", adminToken, nil).wantStatus(t, http.StatusOK)
+	if got := accessList(t, res, "direct_permissions"); !slices.Equal(got, []string{"quiz:create"}) {
+		t.Errorf("got direct permissions %v, want [quiz:create]", got)
+	}
+	ts.request(t, http.MethodPost, "/v1/quizes", token, map[string]any{
+		"category":  "geography",
+		"reward":    10,
+		"questions": []string{"Capital of Kazakhstan?"},
+		"answers":   []string{"Astana"},
+	}).wantStatus(t, http.StatusCreated)
+
+	res = ts.request(t, http.MethodDelete, "/v1/users/"+userID+"/permissions/quiz:create", adminToken, nil).wantStatus(t, http.StatusOK)
+	if got := accessList(t, res, "permissions"); slices.Contains(got, "quiz:create") {
+		t.Errorf("got permissions %v after the revoke, want no quiz:create", got)
+	}
+
+	ts.request(t, http.MethodPut, "/v1/users/"+userID+"/roles/superuser", adminToken, nil).wantStatus(t, http.StatusUnprocessableEntity)
+
+	res = ts.request(t, http.MethodPut, "/v1/users/"+userID+"/roles/"+model.RoleAuthor, adminToken, nil).wantStatus(t, http.StatusOK)
+	if got := accessList(t, res, "permissions"); !slices.Contains(got, "quiz:create") {
+		t.Errorf("got permissions %v for an author, want quiz:create", got)
+	}
+
+	ts.request(t, http.MethodDelete, "/v1/users/"+userID+"/roles/"+model.RoleAuthor, adminToken, nil).wantStatus(t, http.StatusOK)
+	ts.request(t, http.MethodDelete, "/v1/users/"+userID+"/roles/"+model.RoleAuthor, adminToken, nil).wantStatus(t, http.StatusNotFound)
+
+	// There is always an admin left.
+	ts.request(t, http.MethodDelete, "/v1/users/"+adminID+"/roles/"+model.RoleAdmin, adminToken, nil).wantStatus(t, http.StatusConflict)
+	ts.request(t, http.MethodPut, "/v1/users/"+userID+"/roles/"+model.RoleAdmin, adminToken, nil).wantStatus(t, http.StatusOK)
+	ts.request(t, http.MethodDelete, "/v1/users/"+adminID+"/roles/"+model.RoleAdmin, adminToken, nil).wantStatus(t, http.StatusOK)
+}
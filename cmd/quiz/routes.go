@@ -8,6 +8,52 @@ import (
 
 // routes is our main application's router.
 func (app *application) routes() http.Handler {
+	r := app.router()
+
+	// Wrap the router with the middleware chain, outermost first. The request ID comes first so
+	// that every log entry of the request has it. Metrics and traces see every response,
+	// including the ones for panics, which are recovered everywhere,
+	// CORS comes before rate limiting so that browsers can read 429 responses, and the global and
+	// per-IP limits apply before authentication, the per-user limit after it.
+	api := chain(r,
+		app.requestID,
+		app.recordMetrics,
+		app.traceRequest,
+		app.recoverPanic,
+		app.secureHeaders,
+		app.enableCORS,
+		app.rateLimit,
+		app.authenticate,
+		app.rateLimitUser,
+	)
+
+	// Metrics are served on the API address only behind basic auth, with -metrics-addr they get
+	// their own server. They bypass the chain, which would take the basic auth credentials for an
+	// invalid authentication token.
+	var metrics http.Handler
+	if app.config.metrics.addr == "" && app.config.metrics.username != "" {
+		metrics = app.metricsHandler()
+	}
+
+	// The probes bypass the chain too, so that rate limits can't make a healthy instance look
+	// down to its load balancer.
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/livez":
+			app.livezHandler(w, r)
+		case r.URL.Path == "/readyz":
+			app.readyzHandler(w, r)
+		case r.URL.Path == "/metrics" && metrics != nil:
+			metrics.ServeHTTP(w, r)
+		default:
+			api.ServeHTTP(w, r)
+		}
+	})
+}
+
+// router registers the routes of the API. Every route needs an entry in apiDocs, see
+// "justquiz openapi check".
+func (app *application) router() *mux.Router {
 	r := mux.NewRouter()
 	// Convert the app.notFoundResponse helper to a http.Handler using the http.HandlerFunc()
 	// adapter, and then set it as the custom error handler for 404 Not Found responses.
@@ -17,6 +63,9 @@ func (app *application) routes() http.Handler {
 	// error handler for 405 Method Not Allowed responses
 	r.MethodNotAllowedHandler = http.HandlerFunc(app.methodNotAllowedResponse)
 
+	// Tell the metrics middleware which route matched.
+	r.Use(app.recordRoute)
+
 	// healthcheck
 	r.HandleFunc("/v1/healthcheck", app.healthcheckHandler).Methods("GET")
 
@@ -24,28 +73,30 @@ func (app *application) routes() http.Handler {
 	// Players list
 	players.HandleFunc("/players", app.getPlayersList).Methods("GET")
 	// Create a new player
-	players.HandleFunc("/players", app.requireAuthenticatedUser(app.createPlayerHandler)).Methods("POST")
+	players.HandleFunc("/players", app.requirePermissions("player:create", app.createPlayerHandler)).Methods("POST")
 	// Get a player by id
 	players.HandleFunc("/players/{id:[0-9]+}", app.getPlayerHandler).Methods("GET")
-	// Update player data with id
-	players.HandleFunc("/players/{id:[0-9]+}", app.requireAuthenticatedUser(app.updatePlayerHandler)).Methods("PUT")
+	// Update player data with id, the creator of the player may rename it
+	players.HandleFunc("/players/{id:[0-9]+}", app.requireOwnerOrPermission(app.playerOwner, "player:write", app.updatePlayerHandler)).Methods("PUT")
 	// Delete player by id
-	players.HandleFunc("/players/{id:[0-9]+}", app.requirePermissions("player:write", app.deletePlayerHandler)).Methods("DELETE")
+	players.HandleFunc("/players/{id:[0-9]+}", app.requirePermissions("player:delete", app.deletePlayerHandler)).Methods("DELETE")
 	// Quizes that a player finished
 	// Relation
-	players.HandleFunc("/players/{id:[0-9]+}/quizes", app.getPlayerQuizes).Methods("GET")	
+	players.HandleFunc("/players/{id:[0-9]+}/quizes", app.getPlayerQuizes).Methods("GET")
+	// Players ranked by their score, refreshed periodically
+	players.HandleFunc("/leaderboard", app.getLeaderboard).Methods("GET")	
 
 	quizes := r.PathPrefix("/v1").Subrouter()
 	// Quizes list
 	quizes.HandleFunc("/quizes", app.getQuizesList).Methods("GET")
 	// Create a new player
-	quizes.HandleFunc("/quizes", app.requireAuthenticatedUser(app.createQuizHandler)).Methods("POST")
+	quizes.HandleFunc("/quizes", app.requirePermissions("quiz:create", app.createQuizHandler)).Methods("POST")
 	// Get a player by id
 	quizes.HandleFunc("/quizes/{id:[0-9]+}", app.getQuizHandler).Methods("GET")
-	// Update player data with id
-	quizes.HandleFunc("/quizes/{id:[0-9]+}", app.requireAuthenticatedUser(app.updateQuizHandler)).Methods("PUT")
-	// Delete player by id
-	quizes.HandleFunc("/quizes/{id:[0-9]+}", app.requirePermissions("player:write", app.deleteQuizHandler)).Methods("DELETE")
+	// Update quiz data with id, allowed to the author of the quiz too
+	quizes.HandleFunc("/quizes/{id:[0-9]+}", app.requireOwnerOrPermission(app.quizOwner, "quiz:write", app.updateQuizHandler)).Methods("PUT")
+	// Delete quiz by id, allowed to the author of the quiz too
+	quizes.HandleFunc("/quizes/{id:[0-9]+}", app.requireOwnerOrPermission(app.quizOwner, "quiz:delete", app.deleteQuizHandler)).Methods("DELETE")
 	// Players that finished the quiz
 	// Relation
 	quizes.HandleFunc("/quizes/{id:[0-9]+}/players", app.getQuizePlayers).Methods("GET")
@@ -54,20 +105,65 @@ func (app *application) routes() http.Handler {
 	// Games list
 	games.HandleFunc("/games", app.getGamesList).Methods("GET")
 	// Create a new player
-	games.HandleFunc("/games", app.requireAuthenticatedUser(app.createGameHandler)).Methods("POST")
+	games.HandleFunc("/games", app.requirePermissions("game:play", app.createGameHandler)).Methods("POST")
 	// Get a player by id
 	games.HandleFunc("/games/{id:[0-9]+}", app.getGameHandler).Methods("GET")
 	// Answer question
-	games.HandleFunc("/games/{id:[0-9]+}", app.requireAuthenticatedUser(app.answerGameHandler)).Methods("POST")
+	games.HandleFunc("/games/{id:[0-9]+}", app.requirePermissions("game:play", app.rateLimitRoute("answer", app.config.limiter.answer, app.answerGameHandler))).Methods("POST")
 	// Delete player by id
-	games.HandleFunc("/games/{id:[0-9]+}", app.requirePermissions("player:write", app.deleteGameHandler)).Methods("DELETE")
+	games.HandleFunc("/games/{id:[0-9]+}", app.requirePermissions("game:delete", app.deleteGameHandler)).Methods("DELETE")
 
 	users := r.PathPrefix("/v1").Subrouter()
 	// User handlers with Authentication
 	users.HandleFunc("/users", app.registerUserHandler).Methods("POST")
 	users.HandleFunc("/users/activated", app.activateUserHandler).Methods("PUT")
-	users.HandleFunc("/users/login", app.createAuthenticationTokenHandler).Methods("POST")
+	users.HandleFunc("/users/login", app.rateLimitRoute("login", app.config.limiter.login, app.createAuthenticationTokenHandler)).Methods("POST")
+	users.HandleFunc("/users/login/2fa", app.rateLimitRoute("login", app.config.limiter.login, app.completeTwoFactorLoginHandler)).Methods("POST")
+	// Two-factor authentication management
+	users.HandleFunc("/users/2fa/totp", app.requireUserSession(app.enrollTOTPHandler)).Methods("POST")
+	users.HandleFunc("/users/2fa/totp", app.requireUserSession(app.disableTOTPHandler)).Methods("DELETE")
+	users.HandleFunc("/users/2fa/totp/confirm", app.requireUserSession(app.confirmTOTPHandler)).Methods("POST")
+	users.HandleFunc("/users/2fa/recovery-codes", app.requireUserSession(app.regenerateRecoveryCodesHandler)).Methods("POST")
+	// API keys of the authenticated user for machine-to-machine integrations
+	users.HandleFunc("/api-keys", app.requireUserSession(app.createAPIKeyHandler)).Methods("POST")
+	users.HandleFunc("/api-keys", app.requireUserSession(app.getAPIKeysList)).Methods("GET")
+	users.HandleFunc("/api-keys/{id:[0-9]+}", app.requireUserSession(app.revokeAPIKeyHandler)).Methods("DELETE")
+	// Webhooks of the authenticated user, notified of events
+	users.HandleFunc("/webhooks", app.requireUserSession(app.createWebhookHandler)).Methods("POST")
+	users.HandleFunc("/webhooks", app.requireUserSession(app.getWebhooksList)).Methods("GET")
+	users.HandleFunc("/webhooks/{id:[0-9]+}", app.requireUserSession(app.getWebhookHandler)).Methods("GET")
+	users.HandleFunc("/webhooks/{id:[0-9]+}", app.requireUserSession(app.updateWebhookHandler)).Methods("PUT")
+	users.HandleFunc("/webhooks/{id:[0-9]+}", app.requireUserSession(app.deleteWebhookHandler)).Methods("DELETE")
+	users.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", app.requireUserSession(app.getWebhookDeliveriesList)).Methods("GET")
+	users.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/{delivery:[0-9]+}/redeliver", app.requireUserSession(app.redeliverWebhookHandler)).Methods("POST")
+	users.HandleFunc("/webhooks/{id:[0-9]+}/ping", app.requireUserSession(app.pingWebhookHandler)).Methods("POST")
+	// Brute-force protection administration
+	users.HandleFunc("/users/{id:[0-9]+}/unlock", app.requireAllPermissions([]string{"user:read", "user:write"}, app.unlockUserHandler)).Methods("PUT")
+	users.HandleFunc("/login-attempts", app.requireAnyPermission([]string{"user:read", "user:write"}, app.getLoginAttemptsList)).Methods("GET")
+	// Roles and permissions administration
+	users.HandleFunc("/roles", app.requirePermissions("user:read", app.getRolesList)).Methods("GET")
+	users.HandleFunc("/permissions", app.requirePermissions("user:read", app.getPermissionsList)).Methods("GET")
+	users.HandleFunc("/users/{id:[0-9]+}/access", app.requirePermissions("user:read", app.getUserAccessHandler)).Methods("GET")
+	users.HandleFunc("/users/{id:[0-9]+}/roles/{role}", app.requirePermissions("user:write", app.grantUserRoleHandler)).Methods("PUT")
+	users.HandleFunc("/users/{id:[0-9]+}/roles/{role}", app.requirePermissions("user:write", app.revokeUserRoleHandler)).Methods("DELETE")
+	users.HandleFunc("/users/{id:[0-9]+}/permissions/{code}", app.requirePermissions("user:write", app.grantUserPermissionHandler)).Methods("PUT")
+	users.HandleFunc("/users/{id:[0-9]+}/permissions/{code}", app.requirePermissions("user:write", app.revokeUserPermissionHandler)).Methods("DELETE")
+	// History of the periodic jobs
+	users.HandleFunc("/jobs/runs", app.requirePermissions("user:read", app.getJobRunsList)).Methods("GET")
+	// Login with an external OpenID Connect provider
+	users.HandleFunc("/users/oidc/{provider}/login", app.oidcLoginHandler).Methods("GET")
+	users.HandleFunc("/users/oidc/{provider}/callback", app.oidcCallbackHandler).Methods("GET")
+
+	// The OpenAPI document of the routes, and a page to read it. The document describes all the
+	// routes, its own too, so it is generated once they are registered.
+	var spec envelope
+	r.HandleFunc("/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		app.writeJSON(w, http.StatusOK, spec, nil)
+	}).Methods("GET")
+	r.Handle("/v1/docs", http.RedirectHandler("/v1/docs/", http.StatusMovedPermanently)).Methods("GET")
+	r.PathPrefix("/v1/docs/").Handler(app.docsHandler()).Methods("GET")
+
+	spec = app.openAPIDocument(r)
 
-	// Wrap the router with the panic recovery middleware and rate limit middleware.
-	return app.authenticate(r)
+	return r
 }
@@ -23,6 +23,33 @@ func (app *application) serve() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// Serve the metrics on their own address if one is configured, so that they don't have to be
+	// exposed to the clients of the API.
+	var metricsSrv *http.Server
+	if app.config.metrics.addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", app.metricsHandler())
+
+		metricsSrv = &http.Server{
+			Addr:         app.config.metrics.addr,
+			Handler:      mux,
+			ErrorLog:     log.New(app.logger, "", 0),
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+
+		go func() {
+			app.logger.PrintInfo("starting metrics server", map[string]string{
+				"addr": metricsSrv.Addr,
+			})
+
+			err := metricsSrv.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				app.logger.PrintError(err, nil)
+			}
+		}()
+	}
+
 	// Create a shutdownError channel. We will use this to receive any errors returned
 	// by the graceful Shutdown() function.
 	shutdownError := make(chan error)
@@ -48,6 +75,11 @@ func (app *application) serve() error {
 			"signal": s.String(),
 		})
 
+		// Report not ready and keep serving for a while, so that load balancers stop sending
+		// requests before the server stops accepting them.
+		app.shuttingDown.Store(true)
+		time.Sleep(app.config.shutdownDelay)
+
 		// Create a context with a 5-second timeout.
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -59,6 +91,28 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 
+		if metricsSrv != nil {
+			if err := metricsSrv.Shutdown(ctx); err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		}
+
+		// Stop scheduling jobs, the runs in progress are waited for with the other background
+		// tasks.
+		if app.stopJobs != nil {
+			app.stopJobs()
+		}
+
+		// Stop looking for webhook deliveries, the attempts in progress are waited for too.
+		if app.stopWebhooks != nil {
+			app.stopWebhooks()
+		}
+
+		// Stop evicting rate limit buckets.
+		if app.stopRateLimitEviction != nil {
+			app.stopRateLimitEviction()
+		}
+
 		// Log a message to say that we're waiting for any background goroutines to complete
 		// their tasks.
 		app.logger.PrintInfo("completing background tasks", map[string]string{
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/jsonlog"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/oidc"
+)
+
+// testPassword is the password of the users created by newTestUser.
+const testPassword = "pa55word123"
+
+// requestedRoutes records the routes the tests requested, as "METHOD /path" like the keys of
+// apiDocs.
+var requestedRoutes = struct {
+	sync.Mutex
+	routes map[string]bool
+}{routes: make(map[string]bool)}
+
+// TestMain runs the tests and then fails if a route wasn't requested by any of them. The check is
+// skipped when only some tests run.
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	code := m.Run()
+
+	if code == 0 && flag.Lookup("test.run").Value.String() == "" && flag.Lookup("test.skip").Value.String() == "" {
+		missing, err := unrequestedRoutes()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+		if len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "FAIL: routes not requested by any test:\n\t%s\n", strings.Join(missing, "\n\t"))
+			code = 1
+		}
+	}
+
+	os.Exit(code)
+}
+
+// unrequestedRoutes returns the routes which no test requested.
+func unrequestedRoutes() ([]string, error) {
+	app := &application{logger: jsonlog.NewLogger(io.Discard, jsonlog.LevelOff)}
+
+	routes, err := openAPIRoutes(app.router())
+	if err != nil {
+		return nil, err
+	}
+
+	requestedRoutes.Lock()
+	defer requestedRoutes.Unlock()
+
+	var missing []string
+	for _, route := range routes {
+		if !requestedRoutes.routes[route] {
+			missing = append(missing, route)
+		}
+	}
+	slices.Sort(missing)
+
+	return missing, nil
+}
+
+// newTestApplication returns an application which keeps its records in the memory models. The
+// database is an empty in-memory SQLite database, only the readiness probe and the connection
+// metrics use it. Rate limits are off, and webhooks may be sent to the loopback test servers.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	var cfg config
+	cfg.env = "testing"
+	cfg.oidc.redirectBase = "http://localhost:8081"
+	cfg.jobs.purgeTokens = time.Hour
+	cfg.jobs.expireGames = time.Hour
+	cfg.jobs.gamesAbandonedAfter = 24 * time.Hour
+	cfg.jobs.refreshLeaderboard = 5 * time.Minute
+	cfg.webhooks.pollInterval = 5 * time.Second
+	cfg.webhooks.timeout = 10 * time.Second
+	cfg.webhooks.maxAttempts = 6
+	cfg.webhooks.backoff = 30 * time.Second
+	cfg.webhooks.backoffMax = time.Hour
+	cfg.webhooks.allowInternal = true
+	cfg.lockout.maxFailures = 5
+	cfg.lockout.ipMaxFailures = 20
+	cfg.lockout.ipWindow = 15 * time.Minute
+	cfg.lockout.base = time.Minute
+	cfg.lockout.max = time.Hour
+	cfg.cors.maxAge = time.Hour
+	cfg.security.headers = true
+
+	return &application{
+		config:        cfg,
+		db:            db,
+		models:        model.NewMemoryModels(),
+		logger:        jsonlog.NewLogger(io.Discard, jsonlog.LevelOff),
+		oidc:          make(map[string]*oidc.Provider),
+		metrics:       newAppMetrics(db),
+		workers:       newWorkerHealth(),
+		startedAt:     time.Now(),
+		webhookClient: newWebhookClient(true),
+	}
+}
+
+// newTestSQLiteApplication returns a test application which keeps its records in a SQLite
+// database file, migrated with the embedded migrations.
+func newTestSQLiteApplication(t *testing.T) *application {
+	t.Helper()
+
+	db, m := newTestMigrate(t)
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApplication(t)
+	app.db = db
+	app.models = model.NewSQLiteModels(db, 3*time.Second)
+	app.metrics = newAppMetrics(db)
+
+	return app
+}
+
+// testBackends are the applications the handler tests run against, so that the memory and the
+// SQLite models answer the same.
+var testBackends = []struct {
+	name string
+	app  func(t *testing.T) *application
+}{
+	{"memory", newTestApplication},
+	{"sqlite", newTestSQLiteApplication},
+}
+
+// forEachBackend runs test with a new application of each of testBackends.
+func forEachBackend(t *testing.T, test func(t *testing.T, app *application)) {
+	t.Helper()
+
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.app(t))
+		})
+	}
+}
+
+// testServer serves the routes of a test application.
+type testServer struct {
+	*httptest.Server
+	router *mux.Router
+}
+
+func newTestServer(t *testing.T, app *application) *testServer {
+	t.Helper()
+
+	ts := &testServer{Server: httptest.NewServer(app.routes()), router: app.router()}
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// testResponse is a response of the test server with its decoded JSON body.
+type testResponse struct {
+	status int
+	header http.Header
+	body   map[string]any
+}
+
+// request sends a request with body encoded as JSON, authenticated with token if it isn't empty,
+// and records which route it was for.
+func (ts *testServer) request(t *testing.T, method, path, token string, body any) testResponse {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(js)
+	}
+
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	ts.recordRoute(req)
+
+	client := ts.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := testResponse{status: res.StatusCode, header: res.Header}
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") && len(data) > 0 {
+		if err := json.Unmarshal(data, &response.body); err != nil {
+			t.Fatalf("%s %s: %v: %s", method, path, err, data)
+		}
+	}
+
+	return response
+}
+
+// recordRoute records the route of a request, the probes bypass the router.
+func (ts *testServer) recordRoute(req *http.Request) {
+	route := ""
+
+	var match mux.RouteMatch
+	switch {
+	case req.URL.Path == "/livez" || req.URL.Path == "/readyz":
+		route = req.Method + " " + req.URL.Path
+	case ts.router.Match(req, &match) && match.Route != nil:
+		template, err := match.Route.GetPathTemplate()
+		if err != nil {
+			return
+		}
+		route = req.Method + " " + openAPIRoute.ReplaceAllString(template, "{$1}")
+	default:
+		return
+	}
+
+	requestedRoutes.Lock()
+	requestedRoutes.routes[route] = true
+	requestedRoutes.Unlock()
+}
+
+// wantStatus fails the test if the response doesn't have the status code.
+func (res testResponse) wantStatus(t *testing.T, status int) testResponse {
+	t.Helper()
+
+	if res.status != status {
+		t.Fatalf("got status %d, want %d: %v", res.status, status, res.body)
+	}
+
+	return res
+}
+
+// object returns the JSON object at the path of keys in the body.
+func (res testResponse) object(t *testing.T, keys ...string) map[string]any {
+	t.Helper()
+
+	object := res.body
+	for _, key := range keys {
+		next, ok := object[key].(map[string]any)
+		if !ok {
+			t.Fatalf("%q is not an object in %v", key, res.body)
+		}
+		object = next
+	}
+
+	return object
+}
+
+// list returns the JSON array under key in the body.
+func (res testResponse) list(t *testing.T, key string) []any {
+	t.Helper()
+
+	list, ok := res.body[key].([]any)
+	if !ok && res.body[key] != nil {
+		t.Fatalf("%q is not an array in %v", key, res.body)
+	}
+
+	return list
+}
+
+// newTestUser inserts an activated user with testPassword and the roles, and returns them with an
+// authentication token.
+func newTestUser(t *testing.T, app *application, email string, roles ...string) (*model.User, string) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	user := &model.User{Name: strings.Split(email, "@")[0], Email: email, Activated: true}
+	if err := user.Password.Set(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.Insert(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Roles.AddForUser(ctx, user.ID, roles...); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := app.models.Tokens.New(ctx, user.ID, time.Hour, model.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user, token.Plaintext
+}
+
+// id returns a JSON id, which is a number or a string of digits, as a string for URLs.
+func id(t *testing.T, value any) string {
+	t.Helper()
+
+	switch v := value.(type) {
+	case float64:
+		return fmt.Sprint(int64(v))
+	case string:
+		return v
+	default:
+		t.Fatalf("%v is not an id", value)
+		return ""
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	// tz has to work on images without a time zone database, like alpine.
+	_ "time/tzdata"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// timeLayouts are the layouts of times in query strings: RFC 3339 timestamps, also without an
+// offset, and dates.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// readTimeRange reads the range of times of field from the query string: <field>From and
+// <field>To, or last, a duration back from now like 90m, 12h, 7d or 2w, instead of <field>From.
+// Dates and timestamps without an offset are in the time zone tz, UTC if it's not given. Every
+// invalid value is recorded in v.
+func (app *application) readTimeRange(qs url.Values, field string, v *validator.Validator) model.TimeRange {
+	fromKey, toKey := field+"From", field+"To"
+
+	loc := time.UTC
+	if name := qs.Get("tz"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			v.AddError("tz", "must be a time zone name, like Asia/Almaty")
+		} else {
+			loc = l
+		}
+	}
+
+	r := model.TimeRange{
+		From: app.readTime(qs, fromKey, loc, v),
+		To:   app.readTime(qs, toKey, loc, v),
+	}
+
+	if s := qs.Get("last"); s != "" {
+		d, ok := parseLast(s)
+		switch {
+		case !ok:
+			v.AddError("last", "must be a positive duration, like 90m, 12h, 7d or 2w")
+		case qs.Get(fromKey) != "":
+			v.AddError("last", "must not be given together with "+fromKey)
+		default:
+			r.From = time.Now().Add(-d)
+		}
+	}
+
+	if !r.From.IsZero() && !r.To.IsZero() && !r.To.After(r.From) {
+		v.AddError(toKey, "must be after "+fromKey)
+	}
+
+	return r
+}
+
+// readFinishedRange reads the range of the times games finished. finisedFrom is still read, it's
+// the misspelt name finishedFrom used to have.
+func (app *application) readFinishedRange(qs url.Values, v *validator.Validator) model.TimeRange {
+	if qs.Get("finishedFrom") == "" && qs.Get("finisedFrom") != "" {
+		qs.Set("finishedFrom", qs.Get("finisedFrom"))
+	}
+
+	return app.readTimeRange(qs, "finished", v)
+}
+
+// readTime reads a time in one of timeLayouts from the query string. If no matching key is found
+// it returns the zero time, which leaves that end of a range open.
+func (app *application) readTime(qs url.Values, key string, loc *time.Location, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t
+		}
+	}
+
+	v.AddError(key, "must be an RFC 3339 timestamp or a date")
+	return time.Time{}
+}
+
+// parseLast parses the duration of last: a duration of time.ParseDuration, or a number of days
+// or weeks. Numbers of days or weeks longer than a time.Duration can hold are invalid.
+func parseLast(s string) (time.Duration, bool) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+
+	if unit != 0 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n <= 0 || n > int(math.MaxInt64/int64(unit)) {
+			return 0, false
+		}
+		return time.Duration(n) * unit, true
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+
+	return d, true
+}
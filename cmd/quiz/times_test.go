@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLast(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{"90m", 90 * time.Minute, true},
+		{"12h", 12 * time.Hour, true},
+		{"7d", 7 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"106751d", 106751 * 24 * time.Hour, true},
+		{"0d", 0, false},
+		{"-1w", 0, false},
+		{"d", 0, false},
+		{"forever", 0, false},
+		{"106752d", 0, false},
+		{"15251w", 0, false},
+		{"9999999999999999d", 0, false},
+		{"99999999999999999999w", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseLast(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseLast(%q) = %v, %t, want %v, %t", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -33,13 +33,27 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
+	// Refuse clients that have failed too often, before doing any expensive work like hashing
+	// the password.
+	wait, err := app.ipLockout(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if wait > 0 {
+		app.metrics.loginsFailed.Inc("ip blocked")
+		app.tooManyLoginAttemptsResponse(w, r, wait)
+		return
+	}
+
 	// Lookup the user record based on the email address. If no matching user was found, then we
 	// call the app.invalidCredentialsResponse() helper to send a 501 Unauthorized response to
 	// the client.
-	user, err := app.models.Users.GetByEmail(input.Email)
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
+			app.recordLoginAttempt(r, input.Email, nil, false, "unknown email")
 			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -47,6 +61,13 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
+	// A locked account is refused without checking the password.
+	if locked, wait := user.IsLocked(); locked {
+		app.recordLoginAttempt(r, input.Email, user, false, "account locked")
+		app.tooManyLoginAttemptsResponse(w, r, wait)
+		return
+	}
+
 	// Check if the provided password matches the actual password for the user.
 	match, err := user.Password.Matches(input.Password)
 	if err != nil {
@@ -54,23 +75,73 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	// If the passwords don't match, then call the app.invalidCredentialsResponse() helper
-	// and return
+	// If the passwords don't match, then count the failure against the account and call the
+	// app.invalidCredentialsResponse() helper and return
 	if !match {
+		app.recordLoginAttempt(r, input.Email, user, false, "invalid password")
+
+		err = app.registerFailedLogin(r.Context(), user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
 
-	// Otherwise, if the password is correct, we generate a new token with a 24-hour expiry time
-	// and the scope 'authentication'.
-	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, model.ScopeAuthentication)
+	app.recordLoginAttempt(r, input.Email, user, true, "password")
+
+	// Otherwise, the password is correct and we either issue the authentication token or ask
+	// for the second factor.
+	app.writeAuthenticationResponse(w, r, user, nil)
+}
+
+// writeAuthenticationResponse completes a successful first factor login of the user. If the user
+// has two-factor authentication enabled, it responds with 202 Accepted and a short-lived
+// two_factor_token that must be exchanged, together with a code, at POST /v1/users/login/2fa.
+// Otherwise we generate a new token with a 24-hour expiry time and the scope 'authentication'.
+// Any data in env is included in the response.
+func (app *application) writeAuthenticationResponse(w http.ResponseWriter, r *http.Request, user *model.User, env envelope) {
+	if env == nil {
+		env = envelope{}
+	}
+
+	enabled, err := app.models.TwoFactor.Enabled(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if enabled {
+		challenge, err := app.models.Tokens.New(r.Context(), user.ID, 5*time.Minute, model.ScopeTwoFactor)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env["two_factor_token"] = challenge
+		app.writeJSON(w, http.StatusAccepted, env, nil)
+		return
+	}
+
+	// The failed login counter is only reset once the user is fully authenticated, so that a known
+	// password can't be used to reset it between guesses of the second factor.
+	err = app.resetFailedLogins(r.Context(), user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := app.models.Tokens.New(r.Context(), user.ID, 24*time.Hour, model.ScopeAuthentication)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	// Encode the token to JSON and send it in the response along with a 201 Created status code.
-	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	env["authentication_token"] = token
+	err = app.writeJSON(w, http.StatusCreated, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/jsonlog"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/tracing"
+)
+
+// maxRequestIDLength limits inbound request IDs, they end up in every log entry of the request.
+const maxRequestIDLength = 128
+
+// requestID gives every request an ID, returned in the X-Request-ID header and added to the log
+// entries and error responses of the request. An ID sent by the client or a proxy is kept, so that
+// their logs can be matched with ours.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		r = r.WithContext(jsonlog.ContextWithRequestID(r.Context(), id))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID reports whether an inbound request ID is short and only made of characters that
+// are safe to log and echo in a header.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '/', c == '+', c == '=':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// traceRequest starts the server span of a request, which the spans of the queries it runs become
+// children of. It continues the trace of the caller if the request has a traceparent header. It
+// has to run inside recordMetrics, which provides the route template.
+func (app *application) traceRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.StartServer(r.Context(), r.Method, r.Header.Get("traceparent"))
+		defer span.End()
+
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("url.path", r.URL.Path)
+		span.SetAttribute("client.address", app.clientIP(r))
+		span.SetAttribute("request_id", jsonlog.RequestID(ctx))
+
+		sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		if route, ok := r.Context().Value(routeContextKey).(*string); ok {
+			span.SetName(r.Method + " " + *route)
+			span.SetAttribute("http.route", *route)
+		}
+
+		span.SetAttribute("http.response.status_code", strconv.Itoa(sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.SetError(errorStatus(sw.status))
+		}
+	})
+}
+
+// errorStatus is the error of a span whose request ended with a server error.
+type errorStatus int
+
+func (s errorStatus) Error() string {
+	return strconv.Itoa(int(s)) + " " + http.StatusText(int(s))
+}
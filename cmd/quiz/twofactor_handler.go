@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/totp"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// totpIssuer is the issuer shown next to the account in authenticator apps.
+const totpIssuer = "JustQuiz"
+
+// enrollTOTPHandler starts a TOTP enrollment for the authenticated user. It returns the secret
+// and the otpauth:// provisioning URI to be shown as a QR code. The enrollment has no effect until
+// it is confirmed with a code from the authenticator app.
+func (app *application) enrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.TwoFactor.Enroll(r.Context(), user.ID, secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrEditConflict):
+			app.twoFactorEnabledResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"totp": envelope{
+		"secret":           secret,
+		"provisioning_uri": totp.URI(totpIssuer, user.Email, secret),
+	}}, nil)
+}
+
+// confirmTOTPHandler confirms the pending TOTP enrollment of the authenticated user with a code
+// from the authenticator app, which turns on two-factor authentication. The response contains
+// the recovery codes, which are never shown again.
+func (app *application) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if model.ValidateSecondFactor(v, input.Code, ""); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	enrollment, err := app.models.TwoFactor.Get(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			v.AddError("code", "no two-factor enrollment is pending")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if enrollment.Confirmed {
+		app.twoFactorEnabledResponse(w, r)
+		return
+	}
+
+	step, ok := totp.Validate(enrollment.Secret, input.Code, time.Now())
+	if !ok {
+		v.AddError("code", "invalid code")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.models.TwoFactor.UseStep(r.Context(), user.ID, step)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.TwoFactor.Confirm(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	codes, err := app.models.TwoFactor.NewRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"recovery_codes": codes}, nil)
+}
+
+// disableTOTPHandler turns off two-factor authentication for the authenticated user. A valid
+// code or recovery code is required, so that a stolen authentication token alone can't be used
+// to remove the second factor.
+func (app *application) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if model.ValidateSecondFactor(v, input.Code, input.RecoveryCode); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ok, err := app.verifySecondFactor(r.Context(), user.ID, input.Code, input.RecoveryCode)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !ok {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	err = app.models.TwoFactor.Delete(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "two-factor authentication disabled"}, nil)
+}
+
+// regenerateRecoveryCodesHandler replaces the recovery codes of the authenticated user.
+func (app *application) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if model.ValidateSecondFactor(v, input.Code, ""); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ok, err := app.verifySecondFactor(r.Context(), user.ID, input.Code, "")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !ok {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	codes, err := app.models.TwoFactor.NewRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"recovery_codes": codes}, nil)
+}
+
+// completeTwoFactorLoginHandler exchanges the two_factor_token returned by the login endpoint
+// and a TOTP code or recovery code for an authentication token. A challenge can only be
+// attempted once: after a wrong code the client must log in with the password again, which keeps
+// the codes from being guessed.
+func (app *application) completeTwoFactorLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	model.ValidateTokenPlaintext(v, input.TokenPlaintext)
+	if model.ValidateSecondFactor(v, input.Code, input.RecoveryCode); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(r.Context(), model.ScopeTwoFactor, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired two-factor token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Delete the challenge before checking the code, so that it can't be tried again.
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), model.ScopeTwoFactor, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	ok, err := app.verifySecondFactor(r.Context(), user.ID, input.Code, input.RecoveryCode)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !ok {
+		app.recordLoginAttempt(r, user.Email, user, false, "invalid second factor")
+
+		err = app.registerFailedLogin(r.Context(), user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	app.recordLoginAttempt(r, user.Email, user, true, "second factor")
+
+	err = app.resetFailedLogins(r.Context(), user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := app.models.Tokens.New(r.Context(), user.ID, 24*time.Hour, model.ScopeAuthentication)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+}
+
+// verifySecondFactor checks a TOTP code or, if code is empty, a recovery code for the user. Both
+// can only be used once.
+func (app *application) verifySecondFactor(ctx context.Context, userID int64, code, recoveryCode string) (bool, error) {
+	if code == "" {
+		return app.models.TwoFactor.UseRecoveryCode(ctx, userID, recoveryCode)
+	}
+
+	enrollment, err := app.models.TwoFactor.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !enrollment.Confirmed {
+		return false, nil
+	}
+
+	step, ok := totp.Validate(enrollment.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return app.models.TwoFactor.UseStep(ctx, userID, step)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/totp"
+)
+
+// totpCode returns the code of the time step offset from the current one. A code is accepted for
+// one step before and after the current one, and each step only once.
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	code, err := totp.Code(secret, totp.Step(time.Now())+offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code
+}
+
+func TestTwoFactor(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+
+	_, token := newTestUser(t, app, "alice@example.com", model.RolePlayer)
+	credentials := map[string]any{"email": "alice@example.com", "password": testPassword}
+
+	res := ts.request(t, http.MethodPost, "/v1/users/2fa/totp", token, nil).wantStatus(t, http.StatusCreated)
+	secret, _ := res.object(t, "totp")["secret"].(string)
+
+	ts.request(t, http.MethodPost, "/v1/users/2fa/totp/confirm", token, map[string]any{"code": "000000"}).wantStatus(t, http.StatusUnprocessableEntity)
+
+	res = ts.request(t, http.MethodPost, "/v1/users/2fa/totp/confirm", token, map[string]any{
+		"code": totpCode(t, secret, -1),
+	}).wantStatus(t, http.StatusOK)
+	recoveryCodes := res.list(t, "recovery_codes")
+	if len(recoveryCodes) == 0 {
+		t.Fatal("got no recovery codes")
+	}
+
+	ts.request(t, http.MethodPost, "/v1/users/2fa/totp", token, nil).wantStatus(t, http.StatusConflict)
+
+	// The password alone is no longer enough, the challenge takes one attempt at the code.
+	res = ts.request(t, http.MethodPost, "/v1/users/login", "", credentials).wantStatus(t, http.StatusAccepted)
+	challenge, _ := res.object(t, "two_factor_token")["token"].(string)
+
+	ts.request(t, http.MethodPost, "/v1/users/login/2fa", "", map[string]any{"token": challenge, "code": "000000"}).wantStatus(t, http.StatusUnauthorized)
+	ts.request(t, http.MethodPost, "/v1/users/login/2fa", "", map[string]any{
+		"token": challenge,
+		"code":  totpCode(t, secret, 0),
+	}).wantStatus(t, http.StatusUnprocessableEntity)
+
+	res = ts.request(t, http.MethodPost, "/v1/users/login", "", credentials).wantStatus(t, http.StatusAccepted)
+	challenge, _ = res.object(t, "two_factor_token")["token"].(string)
+
+	res = ts.request(t, http.MethodPost, "/v1/users/login/2fa", "", map[string]any{
+		"token": challenge,
+		"code":  totpCode(t, secret, 0),
+	}).wantStatus(t, http.StatusCreated)
+	token, _ = res.object(t, "authentication_token")["token"].(string)
+
+	res = ts.request(t, http.MethodPost, "/v1/users/2fa/recovery-codes", token, map[string]any{
+		"code": totpCode(t, secret, 1),
+	}).wantStatus(t, http.StatusOK)
+	fresh := res.list(t, "recovery_codes")
+
+	// The regenerated codes replace the old ones.
+	ts.request(t, http.MethodDelete, "/v1/users/2fa/totp", token, map[string]any{"recovery_code": recoveryCodes[0]}).wantStatus(t, http.StatusUnauthorized)
+	ts.request(t, http.MethodDelete, "/v1/users/2fa/totp", token, map[string]any{"recovery_code": fresh[0]}).wantStatus(t, http.StatusOK)
+
+	ts.request(t, http.MethodPost, "/v1/users/login", "", credentials).wantStatus(t, http.StatusCreated)
+}
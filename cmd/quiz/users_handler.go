@@ -51,7 +51,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Insert the user data into the database.
-	err = app.models.Users.Insert(user)
+	err = app.models.Users.Insert(r.Context(), user)
 	if err != nil {
 		switch {
 		// If we get an ErrDuplicateEmail error, use the v.AddError() method to manually add
@@ -67,15 +67,19 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Permissions.AddForUser(user.ID, "menus:read")
+	// Every new user starts out as a player, more privileged roles are granted by admins.
+	err = app.models.Roles.AddForUser(r.Context(), user.ID, model.RolePlayer)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.metrics.registrations.Inc("password")
+	app.publishEvent(r.Context(), model.EventUserRegistered, envelope{"user": user, "method": "password"})
+
 	// After the user record has been created in the database, generate a new activation
 	// token for the user.
-	token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, model.ScopeActivation)
+	token, err := app.models.Tokens.New(r.Context(), user.ID, 3*24*time.Hour, model.ScopeActivation)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -117,7 +121,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	// Retrieve the details of the user associated with the token using the GetForToken() method.
 	// If no matching record is found, then we let the client know that the token they provided
 	// is not valid.
-	user, err := app.models.Users.GetForToken(model.ScopeActivation, input.TokenPlaintext)
+	user, err := app.models.Users.GetForToken(r.Context(), model.ScopeActivation, input.TokenPlaintext)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrRecordNotFound):
@@ -134,7 +138,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 
 	// Save the updated user record in our database, checking for any edit conflicts in the same
 	// way that we did for our move records.
-	err = app.models.Users.Update(user)
+	err = app.models.Users.Update(r.Context(), user)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrEditConflict):
@@ -146,11 +150,78 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// If everything went successfully above, then delete all activation tokens for the user.
-	err = app.models.Tokens.DeleteAllForUser(model.ScopeActivation, user.ID)
+	err = app.models.Tokens.DeleteAllForUser(r.Context(), model.ScopeActivation, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+}
+
+// unlockUserHandler lifts the lockout of a user after too many failed logins and resets their
+// failed login counter.
+func (app *application) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Users.Unlock(r.Context(), int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.Get(r.Context(), int64(id))
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
-}
\ No newline at end of file
+}
+
+// getLoginAttemptsList returns the audit records of login attempts, filtered by email and ip.
+func (app *application) getLoginAttemptsList(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string
+		IP    string
+		model.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Email = app.readStrings(qs, "email", "")
+	input.IP = app.readStrings(qs, "ip", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	// Show the most recent attempts first unless asked otherwise.
+	input.Filters.Sort = app.readStrings(qs, "sort", "-id")
+
+	input.Filters.SortSafeList = []string{
+		"id", "email", "ip", "created_at",
+		"-id", "-email", "-ip", "-created_at",
+	}
+
+	if model.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	attempts, metadata, err := app.models.LoginAttempts.GetAll(r.Context(), input.Email, input.IP, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"login_attempts": attempts, "metadata": metadata}, nil)
+}
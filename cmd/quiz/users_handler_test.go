@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+func TestRegisterActivateLogin(t *testing.T) {
+	forEachBackend(t, testRegisterActivateLogin)
+}
+
+func testRegisterActivateLogin(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+
+	credentials := map[string]any{"email": "alice@example.com", "password": testPassword}
+
+	res := ts.request(t, http.MethodPost, "/v1/users", "", map[string]any{
+		"name":     "alice",
+		"email":    "alice@example.com",
+		"password": testPassword,
+	}).wantStatus(t, http.StatusCreated)
+	activation, _ := res.object(t, "user")["token"].(string)
+
+	ts.request(t, http.MethodPost, "/v1/users", "", map[string]any{
+		"name":     "alice",
+		"email":    "alice@example.com",
+		"password": testPassword,
+	}).wantStatus(t, http.StatusUnprocessableEntity)
+
+	// An inactive user can log in, but not manage their credentials.
+	res = ts.request(t, http.MethodPost, "/v1/users/login", "", credentials).wantStatus(t, http.StatusCreated)
+	token, _ := res.object(t, "authentication_token")["token"].(string)
+	ts.request(t, http.MethodGet, "/v1/api-keys", token, nil).wantStatus(t, http.StatusForbidden)
+
+	ts.request(t, http.MethodPut, "/v1/users/activated", "", map[string]any{"token": activation}).wantStatus(t, http.StatusOK)
+	ts.request(t, http.MethodPut, "/v1/users/activated", "", map[string]any{"token": activation}).wantStatus(t, http.StatusUnprocessableEntity)
+
+	ts.request(t, http.MethodGet, "/v1/api-keys", token, nil).wantStatus(t, http.StatusOK)
+
+	// Registered users are players.
+	ts.request(t, http.MethodPost, "/v1/players", token, map[string]any{"name": "alice"}).wantStatus(t, http.StatusCreated)
+
+	ts.request(t, http.MethodPost, "/v1/users/login", "", map[string]any{
+		"email":    "alice@example.com",
+		"password": "wrong password",
+	}).wantStatus(t, http.StatusUnauthorized)
+}
+
+func TestLoginLockout(t *testing.T) {
+	forEachBackend(t, testLoginLockout)
+}
+
+func testLoginLockout(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+
+	user, _ := newTestUser(t, app, "alice@example.com", model.RolePlayer)
+	_, admin := newTestUser(t, app, "admin@example.com", model.RoleAdmin)
+
+	wrong := map[string]any{"email": "alice@example.com", "password": "wrong password"}
+	for i := 0; i < app.config.lockout.maxFailures; i++ {
+		ts.request(t, http.MethodPost, "/v1/users/login", "", wrong).wantStatus(t, http.StatusUnauthorized)
+	}
+
+	credentials := map[string]any{"email": "alice@example.com", "password": testPassword}
+	res := ts.request(t, http.MethodPost, "/v1/users/login", "", credentials).wantStatus(t, http.StatusTooManyRequests)
+	if res.header.Get("Retry-After") == "" {
+		t.Error("got no Retry-After header for a locked account")
+	}
+
+	// The refused login is recorded too.
+	res = ts.request(t, http.MethodGet, "/v1/login-attempts?email=ALICE@example.com", admin, nil).wantStatus(t, http.StatusOK)
+	if got, want := len(res.list(t, "login_attempts")), app.config.lockout.maxFailures+1; got != want {
+		t.Errorf("got %d login attempts, want %d", got, want)
+	}
+
+	ts.request(t, http.MethodPut, "/v1/users/"+id(t, float64(user.ID))+"/unlock", admin, nil).wantStatus(t, http.StatusOK)
+	ts.request(t, http.MethodPost, "/v1/users/login", "", credentials).wantStatus(t, http.StatusCreated)
+}
+
+func TestUsersErrors(t *testing.T) {
+	forEachBackend(t, testUsersErrors)
+}
+
+func testUsersErrors(t *testing.T, app *application) {
+	ts := newTestServer(t, app)
+
+	user, token := newTestUser(t, app, "alice@example.com", model.RolePlayer)
+	_, admin := newTestUser(t, app, "admin@example.com", model.RoleAdmin)
+
+	alice := "/v1/users/" + id(t, float64(user.ID))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   any
+		want   int
+	}{
+		{"register with an invalid email", http.MethodPost, "/v1/users", "", map[string]any{"name": "bob", "email": "bob", "password": testPassword}, http.StatusUnprocessableEntity},
+		{"register with a short password", http.MethodPost, "/v1/users", "", map[string]any{"name": "bob", "email": "bob@example.com", "password": "short"}, http.StatusUnprocessableEntity},
+		{"activate with an invalid token", http.MethodPut, "/v1/users/activated", "", map[string]any{"token": "invalid"}, http.StatusUnprocessableEntity},
+		{"login without a password", http.MethodPost, "/v1/users/login", "", map[string]any{"email": "alice@example.com"}, http.StatusUnprocessableEntity},
+		{"login as a missing user", http.MethodPost, "/v1/users/login", "", map[string]any{"email": "nobody@example.com", "password": testPassword}, http.StatusUnauthorized},
+		{"unlock a missing user", http.MethodPut, "/v1/users/999999/unlock", admin, nil, http.StatusNotFound},
+		{"unlock without the permission", http.MethodPut, alice + "/unlock", token, nil, http.StatusForbidden},
+		{"access of a missing user", http.MethodGet, "/v1/users/999999/access", admin, nil, http.StatusNotFound},
+		{"access without the permission", http.MethodGet, alice + "/access", token, nil, http.StatusForbidden},
+		{"grant a role to a missing user", http.MethodPut, "/v1/users/999999/roles/author", admin, nil, http.StatusNotFound},
+		{"grant an unknown role", http.MethodPut, alice + "/roles/wizard", admin, nil, http.StatusUnprocessableEntity},
+		{"grant a role without the permission", http.MethodPut, alice + "/roles/author", token, nil, http.StatusForbidden},
+		{"revoke a permission of a missing user", http.MethodDelete, "/v1/users/999999/permissions/quiz:create", admin, nil, http.StatusNotFound},
+		{"login attempts without the permission", http.MethodGet, "/v1/login-attempts", token, nil, http.StatusForbidden},
+		{"revoke a missing api key", http.MethodDelete, "/v1/api-keys/999999", token, nil, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.request(t, tt.method, tt.path, tt.token, tt.body).wantStatus(t, tt.want)
+		})
+	}
+}
@@ -0,0 +1,624 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// webhookBatchSize is how many due deliveries the worker claims and attempts at once.
+const webhookBatchSize = 10
+
+// webhookEvent is the body of a webhook delivery.
+type webhookEvent struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// newWebhookEvent encodes an event with its data, and returns the event id and the payload.
+func newWebhookEvent(event string, data any) (string, []byte, error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", nil, err
+	}
+	id := "evt_" + hex.EncodeToString(randomBytes)
+
+	payload, err := json.Marshal(webhookEvent{
+		ID:        id,
+		Event:     event,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Data:      data,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return id, payload, nil
+}
+
+// publishEvent queues a delivery of the event to every active webhook subscribed to it whose
+// owner still has the permission to read it. Errors are only logged: the action that caused the
+// event succeeded, and failing its request would make clients retry it.
+func (app *application) publishEvent(ctx context.Context, event string, data any) {
+	properties := map[string]string{"event": event}
+
+	webhooks, err := app.models.Webhooks.GetAllForEvent(ctx, event)
+	if err != nil {
+		app.logger.PrintError(err, properties)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	id, payload, err := newWebhookEvent(event, data)
+	if err != nil {
+		app.logger.PrintError(err, properties)
+		return
+	}
+
+	// Owners may have lost the permission of the event since they subscribed to it.
+	permitted := make(map[int64]bool)
+	for _, webhook := range webhooks {
+		allowed, ok := permitted[webhook.UserID]
+		if !ok {
+			permissions, err := app.models.Permissions.GetAllForUser(ctx, webhook.UserID)
+			if err != nil {
+				app.logger.PrintError(err, properties)
+				return
+			}
+			allowed = permissions.Include(model.WebhookEvents[event])
+			permitted[webhook.UserID] = allowed
+		}
+		if !allowed {
+			continue
+		}
+
+		if _, err := app.queueDelivery(ctx, webhook.ID, event, id, payload); err != nil {
+			app.logger.PrintError(err, properties)
+		}
+	}
+}
+
+// queueDelivery adds a delivery of an event to a webhook, due right away, and wakes up the
+// delivery worker.
+func (app *application) queueDelivery(ctx context.Context, webhookID int64, event, eventID string, payload []byte) (*model.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := &model.WebhookDelivery{
+		WebhookID:     webhookID,
+		Event:         event,
+		EventID:       eventID,
+		Payload:       payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+
+	if err := app.models.Webhooks.InsertDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	// The worker is busy or not running if the wake up is already pending or there is no
+	// channel. Either way it finds the delivery on its next poll.
+	select {
+	case app.webhookWake <- struct{}{}:
+	default:
+	}
+
+	return delivery, nil
+}
+
+// startWebhooks starts the worker delivering the webhooks. It is added to app.wg, and stops once
+// app.stopWebhooks is called. Several instances can run it, each delivery is claimed by one.
+func (app *application) startWebhooks() {
+	interval := app.config.webhooks.pollInterval
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.stopWebhooks = cancel
+	app.webhookWake = make(chan struct{}, 1)
+	app.webhookClient = newWebhookClient(app.config.webhooks.allowInternal)
+
+	app.workers.beat("webhooks", interval)
+
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			app.deliverWebhooks(ctx)
+			app.workers.beat("webhooks", interval)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-app.webhookWake:
+			}
+		}
+	}()
+}
+
+// deliverWebhooks attempts the due deliveries, a batch at a time, until none are left or ctx is
+// done. The attempts of a batch run concurrently, so that a slow receiver doesn't hold up the
+// others.
+func (app *application) deliverWebhooks(ctx context.Context) {
+	// A claimed delivery is only attempted again by another worker if this one didn't record
+	// the attempt long after the request timed out.
+	lease := app.config.webhooks.timeout + time.Minute
+
+	for ctx.Err() == nil {
+		deliveries, err := app.models.Webhooks.ClaimDeliveries(ctx, time.Now(), webhookBatchSize, lease)
+		if err != nil {
+			if ctx.Err() == nil {
+				app.logger.PrintError(err, nil)
+			}
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func(delivery *model.WebhookDelivery) {
+				defer wg.Done()
+				app.attemptDelivery(delivery)
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// attemptDelivery posts a delivery to its webhook and records the attempt. A failed attempt is
+// retried with an exponential backoff until the attempts run out. Attempts aren't canceled on
+// shutdown, they take at most the request timeout.
+func (app *application) attemptDelivery(delivery *model.WebhookDelivery) {
+	start := time.Now()
+	status, err := app.postWebhook(delivery, start)
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &start
+	delivery.DurationMS = time.Since(start).Milliseconds()
+	delivery.ResponseStatus = status
+	delivery.Error = ""
+
+	result := "succeeded"
+	switch {
+	case err == nil:
+		delivery.Status = model.DeliverySucceeded
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= app.config.webhooks.maxAttempts:
+		result = "failed"
+		delivery.Status = model.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.Error = err.Error()
+	default:
+		result = "retried"
+		next := start.Add(app.webhookBackoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+		delivery.Error = err.Error()
+	}
+
+	app.metrics.webhookDeliveries.Inc(result)
+
+	if err := app.models.Webhooks.UpdateDelivery(context.Background(), delivery); err != nil {
+		app.logger.PrintError(err, map[string]string{"delivery": fmt.Sprint(delivery.ID)})
+	}
+}
+
+// webhookBackoff returns how long to wait before the next attempt after a number of failed
+// attempts: the backoff doubled with every attempt, up to the maximum.
+func (app *application) webhookBackoff(attempts int) time.Duration {
+	backoff := app.config.webhooks.backoff
+	for i := 1; i < attempts && backoff < app.config.webhooks.backoffMax; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, app.config.webhooks.backoffMax)
+}
+
+// postWebhook posts the payload of a delivery to its webhook, signed with the secret of the
+// webhook, and returns the status code of the response. Any status but 2xx is an error, and
+// redirects aren't followed.
+func (app *application) postWebhook(delivery *model.WebhookDelivery, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), app.config.webhooks.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "JustQuiz-Webhooks/"+version)
+	req.Header.Set("X-JustQuiz-Event", delivery.Event)
+	req.Header.Set("X-JustQuiz-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-JustQuiz-Timestamp", timestamp)
+	req.Header.Set("X-JustQuiz-Signature", signWebhook(delivery.Webhook.Secret, timestamp, delivery.Payload))
+
+	res, err := app.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// Read a little of the body so that the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected response status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// errInternalWebhookAddr is returned when a webhook delivery would connect to an internal address.
+var errInternalWebhookAddr = errors.New("webhook URL resolves to an internal address")
+
+// newWebhookClient returns the client sending the webhook deliveries. A redirect is returned as
+// the response, so that deliveries only go to the URL of the webhook. Unless allowInternal is
+// set, the client refuses to connect to internal addresses. They are checked when the connection
+// is made, after the name of the host is resolved, so that a name which resolved to a public
+// address when the webhook was saved can't be pointed at an internal one later. Deliveries then
+// don't use the proxy of the environment either, as it would connect on their behalf.
+func newWebhookClient(allowInternal bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if !allowInternal {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if model.InternalWebhookAddr(addrPort.Addr()) {
+				return errInternalWebhookAddr
+			}
+
+			return nil
+		}
+		transport.Proxy = nil
+	}
+
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// signWebhook returns the X-JustQuiz-Signature of a payload sent at timestamp: the hex encoded
+// HMAC-SHA256 of the timestamp, a dot and the payload, keyed with the secret of the webhook.
+func signWebhook(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// createWebhookHandler subscribes a webhook of the authenticated user to events. The secret
+// signing the deliveries is generated unless one is given, and only part of this response.
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+		Active *bool    `json:"active"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	webhook := &model.Webhook{
+		UserID: user.ID,
+		URL:    input.URL,
+		Events: input.Events,
+		Secret: input.Secret,
+		Active: true,
+	}
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+	if webhook.Secret == "" {
+		webhook.Secret, err = model.NewWebhookSecret()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+
+	if model.ValidateWebhook(v, webhook, permissions, app.config.webhooks.allowInternal); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Webhooks.Insert(r.Context(), webhook)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/webhooks/%d", webhook.ID))
+
+	app.writeJSON(w, http.StatusCreated, envelope{"webhook": webhook, "secret": webhook.Secret}, headers)
+}
+
+// getWebhooksList returns the webhooks of the authenticated user, without their secrets.
+func (app *application) getWebhooksList(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	webhooks, err := app.models.Webhooks.GetAllForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"webhooks": webhooks}, nil)
+}
+
+// readWebhook returns the webhook of the id in the URL if it belongs to the authenticated user.
+// Otherwise it writes the error response and returns nil: webhooks of other users are not found.
+func (app *application) readWebhook(w http.ResponseWriter, r *http.Request) *model.Webhook {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	webhook, err := app.models.Webhooks.Get(r.Context(), user.ID, int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	return webhook
+}
+
+func (app *application) getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := app.readWebhook(w, r)
+	if webhook == nil {
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"webhook": webhook}, nil)
+}
+
+// updateWebhookHandler changes the URL, events or active flag of a webhook of the authenticated
+// user. The secret can't be changed, a new one needs a new webhook.
+func (app *application) updateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := app.readWebhook(w, r)
+	if webhook == nil {
+		return
+	}
+
+	var input struct {
+		URL    *string   `json:"url"`
+		Events *[]string `json:"events"`
+		Active *bool     `json:"active"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.URL != nil {
+		webhook.URL = *input.URL
+	}
+	if input.Events != nil {
+		webhook.Events = *input.Events
+	}
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(r.Context(), webhook.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if model.ValidateWebhook(v, webhook, permissions, app.config.webhooks.allowInternal); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Webhooks.Update(r.Context(), webhook)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"webhook": webhook}, nil)
+}
+
+// deleteWebhookHandler deletes a webhook of the authenticated user with its delivery log.
+// Pending deliveries are dropped.
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Webhooks.Delete(r.Context(), user.ID, int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "webhook successfully deleted"}, nil)
+}
+
+// getWebhookDeliveriesList returns the delivery log of a webhook of the authenticated user, the
+// most recent deliveries first unless asked otherwise.
+func (app *application) getWebhookDeliveriesList(w http.ResponseWriter, r *http.Request) {
+	webhook := app.readWebhook(w, r)
+	if webhook == nil {
+		return
+	}
+
+	var input struct {
+		Status string
+		model.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Status = app.readStrings(qs, "status", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readStrings(qs, "sort", "-id")
+
+	input.Filters.SortSafeList = []string{
+		"id", "created_at", "attempts",
+		"-id", "-created_at", "-attempts",
+	}
+
+	if input.Status != "" {
+		v.Check(validator.In(input.Status, model.DeliveryPending, model.DeliverySucceeded, model.DeliveryFailed), "status", "must be pending, succeeded or failed")
+	}
+
+	if model.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	deliveries, metadata, err := app.models.Webhooks.GetDeliveries(r.Context(), webhook.ID, input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"deliveries": deliveries, "metadata": metadata}, nil)
+}
+
+// redeliverWebhookHandler queues a delivery of the event of a past delivery again, with the same
+// payload and event id, whatever the status of the past one. The new delivery starts over with
+// all attempts.
+func (app *application) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := app.readWebhook(w, r)
+	if webhook == nil {
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(mux.Vars(r)["delivery"], 10, 64)
+	if err != nil || deliveryID < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	delivery, err := app.models.Webhooks.GetDelivery(r.Context(), webhook.ID, deliveryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeDelivery(w, r, webhook, delivery.Event, delivery.EventID, delivery.Payload)
+}
+
+// pingWebhookHandler queues a webhook.ping event to a webhook of the authenticated user, to test
+// the receiver. Its data is the webhook.
+func (app *application) pingWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := app.readWebhook(w, r)
+	if webhook == nil {
+		return
+	}
+
+	id, payload, err := newWebhookEvent(model.EventWebhookPing, envelope{"webhook": webhook})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeDelivery(w, r, webhook, model.EventWebhookPing, id, payload)
+}
+
+// writeDelivery queues a delivery of an event to the webhook and responds with it. Deliveries to
+// inactive webhooks stay pending until the webhook is activated.
+func (app *application) writeDelivery(w http.ResponseWriter, r *http.Request, webhook *model.Webhook, event, eventID string, payload []byte) {
+	delivery, err := app.queueDelivery(r.Context(), webhook.ID, event, eventID, payload)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusAccepted, envelope{"delivery": delivery}, nil)
+}
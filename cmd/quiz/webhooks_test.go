@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+// webhookReceiver records the deliveries posted to it and responds with status.
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	received []*http.Request
+	payloads [][]byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	t.Helper()
+
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+
+		receiver.received = append(receiver.received, r)
+		receiver.payloads = append(receiver.payloads, payload)
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(receiver.Close)
+
+	return receiver
+}
+
+// deliver delivers the due webhooks until the receiver got n deliveries. The times of the
+// deliveries are stored with the precision of a second, so they may be due a moment later.
+func (receiver *webhookReceiver) deliver(t *testing.T, app *application, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		app.deliverWebhooks(context.Background())
+
+		receiver.mu.Lock()
+		received := len(receiver.received)
+		receiver.mu.Unlock()
+
+		if received >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d deliveries, want %d", received, n)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app)
+	receiver := newWebhookReceiver(t)
+
+	_, token := newTestUser(t, app, "alice@example.com", model.RoleAuthor)
+	_, other := newTestUser(t, app, "bob@example.com", model.RoleAuthor)
+
+	// Internal addresses, such as the receiver's, are refused unless they are allowed.
+	app.config.webhooks.allowInternal = false
+	ts.request(t, http.MethodPost, "/v1/webhooks", token, map[string]any{
+		"url":    receiver.URL,
+		"events": []string{model.EventQuizPublished},
+	}).wantStatus(t, http.StatusUnprocessableEntity)
+	app.config.webhooks.allowInternal = true
+
+	res := ts.request(t, http.MethodPost, "/v1/webhooks", token, map[string]any{
+		"url":    receiver.URL,
+		"events": []string{model.EventQuizPublished},
+		"secret": "0123456789abcdef0123456789abcdef",
+	}).wantStatus(t, http.StatusCreated)
+	webhook := id(t, res.object(t, "webhook")["id"])
+	if res.body["secret"] != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("got secret %v, want the given one", res.body["secret"])
+	}
+	if res.header.Get("Location") != "/v1/webhooks/"+webhook {
+		t.Errorf("got Location %q, want /v1/webhooks/%s", res.header.Get("Location"), webhook)
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/webhooks", token, nil).wantStatus(t, http.StatusOK)
+	if got := len(res.list(t, "webhooks")); got != 1 {
+		t.Errorf("got %d webhooks, want 1", got)
+	}
+	ts.request(t, http.MethodGet, "/v1/webhooks/"+webhook, other, nil).wantStatus(t, http.StatusNotFound)
+
+	res = ts.request(t, http.MethodGet, "/v1/webhooks/"+webhook, token, nil).wantStatus(t, http.StatusOK)
+	if _, ok := res.object(t, "webhook")["secret"]; ok {
+		t.Error("got the secret of a saved webhook")
+	}
+
+	ts.request(t, http.MethodPost, "/v1/quizes", token, map[string]any{
+		"category":  "geography",
+		"reward":    10,
+		"questions": []string{"Capital of Kazakhstan?"},
+		"answers":   []string{"Astana"},
+	}).wantStatus(t, http.StatusCreated)
+
+	receiver.deliver(t, app, 1)
+
+	receiver.mu.Lock()
+	req, payload := receiver.received[0], receiver.payloads[0]
+	receiver.mu.Unlock()
+
+	if got := req.Header.Get("X-JustQuiz-Event"); got != model.EventQuizPublished {
+		t.Errorf("got event %q, want %q", got, model.EventQuizPublished)
+	}
+	want := signWebhook("0123456789abcdef0123456789abcdef", req.Header.Get("X-JustQuiz-Timestamp"), payload)
+	if got := req.Header.Get("X-JustQuiz-Signature"); got != want {
+		t.Errorf("got signature %q, want %q", got, want)
+	}
+
+	var event struct {
+		Data struct {
+			Quiz map[string]any `json:"quiz"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := event.Data.Quiz["answers"]; ok {
+		t.Error("got the answers of the quiz in the event")
+	}
+
+	res = ts.request(t, http.MethodGet, "/v1/webhooks/"+webhook+"/deliveries", token, nil).wantStatus(t, http.StatusOK)
+	deliveries := res.list(t, "deliveries")
+	if len(deliveries) != 1 || deliveries[0].(map[string]any)["status"] != model.DeliverySucceeded {
+		t.Fatalf("got deliveries %v, want one that succeeded", deliveries)
+	}
+	delivery := id(t, deliveries[0].(map[string]any)["id"])
+
+	// A failed delivery is retried later.
+	receiver.mu.Lock()
+	receiver.status = http.StatusInternalServerError
+	receiver.mu.Unlock()
+
+	res = ts.request(t, http.MethodPost, "/v1/webhooks/"+webhook+"/deliveries/"+delivery+"/redeliver", token, nil).wantStatus(t, http.StatusAccepted)
+	redelivery := id(t, res.object(t, "delivery")["id"])
+
+	receiver.deliver(t, app, 2)
+
+	res = ts.request(t, http.MethodGet, "/v1/webhooks/"+webhook+"/deliveries?status=pending", token, nil).wantStatus(t, http.StatusOK)
+	deliveries = res.list(t, "deliveries")
+	if len(deliveries) != 1 || id(t, deliveries[0].(map[string]any)["id"]) != redelivery {
+		t.Fatalf("got pending deliveries %v, want the redelivery %s", deliveries, redelivery)
+	}
+	if got := deliveries[0].(map[string]any)["response_status"]; got != float64(http.StatusInternalServerError) {
+		t.Errorf("got response status %v, want 500", got)
+	}
+
+	// An inactive webhook can still be pinged, the delivery waits until it is activated.
+	res = ts.request(t, http.MethodPut, "/v1/webhooks/"+webhook, token, map[string]any{"active": false}).wantStatus(t, http.StatusOK)
+	if got := res.object(t, "webhook")["version"]; got != float64(2) {
+		t.Errorf("got version %v, want 2", got)
+	}
+
+	res = ts.request(t, http.MethodPost, "/v1/webhooks/"+webhook+"/ping", token, nil).wantStatus(t, http.StatusAccepted)
+	if got := res.object(t, "delivery")["event"]; got != model.EventWebhookPing {
+		t.Errorf("got event %v, want %s", got, model.EventWebhookPing)
+	}
+
+	ts.request(t, http.MethodDelete, "/v1/webhooks/"+webhook, other, nil).wantStatus(t, http.StatusNotFound)
+	ts.request(t, http.MethodDelete, "/v1/webhooks/"+webhook, token, nil).wantStatus(t, http.StatusOK)
+	ts.request(t, http.MethodGet, "/v1/webhooks/"+webhook+"/deliveries", token, nil).wantStatus(t, http.StatusNotFound)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// input reads the lines typed by the user in the background, so that reading an answer can
+// time out.
+type input struct {
+	lines chan string
+}
+
+func newInput(r io.Reader) *input {
+	in := &input{lines: make(chan string)}
+
+	go func() {
+		defer close(in.lines)
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			in.lines <- strings.TrimSpace(scanner.Text())
+		}
+	}()
+
+	return in
+}
+
+// read returns the next line, ok is false at the end of the input.
+func (in *input) read() (line string, ok bool) {
+	line, ok = <-in.lines
+	return line, ok
+}
+
+// readTimeout returns the next line typed within limit, or timedOut. warn is called once with
+// the time left when warnAt is left. A limit of 0 waits for the line however long it takes.
+func (in *input) readTimeout(limit, warnAt time.Duration, warn func(left time.Duration)) (line string, ok, timedOut bool) {
+	if limit <= 0 {
+		line, ok = in.read()
+		return line, ok, false
+	}
+
+	timeout := time.NewTimer(limit)
+	defer timeout.Stop()
+
+	var warning <-chan time.Time
+	if warnAt > 0 && warnAt < limit {
+		t := time.NewTimer(limit - warnAt)
+		defer t.Stop()
+		warning = t.C
+	}
+
+	for {
+		select {
+		case line, ok = <-in.lines:
+			return line, ok, false
+		case <-warning:
+			warn(warnAt)
+		case <-timeout.C:
+			return "", true, true
+		}
+	}
+}
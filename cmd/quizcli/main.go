@@ -0,0 +1,90 @@
+// Command quizcli plays JustQuiz in the terminal against the API of any server: it logs in,
+// lists and searches quizes, plays them question by question with a time limit per question,
+// and shows the results and the leaderboard.
+//
+// Without arguments it reads commands until quit, see help. Arguments run a single command:
+//
+//	quizcli -url https://quiz.example.com -email dana@example.com
+//	quizcli -url http://localhost:8081 leaderboard
+//
+// The flags can be set by environment variables prefixed with QUIZCLI, e.g. QUIZCLI_URL and
+// QUIZCLI_PASSWORD.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/client"
+	"github.com/peterbourgon/ff/v3"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "quizcli: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("quizcli", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: quizcli [flags] [command [args]]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+
+	var (
+		baseURL   = fs.String("url", "http://localhost:8081", "Base URL of the JustQuiz server")
+		email     = fs.String("email", "", "Email address to log in with on start")
+		password  = fs.String("password", "", "Password to log in with, asked for if empty")
+		apiKey    = fs.String("api-key", "", "API key to authenticate with instead of logging in")
+		player    = fs.String("player", "", "Id or name of the player to play as")
+		timeLimit = fs.Duration("time-limit", 30*time.Second, "Time to answer each question, 0 for no limit")
+		pageSize  = fs.Int("page-size", 10, "Number of records per page of lists")
+	)
+
+	if err := ff.Parse(fs, args, ff.WithEnvVarPrefix("QUIZCLI")); err != nil {
+		return err
+	}
+
+	if *pageSize < 1 || *pageSize > 100 {
+		return errors.New("-page-size must be between 1 and 100")
+	}
+
+	s := newSession(client.New(strings.TrimSuffix(*baseURL, "/")), os.Stdin, os.Stdout)
+	s.timeLimit = *timeLimit
+	s.pageSize = *pageSize
+
+	ctx := context.Background()
+
+	switch {
+	case *apiKey != "":
+		s.client.SetAPIKey(*apiKey)
+	case *email != "":
+		if err := s.login(ctx, *email, *password); err != nil {
+			return err
+		}
+	}
+
+	if *player != "" {
+		if err := s.selectPlayer(ctx, *player); err != nil {
+			return err
+		}
+	}
+
+	if fs.NArg() > 0 {
+		return s.exec(ctx, fs.Args())
+	}
+
+	s.repl(ctx)
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	ts := httptest.NewServer(newFakeAPI().routes())
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"-url", ts.URL, "show", "1"}, ""},
+		{[]string{"-url", ts.URL + "/", "-player", "alice", "results"}, ""},
+		{[]string{"-url", ts.URL, "-player", "bob", "results"}, `no player is named "bob"`},
+		{[]string{"-url", ts.URL, "-email", testEmail, "-password", "wrong", "quizes"}, "invalid email address, password or code"},
+		{[]string{"-url", ts.URL, "-page-size", "0", "quizes"}, "-page-size must be between 1 and 100"},
+		{[]string{"-url", ts.URL, "show"}, `invalid quiz id ""`},
+		{[]string{"-time-limit", "soon"}, "invalid value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args[min(2, len(tt.args)-1):], " "), func(t *testing.T) {
+			err := run(tt.args)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatal(err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
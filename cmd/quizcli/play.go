@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// warnAt is the time left when the player is warned that the time of a question runs out.
+const warnAt = 10 * time.Second
+
+// answer is the answer to a question of a game.
+type answer struct {
+	text     string
+	took     time.Duration
+	timedOut bool
+	correct  bool
+}
+
+// play plays the quiz with the id as the player. The questions are asked one by one, each with
+// the time limit, then the answers are sent to the API, which only rewards the player if all of
+// them are correct, and the results are shown.
+func (s *session) play(ctx context.Context, id string) error {
+	if s.player == nil {
+		return errors.New("no player selected, select one with player <id|name>")
+	}
+
+	quiz, err := s.quiz(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(quiz.Questions) == 0 {
+		return errors.New("the quiz has no questions")
+	}
+
+	s.printf("Quiz %s: %s, %d questions, reward %d.\n", quiz.ID, quiz.Category, len(quiz.Questions), quiz.Reward)
+	if s.timeLimit > 0 {
+		s.printf("You have %s for each question. ", s.timeLimit)
+	}
+	if _, ok := s.ask("Press enter to start."); !ok {
+		return io.EOF
+	}
+
+	answers := make([]answer, len(quiz.Questions))
+	for i, question := range quiz.Questions {
+		s.printf("\nQuestion %d/%d: %s\n", i+1, len(quiz.Questions), question)
+
+		start := time.Now()
+		s.printf("> ")
+		text, ok, timedOut := s.in.readTimeout(s.timeLimit, warnAt, func(left time.Duration) {
+			s.printf("\n%s left\n> ", left)
+		})
+		if !ok {
+			return io.EOF
+		}
+
+		a := answer{text: text, took: time.Since(start), timedOut: timedOut}
+		if i < len(quiz.Answers) {
+			// The API compares the answers exactly.
+			a.correct = !timedOut && text == quiz.Answers[i]
+		}
+		answers[i] = a
+
+		// An answer typed too late would be taken as the answer to the next question, so the
+		// player confirms that the time is up first.
+		if timedOut {
+			if _, ok := s.ask("\ntime's up, press enter for the next question"); !ok {
+				return io.EOF
+			}
+		}
+	}
+
+	texts := make([]string, len(answers))
+	for i, a := range answers {
+		texts[i] = a.text
+	}
+
+	// The id of the answered game is the one of its quiz.
+	quizID, err := recordID("quiz", quiz.ID)
+	if err != nil {
+		return err
+	}
+	playerID, err := recordID("player", s.player.ID)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.client.AnswerGame(ctx, quizID, s.player.ID, texts)
+	if err != nil {
+		return err
+	}
+
+	s.printf("\n")
+	correct := 0
+	var total time.Duration
+	rows := make([][]string, len(answers))
+	for i, a := range answers {
+		mark := "x"
+		if a.correct {
+			mark = "ok"
+			correct++
+		}
+
+		given := a.text
+		if a.timedOut {
+			given = "(time's up)"
+		}
+
+		want := ""
+		if i < len(quiz.Answers) {
+			want = quiz.Answers[i]
+		}
+
+		total += a.took
+		rows[i] = []string{strconv.Itoa(i + 1), mark, given, want, a.took.Round(100 * time.Millisecond).String()}
+	}
+	s.table([]string{"#", "", "YOUR ANSWER", "ANSWER", "TIME"}, rows)
+
+	s.printf("\n%d of %d correct in %s. %s.\n", correct, len(answers), total.Round(time.Second), result)
+
+	player, err := s.client.GetPlayer(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	if gained := player.Score - s.player.Score; gained > 0 {
+		s.printf("%s won %d points, the score is now %d.\n", player.Name, gained, player.Score)
+	}
+	s.player = player
+
+	return nil
+}
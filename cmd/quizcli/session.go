@@ -0,0 +1,480 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/client"
+)
+
+const help = `Commands:
+  login [email]          log in, asking for the password
+  quizes [category]      list the quizes, of the category only if given
+  search <text>          search the categories and questions of the quizes
+  more                   show the next page of the last list
+  show <id>              show a quiz without its answers
+  play <id>              play a quiz as the player
+  player [id|name]       show or select the player to play as
+  player new <name>      create a player and select them
+  players [name]         list the players
+  results                list the quizes the player finished
+  leaderboard            show the leaderboard
+  help                   show this help
+  quit                   leave`
+
+// session is the state of a user playing in the terminal.
+type session struct {
+	client *client.Client
+	in     *input
+	out    io.Writer
+
+	timeLimit time.Duration
+	pageSize  int
+	// interactive is set while commands are read from the user, only then more can be typed.
+	interactive bool
+
+	// player is who the games are played as.
+	player *client.Player
+	// more shows the next page of the last list, it is nil after the last page.
+	more func(ctx context.Context) error
+}
+
+func newSession(c *client.Client, r io.Reader, out io.Writer) *session {
+	return &session{client: c, in: newInput(r), out: out}
+}
+
+func (s *session) printf(format string, args ...any) {
+	fmt.Fprintf(s.out, format, args...)
+}
+
+// table prints rows of cells under a header, aligned in columns.
+func (s *session) table(header []string, rows [][]string) {
+	w := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
+
+// ask prints a prompt and returns the line typed, ok is false at the end of the input.
+func (s *session) ask(prompt string) (string, bool) {
+	s.printf("%s", prompt)
+	return s.in.read()
+}
+
+// repl runs the commands typed by the user until quit or the end of the input. Failed commands
+// print their error and the next one is read.
+func (s *session) repl(ctx context.Context) {
+	s.printf("JustQuiz in the terminal, type help for the commands.\n")
+	s.interactive = true
+
+	for {
+		prompt := "> "
+		if s.player != nil {
+			prompt = s.player.Name + "> "
+		}
+
+		line, ok := s.ask(prompt)
+		if !ok {
+			s.printf("\n")
+			return
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "quit" || args[0] == "exit" {
+			return
+		}
+
+		err := s.exec(ctx, args)
+		switch {
+		case errors.Is(err, io.EOF):
+			s.printf("\n")
+			return
+		case err != nil:
+			s.printf("error: %s\n", describe(err))
+		}
+	}
+}
+
+// describe explains an error of a command to the user.
+func describe(err error) string {
+	var apiErr *client.Error
+	switch {
+	case client.IsUnauthorized(err):
+		return "not logged in or the login expired, log in with login"
+	case client.IsForbidden(err):
+		return "you aren't permitted to do that"
+	case errors.As(err, &apiErr):
+		return strings.TrimPrefix(apiErr.Error(), "justquiz: ")
+	default:
+		return err.Error()
+	}
+}
+
+// exec runs a command with its args.
+func (s *session) exec(ctx context.Context, args []string) error {
+	name, rest := args[0], strings.Join(args[1:], " ")
+
+	switch name {
+	case "help":
+		s.printf("%s\n", help)
+		return nil
+	case "login":
+		return s.login(ctx, rest, "")
+	case "quizes":
+		return s.listQuizes(ctx, rest)
+	case "search":
+		return s.search(ctx, rest)
+	case "more":
+		if s.more == nil {
+			return errors.New("there is no more to show")
+		}
+		return s.more(ctx)
+	case "show":
+		return s.showQuiz(ctx, rest)
+	case "play":
+		return s.play(ctx, rest)
+	case "player":
+		return s.playerCommand(ctx, args[1:])
+	case "players":
+		return s.listPlayers(ctx, rest)
+	case "results":
+		return s.results(ctx)
+	case "leaderboard":
+		return s.leaderboard(ctx)
+	default:
+		return fmt.Errorf("unknown command %q, type help for the commands", name)
+	}
+}
+
+// login logs in, asking for what isn't given and for the second factor of users with two-factor
+// authentication. The password is echoed, pass -password or QUIZCLI_PASSWORD to not type it.
+func (s *session) login(ctx context.Context, email, password string) error {
+	var ok bool
+	if email == "" {
+		if email, ok = s.ask("email: "); !ok {
+			return io.EOF
+		}
+	}
+	if password == "" {
+		if password, ok = s.ask("password: "); !ok {
+			return io.EOF
+		}
+	}
+
+	err := s.client.Login(ctx, email, password)
+	if errors.Is(err, client.ErrTwoFactorRequired) {
+		code, ok := s.ask("code of the authenticator app or a recovery code: ")
+		if !ok {
+			return io.EOF
+		}
+
+		if _, convErr := strconv.Atoi(code); convErr == nil && len(code) == 6 {
+			err = s.client.LoginTwoFactor(ctx, code)
+		} else {
+			err = s.client.LoginRecoveryCode(ctx, code)
+		}
+	}
+	if err != nil {
+		if client.IsUnauthorized(err) {
+			return errors.New("invalid email address, password or code")
+		}
+		return err
+	}
+
+	s.printf("logged in as %s\n", email)
+	return nil
+}
+
+// page shows the page of opts with show, which returns its metadata, and lets more show the
+// next one.
+func (s *session) page(ctx context.Context, opts client.ListOptions, show func(context.Context, client.ListOptions) (client.Metadata, error)) error {
+	metadata, err := show(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	s.more = nil
+	switch {
+	case metadata.NextCursor != "":
+		opts.After, opts.Page = metadata.NextCursor, 0
+	case metadata.CurrentPage < metadata.LastPage:
+		opts.Page = metadata.CurrentPage + 1
+	default:
+		return nil
+	}
+
+	s.more = func(ctx context.Context) error {
+		return s.page(ctx, opts, show)
+	}
+	if s.interactive {
+		s.printf("type more for the next page\n")
+	}
+
+	return nil
+}
+
+func quizRows(quizes []*client.Quiz) [][]string {
+	rows := make([][]string, len(quizes))
+	for i, q := range quizes {
+		rows[i] = []string{q.ID, q.Category, strconv.Itoa(q.Reward), strconv.Itoa(len(q.Questions))}
+	}
+
+	return rows
+}
+
+var quizHeader = []string{"ID", "CATEGORY", "REWARD", "QUESTIONS"}
+
+func (s *session) listQuizes(ctx context.Context, category string) error {
+	filter := client.QuizFilter{Category: category}
+
+	return s.page(ctx, client.ListOptions{PageSize: s.pageSize}, func(ctx context.Context, opts client.ListOptions) (client.Metadata, error) {
+		filter.ListOptions = opts
+
+		quizes, metadata, err := s.client.ListQuizes(ctx, filter)
+		if err != nil {
+			return client.Metadata{}, err
+		}
+		if len(quizes) == 0 {
+			s.printf("no quizes found\n")
+			return metadata, nil
+		}
+
+		s.table(quizHeader, quizRows(quizes))
+		return metadata, nil
+	})
+}
+
+// search lists the quizes whose category or questions contain the text. The API only filters by
+// the whole category, so the quizes are searched here.
+func (s *session) search(ctx context.Context, text string) error {
+	if text == "" {
+		return errors.New("usage: search <text>")
+	}
+	text = strings.ToLower(text)
+
+	var found []*client.Quiz
+
+	it := s.client.Quizes(client.QuizFilter{ListOptions: client.ListOptions{PageSize: 100}})
+	for it.Next(ctx) {
+		q := it.Value()
+
+		match := strings.Contains(strings.ToLower(q.Category), text)
+		for _, question := range q.Questions {
+			match = match || strings.Contains(strings.ToLower(question), text)
+		}
+		if match {
+			found = append(found, q)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	if len(found) == 0 {
+		s.printf("no quizes found\n")
+		return nil
+	}
+
+	s.more = nil
+	s.table(quizHeader, quizRows(found))
+	return nil
+}
+
+// quiz returns the quiz with the id s.
+func (s *session) quiz(ctx context.Context, id string) (*client.Quiz, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid quiz id %q", id)
+	}
+
+	quiz, err := s.client.GetQuiz(ctx, n)
+	if client.IsNotFound(err) {
+		return nil, fmt.Errorf("quiz %d not found", n)
+	}
+
+	return quiz, err
+}
+
+// showQuiz shows a quiz with its questions, but not their answers.
+func (s *session) showQuiz(ctx context.Context, id string) error {
+	quiz, err := s.quiz(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.printf("Quiz %s: %s, %d questions, reward %d\n", quiz.ID, quiz.Category, len(quiz.Questions), quiz.Reward)
+	for i, question := range quiz.Questions {
+		s.printf("  %d. %s\n", i+1, question)
+	}
+
+	return nil
+}
+
+func (s *session) playerCommand(ctx context.Context, args []string) error {
+	switch {
+	case len(args) == 0:
+		if s.player == nil {
+			return errors.New("no player selected, select one with player <id|name>")
+		}
+
+		id, err := recordID("player", s.player.ID)
+		if err != nil {
+			return err
+		}
+
+		player, err := s.client.GetPlayer(ctx, id)
+		if err != nil {
+			return err
+		}
+		s.player = player
+
+		s.table([]string{"ID", "NAME", "SCORE", "JOINED"}, [][]string{
+			{player.ID, player.Name, strconv.Itoa(player.Score), player.Joined.Format(time.DateOnly)},
+		})
+		return nil
+	case args[0] == "new":
+		name := strings.Join(args[1:], " ")
+		if name == "" {
+			return errors.New("usage: player new <name>")
+		}
+
+		player, err := s.client.CreatePlayer(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		s.player = player
+		s.printf("created player %s, %s\n", player.ID, player.Name)
+		return nil
+	default:
+		return s.selectPlayer(ctx, strings.Join(args, " "))
+	}
+}
+
+// selectPlayer selects the player with the id, or with the name, to play as.
+func (s *session) selectPlayer(ctx context.Context, player string) error {
+	if n, err := strconv.Atoi(player); err == nil {
+		p, err := s.client.GetPlayer(ctx, n)
+		if err != nil {
+			if client.IsNotFound(err) {
+				return fmt.Errorf("player %d not found", n)
+			}
+			return err
+		}
+
+		s.player = p
+	} else {
+		players, _, err := s.client.ListPlayers(ctx, client.PlayerFilter{Name: player, ListOptions: client.ListOptions{PageSize: 1}})
+		if err != nil {
+			return err
+		}
+		if len(players) == 0 {
+			return fmt.Errorf("no player is named %q, create one with player new %s", player, player)
+		}
+
+		s.player = players[0]
+	}
+
+	s.printf("playing as %s (%s), score %d\n", s.player.Name, s.player.ID, s.player.Score)
+	return nil
+}
+
+// recordID converts the id of a record of the API, which is a number. An id which isn't is an
+// error rather than a request for the record 0.
+func recordID(kind, id string) (int, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s id %q from the API", kind, id)
+	}
+
+	return n, nil
+}
+
+func (s *session) listPlayers(ctx context.Context, name string) error {
+	filter := client.PlayerFilter{Name: name}
+
+	return s.page(ctx, client.ListOptions{PageSize: s.pageSize}, func(ctx context.Context, opts client.ListOptions) (client.Metadata, error) {
+		filter.ListOptions = opts
+
+		players, metadata, err := s.client.ListPlayers(ctx, filter)
+		if err != nil {
+			return client.Metadata{}, err
+		}
+		if len(players) == 0 {
+			s.printf("no players found\n")
+			return metadata, nil
+		}
+
+		rows := make([][]string, len(players))
+		for i, p := range players {
+			rows[i] = []string{p.ID, p.Name, strconv.Itoa(p.Score), p.Joined.Format(time.DateOnly)}
+		}
+		s.table([]string{"ID", "NAME", "SCORE", "JOINED"}, rows)
+		return metadata, nil
+	})
+}
+
+// results lists the quizes the player finished, the latest first.
+func (s *session) results(ctx context.Context) error {
+	if s.player == nil {
+		return errors.New("no player selected, select one with player <id|name>")
+	}
+
+	id, err := recordID("player", s.player.ID)
+	if err != nil {
+		return err
+	}
+	var filter client.GameFilter
+
+	return s.page(ctx, client.ListOptions{PageSize: s.pageSize, Sort: "-finished"}, func(ctx context.Context, opts client.ListOptions) (client.Metadata, error) {
+		filter.ListOptions = opts
+
+		quizes, metadata, err := s.client.ListPlayerQuizes(ctx, id, filter)
+		if err != nil {
+			return client.Metadata{}, err
+		}
+		if len(quizes) == 0 {
+			s.printf("%s hasn't finished a quiz yet\n", s.player.Name)
+			return metadata, nil
+		}
+
+		s.table(quizHeader, quizRows(quizes))
+		return metadata, nil
+	})
+}
+
+func (s *session) leaderboard(ctx context.Context) error {
+	return s.page(ctx, client.ListOptions{PageSize: s.pageSize}, func(ctx context.Context, opts client.ListOptions) (client.Metadata, error) {
+		entries, metadata, err := s.client.Leaderboard(ctx, opts)
+		if err != nil {
+			return client.Metadata{}, err
+		}
+		if len(entries) == 0 {
+			s.printf("the leaderboard is empty\n")
+			return metadata, nil
+		}
+
+		rows := make([][]string, len(entries))
+		for i, e := range entries {
+			rank := strconv.Itoa(e.Rank)
+			if s.player != nil && strconv.FormatInt(e.PlayerID, 10) == s.player.ID {
+				rank = "*" + rank
+			}
+			rows[i] = []string{rank, e.Name, strconv.Itoa(e.Score), strconv.Itoa(e.Games)}
+		}
+		s.table([]string{"RANK", "PLAYER", "SCORE", "GAMES"}, rows)
+		s.printf("as of %s\n", entries[0].RefreshedAt.Local().Format(time.DateTime))
+		return metadata, nil
+	})
+}
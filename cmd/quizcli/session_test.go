@@ -0,0 +1,493 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/margulan-kalykul/JustQuiz/pkg/client"
+)
+
+const (
+	testEmail    = "dana@example.com"
+	testPassword = "pa55word123"
+	testToken    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+// fakeAPI serves the routes of the API quizcli uses, with records kept in memory. Creating players
+// and answering quizes need the token of a login as testEmail.
+type fakeAPI struct {
+	mu       sync.Mutex
+	quizes   []*client.Quiz
+	players  []*client.Player
+	finished map[string][]*client.Quiz
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{
+		quizes: []*client.Quiz{
+			{ID: "1", Category: "geography", Reward: 10, Questions: []string{"Capital of Kazakhstan?", "Longest river?"}, Answers: []string{"Astana", "Nile"}},
+			{ID: "2", Category: "history", Reward: 5, Questions: []string{"Year of the moon landing?"}, Answers: []string{"1969"}},
+		},
+		players: []*client.Player{
+			{ID: "1", Name: "alice", Score: 20, Joined: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		},
+		finished: make(map[string][]*client.Quiz),
+	}
+}
+
+// newTestSession returns a session with the fake API, reading the lines of input, and its output.
+func newTestSession(t *testing.T, api *fakeAPI, input string) (*session, *bytes.Buffer) {
+	t.Helper()
+
+	ts := httptest.NewServer(api.routes())
+	t.Cleanup(ts.Close)
+
+	var out bytes.Buffer
+	s := newSession(client.New(ts.URL), strings.NewReader(input), &out)
+	s.pageSize = 10
+
+	return s, &out
+}
+
+func (api *fakeAPI) routes() http.Handler {
+	r := mux.NewRouter()
+
+	r.HandleFunc("/v1/users/login", api.login).Methods("POST")
+	r.HandleFunc("/v1/quizes", api.listQuizes).Methods("GET")
+	r.HandleFunc("/v1/quizes/{id}", api.getQuiz).Methods("GET")
+	r.HandleFunc("/v1/players", api.listPlayers).Methods("GET")
+	r.HandleFunc("/v1/players", api.authenticated(api.createPlayer)).Methods("POST")
+	r.HandleFunc("/v1/players/{id}", api.getPlayer).Methods("GET")
+	r.HandleFunc("/v1/players/{id}/quizes", api.listPlayerQuizes).Methods("GET")
+	r.HandleFunc("/v1/games/{id}", api.authenticated(api.answerGame)).Methods("POST")
+	r.HandleFunc("/v1/leaderboard", api.leaderboard).Methods("GET")
+
+	return r
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message any) {
+	writeJSON(w, status, map[string]any{"error": message})
+}
+
+func (api *fakeAPI) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			writeError(w, http.StatusUnauthorized, "invalid or missing authentication token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// page returns the page of n records the query asks for and its metadata.
+func page(r *http.Request, n int) (from, to int, metadata client.Metadata) {
+	number, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	size, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if number < 1 {
+		number = 1
+	}
+	if size < 1 {
+		size = 20
+	}
+
+	from, to = min((number-1)*size, n), min(number*size, n)
+	metadata = client.Metadata{CurrentPage: number, PageSize: size, FirstPage: 1, LastPage: (n + size - 1) / size, TotalRecords: n}
+
+	return from, to, metadata
+}
+
+func (api *fakeAPI) login(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	if input.Email != testEmail || input.Password != testPassword {
+		writeError(w, http.StatusUnauthorized, "invalid authentication credentials")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"authentication_token": client.Token{Plaintext: testToken, Expiry: time.Now().Add(time.Hour)}})
+}
+
+func (api *fakeAPI) listQuizes(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	var quizes []*client.Quiz
+	for _, q := range api.quizes {
+		if category := r.URL.Query().Get("category"); category == "" || q.Category == category {
+			quizes = append(quizes, q)
+		}
+	}
+
+	from, to, metadata := page(r, len(quizes))
+	writeJSON(w, http.StatusOK, map[string]any{"quizes": quizes[from:to], "metadata": metadata})
+}
+
+func (api *fakeAPI) quiz(id string) *client.Quiz {
+	for _, q := range api.quizes {
+		if q.ID == id {
+			return q
+		}
+	}
+
+	return nil
+}
+
+func (api *fakeAPI) getQuiz(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	quiz := api.quiz(mux.Vars(r)["id"])
+	if quiz == nil {
+		writeError(w, http.StatusNotFound, "the requested resource could not be found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"quiz": quiz})
+}
+
+func (api *fakeAPI) listPlayers(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	var players []*client.Player
+	for _, p := range api.players {
+		if name := r.URL.Query().Get("name"); name == "" || p.Name == name {
+			players = append(players, p)
+		}
+	}
+
+	from, to, metadata := page(r, len(players))
+	writeJSON(w, http.StatusOK, map[string]any{"players": players[from:to], "metadata": metadata})
+}
+
+func (api *fakeAPI) createPlayer(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	var input struct {
+		Name string `json:"name"`
+	}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	player := &client.Player{ID: strconv.Itoa(len(api.players) + 1), Name: input.Name, Joined: time.Now()}
+	api.players = append(api.players, player)
+
+	writeJSON(w, http.StatusCreated, map[string]any{"player": player})
+}
+
+func (api *fakeAPI) player(id string) *client.Player {
+	for _, p := range api.players {
+		if p.ID == id {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func (api *fakeAPI) getPlayer(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	player := api.player(mux.Vars(r)["id"])
+	if player == nil {
+		writeError(w, http.StatusNotFound, "the requested resource could not be found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"player": player})
+}
+
+func (api *fakeAPI) listPlayerQuizes(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	quizes := api.finished[mux.Vars(r)["id"]]
+	from, to, metadata := page(r, len(quizes))
+	writeJSON(w, http.StatusOK, map[string]any{"quizes": quizes[from:to], "metadata": metadata})
+}
+
+func (api *fakeAPI) answerGame(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	quiz := api.quiz(mux.Vars(r)["id"])
+	if quiz == nil {
+		writeError(w, http.StatusNotFound, "the requested resource could not be found")
+		return
+	}
+
+	var input struct {
+		Player  string   `json:"playerId"`
+		Answers []string `json:"answers"`
+	}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	if !reflect.DeepEqual(input.Answers, quiz.Answers) {
+		writeJSON(w, http.StatusOK, map[string]any{"result": "Answers are incorrect"})
+		return
+	}
+
+	player := api.player(input.Player)
+	if player == nil {
+		writeError(w, http.StatusNotFound, "the requested resource could not be found")
+		return
+	}
+	player.Score += quiz.Reward
+	api.finished[player.ID] = append(api.finished[player.ID], quiz)
+
+	writeJSON(w, http.StatusOK, map[string]any{"result": "Answers are correct"})
+}
+
+func (api *fakeAPI) leaderboard(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	players := append([]*client.Player(nil), api.players...)
+	sort.SliceStable(players, func(i, j int) bool { return players[i].Score > players[j].Score })
+
+	entries := make([]client.LeaderboardEntry, len(players))
+	for i, p := range players {
+		id, _ := strconv.ParseInt(p.ID, 10, 64)
+		entries[i] = client.LeaderboardEntry{Rank: i + 1, PlayerID: id, Name: p.Name, Score: p.Score, Games: len(api.finished[p.ID]), RefreshedAt: time.Now()}
+	}
+
+	from, to, metadata := page(r, len(entries))
+	writeJSON(w, http.StatusOK, map[string]any{"leaderboard": entries[from:to], "metadata": metadata})
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    []string
+		wantErr string
+	}{
+		{args: "help", want: []string{"Commands:", "player new <name>"}},
+		{args: "quizes", want: []string{"ID  CATEGORY   REWARD  QUESTIONS", "1   geography  10      2", "2   history    5       1"}},
+		{args: "quizes history", want: []string{"2   history   5       1"}},
+		{args: "quizes art", want: []string{"no quizes found"}},
+		{args: "search RIVER", want: []string{"1   geography  10      2"}},
+		{args: "search", wantErr: "usage: search <text>"},
+		{args: "show 2", want: []string{"Quiz 2: history, 1 questions, reward 5", "1. Year of the moon landing?"}},
+		{args: "show two", wantErr: `invalid quiz id "two"`},
+		{args: "show 99", wantErr: "quiz 99 not found"},
+		{args: "players", want: []string{"1   alice  20     2024-01-02"}},
+		{args: "player alice", want: []string{"playing as alice (1), score 20"}},
+		{args: "player 99", wantErr: "player 99 not found"},
+		{args: "player bob", wantErr: `no player is named "bob"`},
+		{args: "player", wantErr: "no player selected"},
+		{args: "results", wantErr: "no player selected"},
+		{args: "leaderboard", want: []string{"RANK  PLAYER  SCORE  GAMES", "1     alice   20     0"}},
+		{args: "more", wantErr: "there is no more to show"},
+		{args: "player new bob", wantErr: "justquiz: 401"},
+		{args: "dance", wantErr: `unknown command "dance"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			s, out := newTestSession(t, newFakeAPI(), "")
+
+			err := s.exec(context.Background(), strings.Fields(tt.args))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("got output\n%s\nwant %q", out, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+
+	s, out := newTestSession(t, newFakeAPI(), "wrong password\n"+testPassword+"\n")
+
+	if err := s.login(ctx, testEmail, ""); err == nil || err.Error() != "invalid email address, password or code" {
+		t.Fatalf("got error %v, want the login refused", err)
+	}
+	if err := s.exec(ctx, []string{"login", testEmail}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "logged in as "+testEmail) {
+		t.Errorf("got output\n%s\nwant the login", out)
+	}
+
+	if err := s.exec(ctx, []string{"player", "new", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if s.player == nil || s.player.Name != "bob" {
+		t.Errorf("got player %+v, want bob selected", s.player)
+	}
+
+	// The input ends before the password.
+	if err := s.login(ctx, testEmail, ""); err == nil || err.Error() != "EOF" {
+		t.Errorf("got error %v, want EOF", err)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeAPI()
+
+	s, out := newTestSession(t, api, "\nAstana\nNile\n\nAstana\nAmazon\n")
+	if err := s.login(ctx, testEmail, testPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.exec(ctx, []string{"play", "1"}); err == nil || !strings.Contains(err.Error(), "no player selected") {
+		t.Fatalf("got error %v, want a player to be selected first", err)
+	}
+
+	if err := s.selectPlayer(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.exec(ctx, []string{"play", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Question 2/2: Longest river?", "2 of 2 correct", "Answers are correct", "alice won 10 points, the score is now 30."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got output\n%s\nwant %q", out, want)
+		}
+	}
+
+	out.Reset()
+	if err := s.exec(ctx, []string{"play", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "1 of 2 correct") || strings.Contains(out.String(), "won") {
+		t.Errorf("got output\n%s\nwant 1 correct answer and no points", out)
+	}
+
+	out.Reset()
+	if err := s.exec(ctx, []string{"results"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "1   geography  10      2") {
+		t.Errorf("got output\n%s\nwant the finished quiz", out)
+	}
+}
+
+func TestPlayTimeLimit(t *testing.T) {
+	ctx := context.Background()
+
+	// The answer to the first question comes too late, after the time is up.
+	r, w := io.Pipe()
+	s, out := newTestSession(t, newFakeAPI(), "")
+	s.in = newInput(r)
+	s.timeLimit = 50 * time.Millisecond
+
+	if err := s.selectPlayer(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	s.client.SetToken(testToken)
+
+	go func() {
+		io.WriteString(w, "\n")
+		time.Sleep(200 * time.Millisecond)
+		// Enter for the next question, then the answer to it.
+		io.WriteString(w, "\nNile\n")
+		w.Close()
+	}()
+
+	if err := s.exec(ctx, []string{"play", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"time's up, press enter", "(time's up)", "1 of 2 correct", "Answers are incorrect"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got output\n%s\nwant %q", out, want)
+		}
+	}
+}
+
+func TestMore(t *testing.T) {
+	ctx := context.Background()
+
+	s, out := newTestSession(t, newFakeAPI(), "")
+	s.pageSize = 1
+	s.interactive = true
+
+	if err := s.exec(ctx, []string{"quizes"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "geography") || !strings.Contains(out.String(), "type more for the next page") {
+		t.Errorf("got output\n%s\nwant the first quiz and a hint for more", out)
+	}
+
+	out.Reset()
+	if err := s.exec(ctx, []string{"more"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "history") || strings.Contains(out.String(), "type more") {
+		t.Errorf("got output\n%s\nwant the last quiz", out)
+	}
+
+	if err := s.exec(ctx, []string{"more"}); err == nil {
+		t.Error("got more after the last page, want an error")
+	}
+}
+
+func TestInvalidRecordID(t *testing.T) {
+	api := newFakeAPI()
+	api.players[0].ID = "a1"
+
+	s, _ := newTestSession(t, api, "")
+	if err := s.selectPlayer(context.Background(), "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"player"}, {"results"}} {
+		if err := s.exec(context.Background(), args); err == nil || !strings.Contains(err.Error(), `invalid player id "a1"`) {
+			t.Errorf("%s: got error %v, want the invalid id", args[0], err)
+		}
+	}
+}
+
+func TestRepl(t *testing.T) {
+	s, out := newTestSession(t, newFakeAPI(), "show 2\n\nshow 99\nplayer new bob\nquit\nhelp\n")
+	s.repl(context.Background())
+
+	for _, want := range []string{
+		"Quiz 2: history",
+		"error: quiz 99 not found",
+		"error: not logged in or the login expired, log in with login",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got output\n%s\nwant %q", out, want)
+		}
+	}
+	if strings.Contains(out.String(), "Commands:") {
+		t.Errorf("got output\n%s\nwant nothing after quit", out)
+	}
+}
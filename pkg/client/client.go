@@ -0,0 +1,167 @@
+// Package client is a typed client of the JustQuiz API.
+//
+//	c := client.New("http://localhost:8081")
+//	if err := c.Login(ctx, "admin@example.com", "pa55word"); err != nil {
+//		...
+//	}
+//	players := c.Players(client.PlayerFilter{ListOptions: client.ListOptions{Sort: "-score"}})
+//	for players.Next(ctx) {
+//		fmt.Println(players.Value().Name)
+//	}
+//	if err := players.Err(); err != nil {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenRenewal is how long before its expiry an authentication token is renewed by logging in
+// again.
+const tokenRenewal = time.Minute
+
+// Client calls the JustQuiz API at BaseURL. It authenticates with the token of the last login,
+// or with an API key set by SetAPIKey. It is safe for concurrent use.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+
+	mu        sync.Mutex
+	token     Token
+	apiKey    string
+	email     string
+	password  string
+	challenge string
+}
+
+// New returns a Client of the API at baseURL, like http://localhost:8081.
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetToken authenticates the requests with an authentication token from a login made elsewhere.
+// It isn't renewed.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = Token{Plaintext: token}
+	c.email, c.password = "", ""
+}
+
+// SetAPIKey authenticates the requests with an API key instead of a token.
+func (c *Client) SetAPIKey(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.apiKey = key
+}
+
+// Token returns the authentication token of the client, empty if it has none.
+func (c *Client) Token() Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token
+}
+
+// get sends a GET request with the query and decodes the response into dst.
+func (c *Client) get(ctx context.Context, path string, query url.Values, dst any) error {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	return c.do(ctx, http.MethodGet, path, nil, dst)
+}
+
+// do sends a request with body encoded as JSON, if it isn't nil, and decodes the JSON response
+// into dst, if it isn't nil. Responses with an error status are returned as *Error.
+func (c *Client) do(ctx context.Context, method, path string, body, dst any) error {
+	_, err := c.send(ctx, method, path, true, body, dst)
+	return err
+}
+
+// send is do returning the status of the response. Requests are only authenticated if
+// authenticate is set, logins aren't.
+func (c *Client) send(ctx context.Context, method, path string, authenticate bool, body, dst any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(js)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authenticate {
+		if err := c.authorize(ctx, req); err != nil {
+			return 0, err
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.StatusCode, decodeError(resp)
+	}
+
+	if dst != nil {
+		if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+			return resp.StatusCode, fmt.Errorf("decoding response of %s %s: %w", method, path, err)
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// authorize adds the credentials of the client to req, logging in again first if the token of a
+// login is about to expire.
+func (c *Client) authorize(ctx context.Context, req *http.Request) error {
+	c.mu.Lock()
+	apiKey, token := c.apiKey, c.token
+	email, password := c.email, c.password
+	c.mu.Unlock()
+
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+		return nil
+	}
+
+	if email != "" && time.Until(token.Expiry) < tokenRenewal {
+		if err := c.Login(ctx, email, password); err != nil {
+			return fmt.Errorf("renewing the authentication token: %w", err)
+		}
+		token = c.Token()
+	}
+
+	if token.Plaintext != "" {
+		req.Header.Set("Authorization", "Bearer "+token.Plaintext)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrTwoFactorRequired is returned by Login for users with two-factor authentication. The login
+// is completed by LoginTwoFactor.
+var ErrTwoFactorRequired = errors.New("client: two-factor authentication required")
+
+// Error is an error response of the API. Failed validations have a message for each invalid
+// field in Fields, all other errors a single Message.
+type Error struct {
+	StatusCode int
+	Message    string
+	Fields     map[string]string
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("justquiz: %d %s", e.StatusCode, e.Message)
+	}
+
+	fields := make([]string, 0, len(e.Fields))
+	for field, message := range e.Fields {
+		fields = append(fields, field+": "+message)
+	}
+	sort.Strings(fields)
+
+	return fmt.Sprintf("justquiz: %d %s", e.StatusCode, strings.Join(fields, ", "))
+}
+
+// IsNotFound reports whether err is an error response for a record which doesn't exist.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsValidation reports whether err is an error response for invalid values, see Error.Fields.
+func IsValidation(err error) bool {
+	return hasStatus(err, http.StatusUnprocessableEntity)
+}
+
+// IsUnauthorized reports whether err is an error response for invalid or missing credentials.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is an error response for a request the user isn't permitted
+// to make.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+func hasStatus(err error, status int) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == status
+}
+
+// decodeError decodes the error envelope of resp. The error is a message, or an object of
+// messages by field for failed validations.
+func decodeError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	var body struct {
+		Error     json.RawMessage `json:"error"`
+		RequestID string          `json:"request_id"`
+	}
+
+	js, err := io.ReadAll(resp.Body)
+	if err != nil || json.Unmarshal(js, &body) != nil {
+		return e
+	}
+	e.RequestID = body.RequestID
+
+	if err := json.Unmarshal(body.Error, &e.Fields); err == nil {
+		e.Message = "invalid values"
+		return e
+	}
+	e.Fields = nil
+
+	var message string
+	if err := json.Unmarshal(body.Error, &message); err == nil {
+		e.Message = message
+	}
+
+	return e
+}
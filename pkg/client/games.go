@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GameFilter selects the games of a list.
+type GameFilter struct {
+	Player   int
+	Quiz     int
+	Finished TimeRange
+	ListOptions
+}
+
+func (f GameFilter) values() url.Values {
+	qs := url.Values{}
+	setInt(qs, "player", f.Player)
+	setInt(qs, "quiz", f.Quiz)
+	setTimeRange(qs, "finished", f.Finished)
+	f.ListOptions.values(qs)
+	return qs
+}
+
+// ListGames returns a page of the games.
+func (c *Client) ListGames(ctx context.Context, filter GameFilter) ([]*Game, Metadata, error) {
+	var resp struct {
+		Games    []*Game  `json:"games"`
+		Metadata Metadata `json:"metadata"`
+	}
+	err := c.get(ctx, "/v1/games", filter.values(), &resp)
+	return resp.Games, resp.Metadata, err
+}
+
+// Games returns an iterator over all the games of the filter, from its page on.
+func (c *Client) Games(filter GameFilter) *Iterator[*Game] {
+	return newIterator(filter.ListOptions, func(ctx context.Context, opts ListOptions) ([]*Game, Metadata, error) {
+		filter.ListOptions = opts
+		return c.ListGames(ctx, filter)
+	})
+}
+
+// GetGame returns the game with the id, with only fields if any are given.
+func (c *Client) GetGame(ctx context.Context, id int, fields ...string) (*Game, error) {
+	var resp struct {
+		Game *Game `json:"game"`
+	}
+	qs := url.Values{}
+	ListOptions{Fields: fields}.values(qs)
+	err := c.get(ctx, fmt.Sprintf("/v1/games/%d", id), qs, &resp)
+	return resp.Game, err
+}
+
+// CreateGame starts a game of the player with the quiz. It needs the game:play permission.
+func (c *Client) CreateGame(ctx context.Context, player, quiz int) (*Game, error) {
+	var resp struct {
+		Game *Game `json:"game"`
+	}
+	body := map[string]int{"player": player, "quiz": quiz}
+	err := c.do(ctx, http.MethodPost, "/v1/games", body, &resp)
+	return resp.Game, err
+}
+
+// AnswerGame answers the questions of the quiz with the id, as the player with the id player. The
+// API compares the answers exactly and only if all of them are correct it adds the reward of the
+// quiz to the score of the player and records the game. It returns the result the API tells. It
+// needs the game:play permission.
+func (c *Client) AnswerGame(ctx context.Context, id int, player string, answers []string) (string, error) {
+	var resp struct {
+		Result string `json:"result"`
+	}
+	body := map[string]any{"playerId": player, "answers": answers}
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/games/%d", id), body, &resp)
+	return resp.Result, err
+}
+
+// DeleteGame deletes the game with the id. It needs the game:delete permission.
+func (c *Client) DeleteGame(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/v1/games/%d", id), nil, nil)
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListOptions select a page of a list. Zero values leave the defaults of the API.
+type ListOptions struct {
+	Page     int
+	PageSize int
+	// Sort is a column, prefixed with - for descending order.
+	Sort string
+	// After and Before are the NextCursor and PrevCursor of the metadata of a page.
+	After  string
+	Before string
+	// SkipCount skips counting the records, the metadata then only has the cursors.
+	SkipCount bool
+	// Fields are the only fields the records are returned with, all of them if it's empty.
+	Fields []string
+}
+
+// values adds the query string parameters of o to qs.
+func (o ListOptions) values(qs url.Values) {
+	setInt(qs, "page", o.Page)
+	setInt(qs, "page_size", o.PageSize)
+	setString(qs, "sort", o.Sort)
+	setString(qs, "after", o.After)
+	setString(qs, "before", o.Before)
+	if o.SkipCount {
+		qs.Set("count", "false")
+	}
+	setString(qs, "fields", strings.Join(o.Fields, ","))
+}
+
+func setString(qs url.Values, key, value string) {
+	if value != "" {
+		qs.Set(key, value)
+	}
+}
+
+func setInt(qs url.Values, key string, value int) {
+	if value != 0 {
+		qs.Set(key, strconv.Itoa(value))
+	}
+}
+
+// setTimeRange adds the parameters of a range of times of field to qs.
+func setTimeRange(qs url.Values, field string, r TimeRange) {
+	if !r.From.IsZero() {
+		qs.Set(field+"From", r.From.Format(time.RFC3339Nano))
+	}
+	if !r.To.IsZero() {
+		qs.Set(field+"To", r.To.Format(time.RFC3339Nano))
+	}
+}
+
+// Iterator walks through all the records of a list, page by page. Pages follow the cursors of
+// the metadata, or the page numbers of lists without cursors.
+type Iterator[T any] struct {
+	list    func(ctx context.Context, opts ListOptions) ([]T, Metadata, error)
+	opts    ListOptions
+	records []T
+	current T
+	done    bool
+	err     error
+}
+
+// newIterator returns an Iterator over the pages list returns, starting with the one of opts.
+func newIterator[T any](opts ListOptions, list func(context.Context, ListOptions) ([]T, Metadata, error)) *Iterator[T] {
+	return &Iterator[T]{list: list, opts: opts}
+}
+
+// Next advances to the next record, fetching the next page when needed. It returns false when
+// there are no more records or a request failed, see Err.
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	for len(it.records) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch(ctx)
+	}
+
+	it.current, it.records = it.records[0], it.records[1:]
+	return true
+}
+
+// fetch fetches the page of the options and moves them to the page after it.
+func (it *Iterator[T]) fetch(ctx context.Context) {
+	records, metadata, err := it.list(ctx, it.opts)
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.records = records
+
+	switch {
+	case metadata.NextCursor != "":
+		it.opts.After, it.opts.Before, it.opts.Page = metadata.NextCursor, "", 0
+	case it.opts.After == "" && it.opts.Before == "" && metadata.CurrentPage < metadata.LastPage:
+		it.opts.Page = metadata.CurrentPage + 1
+	default:
+		it.done = true
+	}
+}
+
+// Value returns the current record.
+func (it *Iterator[T]) Value() T {
+	return it.current
+}
+
+// Err returns the error of the request which stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All returns all the remaining records.
+func (it *Iterator[T]) All(ctx context.Context) ([]T, error) {
+	var all []T
+	for it.Next(ctx) {
+		all = append(all, it.Value())
+	}
+
+	return all, it.Err()
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// PlayerFilter selects the players of a list.
+type PlayerFilter struct {
+	Name      string
+	ScoreFrom int
+	ScoreTo   int
+	Joined    TimeRange
+	ListOptions
+}
+
+func (f PlayerFilter) values() url.Values {
+	qs := url.Values{}
+	setString(qs, "name", f.Name)
+	setInt(qs, "scoreFrom", f.ScoreFrom)
+	setInt(qs, "scoreTo", f.ScoreTo)
+	setTimeRange(qs, "joined", f.Joined)
+	f.ListOptions.values(qs)
+	return qs
+}
+
+// PlayerUpdate holds the fields of a player to change, nil fields are left as they are.
+type PlayerUpdate struct {
+	Name  *string `json:"name,omitempty"`
+	Score *int    `json:"score,omitempty"`
+}
+
+// ListPlayers returns a page of the players.
+func (c *Client) ListPlayers(ctx context.Context, filter PlayerFilter) ([]*Player, Metadata, error) {
+	var resp struct {
+		Players  []*Player `json:"players"`
+		Metadata Metadata  `json:"metadata"`
+	}
+	err := c.get(ctx, "/v1/players", filter.values(), &resp)
+	return resp.Players, resp.Metadata, err
+}
+
+// Players returns an iterator over all the players of the filter, from its page on.
+func (c *Client) Players(filter PlayerFilter) *Iterator[*Player] {
+	return newIterator(filter.ListOptions, func(ctx context.Context, opts ListOptions) ([]*Player, Metadata, error) {
+		filter.ListOptions = opts
+		return c.ListPlayers(ctx, filter)
+	})
+}
+
+// GetPlayer returns the player with the id, with only fields if any are given.
+func (c *Client) GetPlayer(ctx context.Context, id int, fields ...string) (*Player, error) {
+	var resp struct {
+		Player *Player `json:"player"`
+	}
+	qs := url.Values{}
+	ListOptions{Fields: fields}.values(qs)
+	err := c.get(ctx, fmt.Sprintf("/v1/players/%d", id), qs, &resp)
+	return resp.Player, err
+}
+
+// CreatePlayer creates a player. It needs the player:create permission.
+func (c *Client) CreatePlayer(ctx context.Context, name string) (*Player, error) {
+	var resp struct {
+		Player *Player `json:"player"`
+	}
+	body := map[string]string{"name": name}
+	err := c.do(ctx, http.MethodPost, "/v1/players", body, &resp)
+	return resp.Player, err
+}
+
+// UpdatePlayer changes the fields of update of the player with the id. It needs the
+// player:write permission.
+func (c *Client) UpdatePlayer(ctx context.Context, id int, update PlayerUpdate) (*Player, error) {
+	var resp struct {
+		Player *Player `json:"player"`
+	}
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/v1/players/%d", id), update, &resp)
+	return resp.Player, err
+}
+
+// DeletePlayer deletes the player with the id. It needs the player:delete permission.
+func (c *Client) DeletePlayer(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/v1/players/%d", id), nil, nil)
+}
+
+// ListPlayerQuizes returns a page of the quizes the player with the id finished, one for each
+// of the games of the filter. The Player of the filter is ignored.
+func (c *Client) ListPlayerQuizes(ctx context.Context, id int, filter GameFilter) ([]*Quiz, Metadata, error) {
+	var resp struct {
+		Quizes   []*Quiz  `json:"quizes"`
+		Metadata Metadata `json:"metadata"`
+	}
+	filter.Player = 0
+	err := c.get(ctx, fmt.Sprintf("/v1/players/%d/quizes", id), filter.values(), &resp)
+	return resp.Quizes, resp.Metadata, err
+}
+
+// Leaderboard returns a page of the players ranked by their score.
+func (c *Client) Leaderboard(ctx context.Context, opts ListOptions) ([]*LeaderboardEntry, Metadata, error) {
+	var resp struct {
+		Leaderboard []*LeaderboardEntry `json:"leaderboard"`
+		Metadata    Metadata            `json:"metadata"`
+	}
+	qs := url.Values{}
+	opts.values(qs)
+	err := c.get(ctx, "/v1/leaderboard", qs, &resp)
+	return resp.Leaderboard, resp.Metadata, err
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// QuizFilter selects the quizes of a list.
+type QuizFilter struct {
+	Category   string
+	RewardFrom int
+	RewardTo   int
+	ListOptions
+}
+
+func (f QuizFilter) values() url.Values {
+	qs := url.Values{}
+	setString(qs, "category", f.Category)
+	setInt(qs, "rewardFrom", f.RewardFrom)
+	setInt(qs, "rewardTo", f.RewardTo)
+	f.ListOptions.values(qs)
+	return qs
+}
+
+// QuizInput holds the fields of a new quiz.
+type QuizInput struct {
+	Category  string   `json:"category"`
+	Reward    int      `json:"reward"`
+	Questions []string `json:"questions"`
+	Answers   []string `json:"answers"`
+}
+
+// QuizUpdate holds the fields of a quiz to change, nil fields are left as they are.
+type QuizUpdate struct {
+	Category *string `json:"category,omitempty"`
+	Reward   *int    `json:"reward,omitempty"`
+}
+
+// ListQuizes returns a page of the quizes.
+func (c *Client) ListQuizes(ctx context.Context, filter QuizFilter) ([]*Quiz, Metadata, error) {
+	var resp struct {
+		Quizes   []*Quiz  `json:"quizes"`
+		Metadata Metadata `json:"metadata"`
+	}
+	err := c.get(ctx, "/v1/quizes", filter.values(), &resp)
+	return resp.Quizes, resp.Metadata, err
+}
+
+// Quizes returns an iterator over all the quizes of the filter, from its page on.
+func (c *Client) Quizes(filter QuizFilter) *Iterator[*Quiz] {
+	return newIterator(filter.ListOptions, func(ctx context.Context, opts ListOptions) ([]*Quiz, Metadata, error) {
+		filter.ListOptions = opts
+		return c.ListQuizes(ctx, filter)
+	})
+}
+
+// GetQuiz returns the quiz with the id, with only fields if any are given.
+func (c *Client) GetQuiz(ctx context.Context, id int, fields ...string) (*Quiz, error) {
+	var resp struct {
+		Quiz *Quiz `json:"quiz"`
+	}
+	qs := url.Values{}
+	ListOptions{Fields: fields}.values(qs)
+	err := c.get(ctx, fmt.Sprintf("/v1/quizes/%d", id), qs, &resp)
+	return resp.Quiz, err
+}
+
+// CreateQuiz creates a quiz authored by the user. It needs the quiz:create permission.
+func (c *Client) CreateQuiz(ctx context.Context, input QuizInput) (*Quiz, error) {
+	var resp struct {
+		Quiz *Quiz `json:"quiz"`
+	}
+	err := c.do(ctx, http.MethodPost, "/v1/quizes", input, &resp)
+	return resp.Quiz, err
+}
+
+// UpdateQuiz changes the fields of update of the quiz with the id. It needs the quiz:write
+// permission, unless the user authored the quiz.
+func (c *Client) UpdateQuiz(ctx context.Context, id int, update QuizUpdate) (*Quiz, error) {
+	var resp struct {
+		Quiz *Quiz `json:"quiz"`
+	}
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/v1/quizes/%d", id), update, &resp)
+	return resp.Quiz, err
+}
+
+// DeleteQuiz deletes the quiz with the id. It needs the quiz:delete permission, unless the user
+// authored the quiz.
+func (c *Client) DeleteQuiz(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/v1/quizes/%d", id), nil, nil)
+}
+
+// ListQuizPlayers returns a page of the players who finished the quiz with the id, one for each
+// of the games of the filter. The Quiz of the filter is ignored.
+func (c *Client) ListQuizPlayers(ctx context.Context, id int, filter GameFilter) ([]*Player, Metadata, error) {
+	var resp struct {
+		Players  []*Player `json:"players"`
+		Metadata Metadata  `json:"metadata"`
+	}
+	filter.Quiz = 0
+	err := c.get(ctx, fmt.Sprintf("/v1/quizes/%d/players", id), filter.values(), &resp)
+	return resp.Players, resp.Metadata, err
+}
@@ -0,0 +1,96 @@
+package client
+
+import "time"
+
+// The records of the API, as it encodes them. They mirror the types of the model package, which
+// isn't imported so that clients don't depend on the database drivers.
+type (
+	// Player is a player of quizes. OwnerID is the user who created it.
+	Player struct {
+		ID         string    `json:"id"`
+		Name       string    `json:"name"`
+		Joined     time.Time `json:"joined"`
+		LastUpdate time.Time `json:"last_update"`
+		Score      int       `json:"score"`
+		OwnerID    *int64    `json:"owner_id,omitempty"`
+	}
+
+	// Quiz is a set of questions with their answers. OwnerID is the user who created it.
+	Quiz struct {
+		ID        string   `json:"id"`
+		Category  string   `json:"category"`
+		Reward    int      `json:"reward"`
+		Questions []string `json:"questions"`
+		Answers   []string `json:"answers"`
+		OwnerID   *int64   `json:"owner_id,omitempty"`
+	}
+
+	// Game is a quiz played by a player, which finished when its answers were given. Until then
+	// it is InProgress and Finished is the time it was started.
+	Game struct {
+		ID         string    `json:"id"`
+		Finished   time.Time `json:"finished"`
+		Player     int       `json:"player"`
+		Quiz       int       `json:"quiz"`
+		InProgress bool      `json:"in_progress"`
+	}
+
+	// User is a user of the API. LockedUntil is set while failed logins lock them out.
+	User struct {
+		ID          int64      `json:"id"`
+		CreatedAt   time.Time  `json:"created_at"`
+		Name        string     `json:"name"`
+		Email       string     `json:"email"`
+		Activated   bool       `json:"activated"`
+		LockedUntil *time.Time `json:"locked_until,omitempty"`
+	}
+
+	// Token is an authentication token of a login.
+	Token struct {
+		Plaintext string    `json:"token"`
+		Expiry    time.Time `json:"expiry"`
+	}
+
+	// APIKey is a long-lived credential acting on behalf of its user, limited to its Scopes. The
+	// Plaintext key is only returned when it's created.
+	APIKey struct {
+		ID         int64      `json:"id"`
+		Plaintext  string     `json:"key,omitempty"`
+		Prefix     string     `json:"prefix"`
+		Name       string     `json:"name"`
+		Scopes     []string   `json:"scopes"`
+		CreatedAt  time.Time  `json:"created_at"`
+		Expiry     *time.Time `json:"expiry,omitempty"`
+		LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+		RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	}
+
+	// LeaderboardEntry is the rank of a player on the leaderboard, as of RefreshedAt.
+	LeaderboardEntry struct {
+		Rank        int       `json:"rank"`
+		PlayerID    int64     `json:"player_id"`
+		Name        string    `json:"name"`
+		Score       int       `json:"score"`
+		Games       int       `json:"games"`
+		RefreshedAt time.Time `json:"refreshed_at"`
+	}
+
+	// Metadata describes a page of a list. Lists skipping the count only have the cursors, the
+	// page size and, without a cursor, the current page.
+	Metadata struct {
+		CurrentPage  int    `json:"current_page,omitempty"`
+		PageSize     int    `json:"page_size,omitempty"`
+		FirstPage    int    `json:"first_page,omitempty"`
+		LastPage     int    `json:"last_page,omitempty"`
+		TotalRecords int    `json:"total_records,omitempty"`
+		NextCursor   string `json:"next_cursor,omitempty"`
+		PrevCursor   string `json:"prev_cursor,omitempty"`
+	}
+
+	// TimeRange selects the times from From on and before To. A zero From or To leaves that end
+	// of the range open.
+	TimeRange struct {
+		From time.Time
+		To   time.Time
+	}
+)
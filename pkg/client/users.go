@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Register registers a user. It returns the user and the token to activate them with, which the
+// API also sends to their email address.
+func (c *Client) Register(ctx context.Context, name, email, password string) (*User, string, error) {
+	var resp struct {
+		User struct {
+			Token string `json:"token"`
+			User  *User  `json:"user"`
+		} `json:"user"`
+	}
+	body := map[string]string{"name": name, "email": email, "password": password}
+	err := c.do(ctx, http.MethodPost, "/v1/users", body, &resp)
+	return resp.User.User, resp.User.Token, err
+}
+
+// Activate activates the user of the activation token.
+func (c *Client) Activate(ctx context.Context, token string) (*User, error) {
+	var resp struct {
+		User *User `json:"user"`
+	}
+	err := c.do(ctx, http.MethodPut, "/v1/users/activated", map[string]string{"token": token}, &resp)
+	return resp.User, err
+}
+
+// Login logs in and authenticates the following requests with the token. The token is renewed
+// by logging in again when it is about to expire. Users with two-factor authentication get
+// ErrTwoFactorRequired, their login is completed by LoginTwoFactor or LoginRecoveryCode and
+// isn't renewed.
+func (c *Client) Login(ctx context.Context, email, password string) error {
+	var resp struct {
+		AuthenticationToken Token `json:"authentication_token"`
+		TwoFactorToken      Token `json:"two_factor_token"`
+	}
+	body := map[string]string{"email": email, "password": password}
+
+	status, err := c.send(ctx, http.MethodPost, "/v1/users/login", false, body, &resp)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if status == http.StatusAccepted {
+		c.challenge = resp.TwoFactorToken.Plaintext
+		c.email, c.password = "", ""
+		return ErrTwoFactorRequired
+	}
+
+	c.token = resp.AuthenticationToken
+	c.email, c.password = email, password
+	return nil
+}
+
+// LoginTwoFactor completes a login which returned ErrTwoFactorRequired with a code from the
+// authenticator app.
+func (c *Client) LoginTwoFactor(ctx context.Context, code string) error {
+	return c.completeLogin(ctx, map[string]string{"code": code})
+}
+
+// LoginRecoveryCode completes a login which returned ErrTwoFactorRequired with a recovery code.
+func (c *Client) LoginRecoveryCode(ctx context.Context, recoveryCode string) error {
+	return c.completeLogin(ctx, map[string]string{"recovery_code": recoveryCode})
+}
+
+func (c *Client) completeLogin(ctx context.Context, body map[string]string) error {
+	c.mu.Lock()
+	body["token"] = c.challenge
+	c.mu.Unlock()
+
+	if body["token"] == "" {
+		return errors.New("client: no login waits for a second factor")
+	}
+
+	var resp struct {
+		AuthenticationToken Token `json:"authentication_token"`
+	}
+	if _, err := c.send(ctx, http.MethodPost, "/v1/users/login/2fa", false, body, &resp); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = resp.AuthenticationToken
+	c.challenge = ""
+	return nil
+}
+
+// CreateAPIKey creates an API key of the user with a subset of their permissions as scopes,
+// which expires at expiry unless it's nil. The Plaintext of the key is only returned here. It
+// needs a token from a login.
+func (c *Client) CreateAPIKey(ctx context.Context, name string, scopes []string, expiry *time.Time) (*APIKey, error) {
+	var resp struct {
+		APIKey *APIKey `json:"api_key"`
+	}
+	body := struct {
+		Name   string     `json:"name"`
+		Scopes []string   `json:"scopes"`
+		Expiry *time.Time `json:"expiry,omitempty"`
+	}{name, scopes, expiry}
+	err := c.do(ctx, http.MethodPost, "/v1/api-keys", body, &resp)
+	return resp.APIKey, err
+}
+
+// APIKeys returns the API keys of the user. It needs a token from a login.
+func (c *Client) APIKeys(ctx context.Context) ([]*APIKey, error) {
+	var resp struct {
+		APIKeys []*APIKey `json:"api_keys"`
+	}
+	err := c.get(ctx, "/v1/api-keys", nil, &resp)
+	return resp.APIKeys, err
+}
+
+// RevokeAPIKey revokes the API key of the user with the id. It needs a token from a login.
+func (c *Client) RevokeAPIKey(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/v1/api-keys/%d", id), nil, nil)
+}
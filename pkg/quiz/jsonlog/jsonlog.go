@@ -1,12 +1,15 @@
 package jsonlog
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"os"
 	"runtime/debug"
 	"sync"
 	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/tracing"
 )
 
 // Level represents the severity level of a log entry.
@@ -69,6 +72,49 @@ func (l *Logger) PrintFatal(err error, properties map[string]string) {
 	os.Exit(1)
 }
 
+// PrintInfoContext writes an Info level log entry which also holds the request ID and trace ID
+// in ctx, if any.
+func (l *Logger) PrintInfoContext(ctx context.Context, message string, properties map[string]string) {
+	l.print(LevelInfo, message, contextProperties(ctx, properties))
+}
+
+// PrintErrorContext writes an Error level log entry which also holds the request ID and trace ID
+// in ctx, if any.
+func (l *Logger) PrintErrorContext(ctx context.Context, err error, properties map[string]string) {
+	l.print(LevelError, err.Error(), contextProperties(ctx, properties))
+}
+
+type requestIDContextKey struct{}
+
+// ContextWithRequestID returns a copy of ctx holding the request ID, which the *Context print
+// methods add to their entries.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestID returns the request ID in ctx, or an empty string if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// contextProperties returns a copy of properties with the request ID and trace ID in ctx added.
+func contextProperties(ctx context.Context, properties map[string]string) map[string]string {
+	props := make(map[string]string, len(properties)+2)
+	for key, value := range properties {
+		props[key] = value
+	}
+
+	if id := RequestID(ctx); id != "" {
+		props["request_id"] = id
+	}
+	if span := tracing.FromContext(ctx); span != nil {
+		props["trace_id"] = span.TraceID().String()
+	}
+
+	return props
+}
+
 // print is an internal method for writing a log entry.
 func (l *Logger) print(level Level, message string, properties map[string]string) (int, error) {
 	// If the severity level of the log entry is below the minimum severity for the logger
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("got Content-Type %q, want the Prometheus text format", got)
+	}
+
+	return rec.Body.String()
+}
+
+func TestExpositionFormat(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounterVec("http_requests_total", "Requests by method and status.", "method", "status")
+	requests.Inc("POST", "201")
+	requests.Inc("GET", "200")
+	requests.Add(2, "GET", "200")
+
+	r.NewCounterVec("panics_total", "Recovered panics.")
+
+	inFlight := r.NewGauge("http_requests_in_flight", "Requests being served.")
+	inFlight.Add(3)
+	inFlight.Add(-1)
+
+	r.NewGaugeFunc("goroutines", "Running goroutines.", func() float64 { return 7 })
+	r.NewCounterFunc("db_wait_total", "Waits for a connection.", func() float64 { return 1.5 })
+
+	latency := r.NewHistogramVec("http_request_duration_seconds", "Latency of requests.", []float64{0.5, 1}, "route")
+	latency.Observe(0.25, "/v1/players")
+	latency.Observe(0.5, "/v1/players")
+	latency.Observe(0.75, "/v1/players")
+	latency.Observe(4, "/v1/players")
+
+	want := `# HELP http_requests_total Requests by method and status.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",status="200"} 3
+http_requests_total{method="POST",status="201"} 1
+# HELP panics_total Recovered panics.
+# TYPE panics_total counter
+panics_total 0
+# HELP http_requests_in_flight Requests being served.
+# TYPE http_requests_in_flight gauge
+http_requests_in_flight 2
+# HELP goroutines Running goroutines.
+# TYPE goroutines gauge
+goroutines 7
+# HELP db_wait_total Waits for a connection.
+# TYPE db_wait_total counter
+db_wait_total 1.5
+# HELP http_request_duration_seconds Latency of requests.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{route="/v1/players",le="0.5"} 2
+http_request_duration_seconds_bucket{route="/v1/players",le="1"} 3
+http_request_duration_seconds_bucket{route="/v1/players",le="+Inf"} 4
+http_request_duration_seconds_sum{route="/v1/players"} 5.5
+http_request_duration_seconds_count{route="/v1/players"} 4
+`
+	if got := scrape(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExpositionEscaping(t *testing.T) {
+	r := NewRegistry()
+
+	errors := r.NewCounterVec("errors_total", "Errors\nby message, in \\ units.", "message")
+	errors.Inc("say \"hi\"\n\\")
+
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{1e-3, 1e6})
+	latency.Observe(2e6)
+
+	want := `# HELP errors_total Errors\nby message, in \\ units.
+# TYPE errors_total counter
+errors_total{message="say \"hi\"\n\\"} 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.001"} 0
+latency_seconds_bucket{le="1e+06"} 0
+latency_seconds_bucket{le="+Inf"} 1
+latency_seconds_sum 2e+06
+latency_seconds_count 1
+`
+	if got := scrape(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExpositionEmptyVec(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("logins_total", "Logins by result.", "result")
+	r.NewHistogramVec("query_duration_seconds", "Latency of queries.", DefaultBuckets, "query")
+
+	// A vector without observations has no series yet, only its metadata.
+	got := scrape(t, r)
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if !strings.HasPrefix(line, "# ") {
+			t.Errorf("got sample %q, want none before the first observation", line)
+		}
+	}
+	if !strings.Contains(got, "# TYPE query_duration_seconds histogram\n") {
+		t.Errorf("got\n%s\nwant the type of the histogram", got)
+	}
+}
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS identities
+(
+	id         BIGSERIAL PRIMARY KEY,
+	user_id    BIGINT                      NOT NULL REFERENCES users ON DELETE CASCADE,
+	provider   TEXT                        NOT NULL,
+	subject    TEXT                        NOT NULL,
+	email      CITEXT                      NOT NULL DEFAULT '',
+	created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	UNIQUE (provider, subject)
+);
+
+-- Pending authorization code logins. The state is stored hashed, like tokens.
+CREATE TABLE IF NOT EXISTS oidc_login_states
+(
+	state_hash    BYTEA PRIMARY KEY,
+	provider      TEXT                        NOT NULL,
+	code_verifier TEXT                        NOT NULL,
+	nonce         TEXT                        NOT NULL,
+	expiry        TIMESTAMP(0) WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS users_totp
+(
+	user_id        BIGINT PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+	secret         TEXT                        NOT NULL,
+	confirmed      BOOL                        NOT NULL DEFAULT false,
+	-- The time step of the last accepted code, so a code can't be replayed.
+	last_used_step BIGINT                      NOT NULL DEFAULT 0,
+	created_at     TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+	hash    BYTEA PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS login_attempts;
+
+ALTER TABLE users
+	DROP COLUMN IF EXISTS failed_logins,
+	DROP COLUMN IF EXISTS locked_until;
\ No newline at end of file
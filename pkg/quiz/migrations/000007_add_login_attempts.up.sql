@@ -0,0 +1,18 @@
+ALTER TABLE users
+	ADD COLUMN IF NOT EXISTS failed_logins INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS locked_until  TIMESTAMP(0) WITH TIME ZONE;
+
+-- Audit record of every login attempt, also used to track failures per client IP.
+CREATE TABLE IF NOT EXISTS login_attempts
+(
+	id         BIGSERIAL PRIMARY KEY,
+	email      CITEXT                      NOT NULL,
+	ip         TEXT                        NOT NULL,
+	user_id    BIGINT REFERENCES users ON DELETE SET NULL,
+	succeeded  BOOL                        NOT NULL,
+	reason     TEXT                        NOT NULL DEFAULT '',
+	created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_ip_created_at_idx ON login_attempts (ip, created_at);
+CREATE INDEX IF NOT EXISTS login_attempts_email_idx ON login_attempts (email);
@@ -0,0 +1,53 @@
+ALTER TABLE permissions ADD CONSTRAINT permissions_code_key UNIQUE (code);
+
+INSERT INTO permissions (code)
+VALUES ('quiz:read'), ('quiz:create'), ('quiz:write'),
+       ('game:read'), ('game:write'),
+       ('user:read'), ('user:write')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS roles
+(
+	id          BIGSERIAL PRIMARY KEY,
+	code        TEXT UNIQUE NOT NULL,
+	description TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS roles_permissions
+(
+	role_id       BIGINT NOT NULL REFERENCES roles ON DELETE CASCADE,
+	permission_id BIGINT NOT NULL REFERENCES permissions ON DELETE CASCADE,
+	PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS users_roles
+(
+	user_id BIGINT NOT NULL REFERENCES users ON DELETE CASCADE,
+	role_id BIGINT NOT NULL REFERENCES roles ON DELETE CASCADE,
+	PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles (code, description)
+VALUES ('player', 'Plays quizzes'),
+       ('author', 'Plays and creates quizzes'),
+       ('moderator', 'Manages players, quizzes and games'),
+       ('admin', 'Manages everything, including users and their permissions');
+
+-- Every role includes the permissions of the roles listed before it.
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles
+	INNER JOIN permissions ON permissions.code = ANY (CASE roles.code
+		WHEN 'player' THEN ARRAY['player:read', 'quiz:read', 'game:read']
+		WHEN 'author' THEN ARRAY['player:read', 'quiz:read', 'game:read', 'quiz:create']
+		WHEN 'moderator' THEN ARRAY['player:read', 'quiz:read', 'game:read', 'quiz:create',
+		                            'player:write', 'quiz:write', 'game:write']
+		WHEN 'admin' THEN ARRAY['player:read', 'quiz:read', 'game:read', 'quiz:create',
+		                        'player:write', 'quiz:write', 'game:write', 'user:read', 'user:write']
+	END);
+
+-- Existing users become players.
+INSERT INTO users_roles (user_id, role_id)
+SELECT users.id, roles.id
+FROM users, roles
+WHERE roles.code = 'player';
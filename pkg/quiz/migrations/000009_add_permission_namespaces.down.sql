@@ -0,0 +1,23 @@
+DELETE FROM roles_permissions;
+
+INSERT INTO permissions (code)
+VALUES ('game:write')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles
+	INNER JOIN permissions ON permissions.code = ANY (CASE roles.code
+		WHEN 'player' THEN ARRAY['player:read', 'quiz:read', 'game:read']
+		WHEN 'author' THEN ARRAY['player:read', 'quiz:read', 'game:read', 'quiz:create']
+		WHEN 'moderator' THEN ARRAY['player:read', 'quiz:read', 'game:read', 'quiz:create',
+		                            'player:write', 'quiz:write', 'game:write']
+		WHEN 'admin' THEN ARRAY['player:read', 'quiz:read', 'game:read', 'quiz:create',
+		                        'player:write', 'quiz:write', 'game:write', 'user:read', 'user:write']
+	END);
+
+DELETE FROM permissions
+WHERE code IN ('player:create', 'player:delete', 'quiz:delete', 'game:play', 'game:delete',
+               'player:*', 'quiz:*', 'game:*', 'user:*', '*');
+
+ALTER TABLE quizes DROP COLUMN IF EXISTS owner_id;
\ No newline at end of file
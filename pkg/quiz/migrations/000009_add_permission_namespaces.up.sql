@@ -0,0 +1,37 @@
+ALTER TABLE quizes ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES users ON DELETE SET NULL;
+
+INSERT INTO permissions (code)
+VALUES ('player:create'), ('player:delete'),
+       ('quiz:delete'),
+       ('game:play'), ('game:delete'),
+       ('player:*'), ('quiz:*'), ('game:*'), ('user:*'), ('*')
+ON CONFLICT (code) DO NOTHING;
+
+-- Playing is game:play and removing games game:delete, game:write was never checked.
+DELETE FROM permissions WHERE code = 'game:write';
+
+-- Rebuild the permissions of the roles with the new codes. Moderators get whole namespaces and
+-- admins everything.
+DELETE FROM roles_permissions;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles
+	INNER JOIN permissions ON permissions.code = ANY (CASE roles.code
+		WHEN 'player' THEN ARRAY['player:read', 'player:create', 'quiz:read', 'game:read', 'game:play']
+		WHEN 'author' THEN ARRAY['player:read', 'player:create', 'quiz:read', 'game:read', 'game:play',
+		                         'quiz:create']
+		WHEN 'moderator' THEN ARRAY['player:*', 'quiz:*', 'game:*']
+		WHEN 'admin' THEN ARRAY['*']
+	END);
+
+-- Every write route checked player:write before, so direct grants of it keep creating and
+-- deleting.
+INSERT INTO users_permissions (user_id, permission_id)
+SELECT users_permissions.user_id, permissions.id
+FROM users_permissions
+	INNER JOIN permissions AS granted ON granted.id = users_permissions.permission_id
+	INNER JOIN permissions ON permissions.code = ANY (ARRAY['player:create', 'player:delete', 'quiz:create',
+	                                                       'quiz:delete', 'game:delete'])
+WHERE granted.code = 'player:write'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS api_keys
+(
+	id           BIGSERIAL PRIMARY KEY,
+	hash         BYTEA UNIQUE                NOT NULL,
+	prefix       TEXT                        NOT NULL,
+	user_id      BIGINT                      NOT NULL REFERENCES users ON DELETE CASCADE,
+	name         TEXT                        NOT NULL,
+	scopes       TEXT[]                      NOT NULL,
+	created_at   TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	expiry       TIMESTAMP(0) WITH TIME ZONE,
+	last_used_at TIMESTAMP(0) WITH TIME ZONE,
+	revoked_at   TIMESTAMP(0) WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
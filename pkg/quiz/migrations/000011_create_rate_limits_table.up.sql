@@ -0,0 +1,10 @@
+-- Token buckets of the rate limiter when -limiter-store=postgres is used.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits
+(
+	key        TEXT PRIMARY KEY,
+	tokens     DOUBLE PRECISION            NOT NULL,
+	allowed    BOOL                        NOT NULL,
+	updated_at TIMESTAMP(6) WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_updated_at_idx ON rate_limits (updated_at);
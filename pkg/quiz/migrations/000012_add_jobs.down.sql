@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS leaderboard;
+DROP TABLE IF EXISTS job_runs;
\ No newline at end of file
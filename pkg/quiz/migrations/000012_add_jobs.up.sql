@@ -0,0 +1,26 @@
+-- History of the runs of the periodic jobs.
+CREATE TABLE IF NOT EXISTS job_runs
+(
+	id          BIGSERIAL PRIMARY KEY,
+	job         TEXT                        NOT NULL,
+	started_at  TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+	duration_ms BIGINT                      NOT NULL,
+	succeeded   BOOL                        NOT NULL,
+	affected    BIGINT                      NOT NULL DEFAULT 0,
+	error       TEXT                        NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS job_runs_job_started_at_idx ON job_runs (job, started_at);
+
+-- Ranking of the players, refreshed by the refresh_leaderboard job.
+CREATE TABLE IF NOT EXISTS leaderboard
+(
+	player_id    BIGINT PRIMARY KEY REFERENCES players ON DELETE CASCADE,
+	rank         INTEGER                     NOT NULL,
+	name         TEXT                        NOT NULL,
+	score        INTEGER                     NOT NULL,
+	games        INTEGER                     NOT NULL,
+	refreshed_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS leaderboard_rank_idx ON leaderboard (rank);
@@ -0,0 +1 @@
+ALTER TABLE players ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES users ON DELETE SET NULL;
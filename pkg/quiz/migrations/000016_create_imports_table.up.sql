@@ -0,0 +1,6 @@
+-- Digests of the files imported with justquizctl import, so that a file isn't imported twice.
+CREATE TABLE IF NOT EXISTS imports
+(
+	digest      TEXT PRIMARY KEY,
+	imported_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
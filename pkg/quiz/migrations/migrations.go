@@ -0,0 +1,16 @@
+// Package migrations embeds the SQL migrations into the binary, so that it can migrate the
+// database without the files on disk.
+package migrations
+
+import "embed"
+
+// Postgres holds the PostgreSQL migrations.
+//
+//go:embed *.sql
+var Postgres embed.FS
+
+// SQLite holds the SQLite migrations, in the sqlite directory. They have the same versions as the
+// PostgreSQL ones.
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
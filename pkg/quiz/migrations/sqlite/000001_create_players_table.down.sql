@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS games;
+DROP TABLE IF EXISTS quizes;
+DROP TABLE IF EXISTS players;
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- Timestamps are stored in UTC as 'YYYY-MM-DD HH:MM:SS', the format of CURRENT_TIMESTAMP, and
+-- arrays as JSON.
+CREATE TABLE IF NOT EXISTS players
+(
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	name        TEXT      NOT NULL,
+	joined      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_update TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	score       INTEGER   NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS quizes
+(
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	category  TEXT    DEFAULT 'general',
+	reward    INTEGER DEFAULT 0,
+	questions TEXT    DEFAULT '[]',
+	answers   TEXT    DEFAULT '[]'
+);
+
+CREATE TABLE IF NOT EXISTS games
+(
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	finished TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	player   INTEGER REFERENCES players (id) ON DELETE CASCADE,
+	quiz     INTEGER REFERENCES quizes (id) ON DELETE CASCADE
+);
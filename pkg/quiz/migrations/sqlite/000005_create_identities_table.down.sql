@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS oidc_login_states;
+DROP TABLE IF EXISTS identities;
\ No newline at end of file
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS identities
+(
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id    INTEGER             NOT NULL REFERENCES users ON DELETE CASCADE,
+	provider   TEXT                NOT NULL,
+	subject    TEXT                NOT NULL,
+	email      TEXT COLLATE NOCASE NOT NULL DEFAULT '',
+	created_at TIMESTAMP           NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (provider, subject)
+);
+
+-- Pending authorization code logins. The state is stored hashed, like tokens.
+CREATE TABLE IF NOT EXISTS oidc_login_states
+(
+	state_hash    BLOB PRIMARY KEY,
+	provider      TEXT      NOT NULL,
+	code_verifier TEXT      NOT NULL,
+	nonce         TEXT      NOT NULL,
+	expiry        TIMESTAMP NOT NULL
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS users_totp
+(
+	user_id        INTEGER PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+	secret         TEXT      NOT NULL,
+	confirmed      BOOLEAN   NOT NULL DEFAULT false,
+	-- The time step of the last accepted code, so a code can't be replayed.
+	last_used_step INTEGER   NOT NULL DEFAULT 0,
+	created_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+	hash    BLOB PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE
+);
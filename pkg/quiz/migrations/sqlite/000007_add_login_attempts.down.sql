@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS login_attempts;
+
+ALTER TABLE users DROP COLUMN failed_logins;
+ALTER TABLE users DROP COLUMN locked_until;
\ No newline at end of file
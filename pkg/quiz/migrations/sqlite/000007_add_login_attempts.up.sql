@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN failed_logins INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
+
+-- Audit record of every login attempt, also used to track failures per client IP.
+CREATE TABLE IF NOT EXISTS login_attempts
+(
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	email      TEXT COLLATE NOCASE NOT NULL,
+	ip         TEXT                NOT NULL,
+	user_id    INTEGER REFERENCES users ON DELETE SET NULL,
+	succeeded  BOOLEAN             NOT NULL,
+	reason     TEXT                NOT NULL DEFAULT '',
+	created_at TIMESTAMP           NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_ip_created_at_idx ON login_attempts (ip, created_at);
+CREATE INDEX IF NOT EXISTS login_attempts_email_idx ON login_attempts (email);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS users_roles;
+DROP TABLE IF EXISTS roles_permissions;
+DROP TABLE IF EXISTS roles;
+
+DELETE FROM permissions
+WHERE code IN ('quiz:read', 'quiz:create', 'quiz:write', 'game:read', 'game:write', 'user:read', 'user:write');
+
+DROP INDEX IF EXISTS permissions_code_key;
\ No newline at end of file
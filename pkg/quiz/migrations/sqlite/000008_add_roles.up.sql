@@ -0,0 +1,53 @@
+CREATE UNIQUE INDEX IF NOT EXISTS permissions_code_key ON permissions (code);
+
+INSERT INTO permissions (code)
+VALUES ('quiz:read'), ('quiz:create'), ('quiz:write'),
+       ('game:read'), ('game:write'),
+       ('user:read'), ('user:write')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS roles
+(
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	code        TEXT UNIQUE NOT NULL,
+	description TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS roles_permissions
+(
+	role_id       INTEGER NOT NULL REFERENCES roles ON DELETE CASCADE,
+	permission_id INTEGER NOT NULL REFERENCES permissions ON DELETE CASCADE,
+	PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS users_roles
+(
+	user_id INTEGER NOT NULL REFERENCES users ON DELETE CASCADE,
+	role_id INTEGER NOT NULL REFERENCES roles ON DELETE CASCADE,
+	PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles (code, description)
+VALUES ('player', 'Plays quizzes'),
+       ('author', 'Plays and creates quizzes'),
+       ('moderator', 'Manages players, quizzes and games'),
+       ('admin', 'Manages everything, including users and their permissions');
+
+-- Every role includes the permissions of the roles listed before it.
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles
+	INNER JOIN permissions ON CASE roles.code
+		WHEN 'player' THEN permissions.code IN ('player:read', 'quiz:read', 'game:read')
+		WHEN 'author' THEN permissions.code IN ('player:read', 'quiz:read', 'game:read', 'quiz:create')
+		WHEN 'moderator' THEN permissions.code IN ('player:read', 'quiz:read', 'game:read', 'quiz:create',
+		                                           'player:write', 'quiz:write', 'game:write')
+		WHEN 'admin' THEN permissions.code IN ('player:read', 'quiz:read', 'game:read', 'quiz:create',
+		                                       'player:write', 'quiz:write', 'game:write', 'user:read', 'user:write')
+	END;
+
+-- Existing users become players.
+INSERT INTO users_roles (user_id, role_id)
+SELECT users.id, roles.id
+FROM users, roles
+WHERE roles.code = 'player';
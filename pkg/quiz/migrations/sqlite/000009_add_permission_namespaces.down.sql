@@ -0,0 +1,43 @@
+DELETE FROM roles_permissions;
+
+INSERT INTO permissions (code)
+VALUES ('game:write')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles
+	INNER JOIN permissions ON CASE roles.code
+		WHEN 'player' THEN permissions.code IN ('player:read', 'quiz:read', 'game:read')
+		WHEN 'author' THEN permissions.code IN ('player:read', 'quiz:read', 'game:read', 'quiz:create')
+		WHEN 'moderator' THEN permissions.code IN ('player:read', 'quiz:read', 'game:read', 'quiz:create',
+		                                           'player:write', 'quiz:write', 'game:write')
+		WHEN 'admin' THEN permissions.code IN ('player:read', 'quiz:read', 'game:read', 'quiz:create',
+		                                       'player:write', 'quiz:write', 'game:write', 'user:read', 'user:write')
+	END;
+
+DELETE FROM permissions
+WHERE code IN ('player:create', 'player:delete', 'quiz:delete', 'game:play', 'game:delete',
+               'player:*', 'quiz:*', 'game:*', 'user:*', '*');
+
+-- SQLite can't drop a column with a foreign key, so quizes is rebuilt. Dropping it deletes the
+-- games of its quizes, they are put back afterwards.
+CREATE TEMPORARY TABLE games_backup AS SELECT * FROM games;
+
+CREATE TABLE quizes_old
+(
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	category  TEXT    DEFAULT 'general',
+	reward    INTEGER DEFAULT 0,
+	questions TEXT    DEFAULT '[]',
+	answers   TEXT    DEFAULT '[]'
+);
+
+INSERT INTO quizes_old (id, category, reward, questions, answers)
+SELECT id, category, reward, questions, answers FROM quizes;
+
+DROP TABLE quizes;
+ALTER TABLE quizes_old RENAME TO quizes;
+
+INSERT INTO games SELECT * FROM games_backup;
+DROP TABLE games_backup;
\ No newline at end of file
@@ -0,0 +1,36 @@
+ALTER TABLE quizes ADD COLUMN owner_id INTEGER REFERENCES users ON DELETE SET NULL;
+
+INSERT INTO permissions (code)
+VALUES ('player:create'), ('player:delete'),
+       ('quiz:delete'),
+       ('game:play'), ('game:delete'),
+       ('player:*'), ('quiz:*'), ('game:*'), ('user:*'), ('*')
+ON CONFLICT (code) DO NOTHING;
+
+-- Playing is game:play and removing games game:delete, game:write was never checked.
+DELETE FROM permissions WHERE code = 'game:write';
+
+-- Rebuild the permissions of the roles with the new codes. Moderators get whole namespaces and
+-- admins everything.
+DELETE FROM roles_permissions;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles
+	INNER JOIN permissions ON CASE roles.code
+		WHEN 'player' THEN permissions.code IN ('player:read', 'player:create', 'quiz:read', 'game:read', 'game:play')
+		WHEN 'author' THEN permissions.code IN ('player:read', 'player:create', 'quiz:read', 'game:read', 'game:play',
+		                                        'quiz:create')
+		WHEN 'moderator' THEN permissions.code IN ('player:*', 'quiz:*', 'game:*')
+		WHEN 'admin' THEN permissions.code IN ('*')
+	END;
+
+-- Every write route checked player:write before, so direct grants of it keep creating and
+-- deleting.
+INSERT OR IGNORE INTO users_permissions (user_id, permission_id)
+SELECT users_permissions.user_id, permissions.id
+FROM users_permissions
+	INNER JOIN permissions AS granted ON granted.id = users_permissions.permission_id
+	INNER JOIN permissions ON permissions.code IN ('player:create', 'player:delete', 'quiz:create',
+	                                               'quiz:delete', 'game:delete')
+WHERE granted.code = 'player:write';
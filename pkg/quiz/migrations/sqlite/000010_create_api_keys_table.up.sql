@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS api_keys
+(
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	hash         BLOB UNIQUE NOT NULL,
+	prefix       TEXT        NOT NULL,
+	user_id      INTEGER     NOT NULL REFERENCES users ON DELETE CASCADE,
+	name         TEXT        NOT NULL,
+	-- A JSON array of permission codes.
+	scopes       TEXT        NOT NULL,
+	created_at   TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expiry       TIMESTAMP,
+	last_used_at TIMESTAMP,
+	revoked_at   TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
@@ -0,0 +1,2 @@
+-- Rate limits are only kept in Postgres, this version keeps the SQLite schema in step with it.
+SELECT 1;
@@ -0,0 +1,37 @@
+-- Webhook subscriptions of the users. The secret signs the deliveries, so it is kept in plain
+-- text.
+CREATE TABLE IF NOT EXISTS webhooks
+(
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id    INTEGER   NOT NULL REFERENCES users ON DELETE CASCADE,
+	url        TEXT      NOT NULL,
+	-- A JSON array of event names.
+	events     TEXT      NOT NULL,
+	secret     TEXT      NOT NULL,
+	active     BOOLEAN   NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	version    INTEGER   NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks (user_id);
+
+-- Deliveries of events to the webhooks, pending until they succeed or run out of attempts.
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id      INTEGER   NOT NULL REFERENCES webhooks ON DELETE CASCADE,
+	event           TEXT      NOT NULL,
+	event_id        TEXT      NOT NULL,
+	payload         TEXT      NOT NULL,
+	status          TEXT      NOT NULL DEFAULT 'pending',
+	attempts        INTEGER   NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP,
+	last_attempt_at TIMESTAMP,
+	response_status INTEGER   NOT NULL DEFAULT 0,
+	error           TEXT      NOT NULL DEFAULT '',
+	duration_ms     INTEGER   NOT NULL DEFAULT 0,
+	created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_status_next_attempt_at_idx ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id);
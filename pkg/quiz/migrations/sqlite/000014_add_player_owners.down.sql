@@ -0,0 +1,25 @@
+-- SQLite can't drop a column with a foreign key, so players is rebuilt. Dropping it deletes the
+-- games and the leaderboard rows of its players, they are put back afterwards.
+CREATE TEMPORARY TABLE games_backup AS SELECT * FROM games;
+CREATE TEMPORARY TABLE leaderboard_backup AS SELECT * FROM leaderboard;
+
+CREATE TABLE players_old
+(
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	name        TEXT      NOT NULL,
+	joined      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_update TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	score       INTEGER   NOT NULL DEFAULT 0
+);
+
+INSERT INTO players_old (id, name, joined, last_update, score)
+SELECT id, name, joined, last_update, score FROM players;
+
+DROP TABLE players;
+ALTER TABLE players_old RENAME TO players;
+
+INSERT INTO games SELECT * FROM games_backup;
+DROP TABLE games_backup;
+
+INSERT INTO leaderboard SELECT * FROM leaderboard_backup;
+DROP TABLE leaderboard_backup;
@@ -0,0 +1 @@
+ALTER TABLE players ADD COLUMN owner_id INTEGER REFERENCES users ON DELETE SET NULL;
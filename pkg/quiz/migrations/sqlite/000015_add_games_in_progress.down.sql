@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS games_in_progress_finished_idx;
+
+-- Without the column games in progress would look finished.
+DELETE FROM games WHERE in_progress;
+
+ALTER TABLE games DROP COLUMN in_progress;
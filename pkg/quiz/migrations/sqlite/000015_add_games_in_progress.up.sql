@@ -0,0 +1,5 @@
+-- Games started with POST /v1/games are in progress until they are answered correctly. Until then
+-- finished holds the time they were started.
+ALTER TABLE games ADD COLUMN in_progress BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS games_in_progress_finished_idx ON games (finished) WHERE in_progress;
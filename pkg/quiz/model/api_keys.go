@@ -0,0 +1,286 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// APIKeyPrefix starts every API key, which tells them apart from authentication tokens in the
+// Authorization header.
+const APIKeyPrefix = "jq_"
+
+// apiKeyTouchInterval is how often the last use of an API key is written to the database at most.
+const apiKeyTouchInterval = time.Minute
+
+type (
+	// APIKey is a long-lived credential for machine-to-machine integrations. It acts on behalf of
+	// the user owning it, limited to its Scopes. The plaintext key is only known when the key is
+	// created, only its SHA-256 hash is stored.
+	APIKey struct {
+		ID         int64       `json:"id"`
+		Plaintext  string      `json:"key,omitempty"`
+		Hash       []byte      `json:"-"`
+		Prefix     string      `json:"prefix"`
+		UserID     int64       `json:"-"`
+		Name       string      `json:"name"`
+		Scopes     Permissions `json:"scopes"`
+		CreatedAt  time.Time   `json:"created_at"`
+		Expiry     *time.Time  `json:"expiry,omitempty"`
+		LastUsedAt *time.Time  `json:"last_used_at,omitempty"`
+		RevokedAt  *time.Time  `json:"revoked_at,omitempty"`
+	}
+
+	// APIKeyModel struct wraps a sql.DB connection pool and allows us to work with the api_keys
+	// table.
+	APIKeyModel struct {
+		DB       *sql.DB
+		InfoLog  *log.Logger
+		ErrorLog *log.Logger
+		Timeout  time.Duration
+	}
+)
+
+// Permissions returns the permissions the key actually has: the scopes of the key that the owner
+// still has, as the owner may have lost permissions since the key was created.
+func (k *APIKey) Permissions(owner Permissions) Permissions {
+	permissions := Permissions{}
+	for _, scope := range k.Scopes {
+		if owner.Include(scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	return permissions
+}
+
+// New generates a new API key for the user and inserts it into the api_keys table. The returned
+// key holds the plaintext.
+func (m APIKeyModel) New(ctx context.Context, userID int64, name string, scopes Permissions, expiry *time.Time) (*APIKey, error) {
+	ctx, span := startSpan(ctx, "APIKeyModel.New")
+	defer span.End()
+
+	key, err := generateAPIKey(userID, name, scopes, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO api_keys (hash, prefix, user_id, name, scopes, expiry)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+		`
+
+	args := []interface{}{key.Hash, key.Prefix, key.UserID, key.Name, pq.Array(key.Scopes), key.Expiry}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// generateAPIKey returns a new random API key of the user, which is yet to be inserted.
+func generateAPIKey(userID int64, name string, scopes Permissions, expiry *time.Time) (*APIKey, error) {
+	randomBytes := make([]byte, 20)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, err
+	}
+
+	plaintext := APIKeyPrefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes))
+	hash := sha256.Sum256([]byte(plaintext))
+
+	return &APIKey{
+		Plaintext: plaintext,
+		Hash:      hash[:],
+		// Keep a few characters after the prefix, so that users can tell their keys apart.
+		Prefix: plaintext[:len(APIKeyPrefix)+6],
+		UserID: userID,
+		Name:   name,
+		Scopes: scopes,
+		Expiry: expiry,
+	}, nil
+}
+
+// GetForKey retrieves the API key matching the plaintext key. Revoked and expired keys are
+// treated as if they didn't exist.
+func (m APIKeyModel) GetForKey(ctx context.Context, plaintext string) (*APIKey, error) {
+	ctx, span := startSpan(ctx, "APIKeyModel.GetForKey")
+	defer span.End()
+
+	query := `
+		SELECT id, prefix, user_id, name, scopes, created_at, expiry, last_used_at, revoked_at
+		FROM api_keys
+		WHERE hash = $1
+		AND revoked_at IS NULL
+		AND (expiry IS NULL OR expiry > $2)
+		`
+
+	hash := sha256.Sum256([]byte(plaintext))
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	row := m.DB.QueryRowContext(ctx, query, hash[:], time.Now())
+
+	key, err := scanAPIKey(row.Scan)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// GetAllForUser returns all API keys of a user, including revoked and expired ones.
+func (m APIKeyModel) GetAllForUser(ctx context.Context, userID int64) ([]*APIKey, error) {
+	ctx, span := startSpan(ctx, "APIKeyModel.GetAllForUser")
+	defer span.End()
+
+	query := `
+		SELECT id, prefix, user_id, name, scopes, created_at, expiry, last_used_at, revoked_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY id
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	keys := []*APIKey{}
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Touch records that the key was just used. To keep busy integrations from writing on every
+// request, the time is only updated when the last recorded use is older than a minute.
+func (m APIKeyModel) Touch(ctx context.Context, key *APIKey) error {
+	ctx, span := startSpan(ctx, "APIKeyModel.Touch")
+	defer span.End()
+
+	if key.LastUsedAt != nil && time.Since(*key.LastUsedAt) < apiKeyTouchInterval {
+		return nil
+	}
+
+	query := `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, key.ID)
+	return err
+}
+
+// Revoke revokes an API key of a user. ErrRecordNotFound is returned if the user has no such key
+// or it is already revoked.
+func (m APIKeyModel) Revoke(ctx context.Context, userID, id int64) error {
+	ctx, span := startSpan(ctx, "APIKeyModel.Revoke")
+	defer span.End()
+
+	query := `
+		UPDATE api_keys
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// scanAPIKey scans a row selected by the queries above into an APIKey.
+func scanAPIKey(scan func(dest ...interface{}) error) (*APIKey, error) {
+	var key APIKey
+
+	err := scan(
+		&key.ID,
+		&key.Prefix,
+		&key.UserID,
+		&key.Name,
+		pq.Array(&key.Scopes),
+		&key.CreatedAt,
+		&key.Expiry,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// ValidateAPIKey checks the name, scopes and expiry of a new API key. The scopes must be a subset
+// of the permissions of the owner.
+func ValidateAPIKey(v *validator.Validator, key *APIKey, owner Permissions) {
+	v.Check(key.Name != "", "name", "must be provided")
+	v.Check(len(key.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(len(key.Scopes) > 0, "scopes", "must contain at least one permission")
+	v.Check(validator.Unique(key.Scopes), "scopes", "must not contain duplicate values")
+	for _, scope := range key.Scopes {
+		v.Check(owner.Include(scope), "scopes", "must only contain permissions you have, "+scope+" isn't one of them")
+	}
+
+	if key.Expiry != nil {
+		v.Check(key.Expiry.After(time.Now()), "expiry", "must be in the future")
+	}
+}
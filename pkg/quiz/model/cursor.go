@@ -0,0 +1,262 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// cursor points to a record of a sorted list: Value is the value of its sort column and ID its
+// id, which breaks ties. Sort is the sort of the list, a cursor can't be used with another one.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"i"`
+}
+
+func (c cursor) encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(js, &c)
+	return c, err
+}
+
+// cursorAt returns the cursor of a record with the given id and value of the sort column.
+func (f Filters) cursorAt(id string, value string) string {
+	i, _ := strconv.ParseInt(id, 10, 64)
+	return cursor{Sort: f.Sort, Value: value, ID: i}.encode()
+}
+
+// cursor returns the cursor of the page, the decoded After or Before, and whether it is Before.
+// Cursors are validated by ValidateFilters, an invalid one is treated as none here.
+func (f Filters) cursor() (cursor, bool, bool) {
+	s, before := f.After, false
+	if f.Before != "" {
+		s, before = f.Before, true
+	}
+	if s == "" {
+		return cursor{}, false, false
+	}
+
+	c, err := decodeCursor(s)
+	if err != nil || c.Sort != f.Sort {
+		return cursor{}, false, false
+	}
+
+	return c, before, true
+}
+
+// listQuery returns the query of a page of a list and the arguments of its placeholders, which
+// start at $n. columns are the columns of the records, including the sort columns, and from the
+// rest of a query which selects all records of the list, from its FROM clause on. The first
+// column of the page is the total number of records, or 0 with SkipCount.
+//
+// A page with a cursor is found by comparing the records with it, which an index on the sort
+// column makes fast, instead of skipping all the records before it. Pages before a cursor are
+// selected in reverse, pageOf puts them back in order. value converts the value of a cursor to
+// the one compared with the column, it may be nil.
+func (f Filters) listQuery(columns, from string, n int, value func(column, v string) (any, error)) (string, []any, error) {
+	total := "count(*) OVER()"
+	if f.SkipCount {
+		total = "0"
+	}
+
+	column, desc := f.sortColumn(), f.sortDirection() == "DESC"
+	c, before, ok := f.cursor()
+
+	// Before a cursor the list is read backwards.
+	if before {
+		desc = !desc
+	}
+	direction, idDirection := "ASC", "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	if before {
+		idDirection = "DESC"
+	}
+
+	var (
+		where string
+		args  []any
+	)
+
+	if ok {
+		after, afterID := ">", ">"
+		if desc {
+			after = "<"
+		}
+		if before {
+			afterID = "<"
+		}
+
+		if column == "id" {
+			where = fmt.Sprintf("WHERE id %s $%d", after, n)
+			args = append(args, c.ID)
+			n++
+		} else {
+			var v any = c.Value
+			if value != nil {
+				var err error
+				if v, err = value(column, c.Value); err != nil {
+					return "", nil, err
+				}
+			}
+
+			where = fmt.Sprintf("WHERE %[1]s %[2]s $%[4]d OR (%[1]s = $%[4]d AND id %[3]s $%[5]d)", column, after, afterID, n, n+1)
+			args = append(args, v, c.ID)
+			n += 2
+		}
+	}
+
+	order := fmt.Sprintf("%s %s, id %s", column, direction, idDirection)
+	if column == "id" {
+		order = "id " + direction
+	}
+
+	query := fmt.Sprintf(`
+		SELECT * FROM (
+			SELECT %s AS total, %s
+			%s
+		) AS list
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+		`,
+		total, columns, from, where, order, n, n+1)
+
+	// One more than the page, to know whether there is another page.
+	args = append(args, f.limit()+1, f.listOffset())
+
+	return query, args, nil
+}
+
+// listOffset is the offset of the page, pages with a cursor start at it.
+func (f Filters) listOffset() int {
+	if f.After != "" || f.Before != "" {
+		return 0
+	}
+
+	return f.offset()
+}
+
+// pageOf takes the records selected by the query of listQuery, or by memoryList, and returns the
+// page and its metadata. cursorOf returns the cursor of a record.
+func pageOf[T any](records []T, total int, f Filters, cursorOf func(T) string) ([]T, Metadata) {
+	_, before, cursored := f.cursor()
+
+	more := len(records) > f.limit()
+	if more {
+		records = records[:f.limit()]
+	}
+	if before {
+		slices.Reverse(records)
+	}
+
+	var metadata Metadata
+	switch {
+	case f.SkipCount:
+		metadata = Metadata{PageSize: f.PageSize}
+		if !cursored {
+			metadata.CurrentPage = f.Page
+		}
+	case cursored:
+		metadata = Metadata{PageSize: f.PageSize, TotalRecords: total}
+	default:
+		metadata = calculateMetadata(total, f.Page, f.PageSize)
+	}
+
+	if len(records) == 0 {
+		return nil, metadata
+	}
+
+	// Before a cursor there are more records after the page, the one of the cursor at least, and
+	// the query found out if there are more records before it. Otherwise it is the other way
+	// round, except on the first page.
+	if (before && more) || (!before && (cursored || f.Page > 1)) {
+		metadata.PrevCursor = cursorOf(records[0])
+	}
+	if before || more {
+		metadata.NextCursor = cursorOf(records[len(records)-1])
+	}
+
+	return records, metadata
+}
+
+// validateCursor checks that s is a cursor of the sort.
+func validateCursor(s, sort string) bool {
+	if s == "" {
+		return true
+	}
+
+	c, err := decodeCursor(s)
+	return err == nil && c.Sort == sort
+}
+
+// playerCursor returns the cursors of players in the sort of f.
+func playerCursor(f Filters) func(*Player) string {
+	column := f.sortColumn()
+
+	return func(p *Player) string {
+		var value string
+		switch column {
+		case "name":
+			value = p.Name
+		case "score":
+			value = strconv.Itoa(p.Score)
+		case "joined":
+			value = p.Joined.Format(time.RFC3339Nano)
+		}
+
+		return f.cursorAt(p.Id, value)
+	}
+}
+
+// quizCursor returns the cursors of quizes in the sort of f.
+func quizCursor(f Filters) func(*Quiz) string {
+	column := f.sortColumn()
+
+	return func(q *Quiz) string {
+		var value string
+		switch column {
+		case "category":
+			value = q.Category
+		case "reward":
+			value = strconv.Itoa(q.Reward)
+		}
+
+		return f.cursorAt(q.Id, value)
+	}
+}
+
+// gameCursor returns the cursors of games in the sort of f.
+func gameCursor(f Filters) func(*Game) string {
+	column := f.sortColumn()
+
+	return func(g *Game) string {
+		var value string
+		switch column {
+		case "finished":
+			value = g.Finished.Format(time.RFC3339Nano)
+		case "player":
+			value = strconv.Itoa(g.Player)
+		case "quiz":
+			value = strconv.Itoa(g.Quiz)
+		}
+
+		return f.cursorAt(g.Id, value)
+	}
+}
@@ -12,15 +12,28 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafeList []string
+
+	// After and Before are cursors from the NextCursor and PrevCursor of Metadata. The page then
+	// starts right after or ends right before the record of the cursor, and Page is ignored.
+	// Unlike pages, cursors don't skip or repeat records which are inserted or deleted
+	// meanwhile. Only the players, quizes and games lists support them.
+	After  string
+	Before string
+
+	// SkipCount skips counting the records, which is slow on large tables. Metadata then doesn't
+	// have the total and the last page.
+	SkipCount bool
 }
 
 // Metadata holds pagination metadata.
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
 }
 
 // calculateMetadata calculates the appropriate pagination metadata values given the total number
@@ -51,6 +64,10 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 
 	// Check that the sort parameter matches a value in the safelist.
 	v.Check(validator.In(f.Sort, f.SortSafeList...), "sort", "invalid sort value")
+
+	v.Check(f.After == "" || f.Before == "", "before", "must not be given together with after")
+	v.Check(validateCursor(f.After, f.Sort), "after", "must be a cursor of the same sort")
+	v.Check(validateCursor(f.Before, f.Sort), "before", "must be a cursor of the same sort")
 }
 
 // sortColumn checks that the client-provided Sort field matches one of the entries in our
@@ -3,47 +3,62 @@ package model
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"log"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
 )
 
+// Game is a game of a player on a quiz. Games in progress haven't been answered correctly yet,
+// for them Finished is the time they were started.
 type Game struct {
 	Id			string		`json:"id"`
-	Finished	string		`json:"finished"`
+	Finished	time.Time	`json:"finished"`
 	Player		int			`json:"player"`
 	Quiz		int			`json:"quiz"`
+	InProgress	bool		`json:"in_progress"`
 }
 
 type GameModel struct {
 	DB       *sql.DB
 	InfoLog  *log.Logger
 	ErrorLog *log.Logger
+	Timeout  time.Duration
 }
 
-func (g GameModel) GetAll(player, quiz int, from, to string, filters Filters) ([]*Game, Metadata, error) {
+// GetAll returns a page of the games of player on quiz, 0 selects any. Games in progress are left
+// out when finishedOnly is set or finished has a bound, as they haven't finished yet.
+func (g GameModel) GetAll(ctx context.Context, player, quiz int, finishedOnly bool, finished TimeRange, filters Filters) ([]*Game, Metadata, error) {
+	ctx, span := startSpan(ctx, "GameModel.GetAll")
+	defer span.End()
+
 	// Retrieve all gamees from the database
-	query := fmt.Sprintf(
+	query, pageArgs, err := filters.listQuery(
+		"id, finished, player, quiz, in_progress",
 		`
-		SELECT count(*) OVER(), id, finished, player, quiz
 		FROM games
 		WHERE (player = $1 OR $1 = 0)
 		AND (quiz = $2 OR $2 = 0)
-		AND (finished > $3 OR $3 = '1980-01-01 00:00:00+06')
-		AND (finished < $4 OR $4 = '1980-01-01 00:00:00+06')
-		ORDER BY %s %s, id ASC
-		LIMIT $5 OFFSET $6;
+		AND (finished >= $3 OR $3 IS NULL)
+		AND (finished < $4 OR $4 IS NULL)
+		AND (NOT in_progress OR NOT $5)
 		`,
-		filters.sortColumn(), filters.sortDirection())
+		6, nil)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
 
 	// Create a context with a 3-second timeout.
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, g.Timeout)
 	defer cancel()
 
-	// Organize our four placeholder parameter values in a slice.
-	args := []interface{}{player, quiz, from, to, filters.limit(), filters.offset()}
+	// Games in progress have no finish time, their finished column is the start.
+	finishedOnly = finishedOnly || !finished.IsZero()
+
+	// Organize the placeholder parameter values in a slice, the ones of the page come last.
+	args := append([]interface{}{player, quiz, timeBound(finished.From), timeBound(finished.To), finishedOnly}, pageArgs...)
 
 	// Use QueryContext to execute the query. This returns a sql.Rows result set containing
 	// the result.
@@ -66,7 +81,7 @@ func (g GameModel) GetAll(player, quiz int, from, to string, filters Filters) ([
 	var games []*Game
 	for rows.Next() {
 		var game Game
-		err := rows.Scan(&totalRecords, &game.Id, &game.Finished, &game.Player, &game.Quiz)
+		err := rows.Scan(&totalRecords, &game.Id, &game.Finished, &game.Player, &game.Quiz, &game.InProgress)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -81,29 +96,35 @@ func (g GameModel) GetAll(player, quiz int, from, to string, filters Filters) ([
 		return nil, Metadata{}, err
 	}
 	
-	// Generate a Metadata struct, passing in the total record count and pagination parameters
-	// from the client.
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	// Cut the page out of the rows and generate a Metadata struct, passing in the total record
+	// count and pagination parameters from the client.
+	games, metadata := pageOf(games, totalRecords, filters, gameCursor(filters))
 
 	// If everything went OK, then return the slice of the movies and metadata.
 	return games, metadata, nil
 }
 
-func (g GameModel) Insert(game *Game) error {
+func (g GameModel) Insert(ctx context.Context, game *Game) error {
+	ctx, span := startSpan(ctx, "GameModel.Insert")
+	defer span.End()
+
 	// Create a new game in the database
 	query := `
-		INSERT INTO games(player, quiz) 
-		VALUES ($1, $2)
+		INSERT INTO games(player, quiz, in_progress) 
+		VALUES ($1, $2, $3)
 		RETURNING id, player, quiz;
 		`
-	args := []interface{}{game.Player, game.Quiz}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	args := []interface{}{game.Player, game.Quiz, game.InProgress}
+	ctx, cancel := queryContext(ctx, g.Timeout)
 	defer cancel()
 
 	return g.DB.QueryRowContext(ctx, query, args...).Scan(&game.Id, &game.Player, &game.Quiz)
 }
 
-func (g GameModel) Get(id int) (*Game, error) {
+func (g GameModel) Get(ctx context.Context, id int) (*Game, error) {
+	ctx, span := startSpan(ctx, "GameModel.Get")
+	defer span.End()
+
 	// Invalid id. Return an error if the ID is less than 1.
 	if id < 1 {
 		return nil, ErrRecordNotFound
@@ -111,22 +132,119 @@ func (g GameModel) Get(id int) (*Game, error) {
 
 	// Retrieve a game with its ID
 	query := `
-		SELECT id, finished, player, quiz
+		SELECT id, finished, player, quiz, in_progress
 		FROM games
 		WHERE id = $1;
 		`
 	var game Game
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, g.Timeout)
 	defer cancel()
 
 	row := g.DB.QueryRowContext(ctx, query, id)
-	err := row.Scan(&game.Id, &game.Finished, &game.Player, &game.Quiz)
+	err := row.Scan(&game.Id, &game.Finished, &game.Player, &game.Quiz, &game.InProgress)
 	if err != nil {
-		return nil, fmt.Errorf("cannot retrive game with id: %v, %w", id, err)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
 	}
 	return &game, nil
 }
 
+// GetForPlayers retrieves the finished games of the given players in a single query, the most
+// recent first.
+func (g GameModel) GetForPlayers(ctx context.Context, players []int) ([]*Game, error) {
+	ctx, span := startSpan(ctx, "GameModel.GetForPlayers")
+	defer span.End()
+
+	query := `
+		SELECT id, finished, player, quiz, in_progress
+		FROM games
+		WHERE player = ANY($1) AND NOT in_progress
+		ORDER BY finished DESC, id DESC;
+		`
+	ctx, cancel := queryContext(ctx, g.Timeout)
+	defer cancel()
+
+	rows, err := g.DB.QueryContext(ctx, query, pq.Array(players))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			g.ErrorLog.Println(err)
+		}
+	}()
+
+	var games []*Game
+	for rows.Next() {
+		var game Game
+		err := rows.Scan(&game.Id, &game.Finished, &game.Player, &game.Quiz, &game.InProgress)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, &game)
+	}
+
+	return games, rows.Err()
+}
+
+// Finish records that the player answered the quiz correctly. The oldest game of the player in
+// progress on the quiz is finished, or a finished game is inserted if there is none.
+func (g GameModel) Finish(ctx context.Context, game *Game) error {
+	ctx, span := startSpan(ctx, "GameModel.Finish")
+	defer span.End()
+
+	query := `
+		UPDATE games
+		SET in_progress = false, finished = NOW()
+		WHERE id = (
+			SELECT id
+			FROM games
+			WHERE player = $1 AND quiz = $2 AND in_progress
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, finished;
+		`
+	game.InProgress = false
+
+	err := func() error {
+		ctx, cancel := queryContext(ctx, g.Timeout)
+		defer cancel()
+
+		return g.DB.QueryRowContext(ctx, query, game.Player, game.Quiz).Scan(&game.Id, &game.Finished)
+	}()
+	if errors.Is(err, sql.ErrNoRows) {
+		return g.Insert(ctx, game)
+	}
+	return err
+}
+
+// DeleteAbandoned deletes the games still in progress which were started before the given time,
+// and returns their number.
+func (g GameModel) DeleteAbandoned(ctx context.Context, startedBefore time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "GameModel.DeleteAbandoned")
+	defer span.End()
+
+	query := `
+		DELETE FROM games
+		WHERE in_progress AND finished < $1;
+		`
+	ctx, cancel := queryContext(ctx, g.Timeout)
+	defer cancel()
+
+	result, err := g.DB.ExecContext(ctx, query, startedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 // func (g GameModel) Answer(game *Game) (*Game, error) {
 // 	// Invalid id. Return an error if the ID is less than 1.
 // 	if id < 1 {
@@ -171,7 +289,10 @@ func (g GameModel) Get(id int) (*Game, error) {
 // 	return g.DB.QueryRowContext(ctx, query, args...).Scan(&game.Id)
 // }
 
-func (g GameModel) Delete(id int) error {
+func (g GameModel) Delete(ctx context.Context, id int) error {
+	ctx, span := startSpan(ctx, "GameModel.Delete")
+	defer span.End()
+
 	// Invalid id. Return an error if the ID is less than 1.
 	if id < 1 {
 		return ErrRecordNotFound
@@ -182,13 +303,25 @@ func (g GameModel) Delete(id int) error {
 		DELETE FROM games
 		WHERE id = $1;
 		`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, g.Timeout)
 	defer cancel()
 
-	_, err := g.DB.ExecContext(ctx, query, id)
-	return err
+	result, err := g.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
 }
 
 func ValidateGame(v *validator.Validator, game *Game) {
-	v.Check(game.Finished != "", "finished", "must be provided")
+	v.Check(!game.Finished.IsZero(), "finished", "must be provided")
 }
\ No newline at end of file
@@ -0,0 +1,173 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+var (
+	// ErrDuplicateIdentity is returned when an external identity is already linked to a user.
+	ErrDuplicateIdentity = errors.New("duplicate identity")
+)
+
+type (
+	// Identity links a user to an account at an external OpenID Connect provider.
+	Identity struct {
+		ID        int64     `json:"id"`
+		UserID    int64     `json:"-"`
+		Provider  string    `json:"provider"`
+		Subject   string    `json:"-"`
+		Email     string    `json:"email"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// LoginState is a pending authorization code login. Plaintext is the state value sent to the
+	// provider, only its hash is stored in the database.
+	LoginState struct {
+		Plaintext    string
+		Provider     string
+		CodeVerifier string
+		Nonce        string
+		Expiry       time.Time
+	}
+
+	// IdentityModel struct wraps a sql.DB connection pool and allows us to work with the
+	// identities and oidc_login_states tables.
+	IdentityModel struct {
+		DB       *sql.DB
+		InfoLog  *log.Logger
+		ErrorLog *log.Logger
+		Timeout  time.Duration
+	}
+)
+
+// Insert links a new external identity to a user. If the provider and subject pair is already
+// linked we return ErrDuplicateIdentity.
+func (m IdentityModel) Insert(ctx context.Context, identity *Identity) error {
+	ctx, span := startSpan(ctx, "IdentityModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+		`
+
+	args := []interface{}{identity.UserID, identity.Provider, identity.Subject, identity.Email}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&identity.ID, &identity.CreatedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "identities_provider_subject_key"`:
+			return ErrDuplicateIdentity
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetByProviderSubject retrieves the identity for a subject at a specific provider.
+func (m IdentityModel) GetByProviderSubject(ctx context.Context, provider, subject string) (*Identity, error) {
+	ctx, span := startSpan(ctx, "IdentityModel.GetByProviderSubject")
+	defer span.End()
+
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM identities
+		WHERE provider = $1 AND subject = $2
+		`
+
+	var identity Identity
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, provider, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &identity, nil
+}
+
+// InsertLoginState stores a pending login so that the callback can be matched to it.
+func (m IdentityModel) InsertLoginState(ctx context.Context, state *LoginState) error {
+	ctx, span := startSpan(ctx, "IdentityModel.InsertLoginState")
+	defer span.End()
+
+	query := `
+		INSERT INTO oidc_login_states (state_hash, provider, code_verifier, nonce, expiry)
+		VALUES ($1, $2, $3, $4, $5)
+		`
+
+	hash := sha256.Sum256([]byte(state.Plaintext))
+	args := []interface{}{hash[:], state.Provider, state.CodeVerifier, state.Nonce, state.Expiry}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// ConsumeLoginState deletes and returns the pending login for the given provider and plaintext
+// state. Deleting it makes sure a state can only ever be used once. Expired states are treated
+// as not found.
+func (m IdentityModel) ConsumeLoginState(ctx context.Context, provider, plaintext string) (*LoginState, error) {
+	ctx, span := startSpan(ctx, "IdentityModel.ConsumeLoginState")
+	defer span.End()
+
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1 AND provider = $2
+		RETURNING provider, code_verifier, nonce, expiry
+		`
+
+	hash := sha256.Sum256([]byte(plaintext))
+	state := LoginState{Plaintext: plaintext}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, hash[:], provider).Scan(
+		&state.Provider,
+		&state.CodeVerifier,
+		&state.Nonce,
+		&state.Expiry,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if state.Expiry.Before(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,187 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrDuplicateImport is returned when the records of an import were imported before.
+var ErrDuplicateImport = errors.New("duplicate import")
+
+// Import is a set of players, quizes and games created together, e.g. from an export of another
+// database. The records get new ids and games are linked to the new ids of their player and
+// quiz, given by their ids in the import. Times are kept as they are, records without one get the
+// time of the import.
+type Import struct {
+	// Digest identifies the content of the import, an import with the digest of an earlier one
+	// is refused with ErrDuplicateImport.
+	Digest  string
+	Players []*Player
+	Quizes  []*Quiz
+	Games   []*Game
+}
+
+// importer inserts the records of an import within the transaction of a store. Owners that
+// aren't users of the database are cleared.
+type importer interface {
+	insertPlayer(ctx context.Context, player *Player) error
+	insertQuiz(ctx context.Context, quiz *Quiz) error
+	insertGame(ctx context.Context, game *Game) error
+}
+
+// importRecords inserts the records of imp with ins. Games whose player or quiz isn't in the
+// import are skipped, their number is returned.
+func importRecords(ctx context.Context, ins importer, imp *Import) (int, error) {
+	now := time.Now()
+
+	players := make(map[int]int, len(imp.Players))
+	for i, player := range imp.Players {
+		oldID, _ := strconv.Atoi(player.Id)
+
+		if player.Joined.IsZero() {
+			player.Joined = now
+		}
+		if player.LastUpdate.IsZero() {
+			player.LastUpdate = player.Joined
+		}
+
+		if err := ins.insertPlayer(ctx, player); err != nil {
+			return 0, fmt.Errorf("player %d of the import: %w", i+1, err)
+		}
+
+		players[oldID], _ = strconv.Atoi(player.Id)
+	}
+
+	quizes := make(map[int]int, len(imp.Quizes))
+	for i, quiz := range imp.Quizes {
+		oldID, _ := strconv.Atoi(quiz.Id)
+
+		if err := ins.insertQuiz(ctx, quiz); err != nil {
+			return 0, fmt.Errorf("quiz %d of the import: %w", i+1, err)
+		}
+
+		quizes[oldID], _ = strconv.Atoi(quiz.Id)
+	}
+
+	skipped := 0
+	for i, game := range imp.Games {
+		player, playerOK := players[game.Player]
+		quiz, quizOK := quizes[game.Quiz]
+		if !playerOK || !quizOK {
+			skipped++
+			continue
+		}
+
+		game.Player, game.Quiz = player, quiz
+		if game.Finished.IsZero() {
+			game.Finished = now
+		}
+
+		if err := ins.insertGame(ctx, game); err != nil {
+			return 0, fmt.Errorf("game %d of the import: %w", i+1, err)
+		}
+	}
+
+	return skipped, nil
+}
+
+// ImportModel imports players, quizes and games into Postgres.
+type ImportModel struct {
+	DB       *sql.DB
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+	Timeout  time.Duration
+}
+
+// Import creates the records of imp in one transaction, so that a failed import leaves nothing
+// behind. It returns the number of games skipped. Each query is limited to the timeout, not the
+// whole import.
+func (m ImportModel) Import(ctx context.Context, imp *Import) (int, error) {
+	ctx, span := startSpan(ctx, "ImportModel.Import")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO imports (digest)
+		VALUES ($1)
+		ON CONFLICT DO NOTHING
+		`
+
+	queryCtx, cancel := queryContext(ctx, m.Timeout)
+	result, err := tx.ExecContext(queryCtx, query, imp.Digest)
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if rows == 0 {
+		return 0, ErrDuplicateImport
+	}
+
+	skipped, err := importRecords(ctx, pgImporter{tx: tx, timeout: m.Timeout}, imp)
+	if err != nil {
+		return 0, err
+	}
+
+	return skipped, tx.Commit()
+}
+
+// pgImporter inserts the records of an import in a Postgres transaction.
+type pgImporter struct {
+	tx      *sql.Tx
+	timeout time.Duration
+}
+
+func (i pgImporter) insertPlayer(ctx context.Context, player *Player) error {
+	query := `
+		INSERT INTO players (name, joined, last_update, score, owner_id)
+		VALUES ($1, $2, $3, $4, (SELECT id FROM users WHERE id = $5))
+		RETURNING id, joined, last_update, owner_id
+		`
+
+	ctx, cancel := queryContext(ctx, i.timeout)
+	defer cancel()
+
+	args := []interface{}{player.Name, player.Joined, player.LastUpdate, player.Score, player.OwnerID}
+	return i.tx.QueryRowContext(ctx, query, args...).Scan(&player.Id, &player.Joined, &player.LastUpdate, &player.OwnerID)
+}
+
+func (i pgImporter) insertQuiz(ctx context.Context, quiz *Quiz) error {
+	query := `
+		INSERT INTO quizes (category, reward, questions, answers, owner_id)
+		VALUES ($1, $2, $3, $4, (SELECT id FROM users WHERE id = $5))
+		RETURNING id, owner_id
+		`
+
+	ctx, cancel := queryContext(ctx, i.timeout)
+	defer cancel()
+
+	args := []interface{}{quiz.Category, quiz.Reward, pq.Array(quiz.Questions), pq.Array(quiz.Answers), quiz.OwnerID}
+	return i.tx.QueryRowContext(ctx, query, args...).Scan(&quiz.Id, &quiz.OwnerID)
+}
+
+func (i pgImporter) insertGame(ctx context.Context, game *Game) error {
+	query := `
+		INSERT INTO games (finished, player, quiz, in_progress)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, finished
+		`
+
+	ctx, cancel := queryContext(ctx, i.timeout)
+	defer cancel()
+
+	return i.tx.QueryRowContext(ctx, query, game.Finished, game.Player, game.Quiz, game.InProgress).Scan(&game.Id, &game.Finished)
+}
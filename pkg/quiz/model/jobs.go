@@ -0,0 +1,259 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+type (
+	// JobRun is a record of a single run of a periodic job. Affected counts the rows the job
+	// changed.
+	JobRun struct {
+		ID         int64     `json:"id"`
+		Job        string    `json:"job"`
+		StartedAt  time.Time `json:"started_at"`
+		DurationMS int64     `json:"duration_ms"`
+		Succeeded  bool      `json:"succeeded"`
+		Affected   int64     `json:"affected"`
+		Error      string    `json:"error,omitempty"`
+	}
+
+	// JobRunModel struct wraps a sql.DB connection pool and allows us to work with the job_runs
+	// table.
+	JobRunModel struct {
+		DB       *sql.DB
+		InfoLog  *log.Logger
+		ErrorLog *log.Logger
+		Timeout  time.Duration
+	}
+
+	// LeaderboardEntry is the rank of a player on the leaderboard, as of RefreshedAt.
+	LeaderboardEntry struct {
+		Rank        int       `json:"rank"`
+		PlayerID    int64     `json:"player_id"`
+		Name        string    `json:"name"`
+		Score       int       `json:"score"`
+		Games       int       `json:"games"`
+		RefreshedAt time.Time `json:"refreshed_at"`
+	}
+
+	// LeaderboardModel struct wraps a sql.DB connection pool and allows us to work with the
+	// leaderboard table.
+	LeaderboardModel struct {
+		DB       *sql.DB
+		InfoLog  *log.Logger
+		ErrorLog *log.Logger
+		Timeout  time.Duration
+	}
+)
+
+// Insert records a job run.
+func (m JobRunModel) Insert(ctx context.Context, run *JobRun) error {
+	ctx, span := startSpan(ctx, "JobRunModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO job_runs (job, started_at, duration_ms, succeeded, affected, error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+		`
+
+	args := []interface{}{run.Job, run.StartedAt, run.DurationMS, run.Succeeded, run.Affected, run.Error}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&run.ID)
+}
+
+// LastSucceeded returns the start of the last successful run of job, or the zero time if it
+// never succeeded.
+func (m JobRunModel) LastSucceeded(ctx context.Context, job string) (time.Time, error) {
+	ctx, span := startSpan(ctx, "JobRunModel.LastSucceeded")
+	defer span.End()
+
+	query := `
+		SELECT max(started_at)
+		FROM job_runs
+		WHERE job = $1 AND succeeded
+		`
+
+	var last sql.NullTime
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, job).Scan(&last)
+	return last.Time, err
+}
+
+// GetAll returns a page of job runs, optionally only the ones of a job.
+func (m JobRunModel) GetAll(ctx context.Context, job string, filters Filters) ([]*JobRun, Metadata, error) {
+	ctx, span := startSpan(ctx, "JobRunModel.GetAll")
+	defer span.End()
+
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), id, job, started_at, duration_ms, succeeded, affected, error
+		FROM job_runs
+		WHERE (job = $1 OR $1 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3
+		`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, job, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	return scanJobRuns(rows, filters)
+}
+
+func scanJobRuns(rows *sql.Rows, filters Filters) ([]*JobRun, Metadata, error) {
+	totalRecords := 0
+
+	var runs []*JobRun
+	for rows.Next() {
+		var run JobRun
+		err := rows.Scan(
+			&totalRecords,
+			&run.ID,
+			&run.Job,
+			&run.StartedAt,
+			&run.DurationMS,
+			&run.Succeeded,
+			&run.Affected,
+			&run.Error,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		runs = append(runs, &run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return runs, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// refreshLeaderboardQuery ranks the players by their score. Players with the same score share
+// their rank. Only finished games are counted.
+const refreshLeaderboardQuery = `
+	INSERT INTO leaderboard (player_id, rank, name, score, games)
+	SELECT players.id, rank() OVER (ORDER BY players.score DESC), players.name, players.score, count(games.id)
+	FROM players
+		LEFT JOIN games ON games.player = players.id AND NOT games.in_progress
+	GROUP BY players.id, players.name, players.score
+	`
+
+// Refresh ranks all players again and returns their number. Readers see the previous ranking
+// until the new one is complete.
+func (m LeaderboardModel) Refresh(ctx context.Context) (int64, error) {
+	ctx, span := startSpan(ctx, "LeaderboardModel.Refresh")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return refreshLeaderboard(ctx, m.DB)
+}
+
+func refreshLeaderboard(ctx context.Context, db *sql.DB) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM leaderboard`); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, refreshLeaderboardQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	players, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return players, tx.Commit()
+}
+
+// GetAll returns a page of the leaderboard.
+func (m LeaderboardModel) GetAll(ctx context.Context, filters Filters) ([]*LeaderboardEntry, Metadata, error) {
+	ctx, span := startSpan(ctx, "LeaderboardModel.GetAll")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, leaderboardQuery(filters), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	return scanLeaderboard(rows, filters)
+}
+
+func leaderboardQuery(filters Filters) string {
+	return fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), rank, player_id, name, score, games, refreshed_at
+		FROM leaderboard
+		ORDER BY %s %s, player_id ASC
+		LIMIT $1 OFFSET $2
+		`,
+		filters.sortColumn(), filters.sortDirection())
+}
+
+func scanLeaderboard(rows *sql.Rows, filters Filters) ([]*LeaderboardEntry, Metadata, error) {
+	totalRecords := 0
+
+	var entries []*LeaderboardEntry
+	for rows.Next() {
+		var entry LeaderboardEntry
+		err := rows.Scan(
+			&totalRecords,
+			&entry.Rank,
+			&entry.PlayerID,
+			&entry.Name,
+			&entry.Score,
+			&entry.Games,
+			&entry.RefreshedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return entries, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
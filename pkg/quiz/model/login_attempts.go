@@ -0,0 +1,135 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+type (
+	// LoginAttempt is an audit record of a single login attempt. UserID is nil when the email
+	// address doesn't belong to any user.
+	LoginAttempt struct {
+		ID        int64     `json:"id"`
+		Email     string    `json:"email"`
+		IP        string    `json:"ip"`
+		UserID    *int64    `json:"user_id,omitempty"`
+		Succeeded bool      `json:"succeeded"`
+		Reason    string    `json:"reason,omitempty"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// LoginAttemptModel struct wraps a sql.DB connection pool and allows us to work with the
+	// login_attempts table.
+	LoginAttemptModel struct {
+		DB       *sql.DB
+		InfoLog  *log.Logger
+		ErrorLog *log.Logger
+		Timeout  time.Duration
+	}
+)
+
+// Insert records a login attempt.
+func (m LoginAttemptModel) Insert(ctx context.Context, attempt *LoginAttempt) error {
+	ctx, span := startSpan(ctx, "LoginAttemptModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO login_attempts (email, ip, user_id, succeeded, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+		`
+
+	args := []interface{}{attempt.Email, attempt.IP, attempt.UserID, attempt.Succeeded, attempt.Reason}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&attempt.ID, &attempt.CreatedAt)
+}
+
+// FailuresForIP returns the number of failed login attempts from an IP address since the given
+// time, and the time of the last one.
+func (m LoginAttemptModel) FailuresForIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error) {
+	ctx, span := startSpan(ctx, "LoginAttemptModel.FailuresForIP")
+	defer span.End()
+
+	query := `
+		SELECT count(*), COALESCE(max(created_at), $2)
+		FROM login_attempts
+		WHERE ip = $1 AND NOT succeeded AND created_at > $2
+		`
+
+	var (
+		count int
+		last  time.Time
+	)
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, ip, since).Scan(&count, &last)
+	return count, last, err
+}
+
+// GetAll returns a page of login attempts, optionally filtered by email address and IP address.
+func (m LoginAttemptModel) GetAll(ctx context.Context, email, ip string, filters Filters) ([]*LoginAttempt, Metadata, error) {
+	ctx, span := startSpan(ctx, "LoginAttemptModel.GetAll")
+	defer span.End()
+
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), id, email, ip, user_id, succeeded, reason, created_at
+		FROM login_attempts
+		WHERE (email = $1 OR $1 = '')
+		AND (ip = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4
+		`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, email, ip, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	totalRecords := 0
+
+	var attempts []*LoginAttempt
+	for rows.Next() {
+		var attempt LoginAttempt
+		err := rows.Scan(
+			&totalRecords,
+			&attempt.ID,
+			&attempt.Email,
+			&attempt.IP,
+			&attempt.UserID,
+			&attempt.Succeeded,
+			&attempt.Reason,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		attempts = append(attempts, &attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return attempts, metadata, nil
+}
@@ -0,0 +1,1148 @@
+package model
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryPermissions are the permission codes that the migrations create.
+var memoryPermissions = Permissions{
+	"*",
+	"game:*", "game:delete", "game:play", "game:read",
+	"player:*", "player:create", "player:delete", "player:read", "player:write",
+	"quiz:*", "quiz:create", "quiz:delete", "quiz:read", "quiz:write",
+	"user:*", "user:read", "user:write",
+}
+
+// memoryDB holds the records of the in-memory stores. The stores share it like the models share
+// the database, so that deleting a player deletes their games and users are found by their tokens.
+type memoryDB struct {
+	mu sync.Mutex
+
+	lastID          map[string]int64
+	players         map[int]*Player
+	quizes          map[int]*Quiz
+	games           map[int]*Game
+	users           map[int64]*User
+	tokens          map[string]*Token
+	userPermissions map[int64]map[string]bool
+	userRoles       map[int64]map[string]bool
+	apiKeys         map[int64]*APIKey
+	identities      map[int64]*Identity
+	loginStates     map[string]*LoginState
+	totp            map[int64]*TOTP
+	recoveryCodes   map[int64]map[[32]byte]bool
+	loginAttempts   map[int64]*LoginAttempt
+	jobRuns         map[int64]*JobRun
+	leaderboard     map[int64]*LeaderboardEntry
+	webhooks        map[int64]*Webhook
+	deliveries      map[int64]*WebhookDelivery
+	imports         map[string]time.Time
+}
+
+// NewMemoryModels returns models which keep all records in memory, for tests and for running
+// handlers without Postgres. They follow the Postgres models closely: the same errors for missing
+// records, conflicts and duplicates, the same pagination and sorting, and timestamps with a
+// precision of one second. The roles and permissions are the ones the migrations create.
+func NewMemoryModels() Models {
+	db := &memoryDB{
+		lastID:          make(map[string]int64),
+		players:         make(map[int]*Player),
+		quizes:          make(map[int]*Quiz),
+		games:           make(map[int]*Game),
+		users:           make(map[int64]*User),
+		tokens:          make(map[string]*Token),
+		userPermissions: make(map[int64]map[string]bool),
+		userRoles:       make(map[int64]map[string]bool),
+		apiKeys:         make(map[int64]*APIKey),
+		identities:      make(map[int64]*Identity),
+		loginStates:     make(map[string]*LoginState),
+		totp:            make(map[int64]*TOTP),
+		recoveryCodes:   make(map[int64]map[[32]byte]bool),
+		loginAttempts:   make(map[int64]*LoginAttempt),
+		jobRuns:         make(map[int64]*JobRun),
+		leaderboard:     make(map[int64]*LeaderboardEntry),
+		webhooks:        make(map[int64]*Webhook),
+		deliveries:      make(map[int64]*WebhookDelivery),
+		imports:         make(map[string]time.Time),
+	}
+
+	return Models{
+		Players:       memoryPlayers{db},
+		Quizes:        memoryQuizes{db},
+		Games:         memoryGames{db},
+		Users:         memoryUsers{db},
+		Tokens:        memoryTokens{db},
+		Permissions:   memoryPermissionStore{db},
+		Roles:         memoryRoleStore{db},
+		APIKeys:       memoryAPIKeys{db},
+		Identities:    memoryIdentities{db},
+		TwoFactor:     memoryTwoFactor{db},
+		LoginAttempts: memoryLoginAttempts{db},
+		JobRuns:       memoryJobRuns{db},
+		Leaderboard:   memoryLeaderboard{db},
+		Webhooks:      memoryWebhooks{db},
+		Imports:       memoryImports{db},
+	}
+}
+
+// lock locks the records, unless ctx is already done, like a query would fail.
+func (db *memoryDB) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	return nil
+}
+
+// nextID returns the next id of a table, like a bigserial column.
+func (db *memoryDB) nextID(table string) int64 {
+	db.lastID[table]++
+	return db.lastID[table]
+}
+
+// memoryNow returns the current time with the precision of the timestamp(0) columns.
+func memoryNow() time.Time {
+	return time.Now().UTC().Round(time.Second)
+}
+
+// memoryTime returns a copy of a nullable time with the precision of the timestamp(0) columns.
+func memoryTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	rounded := t.Round(time.Second)
+	return &rounded
+}
+
+// timestampLayouts are the layouts in which the timestamps of cursors are parsed, by the stores
+// that compare timestamps themselves rather than leaving it to Postgres.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05Z07",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid input syntax for type timestamp with time zone: %q", s)
+}
+
+// parseMemoryID parses an id kept in a string field, like Postgres casting it to bigint.
+func parseMemoryID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid input syntax for type bigint: %q", s)
+	}
+
+	return id, nil
+}
+
+func foreignKeyError(table, constraint string) error {
+	return fmt.Errorf("insert or update on table %q violates foreign key constraint %q", table, constraint)
+}
+
+// recordOrder returns the order of records sorted by the sort column of filters, then by id
+// ascending, like the ORDER BY clauses of the models. compare compares two records by a column.
+func recordOrder[T any](filters Filters, compare func(a, b T, column string) int) func(a, b T) int {
+	column := filters.sortColumn()
+	desc := filters.sortDirection() == "DESC"
+
+	return func(a, b T) int {
+		c := compare(a, b, column)
+		if desc {
+			c = -c
+		}
+		if c == 0 {
+			c = compare(a, b, "id")
+		}
+		return c
+	}
+}
+
+// memoryList sorts records and returns the ones the query of Filters.listQuery would select,
+// with the total number of records, which like the count(*) OVER() of the models is 0 if none
+// is selected. probe returns a record with the id and sort value of a cursor, to compare the
+// records with it.
+func memoryList[T any](records []T, filters Filters, compare func(a, b T, column string) int, probe func(c cursor) T) ([]T, int) {
+	order := recordOrder(filters, compare)
+	slices.SortStableFunc(records, order)
+	total := len(records)
+
+	if c, before, ok := filters.cursor(); ok {
+		p := probe(c)
+
+		var selected []T
+		for _, record := range records {
+			if o := order(record, p); (before && o < 0) || (!before && o > 0) {
+				selected = append(selected, record)
+			}
+		}
+		if before {
+			slices.Reverse(selected)
+		}
+		records = selected
+	}
+
+	start := min(filters.listOffset(), len(records))
+	end := min(start+filters.limit()+1, len(records))
+
+	if start == end {
+		return nil, 0
+	}
+
+	return records[start:end], total
+}
+
+// memoryPage returns the page of sorted records which LIMIT and OFFSET select, for the lists
+// without cursors. Like count(*) OVER(), the total is 0 when the page is past the last one.
+func memoryPage[T any](records []T, filters Filters) ([]T, Metadata) {
+	total := len(records)
+	start := min(filters.offset(), total)
+	end := min(start+filters.limit(), total)
+	if start == end {
+		return nil, calculateMetadata(0, filters.Page, filters.PageSize)
+	}
+
+	return records[start:end], calculateMetadata(total, filters.Page, filters.PageSize)
+}
+
+// memoryCursorID returns the id of a cursor as the one of a record.
+func memoryCursorID(c cursor) string {
+	return strconv.FormatInt(c.ID, 10)
+}
+
+type memoryPlayers struct {
+	db *memoryDB
+}
+
+func (m memoryPlayers) GetAll(ctx context.Context, name string, from, to int, joined TimeRange, filters Filters) ([]*Player, Metadata, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.db.mu.Unlock()
+
+	var players []*Player
+	for _, player := range m.db.players {
+		if (name == "" || strings.EqualFold(player.Name, name)) &&
+			(from == 0 || player.Score >= from) &&
+			(to == 0 || player.Score <= to) &&
+			joined.Contains(player.Joined) {
+			p := *player
+			players = append(players, &p)
+		}
+	}
+
+	players, total := memoryList(players, filters, func(a, b *Player, column string) int {
+		switch column {
+		case "name":
+			return cmp.Compare(a.Name, b.Name)
+		case "score":
+			return cmp.Compare(a.Score, b.Score)
+		case "joined":
+			return a.Joined.Compare(b.Joined)
+		default:
+			ida, _ := strconv.Atoi(a.Id)
+			idb, _ := strconv.Atoi(b.Id)
+			return cmp.Compare(ida, idb)
+		}
+	}, func(c cursor) *Player {
+		score, _ := strconv.Atoi(c.Value)
+		joined, _ := parseTimestamp(c.Value)
+		return &Player{Id: memoryCursorID(c), Name: c.Value, Joined: joined, Score: score}
+	})
+
+	players, metadata := pageOf(players, total, filters, playerCursor(filters))
+	return players, metadata, nil
+}
+
+func (m memoryPlayers) Insert(ctx context.Context, player *Player) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	id := m.db.nextID("players")
+	now := memoryNow()
+
+	player.Id = strconv.FormatInt(id, 10)
+	player.Joined = now
+	player.LastUpdate = now
+	player.Score = 0
+
+	p := *player
+	if player.OwnerID != nil {
+		ownerID := *player.OwnerID
+		p.OwnerID = &ownerID
+	}
+	m.db.players[int(id)] = &p
+
+	return nil
+}
+
+func (m memoryPlayers) Get(ctx context.Context, id int) (*Player, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	player, ok := m.db.players[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	p := *player
+	return &p, nil
+}
+
+func (m memoryPlayers) GetByIDs(ctx context.Context, ids []int) ([]*Player, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	var players []*Player
+	for id, player := range m.db.players {
+		if slices.Contains(ids, id) {
+			p := *player
+			players = append(players, &p)
+		}
+	}
+
+	return players, nil
+}
+
+func (m memoryPlayers) Update(ctx context.Context, player *Player) error {
+	id, err := parseMemoryID(player.Id)
+	if err != nil {
+		return err
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.players[id]
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	stored.Name = player.Name
+	stored.Score = player.Score
+	stored.LastUpdate = memoryNow()
+	player.LastUpdate = stored.LastUpdate
+
+	return nil
+}
+
+func (m memoryPlayers) Delete(ctx context.Context, id int) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.players[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.db.players, id)
+
+	// ON DELETE CASCADE
+	for gameID, game := range m.db.games {
+		if game.Player == id {
+			delete(m.db.games, gameID)
+		}
+	}
+	delete(m.db.leaderboard, int64(id))
+
+	return nil
+}
+
+func (m memoryPlayers) GetOwner(ctx context.Context, id int) (int64, error) {
+	if id < 1 {
+		return 0, ErrRecordNotFound
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.db.mu.Unlock()
+
+	player, ok := m.db.players[id]
+	if !ok {
+		return 0, ErrRecordNotFound
+	}
+	if player.OwnerID == nil {
+		return 0, nil
+	}
+
+	return *player.OwnerID, nil
+}
+
+type memoryQuizes struct {
+	db *memoryDB
+}
+
+func copyQuiz(quiz *Quiz) *Quiz {
+	q := *quiz
+	q.Questions = slices.Clone(quiz.Questions)
+	q.Answers = slices.Clone(quiz.Answers)
+	if quiz.OwnerID != nil {
+		ownerID := *quiz.OwnerID
+		q.OwnerID = &ownerID
+	}
+
+	return &q
+}
+
+func (m memoryQuizes) GetAll(ctx context.Context, category string, from, to int, filters Filters) ([]*Quiz, Metadata, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.db.mu.Unlock()
+
+	var quizes []*Quiz
+	for _, quiz := range m.db.quizes {
+		if (category == "" || strings.EqualFold(quiz.Category, category)) &&
+			(from == 0 || quiz.Reward >= from) &&
+			(to == 0 || quiz.Reward <= to) {
+			quizes = append(quizes, copyQuiz(quiz))
+		}
+	}
+
+	quizes, total := memoryList(quizes, filters, func(a, b *Quiz, column string) int {
+		switch column {
+		case "category":
+			return cmp.Compare(a.Category, b.Category)
+		case "reward":
+			return cmp.Compare(a.Reward, b.Reward)
+		default:
+			ida, _ := strconv.Atoi(a.Id)
+			idb, _ := strconv.Atoi(b.Id)
+			return cmp.Compare(ida, idb)
+		}
+	}, func(c cursor) *Quiz {
+		reward, _ := strconv.Atoi(c.Value)
+		return &Quiz{Id: memoryCursorID(c), Category: c.Value, Reward: reward}
+	})
+
+	quizes, metadata := pageOf(quizes, total, filters, quizCursor(filters))
+	return quizes, metadata, nil
+}
+
+func (m memoryQuizes) Insert(ctx context.Context, quiz *Quiz) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	id := m.db.nextID("quizes")
+	quiz.Id = strconv.FormatInt(id, 10)
+	m.db.quizes[int(id)] = copyQuiz(quiz)
+
+	return nil
+}
+
+func (m memoryQuizes) Get(ctx context.Context, id int) (*Quiz, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	quiz, ok := m.db.quizes[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyQuiz(quiz), nil
+}
+
+func (m memoryQuizes) GetByIDs(ctx context.Context, ids []int) ([]*Quiz, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	var quizes []*Quiz
+	for id, quiz := range m.db.quizes {
+		if slices.Contains(ids, id) {
+			quizes = append(quizes, copyQuiz(quiz))
+		}
+	}
+
+	return quizes, nil
+}
+
+func (m memoryQuizes) Update(ctx context.Context, quiz *Quiz) error {
+	id, err := parseMemoryID(quiz.Id)
+	if err != nil {
+		return err
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.quizes[id]
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	stored.Category = quiz.Category
+	stored.Reward = quiz.Reward
+	stored.Questions = slices.Clone(quiz.Questions)
+	stored.Answers = slices.Clone(quiz.Answers)
+
+	return nil
+}
+
+func (m memoryQuizes) Delete(ctx context.Context, id int) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.quizes[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.db.quizes, id)
+
+	// ON DELETE CASCADE
+	for gameID, game := range m.db.games {
+		if game.Quiz == id {
+			delete(m.db.games, gameID)
+		}
+	}
+
+	return nil
+}
+
+func (m memoryQuizes) GetOwner(ctx context.Context, id int) (int64, error) {
+	if id < 1 {
+		return 0, ErrRecordNotFound
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.db.mu.Unlock()
+
+	quiz, ok := m.db.quizes[id]
+	if !ok {
+		return 0, ErrRecordNotFound
+	}
+	if quiz.OwnerID == nil {
+		return 0, nil
+	}
+
+	return *quiz.OwnerID, nil
+}
+
+type memoryGames struct {
+	db *memoryDB
+}
+
+func (m memoryGames) GetAll(ctx context.Context, player, quiz int, finishedOnly bool, finished TimeRange, filters Filters) ([]*Game, Metadata, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.db.mu.Unlock()
+
+	finishedOnly = finishedOnly || !finished.IsZero()
+
+	var games []*Game
+	for _, game := range m.db.games {
+		if (player == 0 || game.Player == player) &&
+			(quiz == 0 || game.Quiz == quiz) &&
+			!(finishedOnly && game.InProgress) &&
+			finished.Contains(game.Finished) {
+			g := *game
+			games = append(games, &g)
+		}
+	}
+
+	games, total := memoryList(games, filters, func(a, b *Game, column string) int {
+		switch column {
+		case "finished":
+			return a.Finished.Compare(b.Finished)
+		case "player":
+			return cmp.Compare(a.Player, b.Player)
+		case "quiz":
+			return cmp.Compare(a.Quiz, b.Quiz)
+		default:
+			ida, _ := strconv.Atoi(a.Id)
+			idb, _ := strconv.Atoi(b.Id)
+			return cmp.Compare(ida, idb)
+		}
+	}, func(c cursor) *Game {
+		number, _ := strconv.Atoi(c.Value)
+		finished, _ := parseTimestamp(c.Value)
+		return &Game{Id: memoryCursorID(c), Finished: finished, Player: number, Quiz: number}
+	})
+
+	games, metadata := pageOf(games, total, filters, gameCursor(filters))
+	return games, metadata, nil
+}
+
+// Insert records a game. Like GameModel.Insert it doesn't set game.Finished.
+func (m memoryGames) Insert(ctx context.Context, game *Game) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	return m.insert(game)
+}
+
+// insert inserts a game, with the lock of the database held.
+func (m memoryGames) insert(game *Game) error {
+	if _, ok := m.db.players[game.Player]; !ok {
+		return foreignKeyError("games", "games_player_fkey")
+	}
+	if _, ok := m.db.quizes[game.Quiz]; !ok {
+		return foreignKeyError("games", "games_quiz_fkey")
+	}
+
+	id := m.db.nextID("games")
+	game.Id = strconv.FormatInt(id, 10)
+
+	g := *game
+	g.Finished = memoryNow()
+	m.db.games[int(id)] = &g
+
+	return nil
+}
+
+func (m memoryGames) Get(ctx context.Context, id int) (*Game, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	game, ok := m.db.games[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	g := *game
+	return &g, nil
+}
+
+func (m memoryGames) Delete(ctx context.Context, id int) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.games[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.db.games, id)
+	return nil
+}
+
+func (m memoryGames) GetForPlayers(ctx context.Context, players []int) ([]*Game, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	var games []*Game
+	for _, game := range m.db.games {
+		if slices.Contains(players, game.Player) && !game.InProgress {
+			g := *game
+			games = append(games, &g)
+		}
+	}
+
+	slices.SortFunc(games, func(a, b *Game) int {
+		if c := b.Finished.Compare(a.Finished); c != 0 {
+			return c
+		}
+		ida, _ := strconv.Atoi(a.Id)
+		idb, _ := strconv.Atoi(b.Id)
+		return cmp.Compare(idb, ida)
+	})
+
+	return games, nil
+}
+
+func (m memoryGames) Finish(ctx context.Context, game *Game) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	game.InProgress = false
+
+	oldest := 0
+	for id, g := range m.db.games {
+		if g.Player == game.Player && g.Quiz == game.Quiz && g.InProgress && (oldest == 0 || id < oldest) {
+			oldest = id
+		}
+	}
+
+	if oldest == 0 {
+		return m.insert(game)
+	}
+
+	g := m.db.games[oldest]
+	g.InProgress = false
+	g.Finished = memoryNow()
+
+	game.Id = g.Id
+	game.Finished = g.Finished
+
+	return nil
+}
+
+func (m memoryGames) DeleteAbandoned(ctx context.Context, startedBefore time.Time) (int64, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.db.mu.Unlock()
+
+	var deleted int64
+	for id, game := range m.db.games {
+		if game.InProgress && game.Finished.Before(startedBefore) {
+			delete(m.db.games, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+type memoryUsers struct {
+	db *memoryDB
+}
+
+// copyUser returns a copy of a user as it is read from the users table, without the plaintext
+// password.
+func copyUser(user *User) *User {
+	u := *user
+	u.Password = password{hash: user.Password.hash}
+	if user.LockedUntil != nil {
+		lockedUntil := *user.LockedUntil
+		u.LockedUntil = &lockedUntil
+	}
+
+	return &u
+}
+
+// emailTaken reports whether another user than id has the email address, which like the citext
+// column is compared case-insensitively.
+func (m memoryUsers) emailTaken(email string, id int64) bool {
+	for _, user := range m.db.users {
+		if user.ID != id && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m memoryUsers) Insert(ctx context.Context, user *User) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if m.emailTaken(user.Email, 0) {
+		return ErrDuplicateEmail
+	}
+
+	user.ID = m.db.nextID("users")
+	user.CreatedAt = memoryNow()
+	user.Version = 1
+
+	m.db.users[user.ID] = copyUser(user)
+
+	return nil
+}
+
+func (m memoryUsers) GetByEmail(ctx context.Context, email string) (*User, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	for _, user := range m.db.users {
+		if strings.EqualFold(user.Email, email) {
+			return copyUser(user), nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m memoryUsers) Get(ctx context.Context, id int64) (*User, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	user, ok := m.db.users[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyUser(user), nil
+}
+
+func (m memoryUsers) GetByIDs(ctx context.Context, ids []int64) ([]*User, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	var users []*User
+	for id, user := range m.db.users {
+		if slices.Contains(ids, id) {
+			users = append(users, copyUser(user))
+		}
+	}
+
+	return users, nil
+}
+
+func (m memoryUsers) GetAll(ctx context.Context, email string, filters Filters) ([]*User, Metadata, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.db.mu.Unlock()
+
+	var users []*User
+	for _, user := range m.db.users {
+		if email == "" || strings.EqualFold(user.Email, email) {
+			users = append(users, copyUser(user))
+		}
+	}
+
+	slices.SortStableFunc(users, recordOrder(filters, func(a, b *User, column string) int {
+		switch column {
+		case "name":
+			return cmp.Compare(a.Name, b.Name)
+		case "email":
+			return cmp.Compare(a.Email, b.Email)
+		case "created_at":
+			return a.CreatedAt.Compare(b.CreatedAt)
+		default:
+			return cmp.Compare(a.ID, b.ID)
+		}
+	}))
+
+	users, metadata := memoryPage(users, filters)
+	return users, metadata, nil
+}
+
+func (m memoryUsers) Update(ctx context.Context, user *User) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if m.emailTaken(user.Email, user.ID) {
+		return ErrDuplicateEmail
+	}
+
+	stored, ok := m.db.users[user.ID]
+	if !ok || stored.Version != user.Version {
+		return ErrEditConflict
+	}
+
+	stored.Name = user.Name
+	stored.Email = user.Email
+	stored.Password = password{hash: user.Password.hash}
+	stored.Activated = user.Activated
+	stored.Version++
+	user.Version = stored.Version
+
+	return nil
+}
+
+func (m memoryUsers) RecordFailedLogin(ctx context.Context, id int64) (int, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.db.mu.Unlock()
+
+	user, ok := m.db.users[id]
+	if !ok {
+		return 0, ErrRecordNotFound
+	}
+
+	user.FailedLogins++
+	return user.FailedLogins, nil
+}
+
+func (m memoryUsers) Lock(ctx context.Context, id int64, until time.Time) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if user, ok := m.db.users[id]; ok {
+		lockedUntil := until.Round(time.Second)
+		user.LockedUntil = &lockedUntil
+	}
+
+	return nil
+}
+
+func (m memoryUsers) Unlock(ctx context.Context, id int64) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	user, ok := m.db.users[id]
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+
+	return nil
+}
+
+func (m memoryUsers) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	token, ok := m.db.tokens[string(tokenHash[:])]
+	if !ok || token.Scope != tokenScope || !token.Expiry.After(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+
+	user, ok := m.db.users[token.UserID]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyUser(user), nil
+}
+
+type memoryTokens struct {
+	db *memoryDB
+}
+
+func (m memoryTokens) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(ctx, token)
+	return token, err
+}
+
+func (m memoryTokens) Insert(ctx context.Context, token *Token) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.users[token.UserID]; !ok {
+		return foreignKeyError("tokens", "tokens_user_id_fkey")
+	}
+
+	key := string(token.Hash)
+	if _, ok := m.db.tokens[key]; ok {
+		return fmt.Errorf("duplicate key value violates unique constraint %q", "tokens_pkey")
+	}
+
+	t := *token
+	t.Plaintext = ""
+	t.Hash = slices.Clone(token.Hash)
+	t.Expiry = token.Expiry.Round(time.Second)
+	m.db.tokens[key] = &t
+
+	return nil
+}
+
+func (m memoryTokens) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	for key, token := range m.db.tokens {
+		if token.Scope == scope && token.UserID == userID {
+			delete(m.db.tokens, key)
+		}
+	}
+
+	return nil
+}
+
+func (m memoryTokens) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.db.mu.Unlock()
+
+	var deleted int64
+	for key, token := range m.db.tokens {
+		if token.Expiry.Before(before) {
+			delete(m.db.tokens, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+type memoryPermissionStore struct {
+	db *memoryDB
+}
+
+func (m memoryPermissionStore) GetAll(ctx context.Context) (Permissions, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return slices.Clone(memoryPermissions), nil
+}
+
+func (m memoryPermissionStore) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	permissions := Permissions{}
+	for code := range m.db.userPermissions[userID] {
+		permissions = append(permissions, code)
+	}
+	for _, role := range memoryRoles {
+		if m.db.userRoles[userID][role.Code] {
+			permissions = append(permissions, role.Permissions...)
+		}
+	}
+	slices.Sort(permissions)
+
+	// UNION
+	return slices.Compact(permissions), nil
+}
+
+func (m memoryPermissionStore) GetDirectForUser(ctx context.Context, userID int64) (Permissions, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	permissions := Permissions{}
+	for code := range m.db.userPermissions[userID] {
+		permissions = append(permissions, code)
+	}
+	slices.Sort(permissions)
+
+	return permissions, nil
+}
+
+func (m memoryPermissionStore) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	for _, code := range codes {
+		// Unknown codes are skipped, like the INSERT ... SELECT finds no permission for them.
+		if !slices.Contains(memoryPermissions, code) {
+			continue
+		}
+
+		if _, ok := m.db.users[userID]; !ok {
+			return foreignKeyError("users_permissions", "users_permissions_user_id_fkey")
+		}
+
+		if m.db.userPermissions[userID] == nil {
+			m.db.userPermissions[userID] = make(map[string]bool)
+		}
+		m.db.userPermissions[userID][code] = true
+	}
+
+	return nil
+}
+
+func (m memoryPermissionStore) RemoveForUser(ctx context.Context, userID int64, code string) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if !m.db.userPermissions[userID][code] {
+		return ErrRecordNotFound
+	}
+
+	delete(m.db.userPermissions[userID], code)
+	return nil
+}
+
+var (
+	_ PlayerStore       = memoryPlayers{}
+	_ QuizStore         = memoryQuizes{}
+	_ GameStore         = memoryGames{}
+	_ UserStore         = memoryUsers{}
+	_ TokenStore        = memoryTokens{}
+	_ PermissionStore   = memoryPermissionStore{}
+	_ RoleStore         = memoryRoleStore{}
+	_ APIKeyStore       = memoryAPIKeys{}
+	_ IdentityStore     = memoryIdentities{}
+	_ TwoFactorStore    = memoryTwoFactor{}
+	_ LoginAttemptStore = memoryLoginAttempts{}
+	_ JobRunStore       = memoryJobRuns{}
+	_ LeaderboardStore  = memoryLeaderboard{}
+	_ WebhookStore      = memoryWebhooks{}
+)
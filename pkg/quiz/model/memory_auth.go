@@ -0,0 +1,566 @@
+package model
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"slices"
+	"strings"
+	"time"
+)
+
+// memoryRoles are the roles that the migrations create, with their permissions sorted by code.
+var memoryRoles = []*Role{
+	{
+		ID:          1,
+		Code:        "player",
+		Description: "Plays quizzes",
+		Permissions: Permissions{"game:play", "game:read", "player:create", "player:read", "quiz:read"},
+	},
+	{
+		ID:          2,
+		Code:        "author",
+		Description: "Plays and creates quizzes",
+		Permissions: Permissions{"game:play", "game:read", "player:create", "player:read", "quiz:create", "quiz:read"},
+	},
+	{
+		ID:          3,
+		Code:        "moderator",
+		Description: "Manages players, quizzes and games",
+		Permissions: Permissions{"game:*", "player:*", "quiz:*"},
+	},
+	{
+		ID:          4,
+		Code:        "admin",
+		Description: "Manages everything, including users and their permissions",
+		Permissions: Permissions{"*"},
+	},
+}
+
+type memoryRoleStore struct {
+	db *memoryDB
+}
+
+func (m memoryRoleStore) GetAll(ctx context.Context) ([]*Role, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	roles := make([]*Role, 0, len(memoryRoles))
+	for _, role := range memoryRoles {
+		r := *role
+		r.Permissions = slices.Clone(role.Permissions)
+		roles = append(roles, &r)
+	}
+
+	return roles, nil
+}
+
+func (m memoryRoleStore) Exists(ctx context.Context, code string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(memoryRoles, func(role *Role) bool { return role.Code == code }), nil
+}
+
+func (m memoryRoleStore) GetAllForUser(ctx context.Context, userID int64) ([]string, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	roles := []string{}
+	for _, role := range memoryRoles {
+		if m.db.userRoles[userID][role.Code] {
+			roles = append(roles, role.Code)
+		}
+	}
+
+	return roles, nil
+}
+
+func (m memoryRoleStore) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	for _, code := range codes {
+		// Unknown codes are skipped, like the INSERT ... SELECT finds no role for them.
+		if !slices.ContainsFunc(memoryRoles, func(role *Role) bool { return role.Code == code }) {
+			continue
+		}
+
+		if _, ok := m.db.users[userID]; !ok {
+			return foreignKeyError("users_roles", "users_roles_user_id_fkey")
+		}
+
+		if m.db.userRoles[userID] == nil {
+			m.db.userRoles[userID] = make(map[string]bool)
+		}
+		m.db.userRoles[userID][code] = true
+	}
+
+	return nil
+}
+
+func (m memoryRoleStore) RemoveForUser(ctx context.Context, userID int64, code string) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if !m.db.userRoles[userID][code] {
+		return ErrRecordNotFound
+	}
+
+	delete(m.db.userRoles[userID], code)
+	return nil
+}
+
+func (m memoryRoleStore) RemoveForUserUnlessLast(ctx context.Context, userID int64, code string) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if !m.db.userRoles[userID][code] {
+		return ErrRecordNotFound
+	}
+	if m.countUsers(code) <= 1 {
+		return ErrLastRoleHolder
+	}
+
+	delete(m.db.userRoles[userID], code)
+	return nil
+}
+
+func (m memoryRoleStore) CountUsers(ctx context.Context, code string) (int, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.db.mu.Unlock()
+
+	return m.countUsers(code), nil
+}
+
+// countUsers counts the holders of a role, with the lock held.
+func (m memoryRoleStore) countUsers(code string) int {
+	count := 0
+	for _, roles := range m.db.userRoles {
+		if roles[code] {
+			count++
+		}
+	}
+
+	return count
+}
+
+type memoryAPIKeys struct {
+	db *memoryDB
+}
+
+// copyAPIKey returns a copy of an API key as it is read from the api_keys table, without the
+// plaintext.
+func copyAPIKey(key *APIKey) *APIKey {
+	k := *key
+	k.Plaintext = ""
+	k.Hash = slices.Clone(key.Hash)
+	k.Scopes = slices.Clone(key.Scopes)
+	k.Expiry = memoryTime(key.Expiry)
+	k.LastUsedAt = memoryTime(key.LastUsedAt)
+	k.RevokedAt = memoryTime(key.RevokedAt)
+
+	return &k
+}
+
+func (m memoryAPIKeys) New(ctx context.Context, userID int64, name string, scopes Permissions, expiry *time.Time) (*APIKey, error) {
+	key, err := generateAPIKey(userID, name, scopes, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.users[userID]; !ok {
+		return nil, foreignKeyError("api_keys", "api_keys_user_id_fkey")
+	}
+
+	key.ID = m.db.nextID("api_keys")
+	key.CreatedAt = memoryNow()
+	m.db.apiKeys[key.ID] = copyAPIKey(key)
+
+	return key, nil
+}
+
+func (m memoryAPIKeys) GetForKey(ctx context.Context, plaintext string) (*APIKey, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	for _, key := range m.db.apiKeys {
+		if bytes.Equal(key.Hash, hash[:]) && key.RevokedAt == nil && (key.Expiry == nil || key.Expiry.After(time.Now())) {
+			return copyAPIKey(key), nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m memoryAPIKeys) GetAllForUser(ctx context.Context, userID int64) ([]*APIKey, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	keys := []*APIKey{}
+	for _, key := range m.db.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	slices.SortFunc(keys, func(a, b *APIKey) int { return cmp.Compare(a.ID, b.ID) })
+
+	return keys, nil
+}
+
+func (m memoryAPIKeys) Touch(ctx context.Context, key *APIKey) error {
+	if key.LastUsedAt != nil && time.Since(*key.LastUsedAt) < apiKeyTouchInterval {
+		return nil
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if stored, ok := m.db.apiKeys[key.ID]; ok {
+		now := memoryNow()
+		stored.LastUsedAt = &now
+	}
+
+	return nil
+}
+
+func (m memoryAPIKeys) Revoke(ctx context.Context, userID, id int64) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	key, ok := m.db.apiKeys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return ErrRecordNotFound
+	}
+
+	now := memoryNow()
+	key.RevokedAt = &now
+
+	return nil
+}
+
+type memoryIdentities struct {
+	db *memoryDB
+}
+
+func (m memoryIdentities) Insert(ctx context.Context, identity *Identity) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.users[identity.UserID]; !ok {
+		return foreignKeyError("identities", "identities_user_id_fkey")
+	}
+
+	for _, stored := range m.db.identities {
+		if stored.Provider == identity.Provider && stored.Subject == identity.Subject {
+			return ErrDuplicateIdentity
+		}
+	}
+
+	identity.ID = m.db.nextID("identities")
+	identity.CreatedAt = memoryNow()
+
+	i := *identity
+	m.db.identities[identity.ID] = &i
+
+	return nil
+}
+
+func (m memoryIdentities) GetByProviderSubject(ctx context.Context, provider, subject string) (*Identity, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	for _, identity := range m.db.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			i := *identity
+			return &i, nil
+		}
+	}
+
+	return nil, ErrRecordNotFound
+}
+
+func (m memoryIdentities) InsertLoginState(ctx context.Context, state *LoginState) error {
+	hash := sha256.Sum256([]byte(state.Plaintext))
+
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	s := *state
+	s.Plaintext = ""
+	s.Expiry = state.Expiry.Round(time.Second)
+	m.db.loginStates[string(hash[:])] = &s
+
+	return nil
+}
+
+func (m memoryIdentities) ConsumeLoginState(ctx context.Context, provider, plaintext string) (*LoginState, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.loginStates[string(hash[:])]
+	if !ok || stored.Provider != provider {
+		return nil, ErrRecordNotFound
+	}
+
+	delete(m.db.loginStates, string(hash[:]))
+
+	if stored.Expiry.Before(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+
+	state := *stored
+	state.Plaintext = plaintext
+
+	return &state, nil
+}
+
+type memoryTwoFactor struct {
+	db *memoryDB
+}
+
+func (m memoryTwoFactor) Get(ctx context.Context, userID int64) (*TOTP, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	enrollment, ok := m.db.totp[userID]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	e := *enrollment
+	return &e, nil
+}
+
+func (m memoryTwoFactor) Enabled(ctx context.Context, userID int64) (bool, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.db.mu.Unlock()
+
+	enrollment, ok := m.db.totp[userID]
+	return ok && enrollment.Confirmed, nil
+}
+
+func (m memoryTwoFactor) Enroll(ctx context.Context, userID int64, secret string) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.users[userID]; !ok {
+		return foreignKeyError("users_totp", "users_totp_user_id_fkey")
+	}
+
+	// A confirmed enrollment is only replaced after it's deleted, like the ON CONFLICT ... WHERE.
+	if enrollment, ok := m.db.totp[userID]; ok && enrollment.Confirmed {
+		return ErrEditConflict
+	}
+
+	m.db.totp[userID] = &TOTP{UserID: userID, Secret: secret, CreatedAt: memoryNow()}
+
+	return nil
+}
+
+func (m memoryTwoFactor) Confirm(ctx context.Context, userID int64) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if enrollment, ok := m.db.totp[userID]; ok {
+		enrollment.Confirmed = true
+	}
+
+	return nil
+}
+
+func (m memoryTwoFactor) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.db.mu.Unlock()
+
+	enrollment, ok := m.db.totp[userID]
+	if !ok || enrollment.LastUsedStep >= step {
+		return false, nil
+	}
+
+	enrollment.LastUsedStep = step
+	return true, nil
+}
+
+func (m memoryTwoFactor) Delete(ctx context.Context, userID int64) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	delete(m.db.recoveryCodes, userID)
+	delete(m.db.totp, userID)
+
+	return nil
+}
+
+func (m memoryTwoFactor) NewRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.users[userID]; !ok {
+		return nil, foreignKeyError("recovery_codes", "recovery_codes_user_id_fkey")
+	}
+
+	hashes := make(map[[32]byte]bool, len(codes))
+	for _, code := range codes {
+		hashes[hashRecoveryCode(code)] = true
+	}
+	m.db.recoveryCodes[userID] = hashes
+
+	return codes, nil
+}
+
+func (m memoryTwoFactor) UseRecoveryCode(ctx context.Context, userID int64, code string) (bool, error) {
+	hash := hashRecoveryCode(code)
+
+	if err := m.db.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.db.mu.Unlock()
+
+	if !m.db.recoveryCodes[userID][hash] {
+		return false, nil
+	}
+
+	delete(m.db.recoveryCodes[userID], hash)
+	return true, nil
+}
+
+type memoryLoginAttempts struct {
+	db *memoryDB
+}
+
+// copyLoginAttempt returns a copy of a login attempt which doesn't share its user id.
+func copyLoginAttempt(attempt *LoginAttempt) *LoginAttempt {
+	a := *attempt
+	if attempt.UserID != nil {
+		userID := *attempt.UserID
+		a.UserID = &userID
+	}
+
+	return &a
+}
+
+func (m memoryLoginAttempts) Insert(ctx context.Context, attempt *LoginAttempt) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if attempt.UserID != nil {
+		if _, ok := m.db.users[*attempt.UserID]; !ok {
+			return foreignKeyError("login_attempts", "login_attempts_user_id_fkey")
+		}
+	}
+
+	attempt.ID = m.db.nextID("login_attempts")
+	attempt.CreatedAt = memoryNow()
+	m.db.loginAttempts[attempt.ID] = copyLoginAttempt(attempt)
+
+	return nil
+}
+
+func (m memoryLoginAttempts) FailuresForIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return 0, time.Time{}, err
+	}
+	defer m.db.mu.Unlock()
+
+	count, last := 0, since
+	for _, attempt := range m.db.loginAttempts {
+		if attempt.IP == ip && !attempt.Succeeded && attempt.CreatedAt.After(since) {
+			count++
+			if count == 1 || attempt.CreatedAt.After(last) {
+				last = attempt.CreatedAt
+			}
+		}
+	}
+
+	return count, last, nil
+}
+
+func (m memoryLoginAttempts) GetAll(ctx context.Context, email, ip string, filters Filters) ([]*LoginAttempt, Metadata, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.db.mu.Unlock()
+
+	var attempts []*LoginAttempt
+	for _, attempt := range m.db.loginAttempts {
+		if (email == "" || strings.EqualFold(attempt.Email, email)) && (ip == "" || attempt.IP == ip) {
+			attempts = append(attempts, copyLoginAttempt(attempt))
+		}
+	}
+
+	slices.SortStableFunc(attempts, recordOrder(filters, func(a, b *LoginAttempt, column string) int {
+		switch column {
+		case "email":
+			return cmp.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+		case "ip":
+			return cmp.Compare(a.IP, b.IP)
+		case "created_at":
+			return a.CreatedAt.Compare(b.CreatedAt)
+		default:
+			return cmp.Compare(a.ID, b.ID)
+		}
+	}))
+
+	attempts, metadata := memoryPage(attempts, filters)
+	return attempts, metadata, nil
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"context"
+	"strconv"
+)
+
+type memoryImports struct {
+	db *memoryDB
+}
+
+func (m memoryImports) Import(ctx context.Context, imp *Import) (int, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.imports[imp.Digest]; ok {
+		return 0, ErrDuplicateImport
+	}
+
+	// The records are kept aside until the whole import succeeded, like in a transaction.
+	ins := &memoryImporter{
+		db:      m.db,
+		players: make(map[int]*Player),
+		quizes:  make(map[int]*Quiz),
+		games:   make(map[int]*Game),
+	}
+	skipped, err := importRecords(ctx, ins, imp)
+	if err != nil {
+		return 0, err
+	}
+
+	for id, player := range ins.players {
+		m.db.players[id] = player
+	}
+	for id, quiz := range ins.quizes {
+		m.db.quizes[id] = quiz
+	}
+	for id, game := range ins.games {
+		m.db.games[id] = game
+	}
+	m.db.imports[imp.Digest] = memoryNow()
+
+	return skipped, nil
+}
+
+// memoryImporter inserts the records of an import, with the lock of the database held.
+type memoryImporter struct {
+	db      *memoryDB
+	players map[int]*Player
+	quizes  map[int]*Quiz
+	games   map[int]*Game
+}
+
+// existingOwner returns the owner if they are a user, nil otherwise.
+func (i *memoryImporter) existingOwner(ownerID *int64) *int64 {
+	if ownerID == nil {
+		return nil
+	}
+	if _, ok := i.db.users[*ownerID]; !ok {
+		return nil
+	}
+
+	id := *ownerID
+	return &id
+}
+
+func (i *memoryImporter) insertPlayer(ctx context.Context, player *Player) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	id := i.db.nextID("players")
+	player.Id = strconv.FormatInt(id, 10)
+	player.Joined = memoryTime(&player.Joined).UTC()
+	player.LastUpdate = memoryTime(&player.LastUpdate).UTC()
+	player.OwnerID = i.existingOwner(player.OwnerID)
+
+	p := *player
+	p.OwnerID = i.existingOwner(player.OwnerID)
+	i.players[int(id)] = &p
+
+	return nil
+}
+
+func (i *memoryImporter) insertQuiz(ctx context.Context, quiz *Quiz) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	id := i.db.nextID("quizes")
+	quiz.Id = strconv.FormatInt(id, 10)
+	quiz.OwnerID = i.existingOwner(quiz.OwnerID)
+	i.quizes[int(id)] = copyQuiz(quiz)
+
+	return nil
+}
+
+func (i *memoryImporter) insertGame(ctx context.Context, game *Game) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := i.players[game.Player]; !ok {
+		return foreignKeyError("games", "games_player_fkey")
+	}
+	if _, ok := i.quizes[game.Quiz]; !ok {
+		return foreignKeyError("games", "games_quiz_fkey")
+	}
+
+	id := i.db.nextID("games")
+	game.Id = strconv.FormatInt(id, 10)
+	game.Finished = memoryTime(&game.Finished).UTC()
+
+	g := *game
+	i.games[int(id)] = &g
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package model
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+)
+
+type memoryJobRuns struct {
+	db *memoryDB
+}
+
+func (m memoryJobRuns) Insert(ctx context.Context, run *JobRun) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	run.ID = m.db.nextID("job_runs")
+
+	r := *run
+	r.StartedAt = run.StartedAt.Round(time.Second)
+	m.db.jobRuns[run.ID] = &r
+
+	return nil
+}
+
+func (m memoryJobRuns) LastSucceeded(ctx context.Context, job string) (time.Time, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return time.Time{}, err
+	}
+	defer m.db.mu.Unlock()
+
+	var last time.Time
+	for _, run := range m.db.jobRuns {
+		if run.Job == job && run.Succeeded && run.StartedAt.After(last) {
+			last = run.StartedAt
+		}
+	}
+
+	return last, nil
+}
+
+func (m memoryJobRuns) GetAll(ctx context.Context, job string, filters Filters) ([]*JobRun, Metadata, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.db.mu.Unlock()
+
+	var runs []*JobRun
+	for _, run := range m.db.jobRuns {
+		if job == "" || run.Job == job {
+			r := *run
+			runs = append(runs, &r)
+		}
+	}
+
+	slices.SortStableFunc(runs, recordOrder(filters, func(a, b *JobRun, column string) int {
+		switch column {
+		case "job":
+			return cmp.Compare(a.Job, b.Job)
+		case "started_at":
+			return a.StartedAt.Compare(b.StartedAt)
+		case "duration_ms":
+			return cmp.Compare(a.DurationMS, b.DurationMS)
+		default:
+			return cmp.Compare(a.ID, b.ID)
+		}
+	}))
+
+	runs, metadata := memoryPage(runs, filters)
+	return runs, metadata, nil
+}
+
+type memoryLeaderboard struct {
+	db *memoryDB
+}
+
+// Refresh ranks the players like refreshLeaderboardQuery: by score, sharing the rank on equal
+// scores, and counting only the finished games.
+func (m memoryLeaderboard) Refresh(ctx context.Context) (int64, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.db.mu.Unlock()
+
+	games := make(map[int]int)
+	for _, game := range m.db.games {
+		if !game.InProgress {
+			games[game.Player]++
+		}
+	}
+
+	now := memoryNow()
+
+	var entries []*LeaderboardEntry
+	for id, player := range m.db.players {
+		entries = append(entries, &LeaderboardEntry{
+			PlayerID:    int64(id),
+			Name:        player.Name,
+			Score:       player.Score,
+			Games:       games[id],
+			RefreshedAt: now,
+		})
+	}
+	slices.SortFunc(entries, func(a, b *LeaderboardEntry) int { return cmp.Compare(b.Score, a.Score) })
+
+	clear(m.db.leaderboard)
+	for i, entry := range entries {
+		// rank() skips the ranks taken by the players with an equal score.
+		entry.Rank = i + 1
+		if i > 0 && entry.Score == entries[i-1].Score {
+			entry.Rank = entries[i-1].Rank
+		}
+
+		m.db.leaderboard[entry.PlayerID] = entry
+	}
+
+	return int64(len(entries)), nil
+}
+
+func (m memoryLeaderboard) GetAll(ctx context.Context, filters Filters) ([]*LeaderboardEntry, Metadata, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.db.mu.Unlock()
+
+	var entries []*LeaderboardEntry
+	for _, entry := range m.db.leaderboard {
+		e := *entry
+		entries = append(entries, &e)
+	}
+
+	slices.SortStableFunc(entries, recordOrder(filters, func(a, b *LeaderboardEntry, column string) int {
+		switch column {
+		case "rank":
+			return cmp.Compare(a.Rank, b.Rank)
+		case "name":
+			return cmp.Compare(a.Name, b.Name)
+		case "score":
+			return cmp.Compare(a.Score, b.Score)
+		case "games":
+			return cmp.Compare(a.Games, b.Games)
+		default:
+			return cmp.Compare(a.PlayerID, b.PlayerID)
+		}
+	}))
+
+	entries, metadata := memoryPage(entries, filters)
+	return entries, metadata, nil
+}
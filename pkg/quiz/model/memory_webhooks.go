@@ -0,0 +1,256 @@
+package model
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+)
+
+type memoryWebhooks struct {
+	db *memoryDB
+}
+
+// copyWebhook returns a copy of a webhook which doesn't share its events.
+func copyWebhook(webhook *Webhook) *Webhook {
+	w := *webhook
+	w.Events = slices.Clone(webhook.Events)
+
+	return &w
+}
+
+// copyDelivery returns a copy of a delivery as it is read from the webhook_deliveries table,
+// without its webhook.
+func copyDelivery(delivery *WebhookDelivery) *WebhookDelivery {
+	d := *delivery
+	d.Payload = slices.Clone(delivery.Payload)
+	d.NextAttemptAt = memoryTime(delivery.NextAttemptAt)
+	d.LastAttemptAt = memoryTime(delivery.LastAttemptAt)
+	d.Webhook = nil
+
+	return &d
+}
+
+func (m memoryWebhooks) Insert(ctx context.Context, webhook *Webhook) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.users[webhook.UserID]; !ok {
+		return foreignKeyError("webhooks", "webhooks_user_id_fkey")
+	}
+
+	webhook.ID = m.db.nextID("webhooks")
+	webhook.CreatedAt = memoryNow()
+	webhook.Version = 1
+	m.db.webhooks[webhook.ID] = copyWebhook(webhook)
+
+	return nil
+}
+
+func (m memoryWebhooks) Get(ctx context.Context, userID, id int64) (*Webhook, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	webhook, ok := m.db.webhooks[id]
+	if !ok || webhook.UserID != userID {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyWebhook(webhook), nil
+}
+
+func (m memoryWebhooks) GetAllForUser(ctx context.Context, userID int64) ([]*Webhook, error) {
+	return m.list(ctx, func(webhook *Webhook) bool { return webhook.UserID == userID })
+}
+
+func (m memoryWebhooks) GetAllForEvent(ctx context.Context, event string) ([]*Webhook, error) {
+	return m.list(ctx, func(webhook *Webhook) bool {
+		return webhook.Active && slices.Contains(webhook.Events, event)
+	})
+}
+
+// list returns the webhooks matching a condition, sorted by id.
+func (m memoryWebhooks) list(ctx context.Context, match func(*Webhook) bool) ([]*Webhook, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	webhooks := []*Webhook{}
+	for _, webhook := range m.db.webhooks {
+		if match(webhook) {
+			webhooks = append(webhooks, copyWebhook(webhook))
+		}
+	}
+	slices.SortFunc(webhooks, func(a, b *Webhook) int { return cmp.Compare(a.ID, b.ID) })
+
+	return webhooks, nil
+}
+
+func (m memoryWebhooks) Update(ctx context.Context, webhook *Webhook) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.webhooks[webhook.ID]
+	if !ok || stored.UserID != webhook.UserID || stored.Version != webhook.Version {
+		return ErrEditConflict
+	}
+
+	stored.URL = webhook.URL
+	stored.Events = slices.Clone(webhook.Events)
+	stored.Active = webhook.Active
+	stored.Version++
+	webhook.Version = stored.Version
+
+	return nil
+}
+
+func (m memoryWebhooks) Delete(ctx context.Context, userID, id int64) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	webhook, ok := m.db.webhooks[id]
+	if !ok || webhook.UserID != userID {
+		return ErrRecordNotFound
+	}
+
+	delete(m.db.webhooks, id)
+
+	// ON DELETE CASCADE
+	for deliveryID, delivery := range m.db.deliveries {
+		if delivery.WebhookID == id {
+			delete(m.db.deliveries, deliveryID)
+		}
+	}
+
+	return nil
+}
+
+func (m memoryWebhooks) InsertDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	if _, ok := m.db.webhooks[delivery.WebhookID]; !ok {
+		return foreignKeyError("webhook_deliveries", "webhook_deliveries_webhook_id_fkey")
+	}
+
+	delivery.ID = m.db.nextID("webhook_deliveries")
+	delivery.CreatedAt = memoryNow()
+	m.db.deliveries[delivery.ID] = copyDelivery(delivery)
+
+	return nil
+}
+
+func (m memoryWebhooks) GetDelivery(ctx context.Context, webhookID, id int64) (*WebhookDelivery, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	delivery, ok := m.db.deliveries[id]
+	if !ok || delivery.WebhookID != webhookID {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyDelivery(delivery), nil
+}
+
+func (m memoryWebhooks) GetDeliveries(ctx context.Context, webhookID int64, status string, filters Filters) ([]*WebhookDelivery, Metadata, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.db.mu.Unlock()
+
+	var deliveries []*WebhookDelivery
+	for _, delivery := range m.db.deliveries {
+		if delivery.WebhookID == webhookID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, copyDelivery(delivery))
+		}
+	}
+
+	slices.SortStableFunc(deliveries, recordOrder(filters, func(a, b *WebhookDelivery, column string) int {
+		switch column {
+		case "created_at":
+			return a.CreatedAt.Compare(b.CreatedAt)
+		case "attempts":
+			return cmp.Compare(a.Attempts, b.Attempts)
+		default:
+			return cmp.Compare(a.ID, b.ID)
+		}
+	}))
+
+	deliveries, metadata := memoryPage(deliveries, filters)
+	return deliveries, metadata, nil
+}
+
+func (m memoryWebhooks) ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	if err := m.db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.db.mu.Unlock()
+
+	var due []*WebhookDelivery
+	for _, delivery := range m.db.deliveries {
+		webhook := m.db.webhooks[delivery.WebhookID]
+		if delivery.Status == DeliveryPending && delivery.NextAttemptAt != nil &&
+			!delivery.NextAttemptAt.After(now) && webhook != nil && webhook.Active {
+			due = append(due, delivery)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+
+	slices.SortFunc(due, func(a, b *WebhookDelivery) int {
+		if c := a.NextAttemptAt.Compare(*b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	due = due[:min(limit, len(due))]
+
+	// The claimed deliveries are returned as they were before their next attempt is postponed.
+	deliveries := make([]*WebhookDelivery, 0, len(due))
+	for _, delivery := range due {
+		d := copyDelivery(delivery)
+		d.Webhook = copyWebhook(m.db.webhooks[delivery.WebhookID])
+		deliveries = append(deliveries, d)
+
+		next := now.Add(lease)
+		delivery.NextAttemptAt = memoryTime(&next)
+	}
+
+	return deliveries, nil
+}
+
+func (m memoryWebhooks) UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	if err := m.db.lock(ctx); err != nil {
+		return err
+	}
+	defer m.db.mu.Unlock()
+
+	stored, ok := m.db.deliveries[delivery.ID]
+	if !ok {
+		return nil
+	}
+
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.NextAttemptAt = memoryTime(delivery.NextAttemptAt)
+	stored.LastAttemptAt = memoryTime(delivery.LastAttemptAt)
+	stored.ResponseStatus = delivery.ResponseStatus
+	stored.Error = delivery.Error
+	stored.DurationMS = delivery.DurationMS
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log"
 	"os"
+	"time"
 )
 
 var (
@@ -15,16 +16,29 @@ var (
 	ErrEditConflict = errors.New("edit conflict")
 )
 
+// Models holds the models of the application. AllModels keeps them in Postgres, NewSQLiteModels
+// in SQLite, and NewMemoryModels keeps some of them in memory.
 type Models struct {
-	Players		PlayerModel
-	Quizes		QuizModel
-	Games		GameModel
-	Users       UserModel
-	Tokens      TokenModel
-	Permissions PermissionModel
+	Players		PlayerStore
+	Quizes		QuizStore
+	Games		GameStore
+	Users         UserStore
+	Tokens        TokenStore
+	Permissions   PermissionStore
+	Roles         RoleStore
+	APIKeys       APIKeyStore
+	Identities    IdentityStore
+	TwoFactor     TwoFactorStore
+	LoginAttempts LoginAttemptStore
+	JobRuns       JobRunStore
+	Leaderboard   LeaderboardStore
+	Webhooks      WebhookStore
+	Imports       ImportStore
 }
 
-func AllModels(db *sql.DB) Models {
+// AllModels returns the models of the database. Each query they run is limited to queryTimeout,
+// or to DefaultQueryTimeout if it is 0.
+func AllModels(db *sql.DB, queryTimeout time.Duration) Models {
 	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
 	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
 	return Models{
@@ -32,31 +46,91 @@ func AllModels(db *sql.DB) Models {
 			DB:       db,
 			InfoLog:  infoLog,
 			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
 		},
 		Quizes: QuizModel{
 			DB:       db,
 			InfoLog:  infoLog,
 			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
 		},
 		Games: GameModel{
 			DB:       db,
 			InfoLog:  infoLog,
 			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
 		},
 		Users: UserModel{
 			DB:       db,
 			InfoLog:  infoLog,
 			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
 		},
 		Tokens: TokenModel{
 			DB:       db,
 			InfoLog:  infoLog,
 			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
 		},
 		Permissions: PermissionModel{
 			DB:       db,
 			InfoLog:  infoLog,
 			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
+		},
+		Roles: RoleModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
+		},
+		APIKeys: APIKeyModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
+		},
+		Identities: IdentityModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
+		},
+		TwoFactor: TwoFactorModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
+		},
+		LoginAttempts: LoginAttemptModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
+		},
+		JobRuns: JobRunModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
+		},
+		Leaderboard: LeaderboardModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
+		},
+		Webhooks: WebhookModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
+		},
+		Imports: ImportModel{
+			DB:       db,
+			InfoLog:  infoLog,
+			ErrorLog: errorLog,
+			Timeout:  queryTimeout,
 		},
 	}
 }
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// SQLiteScheme starts the DSNs of SQLite databases, e.g. sqlite://quiz.db.
+const SQLiteScheme = "sqlite://"
+
+// IsSQLite reports whether dsn is the DSN of a SQLite database.
+func IsSQLite(dsn string) bool {
+	return strings.HasPrefix(dsn, SQLiteScheme)
+}
+
+// Open opens the database of dsn, a PostgreSQL DSN or a sqlite:// one, and checks that it
+// answers.
+func Open(dsn string) (*sql.DB, error) {
+	driverName := "postgres"
+	if IsSQLite(dsn) {
+		driverName, dsn = "sqlite", sqliteDSN(dsn)
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// OpenModels returns the models of the database db was opened with from dsn.
+func OpenModels(db *sql.DB, dsn string, queryTimeout time.Duration) Models {
+	if IsSQLite(dsn) {
+		return NewSQLiteModels(db, queryTimeout)
+	}
+
+	return AllModels(db, queryTimeout)
+}
+
+// sqliteDSN turns a sqlite:// DSN into the file name and parameters of modernc.org/sqlite. Foreign
+// keys are enabled, which the models need for deletes to cascade, and concurrent requests wait
+// for each other's writes instead of failing with "database is locked".
+func sqliteDSN(dsn string) string {
+	dsn = strings.TrimPrefix(dsn, SQLiteScheme)
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+
+	return dsn + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
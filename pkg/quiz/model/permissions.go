@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -12,10 +13,11 @@ import (
 // Permissions holds the permission codes for a single user.
 type Permissions []string
 
-// Include checks whether the Permissions slice contains a specific permission code.
+// Include checks whether the Permissions slice grants a specific permission code, either exactly
+// or through a wildcard.
 func (p Permissions) Include(code string) bool {
 	for i := range p {
-		if code == p[i] {
+		if grants(p[i], code) {
 			return true
 		}
 	}
@@ -23,26 +25,106 @@ func (p Permissions) Include(code string) bool {
 	return false
 }
 
+// IncludeAll checks whether the Permissions slice grants every one of the permission codes.
+func (p Permissions) IncludeAll(codes ...string) bool {
+	for _, code := range codes {
+		if !p.Include(code) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IncludeAny checks whether the Permissions slice grants at least one of the permission codes.
+func (p Permissions) IncludeAny(codes ...string) bool {
+	for _, code := range codes {
+		if p.Include(code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// grants reports whether the granted permission covers code. "*" covers every code and a
+// namespace wildcard such as "quiz:*" covers every code in that namespace, e.g. "quiz:write".
+func grants(granted, code string) bool {
+	if granted == code || granted == "*" {
+		return true
+	}
+
+	namespace, ok := strings.CutSuffix(granted, ":*")
+	return ok && strings.HasPrefix(code, namespace+":")
+}
+
 type PermissionModel struct {
 	DB       *sql.DB
 	InfoLog  *log.Logger
 	ErrorLog *log.Logger
+	Timeout  time.Duration
+}
+
+// GetAll returns the codes of all known permissions.
+func (m PermissionModel) GetAll(ctx context.Context) (Permissions, error) {
+	ctx, span := startSpan(ctx, "PermissionModel.GetAll")
+	defer span.End()
+
+	query := `
+		SELECT code
+		FROM permissions
+		ORDER BY code
+		`
+
+	return m.query(ctx, query)
+}
+
+// GetAllForUser returns all permission codes for a specific user in a Permissions slice. These
+// are the permissions granted to the user directly and the permissions of all of their roles.
+func (m PermissionModel) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
+	ctx, span := startSpan(ctx, "PermissionModel.GetAllForUser")
+	defer span.End()
+
+	query := `
+		SELECT permissions.code
+		FROM permissions
+			INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+		WHERE users_permissions.user_id = $1
+		UNION
+		SELECT permissions.code
+		FROM permissions
+			INNER JOIN roles_permissions ON roles_permissions.permission_id = permissions.id
+			INNER JOIN users_roles ON users_roles.role_id = roles_permissions.role_id
+		WHERE users_roles.user_id = $1
+		ORDER BY code
+		`
+
+	return m.query(ctx, query, userID)
 }
 
-// GetAllForUser returns all permission codes for a specific user in a Permissions slice.
-func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
+// GetDirectForUser returns the permission codes granted to a specific user directly, leaving out
+// the ones that come from roles.
+func (m PermissionModel) GetDirectForUser(ctx context.Context, userID int64) (Permissions, error) {
+	ctx, span := startSpan(ctx, "PermissionModel.GetDirectForUser")
+	defer span.End()
+
 	query := `
 		SELECT permissions.code
 		FROM permissions
 			INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
-			INNER JOIN users ON users_permissions.user_id = users.id
-		WHERE users.id = $1
+		WHERE users_permissions.user_id = $1
+		ORDER BY permissions.code
 		`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	return m.query(ctx, query, userID)
+}
+
+// query runs a query returning a single column of permission codes.
+func (m PermissionModel) query(ctx context.Context, query string, args ...interface{}) (Permissions, error) {
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, userID)
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -52,7 +134,7 @@ func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
 		}
 	}()
 
-	var permissions Permissions
+	permissions := Permissions{}
 
 	for rows.Next() {
 		var permission string
@@ -72,16 +154,53 @@ func (m PermissionModel) GetAllForUser(userID int64) (Permissions, error) {
 	return permissions, nil
 }
 
-// AddForUser adds the provided codes for a specific user.
-func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
+// AddForUser adds the provided codes for a specific user. Permissions the user already has are
+// left as they are.
+func (m PermissionModel) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	ctx, span := startSpan(ctx, "PermissionModel.AddForUser")
+	defer span.End()
+
 	query := `
 		INSERT INTO users_permissions
 		SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
+		ON CONFLICT DO NOTHING
 		`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
 	return err
-}
\ No newline at end of file
+}
+
+// RemoveForUser revokes a permission granted directly to a specific user. ErrRecordNotFound is
+// returned if the user didn't have the permission directly.
+func (m PermissionModel) RemoveForUser(ctx context.Context, userID int64, code string) error {
+	ctx, span := startSpan(ctx, "PermissionModel.RemoveForUser")
+	defer span.End()
+
+	query := `
+		DELETE FROM users_permissions
+		USING permissions
+		WHERE users_permissions.permission_id = permissions.id
+		AND users_permissions.user_id = $1 AND permissions.code = $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, code)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -3,39 +3,48 @@ package model
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"log"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
 )
 
 type Player struct {
 	Id			string 	`json:"id"`
 	Name		string 	`json:"name"`
-	Joined		string 	`json:"joined"`
-	LastUpdate	string 	`json:"last_update"`
+	Joined		time.Time	`json:"joined"`
+	LastUpdate	time.Time	`json:"last_update"`
 	Score		int		`json:"score"`
+	OwnerID		*int64		`json:"owner_id,omitempty"`
 }
 type PlayerModel struct {
 	DB       *sql.DB
 	InfoLog  *log.Logger
 	ErrorLog *log.Logger
+	Timeout  time.Duration
 }
 
-func (m PlayerModel) GetAll(name string, from, to int, filters Filters) ([]*Player, Metadata, error) {
+func (m PlayerModel) GetAll(ctx context.Context, name string, from, to int, joined TimeRange, filters Filters) ([]*Player, Metadata, error) {
+	ctx, span := startSpan(ctx, "PlayerModel.GetAll")
+	defer span.End()
+
 	// Retrieve all players from the database
-	query := fmt.Sprintf(
+	query, pageArgs, err := filters.listQuery(
+		"id, name, joined, last_update, score, owner_id",
 		`
-		SELECT count(*) OVER(), id, name, joined, last_update, score
 		FROM players
 		WHERE (LOWER(name) = LOWER($1) OR $1 = '')
 		AND (score >= $2 OR $2 = 0)
 		AND (score <= $3 OR $3 = 0)
-		ORDER BY %s %s, id ASC
-		LIMIT $4 OFFSET $5
+		AND (joined >= $4 OR $4 IS NULL)
+		AND (joined < $5 OR $5 IS NULL)
 		`,
-		filters.sortColumn(), filters.sortDirection())
+		6, nil)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
 
 	// query := `
 	// 	SELECT id, name, joined, last_update, score
@@ -43,11 +52,11 @@ func (m PlayerModel) GetAll(name string, from, to int, filters Filters) ([]*Play
 	// 	`
 
 	// Create a context with a 3-second timeout.
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
-	// Organize our four placeholder parameter values in a slice.
-	args := []interface{}{name, from, to, filters.limit(), filters.offset()}
+	// Organize the placeholder parameter values in a slice, the ones of the page come last.
+	args := append([]interface{}{name, from, to, timeBound(joined.From), timeBound(joined.To)}, pageArgs...)
 
 	// log.Println(query, title, from, to, filters.limit(), filters.offset())
 	// Use QueryContext to execute the query. This returns a sql.Rows result set containing
@@ -71,7 +80,7 @@ func (m PlayerModel) GetAll(name string, from, to int, filters Filters) ([]*Play
 	var players []*Player
 	for rows.Next() {
 		var player Player
-		err := rows.Scan(&totalRecords, &player.Id, &player.Name, &player.Joined, &player.LastUpdate, &player.Score)
+		err := rows.Scan(&totalRecords, &player.Id, &player.Name, &player.Joined, &player.LastUpdate, &player.Score, &player.OwnerID)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -86,29 +95,35 @@ func (m PlayerModel) GetAll(name string, from, to int, filters Filters) ([]*Play
 		return nil, Metadata{}, err
 	}
 	
-	// Generate a Metadata struct, passing in the total record count and pagination parameters
-	// from the client.
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	// Cut the page out of the rows and generate a Metadata struct, passing in the total record
+	// count and pagination parameters from the client.
+	players, metadata := pageOf(players, totalRecords, filters, playerCursor(filters))
 
 	// If everything went OK, then return the slice of the movies and metadata.
 	return players, metadata, nil
 }
 
-func (p PlayerModel) Insert(player *Player) error {
+func (p PlayerModel) Insert(ctx context.Context, player *Player) error {
+	ctx, span := startSpan(ctx, "PlayerModel.Insert")
+	defer span.End()
+
 	// Create a new player in the database
 	query := `
-		INSERT INTO players(name) 
-		VALUES ($1) 
+		INSERT INTO players(name, owner_id) 
+		VALUES ($1, $2) 
 		RETURNING id, joined, last_update, score;
 		`
-	args := []interface{}{player.Name}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	args := []interface{}{player.Name, player.OwnerID}
+	ctx, cancel := queryContext(ctx, p.Timeout)
 	defer cancel()
 
 	return p.DB.QueryRowContext(ctx, query, args...).Scan(&player.Id, &player.Joined, &player.LastUpdate, &player.Score)
 }
 
-func (p PlayerModel) Get(id int) (*Player, error) {
+func (p PlayerModel) Get(ctx context.Context, id int) (*Player, error) {
+	ctx, span := startSpan(ctx, "PlayerModel.Get")
+	defer span.End()
+
 	// Invalid id. Return an error if the ID is less than 1.
 	if id < 1 {
 		return nil, ErrRecordNotFound
@@ -116,23 +131,69 @@ func (p PlayerModel) Get(id int) (*Player, error) {
 
 	// Retrieve a player with its ID
 	query := `
-		SELECT id, name, joined, last_update, score
+		SELECT id, name, joined, last_update, score, owner_id
 		FROM players
 		WHERE id = $1;
 		`
 	var player Player
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, p.Timeout)
 	defer cancel()
 
 	row := p.DB.QueryRowContext(ctx, query, id)
-	err := row.Scan(&player.Id, &player.Name, &player.Joined, &player.LastUpdate, &player.Score)
+	err := row.Scan(&player.Id, &player.Name, &player.Joined, &player.LastUpdate, &player.Score, &player.OwnerID)
 	if err != nil {
-		return nil, fmt.Errorf("cannot retrive player with id: %v, %w", id, err)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
 	}
 	return &player, nil
 }
 
-func (p PlayerModel) Update(player *Player) error {
+// GetByIDs retrieves the players with the given ids in a single query, in no particular order.
+// Ids of players which don't exist are skipped.
+func (p PlayerModel) GetByIDs(ctx context.Context, ids []int) ([]*Player, error) {
+	ctx, span := startSpan(ctx, "PlayerModel.GetByIDs")
+	defer span.End()
+
+	query := `
+		SELECT id, name, joined, last_update, score, owner_id
+		FROM players
+		WHERE id = ANY($1);
+		`
+
+	ctx, cancel := queryContext(ctx, p.Timeout)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			p.ErrorLog.Println(err)
+		}
+	}()
+
+	var players []*Player
+	for rows.Next() {
+		var player Player
+		err := rows.Scan(&player.Id, &player.Name, &player.Joined, &player.LastUpdate, &player.Score, &player.OwnerID)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, &player)
+	}
+
+	return players, rows.Err()
+}
+
+func (p PlayerModel) Update(ctx context.Context, player *Player) error {
+	ctx, span := startSpan(ctx, "PlayerModel.Update")
+	defer span.End()
+
 	// Update player name and score
 	query := `
 		UPDATE players
@@ -141,13 +202,21 @@ func (p PlayerModel) Update(player *Player) error {
 		RETURNING last_update;
 		`
 	args := []interface{}{player.Name, player.Score, time.Now(), player.Id}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, p.Timeout)
 	defer cancel()
 
-	return p.DB.QueryRowContext(ctx, query, args...).Scan(&player.LastUpdate)
+	err := p.DB.QueryRowContext(ctx, query, args...).Scan(&player.LastUpdate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+
+	return err
 }
 
-func (p PlayerModel) Delete(id int) error {
+func (p PlayerModel) Delete(ctx context.Context, id int) error {
+	ctx, span := startSpan(ctx, "PlayerModel.Delete")
+	defer span.End()
+
 	// Invalid id. Return an error if the ID is less than 1.
 	if id < 1 {
 		return ErrRecordNotFound
@@ -158,11 +227,54 @@ func (p PlayerModel) Delete(id int) error {
 		DELETE FROM players
 		WHERE id = $1;
 		`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, p.Timeout)
 	defer cancel()
 
-	_, err := p.DB.ExecContext(ctx, query, id)
-	return err
+	result, err := p.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetOwner returns the id of the user who created the player. Players created before owners were
+// recorded have no owner, for them zero is returned.
+func (p PlayerModel) GetOwner(ctx context.Context, id int) (int64, error) {
+	ctx, span := startSpan(ctx, "PlayerModel.GetOwner")
+	defer span.End()
+
+	if id < 1 {
+		return 0, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT owner_id
+		FROM players
+		WHERE id = $1;
+		`
+	var ownerID sql.NullInt64
+	ctx, cancel := queryContext(ctx, p.Timeout)
+	defer cancel()
+
+	err := p.DB.QueryRowContext(ctx, query, id).Scan(&ownerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+	return ownerID.Int64, nil
 }
 
 func ValidatePlayer(v *validator.Validator, player *Player) {
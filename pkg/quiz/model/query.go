@@ -0,0 +1,63 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// DefaultQueryTimeout limits queries of models whose Timeout isn't set.
+const DefaultQueryTimeout = 3 * time.Second
+
+var (
+	// ErrQueryCanceled is returned by QueryError when a query was cancelled because the request
+	// it ran for was, usually because the client went away.
+	ErrQueryCanceled = errors.New("query canceled")
+
+	// ErrQueryTimeout is returned by QueryError when a query ran longer than its timeout.
+	ErrQueryTimeout = errors.New("query timed out")
+)
+
+// queryContext returns the context of a single query. It is cancelled with ctx, the context of
+// the request, or after timeout, whichever comes first.
+func queryContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// QueryError tells apart errors of queries that were cancelled. If err comes from a query which
+// was cancelled with ctx, the context passed to the model method, it returns err wrapped in
+// ErrQueryCanceled, if the query timed out instead it wraps err in ErrQueryTimeout. Other errors
+// are returned as they are.
+func QueryError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrQueryCanceled) || errors.Is(err, ErrQueryTimeout) {
+		return err
+	}
+
+	// database/sql reports contexts that were done before or between round trips, Postgres
+	// reports the ones that were cancelled while the query ran with query_canceled and SQLite
+	// with SQLITE_INTERRUPT.
+	var (
+		pqErr     *pq.Error
+		sqliteErr *sqlite.Error
+	)
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+		!(errors.As(err, &pqErr) && pqErr.Code.Name() == "query_canceled") &&
+		!(errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_INTERRUPT) {
+		return err
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ErrQueryCanceled, err)
+	}
+
+	return fmt.Errorf("%w: %w", ErrQueryTimeout, err)
+}
@@ -3,7 +3,7 @@ package model
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"log"
 	"time"
 
@@ -17,34 +17,40 @@ type Quiz struct {
 	Reward		int			`json:"reward"`
 	Questions	[]string	`json:"questions"`
 	Answers		[]string	`json:"answers"`
+	OwnerID		*int64		`json:"owner_id,omitempty"`
 }
 
 type QuizModel struct {
 	DB       *sql.DB
 	InfoLog  *log.Logger
 	ErrorLog *log.Logger
+	Timeout  time.Duration
 }
 
-func (q QuizModel) GetAll(category string, from, to int, filters Filters) ([]*Quiz, Metadata, error) {
+func (q QuizModel) GetAll(ctx context.Context, category string, from, to int, filters Filters) ([]*Quiz, Metadata, error) {
+	ctx, span := startSpan(ctx, "QuizModel.GetAll")
+	defer span.End()
+
 	// Retrieve all quizes from the database
-	query := fmt.Sprintf(
+	query, pageArgs, err := filters.listQuery(
+		"id, category, reward, questions, answers, owner_id",
 		`
-		SELECT count(*) OVER(), id, category, reward, questions, answers
 		FROM quizes
 		WHERE (LOWER(category) = LOWER($1) OR $1 = '')
 		AND (reward >= $2 OR $2 = 0)
 		AND (reward <= $3 OR $3 = 0)
-		ORDER BY %s %s, id ASC
-		LIMIT $4 OFFSET $5;
 		`,
-		filters.sortColumn(), filters.sortDirection())
+		4, nil)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
 
 	// Create a context with a 3-second timeout.
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, q.Timeout)
 	defer cancel()
 
-	// Organize our four placeholder parameter values in a slice.
-	args := []interface{}{category, from, to, filters.limit(), filters.offset()}
+	// Organize the placeholder parameter values in a slice, the ones of the page come last.
+	args := append([]interface{}{category, from, to}, pageArgs...)
 
 	// Use QueryContext to execute the query. This returns a sql.Rows result set containing
 	// the result.
@@ -67,7 +73,7 @@ func (q QuizModel) GetAll(category string, from, to int, filters Filters) ([]*Qu
 	var quizes []*Quiz
 	for rows.Next() {
 		var quiz Quiz
-		err := rows.Scan(&totalRecords, &quiz.Id, &quiz.Category, &quiz.Reward, (*pq.StringArray)(&quiz.Questions), (*pq.StringArray)(&quiz.Answers))
+		err := rows.Scan(&totalRecords, &quiz.Id, &quiz.Category, &quiz.Reward, (*pq.StringArray)(&quiz.Questions), (*pq.StringArray)(&quiz.Answers), &quiz.OwnerID)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -82,29 +88,35 @@ func (q QuizModel) GetAll(category string, from, to int, filters Filters) ([]*Qu
 		return nil, Metadata{}, err
 	}
 	
-	// Generate a Metadata struct, passing in the total record count and pagination parameters
-	// from the client.
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	// Cut the page out of the rows and generate a Metadata struct, passing in the total record
+	// count and pagination parameters from the client.
+	quizes, metadata := pageOf(quizes, totalRecords, filters, quizCursor(filters))
 
 	// If everything went OK, then return the slice of the movies and metadata.
 	return quizes, metadata, nil
 }
 
-func (q QuizModel) Insert(quiz *Quiz) error {
+func (q QuizModel) Insert(ctx context.Context, quiz *Quiz) error {
+	ctx, span := startSpan(ctx, "QuizModel.Insert")
+	defer span.End()
+
 	// Create a new quiz in the database
 	query := `
-		INSERT INTO quizes(category, reward, questions, answers) 
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO quizes(category, reward, questions, answers, owner_id) 
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, category, reward;
 		`
-	args := []interface{}{quiz.Category, quiz.Reward, pq.Array(quiz.Questions), pq.Array(quiz.Answers)}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	args := []interface{}{quiz.Category, quiz.Reward, pq.Array(quiz.Questions), pq.Array(quiz.Answers), quiz.OwnerID}
+	ctx, cancel := queryContext(ctx, q.Timeout)
 	defer cancel()
 
 	return q.DB.QueryRowContext(ctx, query, args...).Scan(&quiz.Id, &quiz.Category, &quiz.Reward)
 }
 
-func (q QuizModel) Get(id int) (*Quiz, error) {
+func (q QuizModel) Get(ctx context.Context, id int) (*Quiz, error) {
+	ctx, span := startSpan(ctx, "QuizModel.Get")
+	defer span.End()
+
 	// Invalid id. Return an error if the ID is less than 1.
 	if id < 1 {
 		return nil, ErrRecordNotFound
@@ -112,23 +124,69 @@ func (q QuizModel) Get(id int) (*Quiz, error) {
 
 	// Retrieve a quiz with its ID
 	query := `
-		SELECT id, category, reward, questions, answers
+		SELECT id, category, reward, questions, answers, owner_id
 		FROM quizes
 		WHERE id = $1;
 		`
 	var quiz Quiz
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, q.Timeout)
 	defer cancel()
 
 	row := q.DB.QueryRowContext(ctx, query, id)
-	err := row.Scan(&quiz.Id, &quiz.Category, &quiz.Reward, (*pq.StringArray)(&quiz.Questions), (*pq.StringArray)(&quiz.Answers))
+	err := row.Scan(&quiz.Id, &quiz.Category, &quiz.Reward, (*pq.StringArray)(&quiz.Questions), (*pq.StringArray)(&quiz.Answers), &quiz.OwnerID)
 	if err != nil {
-		return nil, fmt.Errorf("cannot retrive quiz with id: %v, %w", id, err)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
 	}
 	return &quiz, nil
 }
 
-func (q QuizModel) Update(quiz *Quiz) error {
+// GetByIDs retrieves the quizes with the given ids in a single query, in no particular order.
+// Ids of quizes which don't exist are skipped.
+func (q QuizModel) GetByIDs(ctx context.Context, ids []int) ([]*Quiz, error) {
+	ctx, span := startSpan(ctx, "QuizModel.GetByIDs")
+	defer span.End()
+
+	query := `
+		SELECT id, category, reward, questions, answers, owner_id
+		FROM quizes
+		WHERE id = ANY($1);
+		`
+
+	ctx, cancel := queryContext(ctx, q.Timeout)
+	defer cancel()
+
+	rows, err := q.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			q.ErrorLog.Println(err)
+		}
+	}()
+
+	var quizes []*Quiz
+	for rows.Next() {
+		var quiz Quiz
+		err := rows.Scan(&quiz.Id, &quiz.Category, &quiz.Reward, (*pq.StringArray)(&quiz.Questions), (*pq.StringArray)(&quiz.Answers), &quiz.OwnerID)
+		if err != nil {
+			return nil, err
+		}
+		quizes = append(quizes, &quiz)
+	}
+
+	return quizes, rows.Err()
+}
+
+func (q QuizModel) Update(ctx context.Context, quiz *Quiz) error {
+	ctx, span := startSpan(ctx, "QuizModel.Update")
+	defer span.End()
+
 	// Update quiz name and score
 	query := `
 		UPDATE quizes
@@ -138,13 +196,21 @@ func (q QuizModel) Update(quiz *Quiz) error {
 		`
 		// pq.Array(quiz.Questions), pq.Array(quiz.Answers)
 	args := []interface{}{quiz.Category, quiz.Reward, pq.Array(quiz.Questions), pq.Array(quiz.Answers), quiz.Id}
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, q.Timeout)
 	defer cancel()
 
-	return q.DB.QueryRowContext(ctx, query, args...).Scan(&quiz.Id)
+	err := q.DB.QueryRowContext(ctx, query, args...).Scan(&quiz.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+
+	return err
 }
 
-func (q QuizModel) Delete(id int) error {
+func (q QuizModel) Delete(ctx context.Context, id int) error {
+	ctx, span := startSpan(ctx, "QuizModel.Delete")
+	defer span.End()
+
 	// Invalid id. Return an error if the ID is less than 1.
 	if id < 1 {
 		return ErrRecordNotFound
@@ -155,11 +221,54 @@ func (q QuizModel) Delete(id int) error {
 		DELETE FROM quizes
 		WHERE id = $1;
 		`
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, q.Timeout)
 	defer cancel()
 
-	_, err := q.DB.ExecContext(ctx, query, id)
-	return err
+	result, err := q.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetOwner returns the id of the user who created the quiz. Quizes created before owners were
+// recorded have no owner, for them zero is returned.
+func (q QuizModel) GetOwner(ctx context.Context, id int) (int64, error) {
+	ctx, span := startSpan(ctx, "QuizModel.GetOwner")
+	defer span.End()
+
+	if id < 1 {
+		return 0, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT owner_id
+		FROM quizes
+		WHERE id = $1;
+		`
+	var ownerID sql.NullInt64
+	ctx, cancel := queryContext(ctx, q.Timeout)
+	defer cancel()
+
+	err := q.DB.QueryRowContext(ctx, query, id).Scan(&ownerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+	return ownerID.Int64, nil
 }
 
 func ValidateQuiz(v *validator.Validator, quiz *Quiz) {
@@ -0,0 +1,298 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Codes of the built-in roles, from the least to the most privileged.
+const (
+	RolePlayer    = "player"
+	RoleAuthor    = "author"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var (
+	// ErrLastRoleHolder is returned when a role would be revoked from the only user who has it.
+	ErrLastRoleHolder = errors.New("last role holder")
+)
+
+type (
+	// Role is a named set of permissions that can be granted to users.
+	Role struct {
+		ID          int64       `json:"id"`
+		Code        string      `json:"code"`
+		Description string      `json:"description"`
+		Permissions Permissions `json:"permissions"`
+	}
+
+	// RoleModel struct wraps a sql.DB connection pool and allows us to work with the roles,
+	// roles_permissions and users_roles tables.
+	RoleModel struct {
+		DB       *sql.DB
+		InfoLog  *log.Logger
+		ErrorLog *log.Logger
+		Timeout  time.Duration
+	}
+)
+
+// GetAll returns all roles with their permissions.
+func (m RoleModel) GetAll(ctx context.Context) ([]*Role, error) {
+	ctx, span := startSpan(ctx, "RoleModel.GetAll")
+	defer span.End()
+
+	query := `
+		SELECT roles.id, roles.code, roles.description,
+			COALESCE(array_agg(permissions.code ORDER BY permissions.code) FILTER (WHERE permissions.code IS NOT NULL), '{}')
+		FROM roles
+			LEFT JOIN roles_permissions ON roles_permissions.role_id = roles.id
+			LEFT JOIN permissions ON roles_permissions.permission_id = permissions.id
+		GROUP BY roles.id
+		ORDER BY roles.id
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	var roles []*Role
+
+	for rows.Next() {
+		var role Role
+
+		err := rows.Scan(&role.ID, &role.Code, &role.Description, pq.Array(&role.Permissions))
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, &role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// Exists reports whether a role with the given code exists.
+func (m RoleModel) Exists(ctx context.Context, code string) (bool, error) {
+	ctx, span := startSpan(ctx, "RoleModel.Exists")
+	defer span.End()
+
+	query := `
+		SELECT EXISTS(SELECT 1 FROM roles WHERE code = $1)
+		`
+
+	var exists bool
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, code).Scan(&exists)
+	return exists, err
+}
+
+// GetAllForUser returns the codes of the roles granted to a specific user.
+func (m RoleModel) GetAllForUser(ctx context.Context, userID int64) ([]string, error) {
+	ctx, span := startSpan(ctx, "RoleModel.GetAllForUser")
+	defer span.End()
+
+	query := `
+		SELECT roles.code
+		FROM roles
+			INNER JOIN users_roles ON users_roles.role_id = roles.id
+		WHERE users_roles.user_id = $1
+		ORDER BY roles.id
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	roles := []string{}
+
+	for rows.Next() {
+		var role string
+
+		err := rows.Scan(&role)
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// AddForUser grants the roles with the provided codes to a specific user. Roles the user already
+// has are left as they are.
+func (m RoleModel) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	ctx, span := startSpan(ctx, "RoleModel.AddForUser")
+	defer span.End()
+
+	query := `
+		INSERT INTO users_roles
+		SELECT $1, roles.id FROM roles WHERE roles.code = ANY($2)
+		ON CONFLICT DO NOTHING
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
+	return err
+}
+
+// RemoveForUser revokes a role from a specific user. ErrRecordNotFound is returned if the user
+// didn't have the role.
+func (m RoleModel) RemoveForUser(ctx context.Context, userID int64, code string) error {
+	ctx, span := startSpan(ctx, "RoleModel.RemoveForUser")
+	defer span.End()
+
+	query := `
+		DELETE FROM users_roles
+		USING roles
+		WHERE users_roles.role_id = roles.id AND users_roles.user_id = $1 AND roles.code = $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, code)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// RemoveForUserUnlessLast revokes a role from a specific user, unless the user is the only one
+// left with it. ErrRecordNotFound is returned if the user didn't have the role and
+// ErrLastRoleHolder if nobody else has it. The grants of the role are locked while they are
+// counted, so concurrent revokes can't remove the last holder between them.
+func (m RoleModel) RemoveForUserUnlessLast(ctx context.Context, userID int64, code string) error {
+	ctx, span := startSpan(ctx, "RoleModel.RemoveForUserUnlessLast")
+	defer span.End()
+
+	query := `
+		SELECT users_roles.user_id
+		FROM users_roles
+			INNER JOIN roles ON users_roles.role_id = roles.id
+		WHERE roles.code = $1
+		FOR UPDATE OF users_roles
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, code)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	var holders int
+	found := false
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+
+		holders++
+		found = found || id == userID
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	switch {
+	case !found:
+		return ErrRecordNotFound
+	case holders <= 1:
+		return ErrLastRoleHolder
+	}
+
+	query = `
+		DELETE FROM users_roles
+		USING roles
+		WHERE users_roles.role_id = roles.id AND users_roles.user_id = $1 AND roles.code = $2
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID, code); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CountUsers returns the number of users that have the role with the given code.
+func (m RoleModel) CountUsers(ctx context.Context, code string) (int, error) {
+	ctx, span := startSpan(ctx, "RoleModel.CountUsers")
+	defer span.End()
+
+	query := `
+		SELECT count(*)
+		FROM users_roles
+			INNER JOIN roles ON users_roles.role_id = roles.id
+		WHERE roles.code = $1
+		`
+
+	var count int
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, code).Scan(&count)
+	return count, err
+}
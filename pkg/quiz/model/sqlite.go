@@ -0,0 +1,154 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/tracing"
+)
+
+// sqliteModel is what the SQLite models share: the database, a logger and the query timeout.
+type sqliteModel struct {
+	DB       *sql.DB
+	ErrorLog *log.Logger
+	Timeout  time.Duration
+}
+
+// NewSQLiteModels returns the models of a SQLite database migrated with the migrations in
+// migrations/sqlite. They behave like the Postgres models of AllModels: the same errors for
+// missing records, conflicts and duplicates, the same pagination and sorting, and timestamps with
+// a precision of one second. Each query is limited to queryTimeout, or to DefaultQueryTimeout if
+// it is 0.
+//
+// The database has to be opened with foreign keys enabled, e.g. with the _pragma=foreign_keys(1)
+// parameter of modernc.org/sqlite, or deletes won't cascade.
+func NewSQLiteModels(db *sql.DB, queryTimeout time.Duration) Models {
+	m := sqliteModel{
+		DB:       db,
+		ErrorLog: log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile),
+		Timeout:  queryTimeout,
+	}
+
+	return Models{
+		Players:       sqlitePlayers{m},
+		Quizes:        sqliteQuizes{m},
+		Games:         sqliteGames{m},
+		Users:         sqliteUsers{m},
+		Tokens:        sqliteTokens{m},
+		Permissions:   sqlitePermissions{m},
+		Roles:         sqliteRoles{m},
+		APIKeys:       sqliteAPIKeys{m},
+		Identities:    sqliteIdentities{m},
+		TwoFactor:     sqliteTwoFactor{m},
+		LoginAttempts: sqliteLoginAttempts{m},
+		JobRuns:       sqliteJobRuns{m},
+		Leaderboard:   sqliteLeaderboard{m},
+		Webhooks:      sqliteWebhooks{m},
+		Imports:       sqliteImports{m},
+	}
+}
+
+// startSQLiteSpan starts the span of a SQLite model method. Spans are named like the ones of the
+// Postgres models, so that traces read the same with both databases.
+func startSQLiteSpan(ctx context.Context, name string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, name)
+	span.SetAttribute("db.system", "sqlite")
+
+	return ctx, span
+}
+
+// closeRows closes rows and logs the error, like the deferred closes of the Postgres models.
+func (m sqliteModel) closeRows(rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		m.ErrorLog.Println(err)
+	}
+}
+
+// sqliteTimeLayout is the layout of the timestamps in the database, the one of CURRENT_TIMESTAMP.
+// They are always in UTC.
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// sqliteTime formats a time as a timestamp parameter. It's rounded to the second, like times
+// stored in the timestamp(0) columns of Postgres.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Round(time.Second).Format(sqliteTimeLayout)
+}
+
+// sqliteIDs passes ids as a JSON array, SQLite has no arrays. Queries expand it with json_each.
+func sqliteIDs(ids []int) string {
+	js, _ := json.Marshal(ids)
+	return string(js)
+}
+
+// sqliteCursorValue converts the value of a cursor to the one stored in column: timestamps are
+// compared as text in sqliteTimeLayout, and numbers as integers.
+func sqliteCursorValue(column, v string) (any, error) {
+	switch column {
+	case "joined", "finished":
+		t, err := parseTimestamp(v)
+		if err != nil {
+			return nil, err
+		}
+		return sqliteTime(t), nil
+	case "score", "reward", "player", "quiz":
+		return strconv.ParseInt(v, 10, 64)
+	}
+
+	return v, nil
+}
+
+// sqliteNullTime formats an optional time as a timestamp parameter, nil becomes NULL.
+func sqliteNullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return sqliteTime(*t)
+}
+
+// parseSQLiteTime parses a timestamp which the driver returns as text, e.g. the result of an
+// aggregate, which has no declared type.
+func parseSQLiteTime(s string) (time.Time, error) {
+	return time.ParseInLocation(sqliteTimeLayout, s, time.UTC)
+}
+
+// sqliteUniqueViolation reports whether err violates the unique constraint on the given columns,
+// e.g. "users.email".
+func sqliteUniqueViolation(err error, columns string) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed: "+columns)
+}
+
+// sqliteStrings stores a string slice as a JSON array, the SQLite equivalent of the text[] columns
+// of Postgres. A nil slice is stored as NULL.
+type sqliteStrings []string
+
+func (a sqliteStrings) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal([]string(a))
+	return string(b), err
+}
+
+func (a *sqliteStrings) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(src), (*[]string)(a))
+	case []byte:
+		return json.Unmarshal(src, (*[]string)(a))
+	default:
+		return fmt.Errorf("cannot scan %T into a string array", src)
+	}
+}
@@ -0,0 +1,574 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type sqliteAPIKeys struct{ sqliteModel }
+
+func (m sqliteAPIKeys) New(ctx context.Context, userID int64, name string, scopes Permissions, expiry *time.Time) (*APIKey, error) {
+	ctx, span := startSQLiteSpan(ctx, "APIKeyModel.New")
+	defer span.End()
+
+	key, err := generateAPIKey(userID, name, scopes, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO api_keys (hash, prefix, user_id, name, scopes, expiry)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+		`
+
+	args := []interface{}{key.Hash, key.Prefix, key.UserID, key.Name, sqliteStrings(key.Scopes), sqliteNullTime(key.Expiry)}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (m sqliteAPIKeys) GetForKey(ctx context.Context, plaintext string) (*APIKey, error) {
+	ctx, span := startSQLiteSpan(ctx, "APIKeyModel.GetForKey")
+	defer span.End()
+
+	query := `
+		SELECT id, prefix, user_id, name, scopes, created_at, expiry, last_used_at, revoked_at
+		FROM api_keys
+		WHERE hash = $1
+		AND revoked_at IS NULL
+		AND (expiry IS NULL OR expiry > $2)
+		`
+
+	hash := sha256.Sum256([]byte(plaintext))
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	row := m.DB.QueryRowContext(ctx, query, hash[:], sqliteTime(time.Now()))
+
+	key, err := scanSQLiteAPIKey(row.Scan)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+func (m sqliteAPIKeys) GetAllForUser(ctx context.Context, userID int64) ([]*APIKey, error) {
+	ctx, span := startSQLiteSpan(ctx, "APIKeyModel.GetAllForUser")
+	defer span.End()
+
+	query := `
+		SELECT id, prefix, user_id, name, scopes, created_at, expiry, last_used_at, revoked_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY id
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer m.closeRows(rows)
+
+	keys := []*APIKey{}
+
+	for rows.Next() {
+		key, err := scanSQLiteAPIKey(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (m sqliteAPIKeys) Touch(ctx context.Context, key *APIKey) error {
+	ctx, span := startSQLiteSpan(ctx, "APIKeyModel.Touch")
+	defer span.End()
+
+	if key.LastUsedAt != nil && time.Since(*key.LastUsedAt) < apiKeyTouchInterval {
+		return nil
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1`, key.ID)
+	return err
+}
+
+func (m sqliteAPIKeys) Revoke(ctx context.Context, userID, id int64) error {
+	ctx, span := startSQLiteSpan(ctx, "APIKeyModel.Revoke")
+	defer span.End()
+
+	query := `
+		UPDATE api_keys
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+		`
+
+	return m.execOne(ctx, query, id, userID)
+}
+
+// scanSQLiteAPIKey is scanAPIKey for the JSON array of scopes.
+func scanSQLiteAPIKey(scan func(dest ...interface{}) error) (*APIKey, error) {
+	var key APIKey
+
+	err := scan(
+		&key.ID,
+		&key.Prefix,
+		&key.UserID,
+		&key.Name,
+		(*sqliteStrings)(&key.Scopes),
+		&key.CreatedAt,
+		&key.Expiry,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+type sqliteIdentities struct{ sqliteModel }
+
+func (m sqliteIdentities) Insert(ctx context.Context, identity *Identity) error {
+	ctx, span := startSQLiteSpan(ctx, "IdentityModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+		`
+
+	args := []interface{}{identity.UserID, identity.Provider, identity.Subject, identity.Email}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&identity.ID, &identity.CreatedAt)
+	if err != nil {
+		switch {
+		case sqliteUniqueViolation(err, "identities.provider, identities.subject"):
+			return ErrDuplicateIdentity
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m sqliteIdentities) GetByProviderSubject(ctx context.Context, provider, subject string) (*Identity, error) {
+	ctx, span := startSQLiteSpan(ctx, "IdentityModel.GetByProviderSubject")
+	defer span.End()
+
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM identities
+		WHERE provider = $1 AND subject = $2
+		`
+
+	var identity Identity
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, provider, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &identity, nil
+}
+
+func (m sqliteIdentities) InsertLoginState(ctx context.Context, state *LoginState) error {
+	ctx, span := startSQLiteSpan(ctx, "IdentityModel.InsertLoginState")
+	defer span.End()
+
+	query := `
+		INSERT INTO oidc_login_states (state_hash, provider, code_verifier, nonce, expiry)
+		VALUES ($1, $2, $3, $4, $5)
+		`
+
+	hash := sha256.Sum256([]byte(state.Plaintext))
+	args := []interface{}{hash[:], state.Provider, state.CodeVerifier, state.Nonce, sqliteTime(state.Expiry)}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (m sqliteIdentities) ConsumeLoginState(ctx context.Context, provider, plaintext string) (*LoginState, error) {
+	ctx, span := startSQLiteSpan(ctx, "IdentityModel.ConsumeLoginState")
+	defer span.End()
+
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1 AND provider = $2
+		RETURNING provider, code_verifier, nonce, expiry
+		`
+
+	hash := sha256.Sum256([]byte(plaintext))
+	state := LoginState{Plaintext: plaintext}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, hash[:], provider).Scan(
+		&state.Provider,
+		&state.CodeVerifier,
+		&state.Nonce,
+		&state.Expiry,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if state.Expiry.Before(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+
+	return &state, nil
+}
+
+type sqliteTwoFactor struct{ sqliteModel }
+
+func (m sqliteTwoFactor) Get(ctx context.Context, userID int64) (*TOTP, error) {
+	ctx, span := startSQLiteSpan(ctx, "TwoFactorModel.Get")
+	defer span.End()
+
+	query := `
+		SELECT user_id, secret, confirmed, last_used_step, created_at
+		FROM users_totp
+		WHERE user_id = $1
+		`
+
+	var enrollment TOTP
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&enrollment.UserID,
+		&enrollment.Secret,
+		&enrollment.Confirmed,
+		&enrollment.LastUsedStep,
+		&enrollment.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &enrollment, nil
+}
+
+func (m sqliteTwoFactor) Enabled(ctx context.Context, userID int64) (bool, error) {
+	ctx, span := startSQLiteSpan(ctx, "TwoFactorModel.Enabled")
+	defer span.End()
+
+	var enabled bool
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users_totp WHERE user_id = $1 AND confirmed)`, userID).Scan(&enabled)
+	return enabled, err
+}
+
+func (m sqliteTwoFactor) Enroll(ctx context.Context, userID int64, secret string) error {
+	ctx, span := startSQLiteSpan(ctx, "TwoFactorModel.Enroll")
+	defer span.End()
+
+	query := `
+		INSERT INTO users_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+			SET secret = excluded.secret, last_used_step = 0, created_at = CURRENT_TIMESTAMP
+			WHERE users_totp.confirmed = false
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+func (m sqliteTwoFactor) Confirm(ctx context.Context, userID int64) error {
+	ctx, span := startSQLiteSpan(ctx, "TwoFactorModel.Confirm")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `UPDATE users_totp SET confirmed = true WHERE user_id = $1`, userID)
+	return err
+}
+
+func (m sqliteTwoFactor) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	ctx, span := startSQLiteSpan(ctx, "TwoFactorModel.UseStep")
+	defer span.End()
+
+	query := `
+		UPDATE users_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+func (m sqliteTwoFactor) Delete(ctx context.Context, userID int64) error {
+	ctx, span := startSQLiteSpan(ctx, "TwoFactorModel.Delete")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m sqliteTwoFactor) NewRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	ctx, span := startSQLiteSpan(ctx, "TwoFactorModel.NewRecoveryCodes")
+	defer span.End()
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	for _, code := range codes {
+		hash := hashRecoveryCode(code)
+		_, err := tx.ExecContext(ctx, `INSERT INTO recovery_codes (hash, user_id) VALUES ($1, $2)`, hash[:], userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (m sqliteTwoFactor) UseRecoveryCode(ctx context.Context, userID int64, code string) (bool, error) {
+	ctx, span := startSQLiteSpan(ctx, "TwoFactorModel.UseRecoveryCode")
+	defer span.End()
+
+	hash := hashRecoveryCode(code)
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1 AND hash = $2`, userID, hash[:])
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+type sqliteLoginAttempts struct{ sqliteModel }
+
+func (m sqliteLoginAttempts) Insert(ctx context.Context, attempt *LoginAttempt) error {
+	ctx, span := startSQLiteSpan(ctx, "LoginAttemptModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO login_attempts (email, ip, user_id, succeeded, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+		`
+
+	args := []interface{}{attempt.Email, attempt.IP, attempt.UserID, attempt.Succeeded, attempt.Reason}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&attempt.ID, &attempt.CreatedAt)
+}
+
+func (m sqliteLoginAttempts) FailuresForIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error) {
+	ctx, span := startSQLiteSpan(ctx, "LoginAttemptModel.FailuresForIP")
+	defer span.End()
+
+	query := `
+		SELECT count(*), max(created_at)
+		FROM login_attempts
+		WHERE ip = $1 AND NOT succeeded AND created_at > $2
+		`
+
+	var (
+		count int
+		last  sql.NullString
+	)
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, ip, sqliteTime(since)).Scan(&count, &last)
+	if err != nil || !last.Valid {
+		return count, since, err
+	}
+
+	// The result of max has no declared type, so the driver returns it as text.
+	lastTime, err := parseSQLiteTime(last.String)
+	return count, lastTime, err
+}
+
+func (m sqliteLoginAttempts) GetAll(ctx context.Context, email, ip string, filters Filters) ([]*LoginAttempt, Metadata, error) {
+	ctx, span := startSQLiteSpan(ctx, "LoginAttemptModel.GetAll")
+	defer span.End()
+
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), id, email, ip, user_id, succeeded, reason, created_at
+		FROM login_attempts
+		WHERE (email = $1 OR $1 = '')
+		AND (ip = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4
+		`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, email, ip, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.closeRows(rows)
+
+	totalRecords := 0
+
+	var attempts []*LoginAttempt
+	for rows.Next() {
+		var attempt LoginAttempt
+		err := rows.Scan(
+			&totalRecords,
+			&attempt.ID,
+			&attempt.Email,
+			&attempt.IP,
+			&attempt.UserID,
+			&attempt.Succeeded,
+			&attempt.Reason,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		attempts = append(attempts, &attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return attempts, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
@@ -0,0 +1,643 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type sqlitePlayers struct{ sqliteModel }
+
+func (m sqlitePlayers) GetAll(ctx context.Context, name string, from, to int, joined TimeRange, filters Filters) ([]*Player, Metadata, error) {
+	ctx, span := startSQLiteSpan(ctx, "PlayerModel.GetAll")
+	defer span.End()
+
+	query, pageArgs, err := filters.listQuery(
+		"id, name, joined, last_update, score, owner_id",
+		`
+		FROM players
+		WHERE (LOWER(name) = LOWER($1) OR $1 = '')
+		AND (score >= $2 OR $2 = 0)
+		AND (score <= $3 OR $3 = 0)
+		AND (joined >= $4 OR $4 IS NULL)
+		AND (joined < $5 OR $5 IS NULL)
+		`,
+		6, sqliteCursorValue)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	args := append([]interface{}{name, from, to, sqliteTimeBound(joined.From), sqliteTimeBound(joined.To)}, pageArgs...)
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.closeRows(rows)
+
+	totalRecords := 0
+
+	var players []*Player
+	for rows.Next() {
+		var player Player
+		err := rows.Scan(&totalRecords, &player.Id, &player.Name, &player.Joined, &player.LastUpdate, &player.Score, &player.OwnerID)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		players = append(players, &player)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	players, metadata := pageOf(players, totalRecords, filters, playerCursor(filters))
+
+	return players, metadata, nil
+}
+
+func (m sqlitePlayers) GetByIDs(ctx context.Context, ids []int) ([]*Player, error) {
+	ctx, span := startSQLiteSpan(ctx, "PlayerModel.GetByIDs")
+	defer span.End()
+
+	query := `
+		SELECT id, name, joined, last_update, score, owner_id
+		FROM players
+		WHERE id IN (SELECT value FROM json_each($1))
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, sqliteIDs(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer m.closeRows(rows)
+
+	var players []*Player
+	for rows.Next() {
+		var player Player
+		err := rows.Scan(&player.Id, &player.Name, &player.Joined, &player.LastUpdate, &player.Score, &player.OwnerID)
+		if err != nil {
+			return nil, err
+		}
+
+		players = append(players, &player)
+	}
+
+	return players, rows.Err()
+}
+
+func (m sqlitePlayers) Insert(ctx context.Context, player *Player) error {
+	ctx, span := startSQLiteSpan(ctx, "PlayerModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO players (name, owner_id)
+		VALUES ($1, $2)
+		RETURNING id, joined, last_update, score
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, player.Name, player.OwnerID).Scan(&player.Id, &player.Joined, &player.LastUpdate, &player.Score)
+}
+
+func (m sqlitePlayers) Get(ctx context.Context, id int) (*Player, error) {
+	ctx, span := startSQLiteSpan(ctx, "PlayerModel.Get")
+	defer span.End()
+
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, name, joined, last_update, score, owner_id
+		FROM players
+		WHERE id = $1
+		`
+
+	var player Player
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&player.Id, &player.Name, &player.Joined, &player.LastUpdate, &player.Score, &player.OwnerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &player, nil
+}
+
+func (m sqlitePlayers) Update(ctx context.Context, player *Player) error {
+	ctx, span := startSQLiteSpan(ctx, "PlayerModel.Update")
+	defer span.End()
+
+	query := `
+		UPDATE players
+		SET name = $1, score = $2, last_update = $3
+		WHERE id = $4
+		RETURNING last_update
+		`
+
+	args := []interface{}{player.Name, player.Score, sqliteTime(time.Now()), player.Id}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&player.LastUpdate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+
+	return err
+}
+
+func (m sqlitePlayers) Delete(ctx context.Context, id int) error {
+	ctx, span := startSQLiteSpan(ctx, "PlayerModel.Delete")
+	defer span.End()
+
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM players WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m sqlitePlayers) GetOwner(ctx context.Context, id int) (int64, error) {
+	ctx, span := startSQLiteSpan(ctx, "PlayerModel.GetOwner")
+	defer span.End()
+
+	if id < 1 {
+		return 0, ErrRecordNotFound
+	}
+
+	var ownerID sql.NullInt64
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, `SELECT owner_id FROM players WHERE id = $1`, id).Scan(&ownerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return ownerID.Int64, nil
+}
+
+type sqliteQuizes struct{ sqliteModel }
+
+func (m sqliteQuizes) GetAll(ctx context.Context, category string, from, to int, filters Filters) ([]*Quiz, Metadata, error) {
+	ctx, span := startSQLiteSpan(ctx, "QuizModel.GetAll")
+	defer span.End()
+
+	query, pageArgs, err := filters.listQuery(
+		"id, category, reward, questions, answers, owner_id",
+		`
+		FROM quizes
+		WHERE (LOWER(category) = LOWER($1) OR $1 = '')
+		AND (reward >= $2 OR $2 = 0)
+		AND (reward <= $3 OR $3 = 0)
+		`,
+		4, sqliteCursorValue)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	args := append([]interface{}{category, from, to}, pageArgs...)
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.closeRows(rows)
+
+	totalRecords := 0
+
+	var quizes []*Quiz
+	for rows.Next() {
+		var quiz Quiz
+		err := rows.Scan(&totalRecords, &quiz.Id, &quiz.Category, &quiz.Reward, (*sqliteStrings)(&quiz.Questions), (*sqliteStrings)(&quiz.Answers), &quiz.OwnerID)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		quizes = append(quizes, &quiz)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	quizes, metadata := pageOf(quizes, totalRecords, filters, quizCursor(filters))
+
+	return quizes, metadata, nil
+}
+
+func (m sqliteQuizes) GetByIDs(ctx context.Context, ids []int) ([]*Quiz, error) {
+	ctx, span := startSQLiteSpan(ctx, "QuizModel.GetByIDs")
+	defer span.End()
+
+	query := `
+		SELECT id, category, reward, questions, answers, owner_id
+		FROM quizes
+		WHERE id IN (SELECT value FROM json_each($1))
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, sqliteIDs(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer m.closeRows(rows)
+
+	var quizes []*Quiz
+	for rows.Next() {
+		var quiz Quiz
+		err := rows.Scan(&quiz.Id, &quiz.Category, &quiz.Reward, (*sqliteStrings)(&quiz.Questions), (*sqliteStrings)(&quiz.Answers), &quiz.OwnerID)
+		if err != nil {
+			return nil, err
+		}
+
+		quizes = append(quizes, &quiz)
+	}
+
+	return quizes, rows.Err()
+}
+
+func (m sqliteQuizes) Insert(ctx context.Context, quiz *Quiz) error {
+	ctx, span := startSQLiteSpan(ctx, "QuizModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO quizes (category, reward, questions, answers, owner_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, category, reward
+		`
+
+	args := []interface{}{quiz.Category, quiz.Reward, sqliteStrings(quiz.Questions), sqliteStrings(quiz.Answers), quiz.OwnerID}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&quiz.Id, &quiz.Category, &quiz.Reward)
+}
+
+func (m sqliteQuizes) Get(ctx context.Context, id int) (*Quiz, error) {
+	ctx, span := startSQLiteSpan(ctx, "QuizModel.Get")
+	defer span.End()
+
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, category, reward, questions, answers, owner_id
+		FROM quizes
+		WHERE id = $1
+		`
+
+	var quiz Quiz
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&quiz.Id, &quiz.Category, &quiz.Reward, (*sqliteStrings)(&quiz.Questions), (*sqliteStrings)(&quiz.Answers), &quiz.OwnerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &quiz, nil
+}
+
+func (m sqliteQuizes) Update(ctx context.Context, quiz *Quiz) error {
+	ctx, span := startSQLiteSpan(ctx, "QuizModel.Update")
+	defer span.End()
+
+	query := `
+		UPDATE quizes
+		SET category = $1, reward = $2, questions = $3, answers = $4
+		WHERE id = $5
+		RETURNING id
+		`
+
+	args := []interface{}{quiz.Category, quiz.Reward, sqliteStrings(quiz.Questions), sqliteStrings(quiz.Answers), quiz.Id}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&quiz.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+
+	return err
+}
+
+func (m sqliteQuizes) Delete(ctx context.Context, id int) error {
+	ctx, span := startSQLiteSpan(ctx, "QuizModel.Delete")
+	defer span.End()
+
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM quizes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m sqliteQuizes) GetOwner(ctx context.Context, id int) (int64, error) {
+	ctx, span := startSQLiteSpan(ctx, "QuizModel.GetOwner")
+	defer span.End()
+
+	if id < 1 {
+		return 0, ErrRecordNotFound
+	}
+
+	var ownerID sql.NullInt64
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, `SELECT owner_id FROM quizes WHERE id = $1`, id).Scan(&ownerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return ownerID.Int64, nil
+}
+
+type sqliteGames struct{ sqliteModel }
+
+func (m sqliteGames) GetAll(ctx context.Context, player, quiz int, finishedOnly bool, finished TimeRange, filters Filters) ([]*Game, Metadata, error) {
+	ctx, span := startSQLiteSpan(ctx, "GameModel.GetAll")
+	defer span.End()
+
+	query, pageArgs, err := filters.listQuery(
+		"id, finished, player, quiz, in_progress",
+		`
+		FROM games
+		WHERE (player = $1 OR $1 = 0)
+		AND (quiz = $2 OR $2 = 0)
+		AND (finished >= $3 OR $3 IS NULL)
+		AND (finished < $4 OR $4 IS NULL)
+		AND (NOT in_progress OR NOT $5)
+		`,
+		6, sqliteCursorValue)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	// Games in progress have no finish time, their finished column is the start.
+	finishedOnly = finishedOnly || !finished.IsZero()
+	args := append([]interface{}{player, quiz, sqliteTimeBound(finished.From), sqliteTimeBound(finished.To), finishedOnly}, pageArgs...)
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.closeRows(rows)
+
+	totalRecords := 0
+
+	var games []*Game
+	for rows.Next() {
+		var game Game
+		err := rows.Scan(&totalRecords, &game.Id, &game.Finished, &game.Player, &game.Quiz, &game.InProgress)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		games = append(games, &game)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	games, metadata := pageOf(games, totalRecords, filters, gameCursor(filters))
+
+	return games, metadata, nil
+}
+
+func (m sqliteGames) Insert(ctx context.Context, game *Game) error {
+	ctx, span := startSQLiteSpan(ctx, "GameModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO games (player, quiz, in_progress)
+		VALUES ($1, $2, $3)
+		RETURNING id, player, quiz
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, game.Player, game.Quiz, game.InProgress).Scan(&game.Id, &game.Player, &game.Quiz)
+}
+
+func (m sqliteGames) Get(ctx context.Context, id int) (*Game, error) {
+	ctx, span := startSQLiteSpan(ctx, "GameModel.Get")
+	defer span.End()
+
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, finished, player, quiz, in_progress
+		FROM games
+		WHERE id = $1
+		`
+
+	var game Game
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&game.Id, &game.Finished, &game.Player, &game.Quiz, &game.InProgress)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &game, nil
+}
+
+func (m sqliteGames) GetForPlayers(ctx context.Context, players []int) ([]*Game, error) {
+	ctx, span := startSQLiteSpan(ctx, "GameModel.GetForPlayers")
+	defer span.End()
+
+	query := `
+		SELECT id, finished, player, quiz, in_progress
+		FROM games
+		WHERE player IN (SELECT value FROM json_each($1)) AND NOT in_progress
+		ORDER BY finished DESC, id DESC
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, sqliteIDs(players))
+	if err != nil {
+		return nil, err
+	}
+	defer m.closeRows(rows)
+
+	var games []*Game
+	for rows.Next() {
+		var game Game
+		if err := rows.Scan(&game.Id, &game.Finished, &game.Player, &game.Quiz, &game.InProgress); err != nil {
+			return nil, err
+		}
+
+		games = append(games, &game)
+	}
+
+	return games, rows.Err()
+}
+
+func (m sqliteGames) Finish(ctx context.Context, game *Game) error {
+	ctx, span := startSQLiteSpan(ctx, "GameModel.Finish")
+	defer span.End()
+
+	query := `
+		UPDATE games
+		SET in_progress = false, finished = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id
+			FROM games
+			WHERE player = $1 AND quiz = $2 AND in_progress
+			ORDER BY id
+			LIMIT 1
+		)
+		RETURNING id, finished
+		`
+
+	game.InProgress = false
+
+	err := func() error {
+		ctx, cancel := queryContext(ctx, m.Timeout)
+		defer cancel()
+
+		return m.DB.QueryRowContext(ctx, query, game.Player, game.Quiz).Scan(&game.Id, &game.Finished)
+	}()
+	if errors.Is(err, sql.ErrNoRows) {
+		return m.Insert(ctx, game)
+	}
+
+	return err
+}
+
+func (m sqliteGames) DeleteAbandoned(ctx context.Context, startedBefore time.Time) (int64, error) {
+	ctx, span := startSQLiteSpan(ctx, "GameModel.DeleteAbandoned")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM games WHERE in_progress AND finished < $1`, sqliteTime(startedBefore))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (m sqliteGames) Delete(ctx context.Context, id int) error {
+	ctx, span := startSQLiteSpan(ctx, "GameModel.Delete")
+	defer span.End()
+
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM games WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+)
+
+type sqliteImports struct{ sqliteModel }
+
+func (m sqliteImports) Import(ctx context.Context, imp *Import) (int, error) {
+	ctx, span := startSQLiteSpan(ctx, "ImportModel.Import")
+	defer span.End()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO imports (digest)
+		VALUES ($1)
+		ON CONFLICT DO NOTHING
+		`
+
+	queryCtx, cancel := queryContext(ctx, m.Timeout)
+	result, err := tx.ExecContext(queryCtx, query, imp.Digest)
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if rows == 0 {
+		return 0, ErrDuplicateImport
+	}
+
+	skipped, err := importRecords(ctx, sqliteImporter{tx: tx, sqliteModel: m.sqliteModel}, imp)
+	if err != nil {
+		return 0, err
+	}
+
+	return skipped, tx.Commit()
+}
+
+// sqliteImporter inserts the records of an import in a SQLite transaction.
+type sqliteImporter struct {
+	sqliteModel
+	tx *sql.Tx
+}
+
+func (i sqliteImporter) insertPlayer(ctx context.Context, player *Player) error {
+	query := `
+		INSERT INTO players (name, joined, last_update, score, owner_id)
+		VALUES ($1, $2, $3, $4, (SELECT id FROM users WHERE id = $5))
+		RETURNING id, joined, last_update, owner_id
+		`
+
+	ctx, cancel := queryContext(ctx, i.Timeout)
+	defer cancel()
+
+	args := []interface{}{player.Name, sqliteTime(player.Joined), sqliteTime(player.LastUpdate), player.Score, player.OwnerID}
+	return i.tx.QueryRowContext(ctx, query, args...).Scan(&player.Id, &player.Joined, &player.LastUpdate, &player.OwnerID)
+}
+
+func (i sqliteImporter) insertQuiz(ctx context.Context, quiz *Quiz) error {
+	query := `
+		INSERT INTO quizes (category, reward, questions, answers, owner_id)
+		VALUES ($1, $2, $3, $4, (SELECT id FROM users WHERE id = $5))
+		RETURNING id, owner_id
+		`
+
+	ctx, cancel := queryContext(ctx, i.Timeout)
+	defer cancel()
+
+	args := []interface{}{quiz.Category, quiz.Reward, sqliteStrings(quiz.Questions), sqliteStrings(quiz.Answers), quiz.OwnerID}
+	return i.tx.QueryRowContext(ctx, query, args...).Scan(&quiz.Id, &quiz.OwnerID)
+}
+
+func (i sqliteImporter) insertGame(ctx context.Context, game *Game) error {
+	query := `
+		INSERT INTO games (finished, player, quiz, in_progress)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, finished
+		`
+
+	ctx, cancel := queryContext(ctx, i.Timeout)
+	defer cancel()
+
+	return i.tx.QueryRowContext(ctx, query, sqliteTime(game.Finished), game.Player, game.Quiz, game.InProgress).Scan(&game.Id, &game.Finished)
+}
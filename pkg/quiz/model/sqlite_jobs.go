@@ -0,0 +1,106 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type sqliteJobRuns struct{ sqliteModel }
+
+func (m sqliteJobRuns) Insert(ctx context.Context, run *JobRun) error {
+	ctx, span := startSQLiteSpan(ctx, "JobRunModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO job_runs (job, started_at, duration_ms, succeeded, affected, error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+		`
+
+	args := []interface{}{run.Job, sqliteTime(run.StartedAt), run.DurationMS, run.Succeeded, run.Affected, run.Error}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&run.ID)
+}
+
+func (m sqliteJobRuns) LastSucceeded(ctx context.Context, job string) (time.Time, error) {
+	ctx, span := startSQLiteSpan(ctx, "JobRunModel.LastSucceeded")
+	defer span.End()
+
+	query := `
+		SELECT max(started_at)
+		FROM job_runs
+		WHERE job = $1 AND succeeded
+		`
+
+	var last sql.NullString
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, job).Scan(&last)
+	if err != nil || !last.Valid {
+		return time.Time{}, err
+	}
+
+	// The result of max has no declared type, so the driver returns it as text.
+	return parseSQLiteTime(last.String)
+}
+
+func (m sqliteJobRuns) GetAll(ctx context.Context, job string, filters Filters) ([]*JobRun, Metadata, error) {
+	ctx, span := startSQLiteSpan(ctx, "JobRunModel.GetAll")
+	defer span.End()
+
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), id, job, started_at, duration_ms, succeeded, affected, error
+		FROM job_runs
+		WHERE (job = $1 OR $1 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3
+		`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, job, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.closeRows(rows)
+
+	return scanJobRuns(rows, filters)
+}
+
+type sqliteLeaderboard struct{ sqliteModel }
+
+func (m sqliteLeaderboard) Refresh(ctx context.Context) (int64, error) {
+	ctx, span := startSQLiteSpan(ctx, "LeaderboardModel.Refresh")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return refreshLeaderboard(ctx, m.DB)
+}
+
+func (m sqliteLeaderboard) GetAll(ctx context.Context, filters Filters) ([]*LeaderboardEntry, Metadata, error) {
+	ctx, span := startSQLiteSpan(ctx, "LeaderboardModel.GetAll")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, leaderboardQuery(filters), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.closeRows(rows)
+
+	return scanLeaderboard(rows, filters)
+}
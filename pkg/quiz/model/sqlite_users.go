@@ -0,0 +1,685 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type sqliteUsers struct{ sqliteModel }
+
+// scanUser scans the columns selected by the queries below into a user.
+func scanUser(row *sql.Row) (*User, error) {
+	var user User
+
+	err := row.Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+		&user.FailedLogins,
+		&user.LockedUntil,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+func (m sqliteUsers) Insert(ctx context.Context, user *User) error {
+	ctx, span := startSQLiteSpan(ctx, "UserModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO users (name, email, password_hash, activated)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, version
+		`
+
+	args := []interface{}{user.Name, user.Email, user.Password.hash, user.Activated}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	if err != nil {
+		switch {
+		case sqliteUniqueViolation(err, "users.email"):
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m sqliteUsers) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, span := startSQLiteSpan(ctx, "UserModel.GetByEmail")
+	defer span.End()
+
+	query := `
+		SELECT id, created_at, name, email, password_hash, activated, version, failed_logins, locked_until
+		FROM users
+		WHERE email = $1
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return scanUser(m.DB.QueryRowContext(ctx, query, email))
+}
+
+func (m sqliteUsers) Get(ctx context.Context, id int64) (*User, error) {
+	ctx, span := startSQLiteSpan(ctx, "UserModel.Get")
+	defer span.End()
+
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, name, email, password_hash, activated, version, failed_logins, locked_until
+		FROM users
+		WHERE id = $1
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return scanUser(m.DB.QueryRowContext(ctx, query, id))
+}
+
+func (m sqliteUsers) GetByIDs(ctx context.Context, ids []int64) ([]*User, error) {
+	ctx, span := startSQLiteSpan(ctx, "UserModel.GetByIDs")
+	defer span.End()
+
+	query := `
+		SELECT id, created_at, name, email, password_hash, activated, version, failed_logins, locked_until
+		FROM users
+		WHERE id IN (SELECT value FROM json_each($1))
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	js, _ := json.Marshal(ids)
+	rows, err := m.DB.QueryContext(ctx, query, string(js))
+	if err != nil {
+		return nil, err
+	}
+	defer m.closeRows(rows)
+
+	var users []*User
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&user.ID,
+			&user.CreatedAt,
+			&user.Name,
+			&user.Email,
+			&user.Password.hash,
+			&user.Activated,
+			&user.Version,
+			&user.FailedLogins,
+			&user.LockedUntil,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, &user)
+	}
+
+	return users, rows.Err()
+}
+
+func (m sqliteUsers) GetAll(ctx context.Context, email string, filters Filters) ([]*User, Metadata, error) {
+	ctx, span := startSQLiteSpan(ctx, "UserModel.GetAll")
+	defer span.End()
+
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), id, created_at, name, email, password_hash, activated, version, failed_logins, locked_until
+		FROM users
+		WHERE (email = $1 OR $1 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3
+		`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, email, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.closeRows(rows)
+
+	totalRecords := 0
+
+	var users []*User
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&totalRecords,
+			&user.ID,
+			&user.CreatedAt,
+			&user.Name,
+			&user.Email,
+			&user.Password.hash,
+			&user.Activated,
+			&user.Version,
+			&user.FailedLogins,
+			&user.LockedUntil,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return users, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+func (m sqliteUsers) Update(ctx context.Context, user *User) error {
+	ctx, span := startSQLiteSpan(ctx, "UserModel.Update")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING version
+		`
+
+	args := []interface{}{
+		user.Name,
+		user.Email,
+		user.Password.hash,
+		user.Activated,
+		user.ID,
+		user.Version,
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
+	if err != nil {
+		switch {
+		case sqliteUniqueViolation(err, "users.email"):
+			return ErrDuplicateEmail
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m sqliteUsers) RecordFailedLogin(ctx context.Context, id int64) (int, error) {
+	ctx, span := startSQLiteSpan(ctx, "UserModel.RecordFailedLogin")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET failed_logins = failed_logins + 1
+		WHERE id = $1
+		RETURNING failed_logins
+		`
+
+	var failedLogins int
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&failedLogins)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return failedLogins, nil
+}
+
+func (m sqliteUsers) Lock(ctx context.Context, id int64, until time.Time) error {
+	ctx, span := startSQLiteSpan(ctx, "UserModel.Lock")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `UPDATE users SET locked_until = $2 WHERE id = $1`, id, sqliteTime(until))
+	return err
+}
+
+func (m sqliteUsers) Unlock(ctx context.Context, id int64) error {
+	ctx, span := startSQLiteSpan(ctx, "UserModel.Unlock")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `UPDATE users SET failed_logins = 0, locked_until = NULL WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m sqliteUsers) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
+	ctx, span := startSQLiteSpan(ctx, "UserModel.GetForToken")
+	defer span.End()
+
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+		SELECT
+			users.id, users.created_at, users.name, users.email,
+			users.password_hash, users.activated, users.version,
+			users.failed_logins, users.locked_until
+		FROM users
+			INNER JOIN tokens ON users.id = tokens.user_id
+		WHERE tokens.hash = $1
+			AND tokens.scope = $2
+			AND tokens.expiry > $3
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return scanUser(m.DB.QueryRowContext(ctx, query, tokenHash[:], tokenScope, sqliteTime(time.Now())))
+}
+
+type sqliteTokens struct{ sqliteModel }
+
+func (m sqliteTokens) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
+	ctx, span := startSQLiteSpan(ctx, "TokenModel.New")
+	defer span.End()
+
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(ctx, token)
+	return token, err
+}
+
+func (m sqliteTokens) Insert(ctx context.Context, token *Token) error {
+	ctx, span := startSQLiteSpan(ctx, "TokenModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO tokens (hash, user_id, expiry, scope)
+		VALUES ($1, $2, $3, $4)
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, token.Hash, token.UserID, sqliteTime(token.Expiry), token.Scope)
+	return err
+}
+
+func (m sqliteTokens) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	ctx, span := startSQLiteSpan(ctx, "TokenModel.DeleteAllForUser")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM tokens WHERE scope = $1 AND user_id = $2`, scope, userID)
+	return err
+}
+
+func (m sqliteTokens) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSQLiteSpan(ctx, "TokenModel.DeleteExpired")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM tokens WHERE expiry < $1`, sqliteTime(before))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+type sqlitePermissions struct{ sqliteModel }
+
+func (m sqlitePermissions) GetAll(ctx context.Context) (Permissions, error) {
+	ctx, span := startSQLiteSpan(ctx, "PermissionModel.GetAll")
+	defer span.End()
+
+	return m.query(ctx, `SELECT code FROM permissions ORDER BY code`)
+}
+
+func (m sqlitePermissions) GetAllForUser(ctx context.Context, userID int64) (Permissions, error) {
+	ctx, span := startSQLiteSpan(ctx, "PermissionModel.GetAllForUser")
+	defer span.End()
+
+	query := `
+		SELECT permissions.code
+		FROM permissions
+			INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+		WHERE users_permissions.user_id = $1
+		UNION
+		SELECT permissions.code
+		FROM permissions
+			INNER JOIN roles_permissions ON roles_permissions.permission_id = permissions.id
+			INNER JOIN users_roles ON users_roles.role_id = roles_permissions.role_id
+		WHERE users_roles.user_id = $1
+		ORDER BY code
+		`
+
+	return m.query(ctx, query, userID)
+}
+
+func (m sqlitePermissions) GetDirectForUser(ctx context.Context, userID int64) (Permissions, error) {
+	ctx, span := startSQLiteSpan(ctx, "PermissionModel.GetDirectForUser")
+	defer span.End()
+
+	query := `
+		SELECT permissions.code
+		FROM permissions
+			INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+		WHERE users_permissions.user_id = $1
+		ORDER BY permissions.code
+		`
+
+	return m.query(ctx, query, userID)
+}
+
+// query runs a query returning a single column of codes.
+func (m sqliteModel) query(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer m.closeRows(rows)
+
+	codes := []string{}
+
+	for rows.Next() {
+		var code string
+
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (m sqlitePermissions) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	ctx, span := startSQLiteSpan(ctx, "PermissionModel.AddForUser")
+	defer span.End()
+
+	// SQLite has no arrays, the codes are passed as a JSON array and expanded with json_each.
+	query := `
+		INSERT OR IGNORE INTO users_permissions (user_id, permission_id)
+		SELECT $1, permissions.id FROM permissions WHERE permissions.code IN (SELECT value FROM json_each($2))
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, sqliteStrings(codes))
+	return err
+}
+
+func (m sqlitePermissions) RemoveForUser(ctx context.Context, userID int64, code string) error {
+	ctx, span := startSQLiteSpan(ctx, "PermissionModel.RemoveForUser")
+	defer span.End()
+
+	query := `
+		DELETE FROM users_permissions
+		WHERE user_id = $1 AND permission_id IN (SELECT id FROM permissions WHERE code = $2)
+		`
+
+	return m.execOne(ctx, query, userID, code)
+}
+
+// execOne runs a statement and returns ErrRecordNotFound if it didn't change any row.
+func (m sqliteModel) execOne(ctx context.Context, query string, args ...interface{}) error {
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+type sqliteRoles struct{ sqliteModel }
+
+func (m sqliteRoles) GetAll(ctx context.Context) ([]*Role, error) {
+	ctx, span := startSQLiteSpan(ctx, "RoleModel.GetAll")
+	defer span.End()
+
+	query := `
+		SELECT roles.id, roles.code, roles.description,
+			(SELECT json_group_array(code) FROM (
+				SELECT permissions.code
+				FROM roles_permissions
+					INNER JOIN permissions ON roles_permissions.permission_id = permissions.id
+				WHERE roles_permissions.role_id = roles.id
+				ORDER BY permissions.code
+			))
+		FROM roles
+		ORDER BY roles.id
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer m.closeRows(rows)
+
+	var roles []*Role
+
+	for rows.Next() {
+		var role Role
+
+		err := rows.Scan(&role.ID, &role.Code, &role.Description, (*sqliteStrings)(&role.Permissions))
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, &role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (m sqliteRoles) Exists(ctx context.Context, code string) (bool, error) {
+	ctx, span := startSQLiteSpan(ctx, "RoleModel.Exists")
+	defer span.End()
+
+	var exists bool
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM roles WHERE code = $1)`, code).Scan(&exists)
+	return exists, err
+}
+
+func (m sqliteRoles) GetAllForUser(ctx context.Context, userID int64) ([]string, error) {
+	ctx, span := startSQLiteSpan(ctx, "RoleModel.GetAllForUser")
+	defer span.End()
+
+	query := `
+		SELECT roles.code
+		FROM roles
+			INNER JOIN users_roles ON users_roles.role_id = roles.id
+		WHERE users_roles.user_id = $1
+		ORDER BY roles.id
+		`
+
+	return m.query(ctx, query, userID)
+}
+
+func (m sqliteRoles) AddForUser(ctx context.Context, userID int64, codes ...string) error {
+	ctx, span := startSQLiteSpan(ctx, "RoleModel.AddForUser")
+	defer span.End()
+
+	query := `
+		INSERT OR IGNORE INTO users_roles (user_id, role_id)
+		SELECT $1, roles.id FROM roles WHERE roles.code IN (SELECT value FROM json_each($2))
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, sqliteStrings(codes))
+	return err
+}
+
+func (m sqliteRoles) RemoveForUser(ctx context.Context, userID int64, code string) error {
+	ctx, span := startSQLiteSpan(ctx, "RoleModel.RemoveForUser")
+	defer span.End()
+
+	query := `
+		DELETE FROM users_roles
+		WHERE user_id = $1 AND role_id IN (SELECT id FROM roles WHERE code = $2)
+		`
+
+	return m.execOne(ctx, query, userID, code)
+}
+
+// RemoveForUserUnlessLast deletes the grant first and then counts the holders left in the same
+// transaction. The delete takes the database write lock, so concurrent revokes run one after the
+// other and the later one sees that nobody would be left.
+func (m sqliteRoles) RemoveForUserUnlessLast(ctx context.Context, userID int64, code string) error {
+	ctx, span := startSQLiteSpan(ctx, "RoleModel.RemoveForUserUnlessLast")
+	defer span.End()
+
+	query := `
+		DELETE FROM users_roles
+		WHERE user_id = $1 AND role_id IN (SELECT id FROM roles WHERE code = $2)
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, userID, code)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	query = `
+		SELECT count(*)
+		FROM users_roles
+			INNER JOIN roles ON users_roles.role_id = roles.id
+		WHERE roles.code = $1
+		`
+
+	var holders int
+	if err := tx.QueryRowContext(ctx, query, code).Scan(&holders); err != nil {
+		return err
+	}
+	if holders == 0 {
+		return ErrLastRoleHolder
+	}
+
+	return tx.Commit()
+}
+
+func (m sqliteRoles) CountUsers(ctx context.Context, code string) (int, error) {
+	ctx, span := startSQLiteSpan(ctx, "RoleModel.CountUsers")
+	defer span.End()
+
+	query := `
+		SELECT count(*)
+		FROM users_roles
+			INNER JOIN roles ON users_roles.role_id = roles.id
+		WHERE roles.code = $1
+		`
+
+	var count int
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, code).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,366 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type sqliteWebhooks struct{ sqliteModel }
+
+func (m sqliteWebhooks) Insert(ctx context.Context, webhook *Webhook) error {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO webhooks (user_id, url, events, secret, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, version
+		`
+
+	args := []interface{}{webhook.UserID, webhook.URL, sqliteStrings(webhook.Events), webhook.Secret, webhook.Active}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&webhook.ID, &webhook.CreatedAt, &webhook.Version)
+}
+
+func (m sqliteWebhooks) Get(ctx context.Context, userID, id int64) (*Webhook, error) {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.Get")
+	defer span.End()
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE id = $1 AND user_id = $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	var webhook Webhook
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(sqliteWebhookFields(&webhook)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &webhook, nil
+}
+
+func (m sqliteWebhooks) GetAllForUser(ctx context.Context, userID int64) ([]*Webhook, error) {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.GetAllForUser")
+	defer span.End()
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY id
+		`
+
+	return m.query(ctx, query, userID)
+}
+
+func (m sqliteWebhooks) GetAllForEvent(ctx context.Context, event string) ([]*Webhook, error) {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.GetAllForEvent")
+	defer span.End()
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE active AND EXISTS (SELECT 1 FROM json_each(webhooks.events) WHERE json_each.value = $1)
+		ORDER BY id
+		`
+
+	return m.query(ctx, query, event)
+}
+
+func (m sqliteWebhooks) query(ctx context.Context, query string, args ...interface{}) ([]*Webhook, error) {
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer m.closeRows(rows)
+
+	webhooks := []*Webhook{}
+
+	for rows.Next() {
+		var webhook Webhook
+		if err := rows.Scan(sqliteWebhookFields(&webhook)...); err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, &webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (m sqliteWebhooks) Update(ctx context.Context, webhook *Webhook) error {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.Update")
+	defer span.End()
+
+	query := `
+		UPDATE webhooks
+		SET url = $1, events = $2, active = $3, version = version + 1
+		WHERE id = $4 AND user_id = $5 AND version = $6
+		RETURNING version
+		`
+
+	args := []interface{}{webhook.URL, sqliteStrings(webhook.Events), webhook.Active, webhook.ID, webhook.UserID, webhook.Version}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&webhook.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m sqliteWebhooks) Delete(ctx context.Context, userID, id int64) error {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.Delete")
+	defer span.End()
+
+	query := `
+		DELETE FROM webhooks
+		WHERE id = $1 AND user_id = $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m sqliteWebhooks) InsertDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.InsertDelivery")
+	defer span.End()
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, event_id, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+		`
+
+	args := []interface{}{
+		delivery.WebhookID,
+		delivery.Event,
+		delivery.EventID,
+		string(delivery.Payload),
+		delivery.Status,
+		sqliteNullTime(delivery.NextAttemptAt),
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&delivery.ID, &delivery.CreatedAt)
+}
+
+func (m sqliteWebhooks) GetDelivery(ctx context.Context, webhookID, id int64) (*WebhookDelivery, error) {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.GetDelivery")
+	defer span.End()
+
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE id = $1 AND webhook_id = $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	var delivery WebhookDelivery
+	err := m.DB.QueryRowContext(ctx, query, id, webhookID).Scan(webhookDeliveryFields(&delivery)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &delivery, nil
+}
+
+func (m sqliteWebhooks) GetDeliveries(ctx context.Context, webhookID int64, status string, filters Filters) ([]*WebhookDelivery, Metadata, error) {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.GetDeliveries")
+	defer span.End()
+
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), %s
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		AND (status = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4
+		`,
+		webhookDeliveryColumns, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, webhookID, status, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer m.closeRows(rows)
+
+	return scanWebhookDeliveries(rows, filters)
+}
+
+// ClaimDeliveries selects and postpones the due deliveries in a transaction, SQLite has no
+// SKIP LOCKED. Writes to a SQLite database are serialized anyway.
+func (m sqliteWebhooks) ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.ClaimDeliveries")
+	defer span.End()
+
+	query := `
+		SELECT ` + webhookDeliveryColumns + `, ` + webhookColumns + `
+		FROM webhook_deliveries
+			INNER JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+		WHERE webhook_deliveries.status = 'pending'
+		AND webhook_deliveries.next_attempt_at <= $1
+		AND webhooks.active
+		ORDER BY webhook_deliveries.next_attempt_at, webhook_deliveries.id
+		LIMIT $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, sqliteTime(now), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer m.closeRows(rows)
+
+	var (
+		deliveries []*WebhookDelivery
+		ids        []int
+	)
+	for rows.Next() {
+		var (
+			delivery WebhookDelivery
+			webhook  Webhook
+		)
+
+		err := rows.Scan(append(webhookDeliveryFields(&delivery), sqliteWebhookFields(&webhook)...)...)
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.Webhook = &webhook
+		deliveries = append(deliveries, &delivery)
+		ids = append(ids, int(delivery.ID))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	m.closeRows(rows)
+
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+
+	query = `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $1
+		WHERE id IN (SELECT value FROM json_each($2))
+		`
+
+	if _, err := tx.ExecContext(ctx, query, sqliteTime(now.Add(lease)), sqliteIDs(ids)); err != nil {
+		return nil, err
+	}
+
+	return deliveries, tx.Commit()
+}
+
+func (m sqliteWebhooks) UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	ctx, span := startSQLiteSpan(ctx, "WebhookModel.UpdateDelivery")
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3, last_attempt_at = $4,
+			response_status = $5, error = $6, duration_ms = $7
+		WHERE id = $8
+		`
+
+	args := []interface{}{
+		delivery.Status,
+		delivery.Attempts,
+		sqliteNullTime(delivery.NextAttemptAt),
+		sqliteNullTime(delivery.LastAttemptAt),
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.DurationMS,
+		delivery.ID,
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// sqliteWebhookFields returns the destinations of the webhookColumns of a row, with the events
+// stored as a JSON array.
+func sqliteWebhookFields(webhook *Webhook) []interface{} {
+	return []interface{}{
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		(*sqliteStrings)(&webhook.Events),
+		&webhook.Secret,
+		&webhook.Active,
+		&webhook.CreatedAt,
+		&webhook.Version,
+	}
+}
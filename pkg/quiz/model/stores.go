@@ -0,0 +1,176 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// PlayerStore stores players. PlayerModel keeps them in Postgres.
+type PlayerStore interface {
+	GetAll(ctx context.Context, name string, from, to int, joined TimeRange, filters Filters) ([]*Player, Metadata, error)
+	Insert(ctx context.Context, player *Player) error
+	Get(ctx context.Context, id int) (*Player, error)
+	GetByIDs(ctx context.Context, ids []int) ([]*Player, error)
+	Update(ctx context.Context, player *Player) error
+	Delete(ctx context.Context, id int) error
+	GetOwner(ctx context.Context, id int) (int64, error)
+}
+
+// QuizStore stores quizes. QuizModel keeps them in Postgres.
+type QuizStore interface {
+	GetAll(ctx context.Context, category string, from, to int, filters Filters) ([]*Quiz, Metadata, error)
+	Insert(ctx context.Context, quiz *Quiz) error
+	Get(ctx context.Context, id int) (*Quiz, error)
+	GetByIDs(ctx context.Context, ids []int) ([]*Quiz, error)
+	Update(ctx context.Context, quiz *Quiz) error
+	Delete(ctx context.Context, id int) error
+	GetOwner(ctx context.Context, id int) (int64, error)
+}
+
+// GameStore stores games, finished and in progress. GameModel keeps them in Postgres.
+type GameStore interface {
+	GetAll(ctx context.Context, player, quiz int, finishedOnly bool, finished TimeRange, filters Filters) ([]*Game, Metadata, error)
+	Insert(ctx context.Context, game *Game) error
+	Get(ctx context.Context, id int) (*Game, error)
+	GetForPlayers(ctx context.Context, players []int) ([]*Game, error)
+	Finish(ctx context.Context, game *Game) error
+	Delete(ctx context.Context, id int) error
+	DeleteAbandoned(ctx context.Context, startedBefore time.Time) (int64, error)
+}
+
+// UserStore stores users. UserModel keeps them in Postgres.
+type UserStore interface {
+	Insert(ctx context.Context, user *User) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	Get(ctx context.Context, id int64) (*User, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]*User, error)
+	GetAll(ctx context.Context, email string, filters Filters) ([]*User, Metadata, error)
+	Update(ctx context.Context, user *User) error
+	RecordFailedLogin(ctx context.Context, id int64) (int, error)
+	Lock(ctx context.Context, id int64, until time.Time) error
+	Unlock(ctx context.Context, id int64) error
+	GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error)
+}
+
+// TokenStore stores activation, authentication and two-factor tokens. TokenModel keeps them in
+// Postgres.
+type TokenStore interface {
+	New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error)
+	Insert(ctx context.Context, token *Token) error
+	DeleteAllForUser(ctx context.Context, scope string, userID int64) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PermissionStore stores the permissions granted to users. PermissionModel keeps them in
+// Postgres.
+type PermissionStore interface {
+	GetAll(ctx context.Context) (Permissions, error)
+	GetAllForUser(ctx context.Context, userID int64) (Permissions, error)
+	GetDirectForUser(ctx context.Context, userID int64) (Permissions, error)
+	AddForUser(ctx context.Context, userID int64, codes ...string) error
+	RemoveForUser(ctx context.Context, userID int64, code string) error
+}
+
+// RoleStore stores roles and the roles granted to users. RoleModel keeps them in Postgres.
+type RoleStore interface {
+	GetAll(ctx context.Context) ([]*Role, error)
+	Exists(ctx context.Context, code string) (bool, error)
+	GetAllForUser(ctx context.Context, userID int64) ([]string, error)
+	AddForUser(ctx context.Context, userID int64, codes ...string) error
+	RemoveForUser(ctx context.Context, userID int64, code string) error
+	RemoveForUserUnlessLast(ctx context.Context, userID int64, code string) error
+	CountUsers(ctx context.Context, code string) (int, error)
+}
+
+// APIKeyStore stores API keys. APIKeyModel keeps them in Postgres.
+type APIKeyStore interface {
+	New(ctx context.Context, userID int64, name string, scopes Permissions, expiry *time.Time) (*APIKey, error)
+	GetForKey(ctx context.Context, plaintext string) (*APIKey, error)
+	GetAllForUser(ctx context.Context, userID int64) ([]*APIKey, error)
+	Touch(ctx context.Context, key *APIKey) error
+	Revoke(ctx context.Context, userID, id int64) error
+}
+
+// IdentityStore stores external identities and pending OpenID Connect logins. IdentityModel keeps
+// them in Postgres.
+type IdentityStore interface {
+	Insert(ctx context.Context, identity *Identity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*Identity, error)
+	InsertLoginState(ctx context.Context, state *LoginState) error
+	ConsumeLoginState(ctx context.Context, provider, plaintext string) (*LoginState, error)
+}
+
+// TwoFactorStore stores TOTP enrollments and recovery codes. TwoFactorModel keeps them in
+// Postgres.
+type TwoFactorStore interface {
+	Get(ctx context.Context, userID int64) (*TOTP, error)
+	Enabled(ctx context.Context, userID int64) (bool, error)
+	Enroll(ctx context.Context, userID int64, secret string) error
+	Confirm(ctx context.Context, userID int64) error
+	UseStep(ctx context.Context, userID, step int64) (bool, error)
+	Delete(ctx context.Context, userID int64) error
+	NewRecoveryCodes(ctx context.Context, userID int64) ([]string, error)
+	UseRecoveryCode(ctx context.Context, userID int64, code string) (bool, error)
+}
+
+// LoginAttemptStore stores the audit records of login attempts. LoginAttemptModel keeps them in
+// Postgres.
+type LoginAttemptStore interface {
+	Insert(ctx context.Context, attempt *LoginAttempt) error
+	FailuresForIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error)
+	GetAll(ctx context.Context, email, ip string, filters Filters) ([]*LoginAttempt, Metadata, error)
+}
+
+// JobRunStore stores the history of the periodic jobs. JobRunModel keeps it in Postgres.
+type JobRunStore interface {
+	Insert(ctx context.Context, run *JobRun) error
+	LastSucceeded(ctx context.Context, job string) (time.Time, error)
+	GetAll(ctx context.Context, job string, filters Filters) ([]*JobRun, Metadata, error)
+}
+
+// LeaderboardStore stores the ranking of the players. LeaderboardModel keeps it in Postgres.
+type LeaderboardStore interface {
+	Refresh(ctx context.Context) (int64, error)
+	GetAll(ctx context.Context, filters Filters) ([]*LeaderboardEntry, Metadata, error)
+}
+
+// WebhookStore stores the webhooks of the users and their deliveries. WebhookModel keeps them
+// in Postgres.
+type WebhookStore interface {
+	Insert(ctx context.Context, webhook *Webhook) error
+	Get(ctx context.Context, userID, id int64) (*Webhook, error)
+	GetAllForUser(ctx context.Context, userID int64) ([]*Webhook, error)
+	GetAllForEvent(ctx context.Context, event string) ([]*Webhook, error)
+	Update(ctx context.Context, webhook *Webhook) error
+	Delete(ctx context.Context, userID, id int64) error
+	InsertDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	GetDelivery(ctx context.Context, webhookID, id int64) (*WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookID int64, status string, filters Filters) ([]*WebhookDelivery, Metadata, error)
+	ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+}
+
+// ImportStore imports players, quizes and games in one transaction. ImportModel keeps them in
+// Postgres.
+type ImportStore interface {
+	Import(ctx context.Context, imp *Import) (int, error)
+}
+
+var (
+	_ PlayerStore     = PlayerModel{}
+	_ QuizStore       = QuizModel{}
+	_ GameStore       = GameModel{}
+	_ UserStore       = UserModel{}
+	_ TokenStore      = TokenModel{}
+	_ PermissionStore = PermissionModel{}
+	_ RoleStore       = RoleModel{}
+	_ APIKeyStore     = APIKeyModel{}
+	_ IdentityStore   = IdentityModel{}
+	_ TwoFactorStore  = TwoFactorModel{}
+
+	_ LoginAttemptStore = LoginAttemptModel{}
+	_ JobRunStore       = JobRunModel{}
+	_ LeaderboardStore  = LeaderboardModel{}
+	_ WebhookStore      = WebhookModel{}
+	_ ImportStore       = ImportModel{}
+)
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// TimeRange selects the times from From on and before To. A zero From or To leaves that end of
+// the range open, the zero TimeRange selects all times.
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// IsZero reports whether the range selects all times.
+func (r TimeRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// Contains reports whether t is in the range.
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) && (r.To.IsZero() || t.Before(r.To))
+}
+
+// timeBound returns a bound of a range as a query parameter, NULL if that end is open.
+func timeBound(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t
+}
+
+// sqliteTimeBound is timeBound for SQLite, which compares timestamps as text.
+func sqliteTimeBound(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+
+	return sqliteTime(t)
+}
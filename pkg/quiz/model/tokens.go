@@ -13,9 +13,12 @@ import (
 )
 
 // ScopeActivation defines the "activate" scope for scope in the tokens table.
+// ScopeTwoFactor tokens are issued after a correct password for users with two-factor
+// authentication, and can only be exchanged for an authentication token with a valid code.
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopeTwoFactor      = "two-factor"
 )
 
 type (
@@ -35,23 +38,30 @@ type (
 		DB       *sql.DB
 		InfoLog  *log.Logger
 		ErrorLog *log.Logger
+		Timeout  time.Duration
 	}
 )
 
 // New creates a new token and inserts the token record into the tokens table.
-func (m TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token, error) {
+func (m TokenModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
+	ctx, span := startSpan(ctx, "TokenModel.New")
+	defer span.End()
+
 	token, err := generateToken(userID, ttl, scope)
 	if err != nil {
 		return nil, err
 	}
 
-	err = m.Insert(token)
+	err = m.Insert(ctx, token)
 	return token, err
 
 }
 
 // Insert inserts a new token record into the tokens table.
-func (m TokenModel) Insert(token *Token) error {
+func (m TokenModel) Insert(ctx context.Context, token *Token) error {
+	ctx, span := startSpan(ctx, "TokenModel.Insert")
+	defer span.End()
+
 	query := `
 		INSERT INTO tokens (hash, user_id, expiry, scope)
 		VALUES ($1, $2, $3, $4)
@@ -59,7 +69,7 @@ func (m TokenModel) Insert(token *Token) error {
 
 	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, args...)
@@ -67,19 +77,38 @@ func (m TokenModel) Insert(token *Token) error {
 }
 
 // DeleteAllForUser deletes all tokens for a specific user and scope.
-func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
+func (m TokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	ctx, span := startSpan(ctx, "TokenModel.DeleteAllForUser")
+	defer span.End()
+
 	query := `
 		DELETE FROM tokens
 		WHERE scope = $1 AND user_id = $2
 		`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, scope, userID)
 	return err
 }
 
+// DeleteExpired deletes the tokens which expired before the given time and returns their number.
+func (m TokenModel) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "TokenModel.DeleteExpired")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM tokens WHERE expiry < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
 	// Create a Token instance containing the user ID, expiry, and scope information.
 	// Notice that we add the provided ttl (time-to-live) duration parameter to the
@@ -0,0 +1,15 @@
+package model
+
+import (
+	"context"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/tracing"
+)
+
+// startSpan starts the span of a model method, as a child of the span of the request in ctx.
+func startSpan(ctx context.Context, name string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, name)
+	span.SetAttribute("db.system", "postgresql")
+
+	return ctx, span
+}
@@ -0,0 +1,292 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/totp"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// recoveryCodeCount is the number of recovery codes generated for a user at once.
+const recoveryCodeCount = 10
+
+type (
+	// TOTP holds the time-based one-time password enrollment of a user. The enrollment only
+	// counts as two-factor authentication once it's Confirmed with a valid code.
+	TOTP struct {
+		UserID       int64
+		Secret       string
+		Confirmed    bool
+		LastUsedStep int64
+		CreatedAt    time.Time
+	}
+
+	// TwoFactorModel struct wraps a sql.DB connection pool and allows us to work with the
+	// users_totp and recovery_codes tables.
+	TwoFactorModel struct {
+		DB       *sql.DB
+		InfoLog  *log.Logger
+		ErrorLog *log.Logger
+		Timeout  time.Duration
+	}
+)
+
+// Get retrieves the TOTP enrollment of a user.
+func (m TwoFactorModel) Get(ctx context.Context, userID int64) (*TOTP, error) {
+	ctx, span := startSpan(ctx, "TwoFactorModel.Get")
+	defer span.End()
+
+	query := `
+		SELECT user_id, secret, confirmed, last_used_step, created_at
+		FROM users_totp
+		WHERE user_id = $1
+		`
+
+	var enrollment TOTP
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&enrollment.UserID,
+		&enrollment.Secret,
+		&enrollment.Confirmed,
+		&enrollment.LastUsedStep,
+		&enrollment.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &enrollment, nil
+}
+
+// Enabled reports whether the user has a confirmed TOTP enrollment.
+func (m TwoFactorModel) Enabled(ctx context.Context, userID int64) (bool, error) {
+	ctx, span := startSpan(ctx, "TwoFactorModel.Enabled")
+	defer span.End()
+
+	query := `
+		SELECT EXISTS(SELECT 1 FROM users_totp WHERE user_id = $1 AND confirmed)
+		`
+
+	var enabled bool
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// Enroll stores a new unconfirmed secret for the user, replacing any earlier unconfirmed one. A
+// confirmed enrollment is never replaced, in which case ErrEditConflict is returned.
+func (m TwoFactorModel) Enroll(ctx context.Context, userID int64, secret string) error {
+	ctx, span := startSpan(ctx, "TwoFactorModel.Enroll")
+	defer span.End()
+
+	query := `
+		INSERT INTO users_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+			SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+			WHERE users_totp.confirmed = false
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// Confirm marks the enrollment of the user as confirmed.
+func (m TwoFactorModel) Confirm(ctx context.Context, userID int64) error {
+	ctx, span := startSpan(ctx, "TwoFactorModel.Confirm")
+	defer span.End()
+
+	query := `
+		UPDATE users_totp
+		SET confirmed = true
+		WHERE user_id = $1
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
+
+// UseStep records that the code for the given time step has been used. It returns false if a
+// code for this or a later step was already used, which means the code is being replayed.
+func (m TwoFactorModel) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	ctx, span := startSpan(ctx, "TwoFactorModel.UseStep")
+	defer span.End()
+
+	query := `
+		UPDATE users_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// Delete removes the TOTP enrollment and the recovery codes of a user.
+func (m TwoFactorModel) Delete(ctx context.Context, userID int64) error {
+	ctx, span := startSpan(ctx, "TwoFactorModel.Delete")
+	defer span.End()
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NewRecoveryCodes generates a fresh set of recovery codes for the user, replacing the old ones.
+// The plaintext codes are returned so that they can be shown to the user once, only their
+// SHA-256 hashes are stored.
+func (m TwoFactorModel) NewRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	ctx, span := startSpan(ctx, "TwoFactorModel.NewRecoveryCodes")
+	defer span.End()
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	for _, code := range codes {
+		hash := hashRecoveryCode(code)
+		_, err := tx.ExecContext(ctx, `INSERT INTO recovery_codes (hash, user_id) VALUES ($1, $2)`, hash[:], userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// UseRecoveryCode consumes a recovery code of the user. It returns false if the code doesn't
+// exist or has already been used.
+func (m TwoFactorModel) UseRecoveryCode(ctx context.Context, userID int64, code string) (bool, error) {
+	ctx, span := startSpan(ctx, "TwoFactorModel.UseRecoveryCode")
+	defer span.End()
+
+	query := `
+		DELETE FROM recovery_codes
+		WHERE user_id = $1 AND hash = $2
+		`
+
+	hash := hashRecoveryCode(code)
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, hash[:])
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// generateRecoveryCodes returns a new set of random recovery codes.
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		randomBytes := make([]byte, 7)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return nil, err
+		}
+
+		// Format the codes as two groups of five characters, e.g. "x4k2p-mq7ra".
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+
+	return codes, nil
+}
+
+// hashRecoveryCode normalizes a recovery code, so that users may type it with or without the
+// dash and in any case, and returns its SHA-256 hash.
+func hashRecoveryCode(code string) [32]byte {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return sha256.Sum256([]byte(normalized))
+}
+
+// ValidateSecondFactor checks that exactly one of a TOTP code or a recovery code is provided, and
+// that a TOTP code is made up of the expected number of digits.
+func ValidateSecondFactor(v *validator.Validator, code, recoveryCode string) {
+	v.Check(code != "" || recoveryCode != "", "code", "must be provided")
+	v.Check(code == "" || recoveryCode == "", "recovery_code", "must not be provided together with code")
+
+	if code != "" {
+		v.Check(len(code) == totp.Digits && strings.Trim(code, "0123456789") == "", "code", "must be a 6 digit number")
+	}
+}
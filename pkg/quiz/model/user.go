@@ -5,9 +5,11 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -22,25 +24,39 @@ var AnonymousUser = &User{}
 // the Password and Version fields from appearing in any output when we encode it to JSON.
 // Also, notice that the Password field uses the custom password type defined below.
 type User struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	Activated bool      `json:"activated"`
-	Version   int       `json:"-"`
+	ID           int64      `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	Password     password   `json:"-"`
+	Activated    bool       `json:"activated"`
+	Version      int        `json:"-"`
+	FailedLogins int        `json:"-"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
 }
 
 func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+// IsLocked reports whether the user is locked out after too many failed logins, and for how much
+// longer.
+func (u *User) IsLocked() (bool, time.Duration) {
+	if u.LockedUntil == nil {
+		return false, 0
+	}
+
+	remaining := time.Until(*u.LockedUntil)
+	return remaining > 0, remaining
+}
+
 // UserModel struct wraps a sql.DB connection pool and allows us to work with the User struct type
 // and the users table in our database.
 type UserModel struct {
 	DB       *sql.DB
 	InfoLog  *log.Logger
 	ErrorLog *log.Logger
+	Timeout  time.Duration
 }
 
 // password tyep is a struct containing the plaintext and hashed version of a password for a User.
@@ -85,7 +101,10 @@ func (p *password) Matches(plaintextPassword string) (bool, error) {
 // created_at, and version fields are all automatically generated by our database, so we use use
 // the RETURNING clause to read them into the User struct after the insert. Also, we check
 // if our table already contains the same email address and if so return ErrDuplicateEmail error.
-func (m UserModel) Insert(user *User) error {
+func (m UserModel) Insert(ctx context.Context, user *User) error {
+	ctx, span := startSpan(ctx, "UserModel.Insert")
+	defer span.End()
+
 	query := `
 		INSERT INTO users (name, email, password_hash, activated)
 		VALUES ($1, $2, $3, $4)
@@ -94,7 +113,7 @@ func (m UserModel) Insert(user *User) error {
 
 	args := []interface{}{user.Name, user.Email, user.Password.hash, user.Activated}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	// If the table already contains a record with this email address, then when we try to
@@ -118,16 +137,19 @@ func (m UserModel) Insert(user *User) error {
 // GetByEmail retrieves the User details from the database based on the user's email address.
 // Because we have a UNIQUE constraint on the email column, this query will only return one record,
 // or none at all, upon which we return a ErrRecordNotFound error).
-func (m UserModel) GetByEmail(email string) (*User, error) {
+func (m UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, span := startSpan(ctx, "UserModel.GetByEmail")
+	defer span.End()
+
 	query := `
-		SELECT id, created_at, name, email, password_hash, activated, version
+		SELECT id, created_at, name, email, password_hash, activated, version, failed_logins, locked_until
 		FROM users
 		WHERE email = $1
 		`
 
 	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, email).Scan(
@@ -138,6 +160,52 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
+		&user.FailedLogins,
+		&user.LockedUntil,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// Get retrieves the User details from the database based on the user's ID.
+func (m UserModel) Get(ctx context.Context, id int64) (*User, error) {
+	ctx, span := startSpan(ctx, "UserModel.Get")
+	defer span.End()
+
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, name, email, password_hash, activated, version, failed_logins, locked_until
+		FROM users
+		WHERE id = $1
+		`
+
+	var user User
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+		&user.FailedLogins,
+		&user.LockedUntil,
 	)
 
 	if err != nil {
@@ -152,10 +220,121 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+// GetByIDs retrieves the users with the given ids in a single query, in no particular order.
+// Ids of users which don't exist are skipped.
+func (m UserModel) GetByIDs(ctx context.Context, ids []int64) ([]*User, error) {
+	ctx, span := startSpan(ctx, "UserModel.GetByIDs")
+	defer span.End()
+
+	query := `
+		SELECT id, created_at, name, email, password_hash, activated, version, failed_logins, locked_until
+		FROM users
+		WHERE id = ANY($1)
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	var users []*User
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&user.ID,
+			&user.CreatedAt,
+			&user.Name,
+			&user.Email,
+			&user.Password.hash,
+			&user.Activated,
+			&user.Version,
+			&user.FailedLogins,
+			&user.LockedUntil,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, &user)
+	}
+
+	return users, rows.Err()
+}
+
+// GetAll returns a page of the users, of the email address only unless it's empty.
+func (m UserModel) GetAll(ctx context.Context, email string, filters Filters) ([]*User, Metadata, error) {
+	ctx, span := startSpan(ctx, "UserModel.GetAll")
+	defer span.End()
+
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), id, created_at, name, email, password_hash, activated, version, failed_logins, locked_until
+		FROM users
+		WHERE (email = $1 OR $1 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3
+		`,
+		filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, email, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	totalRecords := 0
+
+	var users []*User
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&totalRecords,
+			&user.ID,
+			&user.CreatedAt,
+			&user.Name,
+			&user.Email,
+			&user.Password.hash,
+			&user.Activated,
+			&user.Version,
+			&user.FailedLogins,
+			&user.LockedUntil,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return users, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
 // Update updates the details for a specific user in the users table. Note, we check against the
 // version field to help prevent any race conditions during the request cycle. Also, we check
 // for a violation of the "user_email_key" constraint.
-func (m UserModel) Update(user *User) error {
+func (m UserModel) Update(ctx context.Context, user *User) error {
+	ctx, span := startSpan(ctx, "UserModel.Update")
+	defer span.End()
+
 	query := `
 		UPDATE users
 		SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
@@ -172,7 +351,7 @@ func (m UserModel) Update(user *User) error {
 		user.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
@@ -190,8 +369,92 @@ func (m UserModel) Update(user *User) error {
 	return nil
 }
 
+// RecordFailedLogin increments the failed login counter of a user and returns the new value. The
+// counter isn't part of the optimistic locking with the version field, so that failed logins
+// never cause edit conflicts.
+func (m UserModel) RecordFailedLogin(ctx context.Context, id int64) (int, error) {
+	ctx, span := startSpan(ctx, "UserModel.RecordFailedLogin")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET failed_logins = failed_logins + 1
+		WHERE id = $1
+		RETURNING failed_logins
+		`
+
+	var failedLogins int
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&failedLogins)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return failedLogins, nil
+}
+
+// Lock locks a user out of logging in until the given time.
+func (m UserModel) Lock(ctx context.Context, id int64, until time.Time) error {
+	ctx, span := startSpan(ctx, "UserModel.Lock")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET locked_until = $2
+		WHERE id = $1
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id, until)
+	return err
+}
+
+// Unlock resets the failed login counter and lifts any lockout of a user. It's called after a
+// successful login and by administrators.
+func (m UserModel) Unlock(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "UserModel.Unlock")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET failed_logins = 0, locked_until = NULL
+		WHERE id = $1
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
 // GetForToken retrieves a user record from the users table for an associated token and token scope.
-func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+func (m UserModel) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
+	ctx, span := startSpan(ctx, "UserModel.GetForToken")
+	defer span.End()
+
 	// Calculate the SHA-256 hash for the plaintext token provided by the client.
 	// Note, that this will return a byte *array* with length 32, not a slice.
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
@@ -199,7 +462,8 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	query := `
 		SELECT 
 			users.id, users.created_at, users.name, users.email, 
-			users.password_hash, users.activated, users.version
+			users.password_hash, users.activated, users.version,
+			users.failed_logins, users.locked_until
 		FROM       users
         INNER JOIN tokens
 			ON users.id = tokens.user_id
@@ -218,7 +482,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 
 	var user User
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := queryContext(ctx, m.Timeout)
 	defer cancel()
 
 	// Execute the query, scanning the return values into a User struct. If no matching record
@@ -231,6 +495,8 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
+		&user.FailedLogins,
+		&user.LockedUntil,
 	)
 	if err != nil {
 		switch {
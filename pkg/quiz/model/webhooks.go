@@ -0,0 +1,614 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/validator"
+)
+
+// The events sent to webhooks.
+const (
+	EventGameCompleted  = "game.completed"
+	EventQuizPublished  = "quiz.published"
+	EventUserRegistered = "user.registered"
+
+	// EventWebhookPing is only sent to the webhook that is pinged, webhooks can't subscribe to
+	// it.
+	EventWebhookPing = "webhook.ping"
+)
+
+// WebhookEvents are the events webhooks can subscribe to, with the permission the owner of a
+// webhook needs to receive each of them.
+var WebhookEvents = map[string]string{
+	EventGameCompleted:  "game:read",
+	EventQuizPublished:  "quiz:read",
+	EventUserRegistered: "user:read",
+}
+
+// The statuses of webhook deliveries.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookSecretPrefix starts every generated webhook secret.
+const WebhookSecretPrefix = "whsec_"
+
+type (
+	// Webhook is a subscription of a user to events, which are posted to URL signed with the
+	// Secret. The secret has to be kept to sign the deliveries, so unlike API keys it is stored
+	// as is, and only shown when the webhook is created.
+	Webhook struct {
+		ID        int64     `json:"id"`
+		UserID    int64     `json:"-"`
+		URL       string    `json:"url"`
+		Events    []string  `json:"events"`
+		Secret    string    `json:"-"`
+		Active    bool      `json:"active"`
+		CreatedAt time.Time `json:"created_at"`
+		Version   int       `json:"version"`
+	}
+
+	// WebhookDelivery is the delivery of an event to a webhook. It is pending until a delivery
+	// attempt succeeds or the attempts run out, and NextAttemptAt is when it is attempted next.
+	// Redeliveries of an event share its EventID, so that receivers can tell them apart from new
+	// events.
+	WebhookDelivery struct {
+		ID             int64           `json:"id"`
+		WebhookID      int64           `json:"webhook_id"`
+		Event          string          `json:"event"`
+		EventID        string          `json:"event_id"`
+		Payload        json.RawMessage `json:"payload"`
+		Status         string          `json:"status"`
+		Attempts       int             `json:"attempts"`
+		NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+		LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+		ResponseStatus int             `json:"response_status,omitempty"`
+		Error          string          `json:"error,omitempty"`
+		DurationMS     int64           `json:"duration_ms"`
+		CreatedAt      time.Time       `json:"created_at"`
+
+		// Webhook is the webhook of a delivery returned by ClaimDeliveries.
+		Webhook *Webhook `json:"-"`
+	}
+
+	// WebhookModel struct wraps a sql.DB connection pool and allows us to work with the webhooks
+	// and webhook_deliveries tables.
+	WebhookModel struct {
+		DB       *sql.DB
+		InfoLog  *log.Logger
+		ErrorLog *log.Logger
+		Timeout  time.Duration
+	}
+)
+
+// NewWebhookSecret generates a random secret to sign the deliveries of a webhook with.
+func NewWebhookSecret() (string, error) {
+	randomBytes := make([]byte, 24)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+
+	return WebhookSecretPrefix + hex.EncodeToString(randomBytes), nil
+}
+
+// webhookColumns are the columns of webhookFields.
+const webhookColumns = `webhooks.id, webhooks.user_id, webhooks.url, webhooks.events, webhooks.secret,
+	webhooks.active, webhooks.created_at, webhooks.version`
+
+// webhookDeliveryColumns are the columns of webhookDeliveryFields.
+const webhookDeliveryColumns = `webhook_deliveries.id, webhook_deliveries.webhook_id,
+	webhook_deliveries.event, webhook_deliveries.event_id, webhook_deliveries.payload,
+	webhook_deliveries.status, webhook_deliveries.attempts, webhook_deliveries.next_attempt_at,
+	webhook_deliveries.last_attempt_at, webhook_deliveries.response_status,
+	webhook_deliveries.error, webhook_deliveries.duration_ms, webhook_deliveries.created_at`
+
+// Insert adds a webhook.
+func (m WebhookModel) Insert(ctx context.Context, webhook *Webhook) error {
+	ctx, span := startSpan(ctx, "WebhookModel.Insert")
+	defer span.End()
+
+	query := `
+		INSERT INTO webhooks (user_id, url, events, secret, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, version
+		`
+
+	args := []interface{}{webhook.UserID, webhook.URL, pq.Array(webhook.Events), webhook.Secret, webhook.Active}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&webhook.ID, &webhook.CreatedAt, &webhook.Version)
+}
+
+// Get returns a webhook of a user. ErrRecordNotFound is returned if the user has no such webhook.
+func (m WebhookModel) Get(ctx context.Context, userID, id int64) (*Webhook, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.Get")
+	defer span.End()
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE id = $1 AND user_id = $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	var webhook Webhook
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(webhookFields(&webhook)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &webhook, nil
+}
+
+// GetAllForUser returns the webhooks of a user.
+func (m WebhookModel) GetAllForUser(ctx context.Context, userID int64) ([]*Webhook, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetAllForUser")
+	defer span.End()
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY id
+		`
+
+	return m.query(ctx, query, userID)
+}
+
+// GetAllForEvent returns the active webhooks subscribed to an event.
+func (m WebhookModel) GetAllForEvent(ctx context.Context, event string) ([]*Webhook, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetAllForEvent")
+	defer span.End()
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE active AND $1 = ANY(events)
+		ORDER BY id
+		`
+
+	return m.query(ctx, query, event)
+}
+
+func (m WebhookModel) query(ctx context.Context, query string, args ...interface{}) ([]*Webhook, error) {
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	return scanWebhooks(rows)
+}
+
+// Update updates the URL, events and active flag of a webhook. ErrEditConflict is returned if
+// the webhook was changed or deleted since it was read.
+func (m WebhookModel) Update(ctx context.Context, webhook *Webhook) error {
+	ctx, span := startSpan(ctx, "WebhookModel.Update")
+	defer span.End()
+
+	query := `
+		UPDATE webhooks
+		SET url = $1, events = $2, active = $3, version = version + 1
+		WHERE id = $4 AND user_id = $5 AND version = $6
+		RETURNING version
+		`
+
+	args := []interface{}{webhook.URL, pq.Array(webhook.Events), webhook.Active, webhook.ID, webhook.UserID, webhook.Version}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&webhook.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes a webhook of a user along with its deliveries. ErrRecordNotFound is returned if
+// the user has no such webhook.
+func (m WebhookModel) Delete(ctx context.Context, userID, id int64) error {
+	ctx, span := startSpan(ctx, "WebhookModel.Delete")
+	defer span.End()
+
+	query := `
+		DELETE FROM webhooks
+		WHERE id = $1 AND user_id = $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// InsertDelivery adds a pending delivery, which is attempted at its NextAttemptAt.
+func (m WebhookModel) InsertDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	ctx, span := startSpan(ctx, "WebhookModel.InsertDelivery")
+	defer span.End()
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, event_id, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+		`
+
+	args := []interface{}{
+		delivery.WebhookID,
+		delivery.Event,
+		delivery.EventID,
+		string(delivery.Payload),
+		delivery.Status,
+		delivery.NextAttemptAt,
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&delivery.ID, &delivery.CreatedAt)
+}
+
+// GetDelivery returns a delivery of a webhook. ErrRecordNotFound is returned if the webhook has
+// no such delivery.
+func (m WebhookModel) GetDelivery(ctx context.Context, webhookID, id int64) (*WebhookDelivery, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetDelivery")
+	defer span.End()
+
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE id = $1 AND webhook_id = $2
+		`
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	var delivery WebhookDelivery
+	err := m.DB.QueryRowContext(ctx, query, id, webhookID).Scan(webhookDeliveryFields(&delivery)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &delivery, nil
+}
+
+// GetDeliveries returns a page of the deliveries of a webhook, optionally only the ones with a
+// status.
+func (m WebhookModel) GetDeliveries(ctx context.Context, webhookID int64, status string, filters Filters) ([]*WebhookDelivery, Metadata, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetDeliveries")
+	defer span.End()
+
+	query := fmt.Sprintf(
+		`
+		SELECT count(*) OVER(), %s
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		AND (status = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4
+		`,
+		webhookDeliveryColumns, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, webhookID, status, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	return scanWebhookDeliveries(rows, filters)
+}
+
+// ClaimDeliveries returns up to limit pending deliveries of active webhooks which are due at now,
+// with their webhooks, and postpones their next attempt by lease. Other workers don't claim them
+// again until the lease is over, unless the delivery is updated before. If a worker stops before
+// it updates a claimed delivery, another one attempts it once the lease is over.
+func (m WebhookModel) ClaimDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.ClaimDeliveries")
+	defer span.End()
+
+	query := `
+		WITH due AS (
+			SELECT webhook_deliveries.id
+			FROM webhook_deliveries
+				INNER JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+			WHERE webhook_deliveries.status = 'pending'
+			AND webhook_deliveries.next_attempt_at <= $1
+			AND webhooks.active
+			ORDER BY webhook_deliveries.next_attempt_at, webhook_deliveries.id
+			LIMIT $3
+			FOR UPDATE OF webhook_deliveries SKIP LOCKED
+		)
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		FROM due, webhooks
+		WHERE webhook_deliveries.id = due.id AND webhooks.id = webhook_deliveries.webhook_id
+		RETURNING ` + webhookDeliveryColumns + `, ` + webhookColumns
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			m.ErrorLog.Println(err)
+		}
+	}()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		var (
+			delivery WebhookDelivery
+			webhook  Webhook
+		)
+
+		err := rows.Scan(append(webhookDeliveryFields(&delivery), webhookFields(&webhook)...)...)
+		if err != nil {
+			return nil, err
+		}
+
+		delivery.Webhook = &webhook
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// UpdateDelivery records an attempt of a delivery: its status, attempts, next attempt and the
+// outcome of the last attempt.
+func (m WebhookModel) UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	ctx, span := startSpan(ctx, "WebhookModel.UpdateDelivery")
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3, last_attempt_at = $4,
+			response_status = $5, error = $6, duration_ms = $7
+		WHERE id = $8
+		`
+
+	args := []interface{}{
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastAttemptAt,
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.DurationMS,
+		delivery.ID,
+	}
+
+	ctx, cancel := queryContext(ctx, m.Timeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// webhookFields returns the destinations of the webhookColumns of a row.
+func webhookFields(webhook *Webhook) []interface{} {
+	return []interface{}{
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		pq.Array(&webhook.Events),
+		&webhook.Secret,
+		&webhook.Active,
+		&webhook.CreatedAt,
+		&webhook.Version,
+	}
+}
+
+func scanWebhooks(rows *sql.Rows) ([]*Webhook, error) {
+	webhooks := []*Webhook{}
+
+	for rows.Next() {
+		var webhook Webhook
+		if err := rows.Scan(webhookFields(&webhook)...); err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, &webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// webhookDeliveryFields returns the destinations of the webhookDeliveryColumns of a row.
+func webhookDeliveryFields(delivery *WebhookDelivery) []interface{} {
+	return []interface{}{
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&delivery.EventID,
+		(*[]byte)(&delivery.Payload),
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastAttemptAt,
+		&delivery.ResponseStatus,
+		&delivery.Error,
+		&delivery.DurationMS,
+		&delivery.CreatedAt,
+	}
+}
+
+func scanWebhookDeliveries(rows *sql.Rows, filters Filters) ([]*WebhookDelivery, Metadata, error) {
+	totalRecords := 0
+
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		var delivery WebhookDelivery
+		err := rows.Scan(append([]interface{}{&totalRecords}, webhookDeliveryFields(&delivery)...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return deliveries, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// ValidateWebhook checks the URL and events of a webhook. The owner must have the permission of
+// every event the webhook subscribes to. Unless allowInternal is set the URL must not point to an
+// internal host, see InternalWebhookHost.
+func ValidateWebhook(v *validator.Validator, webhook *Webhook, owner Permissions, allowInternal bool) {
+	v.Check(webhook.URL != "", "url", "must be provided")
+	v.Check(len(webhook.URL) <= 2000, "url", "must not be more than 2000 bytes long")
+	if u, err := url.Parse(webhook.URL); webhook.URL != "" {
+		valid := err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+		v.Check(valid, "url", "must be an absolute http or https URL")
+
+		if valid && !allowInternal {
+			v.Check(!InternalWebhookHost(u.Hostname()), "url", "must not point to a loopback, private or other internal address")
+		}
+	}
+
+	v.Check(len(webhook.Events) > 0, "events", "must contain at least one event")
+	v.Check(validator.Unique(webhook.Events), "events", "must not contain duplicate values")
+	for _, event := range webhook.Events {
+		permission, ok := WebhookEvents[event]
+		if !ok {
+			v.AddError("events", "must only contain known events, "+event+" isn't one of them")
+			continue
+		}
+		v.Check(owner.Include(permission), "events", "must only contain events you may read, "+event+" needs the "+permission+" permission")
+	}
+
+	v.Check(len(webhook.Secret) >= 16, "secret", "must be at least 16 bytes long")
+	v.Check(len(webhook.Secret) <= 200, "secret", "must not be more than 200 bytes long")
+}
+
+// internalPrefixes are the ranges of special-purpose addresses which netip.Addr has no method
+// for and which webhooks must not be sent to.
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved, and the broadcast address
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use IPv4/IPv6 translation
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+	netip.MustParsePrefix("100::/64"),       // discard-only
+}
+
+// InternalWebhookAddr reports whether addr is an address webhooks must not be sent to: a
+// loopback, private, link-local (such as the 169.254.169.254 of cloud metadata services),
+// unspecified, multicast or other special-purpose address. IPv4 addresses mapped to IPv6 are
+// checked as IPv4.
+func InternalWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+
+	for _, prefix := range internalPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InternalWebhookHost reports whether the host of a webhook URL is internal: an internal address
+// as InternalWebhookAddr says, localhost, a name under the localhost, local, internal or
+// home.arpa domains, or a name without dots, which resolvers complete with their search domains.
+// Names that resolve to internal addresses are only caught when the delivery connects.
+func InternalWebhookHost(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return InternalWebhookAddr(addr)
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if !strings.Contains(host, ".") {
+		return true
+	}
+
+	for _, domain := range []string{"localhost", "local", "internal", "home.arpa"} {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
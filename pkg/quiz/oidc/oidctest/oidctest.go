@@ -0,0 +1,288 @@
+// Package oidctest is a minimal OpenID Connect provider for testing the JustQuiz external login
+// flow. It approves every authorization request without asking, and issues RS256 signed ID
+// tokens for a single identity, which can be changed between logins.
+//
+//	provider, err := oidctest.NewProvider("", "justquiz", "secret", oidctest.Identity{...})
+//	srv := httptest.NewServer(provider)
+//
+// cmd/mockoidc serves it for local development.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/oidc"
+)
+
+const keyID = "oidctest"
+
+// Identity is the user the ID tokens are issued for.
+type Identity struct {
+	Subject       string
+	Email         string
+	Name          string
+	EmailVerified bool
+}
+
+// authorization is an issued authorization code waiting to be exchanged at the token endpoint.
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	expiry        time.Time
+}
+
+// Provider is an http.Handler serving the discovery document, the authorization, token and JWKS
+// endpoints of an OpenID Connect provider.
+type Provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+	mux          *http.ServeMux
+
+	mu       sync.Mutex
+	identity Identity
+	codes    map[string]authorization
+}
+
+// NewProvider returns a Provider accepting the client with the secret. If issuer is empty, the
+// provider is the scheme and host it is requested at, like the URL of an httptest.Server.
+func NewProvider(issuer, clientID, clientSecret string, identity Identity) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		key:          key,
+		mux:          http.NewServeMux(),
+		identity:     identity,
+		codes:        make(map[string]authorization),
+	}
+
+	p.mux.HandleFunc("/.well-known/openid-configuration", p.discoveryHandler)
+	p.mux.HandleFunc("/authorize", p.authorizeHandler)
+	p.mux.HandleFunc("/token", p.tokenHandler)
+	p.mux.HandleFunc("/jwks", p.jwksHandler)
+
+	return p, nil
+}
+
+// SetIdentity changes the user of the ID tokens issued from now on.
+func (p *Provider) SetIdentity(identity Identity) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.identity = identity
+}
+
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+// issuerOf returns the issuer of the provider as it's requested by r.
+func (p *Provider) issuerOf(r *http.Request) string {
+	if p.issuer != "" {
+		return p.issuer
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host
+}
+
+func (p *Provider) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	issuer := p.issuerOf(r)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorizeHandler approves the request immediately and redirects back to the client with a code.
+func (p *Provider) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	if qs.Get("client_id") != p.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if qs.Get("code_challenge_method") != "S256" || qs.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(qs.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code, err := oidc.RandomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		clientID:      qs.Get("client_id"),
+		redirectURI:   redirectURI.String(),
+		nonce:         qs.Get("nonce"),
+		codeChallenge: qs.Get("code_challenge"),
+		expiry:        time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", qs.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *Provider) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.clientID || clientSecret != p.clientSecret {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+
+	p.mu.Lock()
+	auth, ok := p.codes[code]
+	delete(p.codes, code)
+	identity := p.identity
+	p.mu.Unlock()
+
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code":
+		tokenError(w, "unsupported_grant_type")
+		return
+	case !ok, auth.expiry.Before(time.Now()), auth.clientID != clientID, auth.redirectURI != r.PostForm.Get("redirect_uri"):
+		tokenError(w, "invalid_grant")
+		return
+	case oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != auth.codeChallenge:
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	idToken, err := p.sign(map[string]interface{}{
+		"iss":            p.issuerOf(r),
+		"sub":            identity.Subject,
+		"aud":            clientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          auth.nonce,
+		"email":          identity.Email,
+		"email_verified": identity.EmailVerified,
+		"name":           identity.Name,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	accessToken, err := oidc.RandomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (p *Provider) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// sign returns a compact serialized RS256 JWT for the claims.
+func (p *Provider) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
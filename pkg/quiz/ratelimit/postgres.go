@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresStore keeps the buckets in the rate_limits table, so that several instances of the
+// API enforce one limit together. Every request costs one statement, which updates the bucket
+// atomically.
+type PostgresStore struct {
+	DB *sql.DB
+}
+
+// Take takes a token from the bucket of key if there is one. New buckets start out full.
+func (s PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	// The refilled amount is computed from the old row in both SET expressions, allowed records
+	// whether a token was taken so that it can be returned.
+	query := `
+		INSERT INTO rate_limits AS rl (key, tokens, allowed, updated_at)
+		VALUES ($1, $2 - 1, true, $4)
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE
+				WHEN LEAST($2, rl.tokens + GREATEST(0, EXTRACT(EPOCH FROM ($4 - rl.updated_at))) * $3) >= 1
+				THEN LEAST($2, rl.tokens + GREATEST(0, EXTRACT(EPOCH FROM ($4 - rl.updated_at))) * $3) - 1
+				ELSE LEAST($2, rl.tokens + GREATEST(0, EXTRACT(EPOCH FROM ($4 - rl.updated_at))) * $3)
+			END,
+			allowed = LEAST($2, rl.tokens + GREATEST(0, EXTRACT(EPOCH FROM ($4 - rl.updated_at))) * $3) >= 1,
+			updated_at = $4
+		RETURNING tokens, allowed
+		`
+
+	var (
+		tokens  float64
+		allowed bool
+	)
+
+	err := s.DB.QueryRowContext(ctx, query, key, float64(limit.Burst), limit.Rate, now).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result(limit, tokens, allowed), nil
+}
+
+// Evict removes the buckets that weren't used since before.
+func (s PostgresStore) Evict(ctx context.Context, before time.Time) error {
+	_, err := s.DB.ExecContext(ctx, `DELETE FROM rate_limits WHERE updated_at < $1`, before)
+	return err
+}
@@ -0,0 +1,75 @@
+// Package ratelimit implements token bucket rate limiting with pluggable storage for the
+// buckets, so that a limit can be enforced by a single instance in memory or shared by several
+// instances through the database.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: it holds up to Burst tokens and is refilled with Rate tokens
+// per second. Every request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled reports whether the limit restricts anything. A zero rate or burst turns a limit off.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	// Allowed reports whether a token was available.
+	Allowed bool
+	// Limit is the size of the bucket.
+	Limit int
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token is available if the request wasn't allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets for each key.
+type Store interface {
+	// Take takes a token from the bucket of key if there is one.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	// Evict removes the buckets that weren't used since the given time. Such buckets have been
+	// refilled anyway, unless the rate is very low.
+	Evict(ctx context.Context, before time.Time) error
+}
+
+// refill returns the tokens in a bucket that had tokens at last, now.
+func refill(limit Limit, tokens float64, last, now time.Time) float64 {
+	elapsed := now.Sub(last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	return math.Min(float64(limit.Burst), tokens+elapsed*limit.Rate)
+}
+
+// result describes a bucket holding tokens after a request that was allowed or not.
+func result(limit Limit, tokens float64, allowed bool) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
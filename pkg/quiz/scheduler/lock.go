@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+)
+
+// Locker hands out the locks of jobs. TryLock doesn't wait: ok is false if the lock is taken,
+// otherwise unlock releases it.
+type Locker interface {
+	TryLock(ctx context.Context, job string) (unlock func(), ok bool, err error)
+}
+
+// lockClass is the first key of the advisory locks of jobs, the second one is a hash of the name
+// of the job. Locks with two keys never conflict with the ones with a single key, like the lock
+// of the migrations.
+const lockClass = 0x4a51
+
+// PostgresLocker takes advisory locks, so that several instances of the API sharing the database
+// don't run a job at the same time.
+type PostgresLocker struct {
+	DB *sql.DB
+}
+
+// TryLock takes the advisory lock of job. It belongs to a connection, which is reserved until the
+// lock is released.
+func (l PostgresLocker) TryLock(ctx context.Context, job string) (func(), bool, error) {
+	conn, err := l.DB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var ok bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1, hashtext($2))`, lockClass, job).Scan(&ok)
+	if err != nil || !ok {
+		conn.Close()
+		return nil, false, err
+	}
+
+	unlock := func() {
+		_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1, hashtext($2))`, lockClass, job)
+		if err != nil {
+			// The lock is only released with the session then, so the connection mustn't go
+			// back to the pool.
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	return unlock, true, nil
+}
+
+// LocalLocker only keeps the jobs of its own process apart, for databases that only a single
+// instance uses, like SQLite files.
+type LocalLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// NewLocalLocker returns a LocalLocker with no locks taken.
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{held: make(map[string]bool)}
+}
+
+func (l *LocalLocker) TryLock(ctx context.Context, job string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held[job] {
+		return nil, false, nil
+	}
+	l.held[job] = true
+
+	unlock := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		delete(l.held, job)
+	}
+
+	return unlock, true, nil
+}
@@ -0,0 +1,136 @@
+// Package scheduler runs periodic jobs in the background of the application. A Locker makes sure
+// that only one of several instances runs a job at a time, the others skip that run. A History
+// makes sure that only one of them runs it every interval.
+package scheduler
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Job is a periodic job. Run returns the number of rows it changed, and gets a context which is
+// cancelled after Timeout.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Timeout  time.Duration
+	Run      func(ctx context.Context) (int64, error)
+}
+
+// Run is the outcome of a run of a job. Skipped runs didn't start because another instance
+// held the lock of the job, or ran it less than an interval ago.
+type Run struct {
+	Job       Job
+	Skipped   bool
+	StartedAt time.Time
+	Duration  time.Duration
+	Affected  int64
+	Err       error
+}
+
+// History tells when a job last succeeded on any instance, the zero time if it never did. The
+// times may be rounded to the second.
+type History interface {
+	LastSucceeded(ctx context.Context, job string) (time.Time, error)
+}
+
+// historyPrecision is how far the times of a History may be off.
+const historyPrecision = time.Second
+
+// Scheduler runs jobs every their interval, moved randomly by up to Jitter times the interval
+// so that instances started together don't run them at the same time. Report is called after
+// every run, before the lock of the job is released, so that it can add the run to the History.
+// Without a History every instance runs every job once per interval.
+type Scheduler struct {
+	Locker  Locker
+	History History
+	Jitter  float64
+	Report  func(Run)
+}
+
+// Start runs every job in a goroutine of its own, which is added to wg, until ctx is cancelled.
+// A run which already started when ctx is cancelled is completed, so waiting for wg waits for
+// it.
+func (s *Scheduler) Start(ctx context.Context, wg *sync.WaitGroup, jobs ...Job) {
+	for _, job := range jobs {
+		wg.Add(1)
+
+		go func(job Job) {
+			defer wg.Done()
+
+			// The first run is only delayed by the jitter, so that a job which runs rarely also
+			// runs soon after the start.
+			timer := time.NewTimer(s.jitter(job.Interval))
+			defer timer.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-timer.C:
+				}
+
+				s.run(job)
+				timer.Reset(job.Interval + s.jitter(job.Interval))
+			}
+		}(job)
+	}
+}
+
+// jitter returns a random duration of up to Jitter times interval.
+func (s *Scheduler) jitter(interval time.Duration) time.Duration {
+	if s.Jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Float64() * s.Jitter * float64(interval))
+}
+
+// run runs job once if its lock is free and no instance ran it within the last interval.
+func (s *Scheduler) run(job Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), job.Timeout)
+	defer cancel()
+
+	run := Run{Job: job, StartedAt: time.Now()}
+
+	unlock, ok, err := s.Locker.TryLock(ctx, job.Name)
+	switch {
+	case err != nil:
+		run.Err = err
+	case !ok:
+		run.Skipped = true
+	default:
+		// The lock is held until the run is reported, so that the next instance to take it
+		// finds the run in the history.
+		defer unlock()
+
+		run.Skipped, run.Err = s.ranRecently(ctx, job, run.StartedAt)
+		if !run.Skipped && run.Err == nil {
+			run.Affected, run.Err = job.Run(ctx)
+		}
+	}
+
+	run.Duration = time.Since(run.StartedAt)
+
+	if s.Report != nil {
+		s.Report(run)
+	}
+}
+
+// ranRecently reports whether job succeeded less than an interval before now. The timer of an
+// instance never fires sooner than an interval after its last run, so an instance doesn't skip
+// its own runs.
+func (s *Scheduler) ranRecently(ctx context.Context, job Job, now time.Time) (bool, error) {
+	if s.History == nil {
+		return false, nil
+	}
+
+	last, err := s.History.LastSucceeded(ctx, job.Name)
+	if err != nil || last.IsZero() {
+		return false, err
+	}
+
+	return now.Sub(last) < job.Interval-historyPrecision, nil
+}
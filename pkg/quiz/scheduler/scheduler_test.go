@@ -0,0 +1,220 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// history is a History of the runs reported to it, shared by the schedulers of a test like the
+// job_runs table.
+type history struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+	err  error
+}
+
+func newHistory() *history {
+	return &history{last: make(map[string]time.Time)}
+}
+
+func (h *history) LastSucceeded(ctx context.Context, job string) (time.Time, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.last[job], h.err
+}
+
+func (h *history) report(run Run) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !run.Skipped && run.Err == nil {
+		h.last[run.Job.Name] = run.StartedAt
+	}
+}
+
+// counter counts the runs of a job.
+type counter struct {
+	mu   sync.Mutex
+	runs int
+}
+
+func (c *counter) job(name string, interval time.Duration) Job {
+	return Job{
+		Name:     name,
+		Interval: interval,
+		Timeout:  time.Second,
+		Run: func(ctx context.Context) (int64, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			c.runs++
+			return 1, nil
+		},
+	}
+}
+
+func (c *counter) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.runs
+}
+
+func TestRunSkipsRecentRuns(t *testing.T) {
+	h := newHistory()
+	var runs []Run
+	s := &Scheduler{Locker: NewLocalLocker(), History: h, Report: func(run Run) {
+		h.report(run)
+		runs = append(runs, run)
+	}}
+
+	var c counter
+	job := c.job("purge", time.Hour)
+
+	s.run(job)
+	if c.count() != 1 || runs[0].Skipped || runs[0].Affected != 1 {
+		t.Fatalf("got %d runs reported as %+v, want the first run to run", c.count(), runs[0])
+	}
+
+	// Another instance, whose timer fires within the interval.
+	other := &Scheduler{Locker: s.Locker, History: h, Report: func(run Run) { runs = append(runs, run) }}
+	other.run(job)
+	if c.count() != 1 || !runs[1].Skipped {
+		t.Fatalf("got %d runs reported as %+v, want a skipped run", c.count(), runs[1])
+	}
+
+	// Once the interval passed, it runs again.
+	h.last[job.Name] = time.Now().Add(-time.Hour)
+	other.run(job)
+	if c.count() != 2 || runs[2].Skipped {
+		t.Fatalf("got %d runs reported as %+v, want a second run", c.count(), runs[2])
+	}
+}
+
+func TestRunRoundedHistory(t *testing.T) {
+	h := newHistory()
+	s := &Scheduler{Locker: NewLocalLocker(), History: h}
+
+	var c counter
+	job := c.job("refresh", time.Minute)
+
+	// The last run is stored rounded up, a little less than an interval ago.
+	h.last[job.Name] = time.Now().Add(-time.Minute + 500*time.Millisecond)
+	s.run(job)
+	if c.count() != 1 {
+		t.Fatalf("got %d runs, want the run an interval after the last one", c.count())
+	}
+}
+
+func TestRunLocked(t *testing.T) {
+	locker := NewLocalLocker()
+	var reported Run
+	s := &Scheduler{Locker: locker, Report: func(run Run) { reported = run }}
+
+	var c counter
+	job := c.job("purge", time.Hour)
+
+	unlock, ok, err := locker.TryLock(context.Background(), job.Name)
+	if err != nil || !ok {
+		t.Fatalf("got ok %t and error %v, want the lock", ok, err)
+	}
+
+	s.run(job)
+	if c.count() != 0 || !reported.Skipped {
+		t.Fatalf("got %d runs reported as %+v, want a skipped run while the lock is held", c.count(), reported)
+	}
+
+	unlock()
+	s.run(job)
+	if c.count() != 1 || reported.Skipped {
+		t.Fatalf("got %d runs reported as %+v, want a run once the lock is free", c.count(), reported)
+	}
+}
+
+func TestRunReportsBeforeUnlock(t *testing.T) {
+	locker := NewLocalLocker()
+
+	var c counter
+	job := c.job("purge", time.Hour)
+
+	// The run must be in the history before another instance can take the lock.
+	locked := false
+	s := &Scheduler{Locker: locker, Report: func(run Run) {
+		_, ok, _ := locker.TryLock(context.Background(), job.Name)
+		locked = !ok
+	}}
+
+	s.run(job)
+	if !locked {
+		t.Error("got the lock free while the run was reported, want it held")
+	}
+	if _, ok, _ := locker.TryLock(context.Background(), job.Name); !ok {
+		t.Error("got the lock held after the run, want it released")
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	h := newHistory()
+	h.err = errors.New("history unavailable")
+
+	var reported Run
+	s := &Scheduler{Locker: NewLocalLocker(), History: h, Report: func(run Run) { reported = run }}
+
+	var c counter
+	s.run(c.job("purge", time.Hour))
+	if c.count() != 0 || reported.Err != h.err {
+		t.Fatalf("got %d runs reported as %+v, want the error of the history", c.count(), reported)
+	}
+
+	failing := Job{Name: "refresh", Interval: time.Hour, Timeout: time.Second, Run: func(ctx context.Context) (int64, error) {
+		return 0, errors.New("refresh failed")
+	}}
+	h.err = nil
+	s.run(failing)
+	if reported.Err == nil || reported.Skipped {
+		t.Fatalf("got run %+v, want the error of the job", reported)
+	}
+
+	// A failed run isn't a reason to skip the next one.
+	if last, _ := h.LastSucceeded(context.Background(), failing.Name); !last.IsZero() {
+		t.Errorf("got last success %v, want none", last)
+	}
+}
+
+func TestStart(t *testing.T) {
+	h := newHistory()
+	locker := NewLocalLocker()
+
+	var c counter
+	job := c.job("purge", 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	// Two instances sharing the lock and the history.
+	for i := 0; i < 2; i++ {
+		s := &Scheduler{Locker: locker, History: h, Jitter: 0.5, Report: h.report}
+		s.Start(ctx, &wg, job)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for c.count() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d runs, want the job to run repeatedly", c.count())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	wg.Wait()
+
+	runs := c.count()
+	time.Sleep(30 * time.Millisecond)
+	if c.count() != runs {
+		t.Errorf("got %d runs after the stop, want %d", c.count(), runs)
+	}
+}
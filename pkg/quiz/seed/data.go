@@ -0,0 +1,163 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Names the players and users are made up from.
+var (
+	firstNames = []string{
+		"Aigerim", "Aruzhan", "Dana", "Dinara", "Kamila", "Madina", "Saule", "Zhanna",
+		"Aibek", "Arman", "Daniyar", "Erlan", "Nurlan", "Timur", "Yerlan", "Zhandos",
+		"Anna", "Maria", "Sofia", "Elena", "Alexei", "Dmitry", "Ivan", "Sergey",
+		"Emma", "Olivia", "Liam", "Noah", "Lucas", "Mia", "Leo", "Chloe",
+	}
+	lastNames = []string{
+		"Abenova", "Akhmetov", "Bekova", "Dauletov", "Ermekova", "Iskakov", "Kalykul", "Kasymova",
+		"Mukanov", "Nurpeisova", "Omarov", "Sadykova", "Serikbayev", "Tulegenova", "Zhakupov",
+		"Ivanova", "Petrov", "Smirnova", "Kuznetsov", "Popova", "Sokolov",
+		"Smith", "Johnson", "Brown", "Garcia", "Miller", "Wilson", "Martin", "Lee",
+	}
+)
+
+// question is a question of a quiz with its answer.
+type question struct {
+	text, answer string
+}
+
+// questionBanks holds the questions of each category. math has none, its questions are
+// generated.
+var questionBanks = map[string][]question{
+	"geography": {
+		{"What is the capital of Kazakhstan?", "Astana"},
+		{"What is the largest city of Kazakhstan?", "Almaty"},
+		{"What is the longest river in the world?", "Nile"},
+		{"What is the largest ocean?", "Pacific"},
+		{"What is the capital of Japan?", "Tokyo"},
+		{"What is the capital of Canada?", "Ottawa"},
+		{"What is the highest mountain in the world?", "Everest"},
+		{"Which country has the most people?", "India"},
+		{"What is the largest desert in the world?", "Sahara"},
+		{"What is the capital of Australia?", "Canberra"},
+		{"Which sea lies to the west of Kazakhstan?", "Caspian"},
+		{"What is the smallest country in the world?", "Vatican"},
+	},
+	"history": {
+		{"In which year did Kazakhstan declare independence?", "1991"},
+		{"In which year did the Second World War end?", "1945"},
+		{"Who was the first man in space?", "Gagarin"},
+		{"From which cosmodrome was the first man launched into space?", "Baikonur"},
+		{"In which year did the Berlin Wall fall?", "1989"},
+		{"Which empire built Machu Picchu?", "Inca"},
+		{"Who was the first president of the United States?", "Washington"},
+		{"In which year did humans first land on the Moon?", "1969"},
+		{"Which ancient city was buried by Vesuvius?", "Pompeii"},
+		{"Who wrote the Book of Words?", "Abai"},
+	},
+	"science": {
+		{"What is the chemical symbol of gold?", "Au"},
+		{"What is the chemical symbol of iron?", "Fe"},
+		{"How many planets are in the solar system?", "8"},
+		{"What planet is known as the red planet?", "Mars"},
+		{"What gas do plants absorb?", "CO2"},
+		{"What is the hardest natural substance?", "Diamond"},
+		{"What is the boiling point of water in Celsius?", "100"},
+		{"What particle has a negative charge?", "Electron"},
+		{"What is the largest planet?", "Jupiter"},
+		{"How many bones does an adult human have?", "206"},
+	},
+	"programming": {
+		{"Which company created Go?", "Google"},
+		{"What does SQL stand for?", "Structured Query Language"},
+		{"What is the HTTP status code for Not Found?", "404"},
+		{"What is the HTTP status code for Too Many Requests?", "429"},
+		{"Which keyword starts a goroutine?", "go"},
+		{"What does JSON stand for?", "JavaScript Object Notation"},
+		{"Which data structure is first in, first out?", "Queue"},
+		{"Which data structure is last in, first out?", "Stack"},
+		{"What is the default port of PostgreSQL?", "5432"},
+		{"Who created Linux?", "Torvalds"},
+	},
+	"literature": {
+		{"Who wrote War and Peace?", "Tolstoy"},
+		{"Who wrote Hamlet?", "Shakespeare"},
+		{"Who wrote The Path of Abai?", "Auezov"},
+		{"Who wrote Crime and Punishment?", "Dostoevsky"},
+		{"Who wrote 1984?", "Orwell"},
+		{"Who wrote Don Quixote?", "Cervantes"},
+		{"Who wrote The Little Prince?", "Saint-Exupery"},
+		{"Who wrote Eugene Onegin?", "Pushkin"},
+	},
+	"music": {
+		{"How many strings does a dombra have?", "2"},
+		{"How many keys does a standard piano have?", "88"},
+		{"Who composed the Moonlight Sonata?", "Beethoven"},
+		{"Who composed The Nutcracker?", "Tchaikovsky"},
+		{"How many lines does a musical staff have?", "5"},
+		{"Who composed The Magic Flute?", "Mozart"},
+		{"Which Kazakh composer wrote the kui Adai?", "Kurmangazy"},
+	},
+	"sports": {
+		{"How many players does a football team have on the field?", "11"},
+		{"How many rings are on the Olympic flag?", "5"},
+		{"Which country hosted the 2011 Asian Winter Games?", "Kazakhstan"},
+		{"How long is a marathon in kilometres, rounded?", "42"},
+		{"How many points is a basketball shot from beyond the arc?", "3"},
+		{"Which sport is played at Wimbledon?", "Tennis"},
+		{"How many players does a volleyball team have on the court?", "6"},
+	},
+}
+
+// categories are the quiz categories, in a fixed order so that the data doesn't depend on map
+// iteration.
+var categories = []string{"math", "geography", "history", "science", "programming", "literature", "music", "sports"}
+
+// personName returns a random first and last name.
+func personName(r *rand.Rand) (string, string) {
+	return firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))]
+}
+
+// mathQuestion generates an arithmetic question.
+func mathQuestion(r *rand.Rand) question {
+	a, b := 2+r.Intn(48), 2+r.Intn(48)
+
+	switch r.Intn(3) {
+	case 0:
+		return question{fmt.Sprintf("What is %d + %d?", a, b), fmt.Sprint(a + b)}
+	case 1:
+		return question{fmt.Sprintf("What is %d - %d?", a+b, b), fmt.Sprint(a)}
+	default:
+		a, b = a%13, b%13
+		return question{fmt.Sprintf("What is %d × %d?", a, b), fmt.Sprint(a * b)}
+	}
+}
+
+// quizQuestions returns between three and five distinct questions of a category.
+func quizQuestions(r *rand.Rand, category string) ([]string, []string) {
+	n := 3 + r.Intn(3)
+
+	var picked []question
+	if bank := questionBanks[category]; bank != nil {
+		for _, i := range r.Perm(len(bank))[:n] {
+			picked = append(picked, bank[i])
+		}
+	} else {
+		seen := make(map[string]bool)
+		for len(picked) < n {
+			q := mathQuestion(r)
+			if !seen[q.text] {
+				seen[q.text] = true
+				picked = append(picked, q)
+			}
+		}
+	}
+
+	questions := make([]string, len(picked))
+	answers := make([]string, len(picked))
+	for i, q := range picked {
+		questions[i], answers[i] = q.text, q.answer
+	}
+
+	return questions, answers
+}
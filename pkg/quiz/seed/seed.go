@@ -0,0 +1,346 @@
+// Package seed fills the database with generated users, players, quizes and games for demos and
+// load testing. The data only depends on the seed and the sizes, and seeding is idempotent:
+// running it again with the same parameters only creates what is missing, so it can run on every
+// start of the application.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/margulan-kalykul/JustQuiz/pkg/quiz/model"
+)
+
+// Password is the password of every seeded user.
+const Password = "justquiz-demo"
+
+// Config holds the seed and the sizes of the generated data. Games is the total number of games,
+// spread randomly over the players and quizes.
+type Config struct {
+	Seed    int64
+	Users   int
+	Players int
+	Quizes  int
+	Games   int
+}
+
+// DefaultConfig is a data set large enough to click through the API.
+var DefaultConfig = Config{Seed: 1, Users: 20, Players: 50, Quizes: 40, Games: 300}
+
+// Counts counts records of each kind.
+type Counts struct {
+	Users   int
+	Players int
+	Quizes  int
+	Games   int
+}
+
+// Result tells how many of the generated records Run created and how many already existed.
+type Result struct {
+	Created  Counts
+	Existing Counts
+}
+
+type (
+	user struct {
+		name, email, role string
+	}
+
+	quiz struct {
+		category           string
+		reward             int
+		questions, answers []string
+		// owner is the index of the author who created the quiz, or -1.
+		owner int
+	}
+
+	// pair is a player and a quiz they played, by index.
+	pair struct {
+		player, quiz int
+	}
+
+	// data is everything generated from a Config.
+	data struct {
+		users   []user
+		players []string
+		quizes  []quiz
+		games   map[pair]int
+	}
+)
+
+// generate generates the data of cfg. Everything is drawn from a single source in a fixed order,
+// so that the same cfg always gives the same data.
+func generate(cfg Config) data {
+	r := rand.New(rand.NewSource(cfg.Seed))
+
+	var d data
+	var authors []int
+
+	for i := 0; i < cfg.Users; i++ {
+		first, last := personName(r)
+
+		// Every fourth user writes quizes.
+		role := model.RolePlayer
+		if i%4 == 0 {
+			role = model.RoleAuthor
+			authors = append(authors, i)
+		}
+
+		d.users = append(d.users, user{
+			name:  first + " " + last,
+			email: fmt.Sprintf("%s.%s+%d.%d@example.com", strings.ToLower(first), strings.ToLower(last), cfg.Seed, i),
+			role:  role,
+		})
+	}
+
+	// The seed and the index keep the names of players apart, their names aren't unique otherwise.
+	for i := 0; i < cfg.Players; i++ {
+		first, last := personName(r)
+		d.players = append(d.players, fmt.Sprintf("%s %s #%d-%d", first, last, cfg.Seed, i))
+	}
+
+	// Quizes are found by their category and questions, so the same quiz isn't generated twice.
+	seen := make(map[string]bool)
+	for i := 0; i < cfg.Quizes; i++ {
+		q := quiz{category: categories[r.Intn(len(categories))], reward: 5 * (1 + r.Intn(10)), owner: -1}
+		q.questions, q.answers = quizQuestions(r, q.category)
+		if len(authors) > 0 {
+			q.owner = authors[r.Intn(len(authors))]
+		}
+
+		if key := quizKey(q.category, q.questions); !seen[key] {
+			seen[key] = true
+			d.quizes = append(d.quizes, q)
+		}
+	}
+
+	d.games = make(map[pair]int)
+	if len(d.players) > 0 && len(d.quizes) > 0 {
+		for i := 0; i < cfg.Games; i++ {
+			d.games[pair{r.Intn(len(d.players)), r.Intn(len(d.quizes))}]++
+		}
+	}
+
+	return d
+}
+
+func quizKey(category string, questions []string) string {
+	return category + "\n" + strings.Join(questions, "\n")
+}
+
+// Run creates the records of cfg that don't exist yet. Users are found by their email address,
+// players by their name, quizes by their category and questions, and games are counted per
+// player and quiz. Like answering a quiz through the API, every game created adds the reward of
+// its quiz to the score of the player.
+func Run(ctx context.Context, models model.Models, cfg Config) (Result, error) {
+	if cfg.Users < 0 || cfg.Players < 0 || cfg.Quizes < 0 || cfg.Games < 0 {
+		return Result{}, errors.New("seed: sizes must not be negative")
+	}
+
+	d := generate(cfg)
+	s := &seeder{models: models}
+
+	userIDs, err := s.users(ctx, d.users)
+	if err != nil {
+		return s.result, err
+	}
+
+	players, err := s.players(ctx, d.players)
+	if err != nil {
+		return s.result, err
+	}
+
+	quizes, err := s.quizes(ctx, d.quizes, userIDs)
+	if err != nil {
+		return s.result, err
+	}
+
+	err = s.games(ctx, d.games, players, quizes)
+	return s.result, err
+}
+
+type seeder struct {
+	models model.Models
+	result Result
+
+	// password is hashed once for all users, bcrypt is slow on purpose.
+	password *model.User
+}
+
+func (s *seeder) users(ctx context.Context, users []user) ([]int64, error) {
+	ids := make([]int64, len(users))
+
+	for i, u := range users {
+		existing, err := s.models.Users.GetByEmail(ctx, u.email)
+		switch {
+		case err == nil:
+			ids[i] = existing.ID
+			s.result.Existing.Users++
+		case errors.Is(err, model.ErrRecordNotFound):
+			if s.password == nil {
+				s.password = &model.User{}
+				if err := s.password.Password.Set(Password); err != nil {
+					return nil, err
+				}
+			}
+
+			created := &model.User{Name: u.name, Email: u.email, Password: s.password.Password, Activated: true}
+			if err := s.models.Users.Insert(ctx, created); err != nil {
+				return nil, fmt.Errorf("seed: user %s: %w", u.email, err)
+			}
+			ids[i] = created.ID
+			s.result.Created.Users++
+		default:
+			return nil, err
+		}
+
+		// Granted every time, in case an earlier run stopped right after creating the user.
+		if s.models.Roles != nil {
+			if err := s.models.Roles.AddForUser(ctx, ids[i], u.role); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+func (s *seeder) players(ctx context.Context, names []string) ([]*model.Player, error) {
+	players := make([]*model.Player, len(names))
+	filters := model.Filters{Page: 1, PageSize: 1, Sort: "id", SortSafeList: []string{"id"}}
+
+	for i, name := range names {
+		existing, _, err := s.models.Players.GetAll(ctx, name, 0, 0, model.TimeRange{}, filters)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(existing) > 0 {
+			players[i] = existing[0]
+			s.result.Existing.Players++
+			continue
+		}
+
+		players[i] = &model.Player{Name: name}
+		if err := s.models.Players.Insert(ctx, players[i]); err != nil {
+			return nil, fmt.Errorf("seed: player %s: %w", name, err)
+		}
+		s.result.Created.Players++
+	}
+
+	return players, nil
+}
+
+func (s *seeder) quizes(ctx context.Context, quizes []quiz, userIDs []int64) ([]*model.Quiz, error) {
+	existing := make(map[string]*model.Quiz)
+	for _, category := range categories {
+		if err := s.listQuizes(ctx, category, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	created := make([]*model.Quiz, len(quizes))
+
+	for i, q := range quizes {
+		if found, ok := existing[quizKey(q.category, q.questions)]; ok {
+			created[i] = found
+			s.result.Existing.Quizes++
+			continue
+		}
+
+		created[i] = &model.Quiz{Category: q.category, Reward: q.reward, Questions: q.questions, Answers: q.answers}
+		if q.owner >= 0 {
+			created[i].OwnerID = &userIDs[q.owner]
+		}
+
+		if err := s.models.Quizes.Insert(ctx, created[i]); err != nil {
+			return nil, fmt.Errorf("seed: %s quiz: %w", q.category, err)
+		}
+		s.result.Created.Quizes++
+	}
+
+	return created, nil
+}
+
+// listQuizes adds the quizes of a category to quizes, by their key.
+func (s *seeder) listQuizes(ctx context.Context, category string, quizes map[string]*model.Quiz) error {
+	filters := model.Filters{Page: 1, PageSize: 100, Sort: "id", SortSafeList: []string{"id"}}
+
+	for {
+		page, metadata, err := s.models.Quizes.GetAll(ctx, category, 0, 0, filters)
+		if err != nil {
+			return err
+		}
+
+		for _, q := range page {
+			quizes[quizKey(q.Category, q.Questions)] = q
+		}
+
+		if filters.Page >= metadata.LastPage {
+			return nil
+		}
+		filters.Page++
+	}
+}
+
+func (s *seeder) games(ctx context.Context, games map[pair]int, players []*model.Player, quizes []*model.Quiz) error {
+	// Go through the pairs in order, so that the games get the same ids every time.
+	pairs := make([]pair, 0, len(games))
+	for p := range games {
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].player != pairs[j].player {
+			return pairs[i].player < pairs[j].player
+		}
+		return pairs[i].quiz < pairs[j].quiz
+	})
+
+	filters := model.Filters{Page: 1, PageSize: 1, Sort: "id", SortSafeList: []string{"id"}}
+	scored := make(map[*model.Player]bool)
+
+	for _, p := range pairs {
+		player, quiz := players[p.player], quizes[p.quiz]
+
+		playerID, err := strconv.Atoi(player.Id)
+		if err != nil {
+			return err
+		}
+		quizID, err := strconv.Atoi(quiz.Id)
+		if err != nil {
+			return err
+		}
+
+		_, metadata, err := s.models.Games.GetAll(ctx, playerID, quizID, false, model.TimeRange{}, filters)
+		if err != nil {
+			return err
+		}
+
+		existing := min(metadata.TotalRecords, games[p])
+		s.result.Existing.Games += existing
+
+		for i := existing; i < games[p]; i++ {
+			if err := s.models.Games.Insert(ctx, &model.Game{Player: playerID, Quiz: quizID}); err != nil {
+				return fmt.Errorf("seed: game of player %d: %w", playerID, err)
+			}
+			player.Score += quiz.Reward
+			scored[player] = true
+			s.result.Created.Games++
+		}
+	}
+
+	for _, player := range players {
+		if scored[player] {
+			if err := s.models.Players.Update(ctx, player); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
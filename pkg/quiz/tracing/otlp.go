@@ -0,0 +1,248 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exporter sends spans in batches to an OpenTelemetry collector, with the OTLP/HTTP protocol and
+// JSON encoding. Spans are dropped rather than slowing down requests when the collector can't
+// keep up.
+type Exporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+	onError  func(error)
+
+	spans chan *Span
+	flush chan chan struct{}
+	done  chan struct{}
+}
+
+// Batching of the export.
+const (
+	queueSize     = 2048
+	maxBatchSize  = 512
+	flushInterval = 5 * time.Second
+)
+
+// NewExporter starts an exporter sending to the collector at endpoint, e.g. http://localhost:4318,
+// under the service name. onError is called with export errors, it may be nil.
+func NewExporter(endpoint, service string, onError func(error)) *Exporter {
+	e := &Exporter{
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		onError:  onError,
+		spans:    make(chan *Span, queueSize),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go e.run()
+
+	return e
+}
+
+// export queues an ended span, or drops it if the queue is full.
+func (e *Exporter) export(s *Span) {
+	select {
+	case e.spans <- s:
+	default:
+	}
+}
+
+// Shutdown sends the queued spans and stops the exporter. Spans ending afterwards are dropped.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	flushed := make(chan struct{})
+
+	select {
+	case e.flush <- flushed:
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Exporter) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+
+	send := func() {
+		if len(batch) > 0 {
+			e.send(batch)
+			batch = nil
+		}
+	}
+
+	for {
+		select {
+		case s := <-e.spans:
+			batch = append(batch, s)
+			if len(batch) >= maxBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case flushed := <-e.flush:
+			// Drain what is queued, then stop.
+			for len(e.spans) > 0 {
+				batch = append(batch, <-e.spans)
+			}
+			send()
+			close(e.done)
+			close(flushed)
+			return
+		}
+	}
+}
+
+func (e *Exporter) send(batch []*Span) {
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		e.error(err)
+		return
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		e.error(err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e.error(fmt.Errorf("tracing: collector responded %s", resp.Status))
+	}
+}
+
+func (e *Exporter) error(err error) {
+	if e.onError != nil {
+		e.onError(err)
+	}
+}
+
+// The OTLP JSON encoding of an ExportTraceServiceRequest. IDs are hex encoded and 64-bit integers
+// are strings, as the OTLP/HTTP specification requires.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// Status codes of the OTLP Status message.
+const statusError = 2
+
+func (e *Exporter) request(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+
+	for _, s := range batch {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           s.traceID.String(),
+			SpanID:            s.spanID.String(),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        attributes(s.attributes),
+		}
+		if s.parentID.IsValid() {
+			span.ParentSpanID = s.parentID.String()
+		}
+		if s.errMessage != "" {
+			span.Status = otlpStatus{Code: statusError, Message: s.errMessage}
+		}
+		s.mu.Unlock()
+
+		spans = append(spans, span)
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: attributes(map[string]string{"service.name": e.service}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/margulan-kalykul/JustQuiz/pkg/quiz/tracing"},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func attributes(m map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		attrs = append(attrs, otlpAttribute{Key: key, Value: otlpValue{StringValue: m[key]}})
+	}
+
+	return attrs
+}
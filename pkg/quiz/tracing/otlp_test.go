@@ -0,0 +1,256 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector is an OTLP/HTTP collector which keeps the decoded export requests.
+type collector struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []map[string]any
+}
+
+func newCollector(t *testing.T) *collector {
+	t.Helper()
+
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" {
+			t.Errorf("got %s %s, want POST /v1/traces", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("got Content-Type %q, want application/json", got)
+		}
+
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding the export request: %v", err)
+		}
+
+		c.mu.Lock()
+		c.requests = append(c.requests, req)
+		c.mu.Unlock()
+	}))
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+// spans returns the spans of all export requests, checking the resource and scope they are
+// reported under.
+func (c *collector) spans(t *testing.T, service string) []map[string]any {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var spans []map[string]any
+	for _, req := range c.requests {
+		for _, rs := range req["resourceSpans"].([]any) {
+			rs := rs.(map[string]any)
+
+			attrs := rs["resource"].(map[string]any)["attributes"]
+			if got := attribute(attrs, "service.name"); got != service {
+				t.Errorf("got service.name %q, want %q", got, service)
+			}
+
+			for _, ss := range rs["scopeSpans"].([]any) {
+				ss := ss.(map[string]any)
+				if got := ss["scope"].(map[string]any)["name"]; got != "github.com/margulan-kalykul/JustQuiz/pkg/quiz/tracing" {
+					t.Errorf("got scope %q, want the tracing package", got)
+				}
+				for _, span := range ss["spans"].([]any) {
+					spans = append(spans, span.(map[string]any))
+				}
+			}
+		}
+	}
+
+	return spans
+}
+
+// attribute returns the string value of the key in a list of OTLP attributes.
+func attribute(attrs any, key string) string {
+	list, _ := attrs.([]any)
+	for _, attr := range list {
+		attr := attr.(map[string]any)
+		if attr["key"] == key {
+			value, _ := attr["value"].(map[string]any)["stringValue"].(string)
+			return value
+		}
+	}
+	return ""
+}
+
+func TestExportOTLP(t *testing.T) {
+	c := newCollector(t)
+
+	e := NewExporter(c.URL+"/", "justquiz-test", func(err error) { t.Error(err) })
+	SetExporter(e)
+	t.Cleanup(func() { SetExporter(nil) })
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	before := time.Now()
+	ctx, server := StartServer(context.Background(), "GET", traceparent)
+	server.SetName("GET /v1/players/{id}")
+	server.SetAttribute("http.status_code", "200")
+
+	_, query := Start(ctx, "PlayerModel.Get")
+	query.SetAttribute("db.system", "postgresql")
+	query.SetError(errors.New("record not found"))
+	query.End()
+	server.End()
+	server.End()
+	after := time.Now()
+
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := c.spans(t, "justquiz-test")
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	got, want := spans[0], spans[1]
+	if got["name"] != "PlayerModel.Get" {
+		got, want = want, got
+	}
+
+	// The query, a child of the request.
+	if got["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" || got["parentSpanId"] != server.SpanID().String() {
+		t.Errorf("got trace %v and parent %v, want the request's", got["traceId"], got["parentSpanId"])
+	}
+	if got["spanId"] != query.SpanID().String() || got["kind"] != float64(KindInternal) {
+		t.Errorf("got span %v of kind %v, want %s of kind %d", got["spanId"], got["kind"], query.SpanID(), KindInternal)
+	}
+	if status := got["status"].(map[string]any); status["code"] != float64(statusError) || status["message"] != "record not found" {
+		t.Errorf("got status %v, want the error", status)
+	}
+	if attr := attribute(got["attributes"], "db.system"); attr != "postgresql" {
+		t.Errorf("got db.system %q, want postgresql", attr)
+	}
+
+	// The request, continuing the caller's trace.
+	if want["name"] != "GET /v1/players/{id}" || want["kind"] != float64(KindServer) {
+		t.Errorf("got name %v of kind %v, want the route of kind %d", want["name"], want["kind"], KindServer)
+	}
+	if want["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" || want["parentSpanId"] != "00f067aa0ba902b7" {
+		t.Errorf("got trace %v and parent %v, want the traceparent's", want["traceId"], want["parentSpanId"])
+	}
+	if status := want["status"].(map[string]any); len(status) != 0 {
+		t.Errorf("got status %v, want an unset status", status)
+	}
+
+	// Timestamps are nanoseconds encoded as strings, as 64-bit integers in OTLP JSON.
+	for _, span := range spans {
+		start, err := strconv.ParseInt(span["startTimeUnixNano"].(string), 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		end, err := strconv.ParseInt(span["endTimeUnixNano"].(string), 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if start < before.UnixNano() || end < start || end > after.UnixNano() {
+			t.Errorf("got span %v from %d to %d, want within %d and %d", span["name"], start, end, before.UnixNano(), after.UnixNano())
+		}
+	}
+}
+
+func TestExportRootSpan(t *testing.T) {
+	c := newCollector(t)
+
+	e := NewExporter(c.URL, "justquiz-test", func(err error) { t.Error(err) })
+	SetExporter(e)
+	t.Cleanup(func() { SetExporter(nil) })
+
+	_, span := StartServer(context.Background(), "GET /v1/healthcheck", "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	span.End()
+
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := c.spans(t, "justquiz-test")
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if _, ok := spans[0]["parentSpanId"]; ok {
+		t.Errorf("got parent %v, want a root span for an invalid traceparent", spans[0]["parentSpanId"])
+	}
+	if got := spans[0]["traceId"]; got != span.TraceID().String() || len(got.(string)) != 32 {
+		t.Errorf("got trace %v, want a new one", got)
+	}
+	if _, ok := spans[0]["attributes"]; ok {
+		t.Errorf("got attributes %v, want them left out", spans[0]["attributes"])
+	}
+}
+
+func TestExportCollectorError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	var errs []error
+	e := NewExporter(srv.URL, "justquiz-test", func(err error) { errs = append(errs, err) })
+	SetExporter(e)
+	t.Cleanup(func() { SetExporter(nil) })
+
+	_, span := Start(context.Background(), "job")
+	span.End()
+
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("got errors %v, want the collector's status", errs)
+	}
+
+	// Spans ending after the shutdown are dropped without blocking.
+	_, span = Start(context.Background(), "job")
+	span.End()
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header string
+		valid  bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{" 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00 ", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		traceID, spanID, ok := parseTraceparent(tt.header)
+		if ok != tt.valid {
+			t.Errorf("got valid %t for %q, want %t", ok, tt.header, tt.valid)
+			continue
+		}
+		if ok && (traceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || spanID.String() != "00f067aa0ba902b7") {
+			t.Errorf("got %s and %s for %q", traceID, spanID, tt.header)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package tracing records spans of requests and the queries they run, and exports them to an
+// OpenTelemetry collector. It implements the small part of OpenTelemetry the API needs: W3C trace
+// context propagation and the OTLP/HTTP JSON export, without pulling in the OpenTelemetry SDK.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kinds of spans, with the values of the OTLP SpanKind enum.
+const (
+	KindInternal = 1
+	KindServer   = 2
+)
+
+// TraceID identifies a trace, all spans of a request share it.
+type TraceID [16]byte
+
+// String returns the trace ID as lowercase hex, the form used in headers and logs.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the trace ID isn't all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// String returns the span ID as lowercase hex.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the span ID isn't all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// Span is a timed operation, such as a request or a query. Spans are always created, so that
+// trace IDs can be logged, but only exported if an exporter is set.
+type Span struct {
+	traceID  TraceID
+	spanID   SpanID
+	parentID SpanID
+	kind     int
+	start    time.Time
+
+	mu         sync.Mutex
+	name       string
+	end        time.Time
+	attributes map[string]string
+	errMessage string
+	ended      bool
+}
+
+// TraceID returns the ID of the trace the span belongs to.
+func (s *Span) TraceID() TraceID {
+	return s.traceID
+}
+
+// SpanID returns the ID of the span.
+func (s *Span) SpanID() SpanID {
+	return s.spanID
+}
+
+// SetName replaces the name of the span, for names only known once the operation ran, like the
+// route of a request.
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.name = name
+}
+
+// SetAttribute adds an attribute to the span, replacing any earlier value of the key.
+func (s *Span) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.attributes == nil {
+		s.attributes = make(map[string]string)
+	}
+	s.attributes[key] = value
+}
+
+// SetError marks the span as failed. A nil err is ignored.
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errMessage = err.Error()
+}
+
+// End records the end time of the span and hands it to the exporter. Calls after the first one
+// have no effect.
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if e := exporter.Load(); e != nil {
+		e.export(s)
+	}
+}
+
+// Traceparent returns the value of the W3C traceparent header which continues the trace with the
+// span as the parent.
+func (s *Span) Traceparent() string {
+	return "00-" + s.traceID.String() + "-" + s.spanID.String() + "-01"
+}
+
+type spanContextKey struct{}
+
+// FromContext returns the span in ctx, or nil if there is none.
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// Start starts a span which is a child of the span in ctx, or the root of a new trace if there
+// is none. The returned context holds the new span. The caller must call End on it.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	span := &Span{name: name, kind: KindInternal, start: time.Now(), spanID: newSpanID()}
+
+	if parent := FromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		span.traceID = newTraceID()
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// StartServer starts the span of an incoming request. If traceparent is a valid W3C traceparent
+// header the span continues the caller's trace, otherwise it starts a new one.
+func StartServer(ctx context.Context, name, traceparent string) (context.Context, *Span) {
+	span := &Span{name: name, kind: KindServer, start: time.Now(), spanID: newSpanID()}
+
+	if traceID, parentID, ok := parseTraceparent(traceparent); ok {
+		span.traceID = traceID
+		span.parentID = parentID
+	} else {
+		span.traceID = newTraceID()
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// parseTraceparent parses a traceparent header of the form
+// "00-<32 hex trace id>-<16 hex parent id>-<2 hex flags>". Later versions may append fields.
+func parseTraceparent(header string) (TraceID, SpanID, bool) {
+	var traceID TraceID
+	var spanID SpanID
+
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return traceID, spanID, false
+	}
+
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, spanID, false
+	}
+
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil {
+		return traceID, spanID, false
+	}
+	if _, err := hex.Decode(spanID[:], []byte(parts[2])); err != nil {
+		return traceID, spanID, false
+	}
+
+	return traceID, spanID, traceID.IsValid() && spanID.IsValid()
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
+
+// exporter receives the ended spans, spans are dropped while it is nil.
+var exporter atomic.Pointer[Exporter]
+
+// SetExporter makes e receive all spans that end from now on. A nil e stops the export.
+func SetExporter(e *Exporter) {
+	exporter.Store(e)
+}
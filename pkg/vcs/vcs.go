@@ -5,32 +5,43 @@ import (
 	"runtime/debug"
 )
 
-func Version() string {
-	var (
-		time     string
-		revision string
-		modified bool
-	)
+// BuildInfo describes the commit the binary was built from.
+type BuildInfo struct {
+	Revision string
+	Time     string
+	Modified bool
+}
+
+// Info reads the commit from the build information, its fields are empty if it wasn't built from
+// a repository.
+func Info() BuildInfo {
+	var info BuildInfo
 
 	bi, ok := debug.ReadBuildInfo()
 	if ok {
 		for _, s := range bi.Settings {
 			switch s.Key {
 			case "vcs.time":
-				time = s.Value
+				info.Time = s.Value
 			case "vcs.revision":
-				revision = s.Value
+				info.Revision = s.Value
 			case "vcs.modified":
 				if s.Value == "true" {
-					modified = true
+					info.Modified = true
 				}
 			}
 		}
 	}
 
-	if modified {
-		return fmt.Sprintf("%s-%s-dirty", time, revision)
+	return info
+}
+
+func Version() string {
+	info := Info()
+
+	if info.Modified {
+		return fmt.Sprintf("%s-%s-dirty", info.Time, info.Revision)
 	}
 
-	return fmt.Sprintf("%s-%s", time, revision)
-}
\ No newline at end of file
+	return fmt.Sprintf("%s-%s", info.Time, info.Revision)
+}